@@ -184,6 +184,16 @@ var (
 		utils.LegacyWSApiFlag,
 		utils.WSAllowedOriginsFlag,
 		utils.LegacyWSAllowedOriginsFlag,
+		utils.RPCJWTSecretFlag,
+		utils.RPCRateLimitFlag,
+		utils.RPCBatchLimitFlag,
+		utils.RPCConcurrencyLimitFlag,
+		utils.RPCResponseLimitFlag,
+		utils.RPCTLSCertFlag,
+		utils.RPCTLSKeyFlag,
+		utils.RPCTLSClientCAFlag,
+		utils.RPCCallTimeoutFlag,
+		utils.RPCNamespaceTimeoutsFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
 		utils.InsecureUnlockAllowedFlag,
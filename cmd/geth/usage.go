@@ -141,6 +141,16 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.WSPortFlag,
 			utils.WSApiFlag,
 			utils.WSAllowedOriginsFlag,
+			utils.RPCJWTSecretFlag,
+			utils.RPCRateLimitFlag,
+			utils.RPCBatchLimitFlag,
+			utils.RPCConcurrencyLimitFlag,
+			utils.RPCResponseLimitFlag,
+			utils.RPCTLSCertFlag,
+			utils.RPCTLSKeyFlag,
+			utils.RPCTLSClientCAFlag,
+			utils.RPCCallTimeoutFlag,
+			utils.RPCNamespaceTimeoutsFlag,
 			utils.GraphQLEnabledFlag,
 			utils.GraphQLCORSDomainFlag,
 			utils.GraphQLVirtualHostsFlag,
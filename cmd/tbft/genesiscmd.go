@@ -0,0 +1,157 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"ethereum/rpc-network/core"
+	"ethereum/rpc-network/crypto"
+	"ethereum/rpc-network/params"
+	"gopkg.in/urfave/cli.v1"
+)
+
+const jsonIndent = "    "
+
+var genesisCommand = cli.Command{
+	Name:      "genesis",
+	Usage:     "Writes the committee of the given validators into a genesis file",
+	ArgsUsage: "<keyfile|pubkey>...",
+	Description: `Validators are given as key files or as hex encoded uncompressed public
+keys, so a genesis can be built without holding the keys of the other
+validators. Without --base a developer genesis funding the first validator
+is written.`,
+	Action: genesis,
+	Flags:  []cli.Flag{baseGenesisFlag, outFlag, epochFlag, committeeSizeFlag},
+}
+
+var (
+	baseGenesisFlag = cli.StringFlag{
+		Name:  "base",
+		Usage: "Genesis file to add the committee to",
+	}
+	outFlag = cli.StringFlag{
+		Name:  "out",
+		Usage: "Output file, - for stdout",
+		Value: "-",
+	}
+	epochFlag = cli.Uint64Flag{
+		Name:  "epoch",
+		Usage: "Number of blocks a committee finalizes before rotating",
+		Value: 10000,
+	}
+	committeeSizeFlag = cli.Uint64Flag{
+		Name:  "committee-size",
+		Usage: "Maximum number of working members, the rest are backups (0 = unlimited)",
+	}
+)
+
+func genesis(ctx *cli.Context) error {
+	if ctx.NArg() < params.MinimumCommitteeNumber {
+		return fmt.Errorf("need at least %d validators", params.MinimumCommitteeNumber)
+	}
+	pubs := make([]*ecdsa.PublicKey, ctx.NArg())
+	for i, arg := range ctx.Args() {
+		pub, err := loadValidator(arg)
+		if err != nil {
+			return err
+		}
+		pubs[i] = pub
+	}
+	var base *core.Genesis
+	if file := ctx.String(baseGenesisFlag.Name); file != "" {
+		base = new(core.Genesis)
+		if err := readJSON(file, base); err != nil {
+			return err
+		}
+	}
+	gen, err := makeGenesis(base, pubs, ctx.Uint64(epochFlag.Name), ctx.Uint64(committeeSizeFlag.Name))
+	if err != nil {
+		return err
+	}
+	return writeJSON(ctx.String(outFlag.Name), gen)
+}
+
+// loadValidator returns the public key in arg, a hex public key or a key file.
+func loadValidator(arg string) (*ecdsa.PublicKey, error) {
+	if _, err := os.Stat(arg); err == nil {
+		key, err := crypto.LoadECDSA(arg)
+		if err != nil {
+			return nil, err
+		}
+		return &key.PublicKey, nil
+	}
+	blob, err := hex.DecodeString(strings.TrimPrefix(arg, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("%s is neither a key file nor a public key", arg)
+	}
+	return crypto.UnmarshalPubkey(blob)
+}
+
+// makeGenesis sets the election of base to the committee of pubs, base being
+// a developer genesis funding the first validator if nil.
+func makeGenesis(base *core.Genesis, pubs []*ecdsa.PublicKey, epoch, size uint64) (*core.Genesis, error) {
+	if len(pubs) == 0 {
+		return nil, errors.New("no validators")
+	}
+	if base == nil {
+		base = core.DeveloperGenesisBlock(0, crypto.PubkeyToAddress(*pubs[0]))
+	}
+	if base.Config == nil {
+		return nil, errors.New("base genesis has no chain config")
+	}
+	config := *base.Config
+	config.Election = &params.ElectionConfig{
+		Epoch:         epoch,
+		CommitteeSize: size,
+	}
+	for _, pub := range pubs {
+		config.Election.Validators = append(config.Election.Validators, crypto.FromECDSAPub(pub))
+	}
+	base.Config = &config
+	return base, nil
+}
+
+func readJSON(file string, v interface{}) error {
+	blob, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(blob, v); err != nil {
+		return fmt.Errorf("invalid %s: %v", file, err)
+	}
+	return nil
+}
+
+func writeJSON(file string, v interface{}) error {
+	blob, err := json.MarshalIndent(v, "", jsonIndent)
+	if err != nil {
+		return err
+	}
+	if file == "-" {
+		_, err = os.Stdout.Write(append(blob, '\n'))
+		return err
+	}
+	return ioutil.WriteFile(file, blob, 0644)
+}
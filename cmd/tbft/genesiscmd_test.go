@@ -0,0 +1,85 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"testing"
+
+	"ethereum/rpc-network/core"
+	"ethereum/rpc-network/crypto"
+	"ethereum/rpc-network/params"
+)
+
+func testPubs(t *testing.T, n int) []*ecdsa.PublicKey {
+	pubs := make([]*ecdsa.PublicKey, n)
+	for i := range pubs {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pubs[i] = &key.PublicKey
+	}
+	return pubs
+}
+
+func TestMakeGenesis(t *testing.T) {
+	pubs := testPubs(t, 4)
+
+	gen, err := makeGenesis(nil, pubs, 100, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	election := gen.Config.Election
+	if election == nil || election.Epoch != 100 || election.CommitteeSize != 3 {
+		t.Fatalf("election config mismatch: %+v", election)
+	}
+	if len(election.Validators) != len(pubs) {
+		t.Fatalf("have %d validators, want %d", len(election.Validators), len(pubs))
+	}
+	for i, pub := range pubs {
+		if !bytes.Equal(election.Validators[i], crypto.FromECDSAPub(pub)) {
+			t.Errorf("validator %d mismatch", i)
+		}
+	}
+	// the developer genesis funds the first validator
+	if _, ok := gen.Alloc[crypto.PubkeyToAddress(*pubs[0])]; !ok {
+		t.Error("first validator not funded")
+	}
+
+	// the chain config of a base genesis is copied, not changed
+	chainConfig := *params.AllEthashProtocolChanges
+	base := &core.Genesis{Config: &chainConfig}
+	gen, err = makeGenesis(base, pubs, 100, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chainConfig.Election != nil {
+		t.Error("chain config of the base genesis changed")
+	}
+	if gen.Config.ChainID.Cmp(chainConfig.ChainID) != 0 || gen.Config.Election == nil {
+		t.Errorf("chain config not carried over: %v", gen.Config)
+	}
+
+	if _, err := makeGenesis(&core.Genesis{}, pubs, 100, 0); err == nil {
+		t.Error("base genesis without chain config accepted")
+	}
+	if _, err := makeGenesis(nil, nil, 100, 0); err == nil {
+		t.Error("genesis without validators accepted")
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+
+	tcrypto "ethereum/rpc-network/consensus/tbft/crypto"
+	"ethereum/rpc-network/consensus/tbft/tp2p"
+	"ethereum/rpc-network/crypto"
+	"ethereum/rpc-network/p2p/enode"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	keyCommand = cli.Command{
+		Name:  "key",
+		Usage: "Operations on validator keys",
+		Subcommands: []cli.Command{
+			keyGenerateCommand,
+			keyInspectCommand,
+		},
+	}
+	keyGenerateCommand = cli.Command{
+		Name:      "generate",
+		Usage:     "Generates validator key files",
+		ArgsUsage: "keyfile...",
+		Action:    genkeys,
+	}
+	keyInspectCommand = cli.Command{
+		Name:      "inspect",
+		Usage:     "Prints the public key, address, tbft ID and enode of a validator key",
+		ArgsUsage: "keyfile",
+		Action:    inspectKey,
+		Flags:     []cli.Flag{hostFlag, portFlag},
+	}
+)
+
+var (
+	hostFlag = cli.StringFlag{
+		Name:  "ip",
+		Usage: "IP address of the node",
+		Value: "127.0.0.1",
+	}
+	portFlag = cli.IntFlag{
+		Name:  "port",
+		Usage: "TCP port of the node",
+		Value: 28890,
+	}
+)
+
+func genkeys(ctx *cli.Context) error {
+	if ctx.NArg() == 0 {
+		return fmt.Errorf("need key files as arguments")
+	}
+	for _, file := range ctx.Args() {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			return fmt.Errorf("could not generate key: %v", err)
+		}
+		if err := crypto.SaveECDSA(file, key); err != nil {
+			return err
+		}
+		fmt.Printf("%s: %s\n", file, tbftID(&key.PublicKey))
+	}
+	return nil
+}
+
+func inspectKey(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("need key file as argument")
+	}
+	key, err := crypto.LoadECDSA(ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(ctx.String(hostFlag.Name))
+	if ip == nil {
+		return fmt.Errorf("invalid IP address %q", ctx.String(hostFlag.Name))
+	}
+	port := ctx.Int(portFlag.Name)
+	fmt.Println("Public key:", fmt.Sprintf("%x", crypto.FromECDSAPub(&key.PublicKey)))
+	fmt.Println("Address:   ", crypto.PubkeyToAddress(key.PublicKey).Hex())
+	fmt.Println("tbft ID:   ", tbftID(&key.PublicKey))
+	fmt.Println("enode:     ", enode.NewV4(&key.PublicKey, ip, port, port).URLv4())
+	return nil
+}
+
+// tbftID returns the tp2p ID of the validator holding pub.
+func tbftID(pub *ecdsa.PublicKey) tp2p.ID {
+	return tp2p.PubKeyToID(tcrypto.PubKeyTrue(*pub))
+}
+
+// saveBLSKey writes the hex encoded BLS key to file.
+func saveBLSKey(file string, key tcrypto.PrivKeyBLS) error {
+	return ioutil.WriteFile(file, []byte(hex.EncodeToString(key[:])), 0600)
+}
+
+// loadBLSKey reads a BLS key written by saveBLSKey.
+func loadBLSKey(file string) (tcrypto.PrivKeyBLS, error) {
+	var key tcrypto.PrivKeyBLS
+	blob, err := ioutil.ReadFile(file)
+	if err != nil {
+		return key, err
+	}
+	raw, err := hex.DecodeString(strings.TrimSpace(string(blob)))
+	if err != nil || len(raw) != len(key) {
+		return key, fmt.Errorf("invalid BLS key file %s", file)
+	}
+	copy(key[:], raw)
+	return key, nil
+}
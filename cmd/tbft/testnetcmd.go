@@ -0,0 +1,491 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"ethereum/rpc-network/consensus/election"
+	"ethereum/rpc-network/consensus/ethash"
+	"ethereum/rpc-network/consensus/tbft"
+	tcrypto "ethereum/rpc-network/consensus/tbft/crypto"
+	ttypes "ethereum/rpc-network/consensus/tbft/types"
+	"ethereum/rpc-network/core"
+	"ethereum/rpc-network/core/rawdb"
+	"ethereum/rpc-network/core/types"
+	"ethereum/rpc-network/core/vm"
+	"ethereum/rpc-network/crypto"
+	"ethereum/rpc-network/miner"
+	"ethereum/rpc-network/params"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	testnetCommand = cli.Command{
+		Name:  "testnet",
+		Usage: "Operations on local testnets",
+		Subcommands: []cli.Command{
+			testnetInitCommand,
+			testnetRunCommand,
+		},
+	}
+	testnetInitCommand = cli.Command{
+		Name:      "init",
+		Usage:     "Generates the keys, genesis, committee and node configs of a testnet",
+		ArgsUsage: "<dir>",
+		Action:    testnetInit,
+		Flags:     []cli.Flag{nodesFlag, hostFlag, portFlag, chainIDFlag, epochFlag, healthEvictFlag, healthTimeoutFlag, healthGraceFlag},
+	}
+	testnetRunCommand = cli.Command{
+		Name:      "run",
+		Usage:     "Runs the nodes of a testnet until interrupted",
+		ArgsUsage: "<dir>",
+		Description: `Every node runs a chain of genesis.json in its directory. The election
+of the genesis hands the committees the node is a member of to its tbft node,
+whose agent proposes the transactions of the node's pool and writes the
+committed blocks into the chain. With --node a subset of the nodes runs, so
+the testnet can be spread over several processes or machines.`,
+		Action: testnetRun,
+		Flags:  []cli.Flag{nodeFlag},
+	}
+)
+
+// minerConfig packs the proposals of the testnet nodes.
+var minerConfig = &miner.Config{
+	GasFloor: params.GenesisGasLimit,
+	GasCeil:  params.GenesisGasLimit,
+	Recommit: 3 * time.Second,
+}
+
+var (
+	nodesFlag = cli.IntFlag{
+		Name:  "nodes",
+		Usage: "Number of validators",
+		Value: params.MinimumCommitteeNumber,
+	}
+	chainIDFlag = cli.StringFlag{
+		Name:  "chainid",
+		Usage: "Chain ID signed into the votes",
+		Value: "tbft-dev",
+	}
+	nodeFlag = cli.IntSliceFlag{
+		Name:  "node",
+		Usage: "Index of a node to run (default all)",
+	}
+	healthEvictFlag = cli.BoolFlag{
+		Name:  "health.evict",
+		Usage: "Propose switches rotating out the members which are down",
+	}
+	healthTimeoutFlag = cli.IntFlag{
+		Name:  "health.timeout",
+		Usage: "Seconds without a message after which a member is down",
+		Value: int(ttypes.DefaultHealthPolicy().Timeout),
+	}
+	healthGraceFlag = cli.IntFlag{
+		Name:  "health.grace",
+		Usage: "Further seconds before a node proposes to rotate a member out",
+		Value: int(ttypes.DefaultHealthPolicy().Grace),
+	}
+)
+
+// committeeFile is the committee of a testnet.
+type committeeFile struct {
+	ChainID string          `json:"chainId"`
+	ID      uint64          `json:"id"`
+	Members []*memberConfig `json:"members"`
+}
+
+// memberConfig is a member of the committee and where it is reached.
+type memberConfig struct {
+	PubKey    hexutil.Bytes `json:"pubKey"`
+	BLSPubKey hexutil.Bytes `json:"blsPubKey,omitempty"`
+	BLSProof  hexutil.Bytes `json:"blsProof,omitempty"`
+	Power     uint64        `json:"power,omitempty"`
+	IP        string        `json:"ip"`
+	Port      uint32        `json:"port"`
+	Port2     uint32        `json:"port2"`
+}
+
+// nodeConfig is the config of one node of a testnet.
+type nodeConfig struct {
+	Moniker        string               `json:"moniker"`
+	ListenAddress1 string               `json:"listenAddress1"`
+	ListenAddress2 string               `json:"listenAddress2"`
+	WalPath        string               `json:"walPath"`
+	Health         *ttypes.HealthPolicy `json:"health,omitempty"`
+	HealthHistory  string               `json:"healthHistory,omitempty"` // journal of the health events
+}
+
+func nodeDir(dir string, i int) string {
+	return filepath.Join(dir, "node"+strconv.Itoa(i))
+}
+
+func testnetInit(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("need testnet directory as argument")
+	}
+	var (
+		dir  = ctx.Args().Get(0)
+		n    = ctx.Int(nodesFlag.Name)
+		ip   = ctx.String(hostFlag.Name)
+		port = ctx.Int(portFlag.Name)
+	)
+	if n < params.MinimumCommitteeNumber {
+		return fmt.Errorf("need at least %d nodes", params.MinimumCommitteeNumber)
+	}
+	health := ttypes.DefaultHealthPolicy()
+	health.Evict = ctx.Bool(healthEvictFlag.Name)
+	health.Timeout = int32(ctx.Int(healthTimeoutFlag.Name))
+	health.Grace = int32(ctx.Int(healthGraceFlag.Name))
+	if err := health.Validate(); err != nil {
+		return err
+	}
+	committee := &committeeFile{ChainID: ctx.String(chainIDFlag.Name), ID: 1}
+	pubs := make([]*ecdsa.PublicKey, n)
+	for i := 0; i < n; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			return fmt.Errorf("could not generate key: %v", err)
+		}
+		pubs[i] = &key.PublicKey
+		if err := os.MkdirAll(nodeDir(dir, i), 0700); err != nil {
+			return err
+		}
+		if err := crypto.SaveECDSA(filepath.Join(nodeDir(dir, i), "nodekey"), key); err != nil {
+			return err
+		}
+		blsKey := tcrypto.GenPrivKeyBLS()
+		if err := saveBLSKey(filepath.Join(nodeDir(dir, i), "blskey"), blsKey); err != nil {
+			return err
+		}
+		proof, err := blsKey.ProofOfPossession()
+		if err != nil {
+			return err
+		}
+		p1, p2 := port+2*i, port+2*i+1
+		conf := &nodeConfig{
+			Moniker:        "node" + strconv.Itoa(i),
+			ListenAddress1: fmt.Sprintf("tcp://%s:%d", ip, p1),
+			ListenAddress2: fmt.Sprintf("tcp://%s:%d", ip, p2),
+			WalPath:        filepath.Join(nodeDir(dir, i), "data", "cs.wal", "wal"),
+			Health:         &health,
+			HealthHistory:  filepath.Join(nodeDir(dir, i), "data", "health.jsonl"),
+		}
+		if err := writeJSON(filepath.Join(nodeDir(dir, i), "config.json"), conf); err != nil {
+			return err
+		}
+		committee.Members = append(committee.Members, &memberConfig{
+			PubKey:    crypto.FromECDSAPub(&key.PublicKey),
+			BLSPubKey: blsKey.PubKey().Bytes(),
+			BLSProof:  proof,
+			IP:        ip,
+			Port:      uint32(p1),
+			Port2:     uint32(p2),
+		})
+		fmt.Printf("node%d: %s %s\n", i, tbftID(&key.PublicKey), conf.ListenAddress1)
+	}
+	if err := writeJSON(filepath.Join(dir, "committee.json"), committee); err != nil {
+		return err
+	}
+	gen, err := makeGenesis(nil, pubs, ctx.Uint64(epochFlag.Name), 0)
+	if err != nil {
+		return err
+	}
+	return writeJSON(filepath.Join(dir, "genesis.json"), gen)
+}
+
+// info returns the committee info and the addresses of its members.
+func (c *committeeFile) info() (*types.CommitteeInfo, []*types.CommitteeNode, error) {
+	info := &types.CommitteeInfo{Id: new(big.Int).SetUint64(c.ID), StartHeight: big.NewInt(1)}
+	nodes := make([]*types.CommitteeNode, 0, len(c.Members))
+	for _, m := range c.Members {
+		pub, err := crypto.UnmarshalPubkey(m.PubKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid member key %x: %v", m.PubKey, err)
+		}
+		addr := crypto.PubkeyToAddress(*pub)
+		info.Members = append(info.Members, &types.CommitteeMember{
+			Coinbase:      addr,
+			CommitteeBase: addr,
+			Publickey:     m.PubKey,
+			Flag:          types.StateUsedFlag,
+			MType:         types.TypeWorked,
+			VotingPower:   m.Power,
+			BLSPublickey:  m.BLSPubKey,
+			BLSPossession: m.BLSProof,
+		})
+		nodes = append(nodes, &types.CommitteeNode{
+			IP:        m.IP,
+			Port:      m.Port,
+			Port2:     m.Port2,
+			Coinbase:  addr,
+			Publickey: m.PubKey,
+		})
+	}
+	return info, nodes, nil
+}
+
+// apply completes the members of an elected committee with the BLS keys they
+// have in the committee file and returns the addresses of the members.
+func (c *committeeFile) apply(elected *types.CommitteeInfo) ([]*types.CommitteeNode, error) {
+	info, nodes, err := c.info()
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range elected.GetAllMembers() {
+		for _, f := range info.Members {
+			if bytes.Equal(m.Publickey, f.Publickey) {
+				m.BLSPublickey, m.BLSPossession = f.BLSPublickey, f.BLSPossession
+			}
+		}
+	}
+	return nodes, nil
+}
+
+// testnetServer puts the committees elected from the genesis on the node,
+// along with the addresses of their members in the committee file.
+type testnetServer struct {
+	*tbft.Node
+	committee *committeeFile
+}
+
+func (s *testnetServer) PutCommittee(info *types.CommitteeInfo) error {
+	nodes, err := s.committee.apply(info)
+	if err != nil {
+		return err
+	}
+	if err := s.Node.PutCommittee(info); err != nil {
+		return err
+	}
+	return s.Node.PutNodes(info.Id, nodes)
+}
+
+// testnetBackend is the chain of a testnet node. Its blocks are finalized by
+// the tbft committee and not sealed.
+type testnetBackend struct {
+	db     ethdb.Database
+	engine *ethash.Ethash
+	chain  *core.BlockChain
+	txPool *core.TxPool
+}
+
+func openBackend(dir string, genesis *core.Genesis) (*testnetBackend, error) {
+	db, err := rawdb.NewLevelDBDatabase(filepath.Join(dir, "chaindata"), 16, 16, "tbft/chaindata/")
+	if err != nil {
+		return nil, err
+	}
+	chainConfig, _, err := core.SetupGenesisBlock(db, genesis)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	engine := ethash.NewFaker()
+	chain, err := core.NewBlockChain(db, nil, chainConfig, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	txConfig := core.DefaultTxPoolConfig
+	txConfig.Journal = filepath.Join(dir, txConfig.Journal)
+	return &testnetBackend{
+		db:     db,
+		engine: engine,
+		chain:  chain,
+		txPool: core.NewTxPool(txConfig, chainConfig, chain),
+	}, nil
+}
+
+func (b *testnetBackend) BlockChain() *core.BlockChain { return b.chain }
+func (b *testnetBackend) TxPool() *core.TxPool         { return b.txPool }
+
+func (b *testnetBackend) close() {
+	b.txPool.Stop()
+	b.chain.Stop()
+	b.engine.Close()
+	b.db.Close()
+}
+
+// testnetNode is a running node of a testnet.
+type testnetNode struct {
+	node     *tbft.Node
+	agent    *miner.PbftAgent
+	election *election.Election
+	backend  *testnetBackend
+	history  *ttypes.HealthHistory
+}
+
+func (n *testnetNode) stop() {
+	n.election.Stop()
+	n.node.Stop()
+	n.backend.close()
+	if n.history != nil {
+		n.history.Close()
+	}
+}
+
+// openHealth applies the health policy of conf to node and journals its
+// health events to the file of conf, if any.
+func openHealth(node *tbft.Node, conf *nodeConfig) (*ttypes.HealthHistory, error) {
+	if conf.Health != nil {
+		if err := node.SetHealthPolicy(*conf.Health); err != nil {
+			return nil, err
+		}
+	}
+	if conf.HealthHistory == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(conf.HealthHistory), 0700); err != nil {
+		return nil, err
+	}
+	history, err := ttypes.OpenHealthHistory(conf.HealthHistory, ttypes.DefaultHealthHistoryLimit)
+	if err != nil {
+		return nil, err
+	}
+	node.SetHealthHistory(history)
+	return history, nil
+}
+
+// startNode starts the node in dir on the chain of genesis. The election of the
+// genesis hands the committees the node is a member of to its tbft node.
+func startNode(dir string, genesis *core.Genesis, committee *committeeFile) (*testnetNode, error) {
+	if genesis.Config == nil || genesis.Config.Election == nil {
+		return nil, fmt.Errorf("genesis has no election config")
+	}
+	key, err := crypto.LoadECDSA(filepath.Join(dir, "nodekey"))
+	if err != nil {
+		return nil, err
+	}
+	conf := new(nodeConfig)
+	if err := readJSON(filepath.Join(dir, "config.json"), conf); err != nil {
+		return nil, err
+	}
+	config := params.DefaultConfig()
+	config.Moniker = conf.Moniker
+	config.P2P.ListenAddress1 = conf.ListenAddress1
+	config.P2P.ListenAddress2 = conf.ListenAddress2
+	config.Consensus.WalPath = conf.WalPath
+
+	backend, err := openBackend(dir, genesis)
+	if err != nil {
+		return nil, err
+	}
+	chainConfig := backend.chain.Config()
+	agent := miner.NewPbftAgent(backend, minerConfig, chainConfig, new(event.TypeMux), backend.engine, key, nil)
+	node, err := tbft.NewNode(config, committee.ChainID, key, agent)
+	if err != nil {
+		backend.close()
+		return nil, err
+	}
+	// nodes of testnets initialized without BLS keys vote with the node key
+	if blsKey, err := loadBLSKey(filepath.Join(dir, "blskey")); err == nil {
+		node.SetBLSKey(blsKey)
+	} else if !os.IsNotExist(err) {
+		backend.close()
+		return nil, err
+	}
+	history, err := openHealth(node, conf)
+	if err != nil {
+		backend.close()
+		return nil, err
+	}
+	closeAll := func() {
+		backend.close()
+		if history != nil {
+			history.Close()
+		}
+	}
+	server := &testnetServer{Node: node, committee: committee}
+	elect, err := election.New(chainConfig.Election, backend.chain, server, crypto.PubkeyToAddress(key.PublicKey))
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	if err := node.Start(); err != nil {
+		closeAll()
+		return nil, err
+	}
+	elect.Start()
+	return &testnetNode{node: node, agent: agent, election: elect, backend: backend, history: history}, nil
+}
+
+func testnetRun(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("need testnet directory as argument")
+	}
+	dir := ctx.Args().Get(0)
+	committee := new(committeeFile)
+	if err := readJSON(filepath.Join(dir, "committee.json"), committee); err != nil {
+		return err
+	}
+	genesis := new(core.Genesis)
+	if err := readJSON(filepath.Join(dir, "genesis.json"), genesis); err != nil {
+		return err
+	}
+	indexes := ctx.IntSlice(nodeFlag.Name)
+	if len(indexes) == 0 {
+		for i := range committee.Members {
+			indexes = append(indexes, i)
+		}
+	}
+	var nodes []*testnetNode
+	defer func() {
+		for _, node := range nodes {
+			node.stop()
+		}
+	}()
+	for _, i := range indexes {
+		if i < 0 || i >= len(committee.Members) {
+			return fmt.Errorf("node %d out of range", i)
+		}
+		node, err := startNode(nodeDir(dir, i), genesis, committee)
+		if err != nil {
+			return fmt.Errorf("node%d: %v", i, err)
+		}
+		nodes = append(nodes, node)
+		log.Info("Started tbft node", "index", i, "dir", nodeDir(dir, i))
+	}
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigc)
+	report := time.NewTicker(5 * time.Second)
+	defer report.Stop()
+	for {
+		select {
+		case <-report.C:
+			for j, node := range nodes {
+				log.Info("Committed", "node", indexes[j], "height", node.agent.GetCurrentHeight())
+			}
+		case <-sigc:
+			log.Info("Shutting down testnet")
+			return nil
+		}
+	}
+}
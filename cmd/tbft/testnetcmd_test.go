@@ -0,0 +1,186 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tcrypto "ethereum/rpc-network/consensus/tbft/crypto"
+	"ethereum/rpc-network/core"
+	"ethereum/rpc-network/core/types"
+	"ethereum/rpc-network/crypto"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// runTestnetInit runs testnet init on a fresh directory with args.
+func runTestnetInit(t *testing.T, args ...string) (string, error) {
+	dir, err := ioutil.TempDir("", "tbft-testnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	a := cli.NewApp()
+	a.Writer = ioutil.Discard
+	a.Commands = []cli.Command{testnetCommand}
+	argv := append([]string{"tbft", "testnet", "init"}, args...)
+	return dir, a.Run(append(argv, dir))
+}
+
+func TestTestnetInit(t *testing.T) {
+	dir, err := runTestnetInit(t, "--nodes", "4", "--port", "30000", "--chainid", "tbft-test", "--health.evict")
+	if err != nil {
+		t.Fatal(err)
+	}
+	committee := new(committeeFile)
+	if err := readJSON(filepath.Join(dir, "committee.json"), committee); err != nil {
+		t.Fatal(err)
+	}
+	if committee.ChainID != "tbft-test" || len(committee.Members) != 4 {
+		t.Fatalf("committee mismatch: %s with %d members", committee.ChainID, len(committee.Members))
+	}
+	genesis := new(core.Genesis)
+	if err := readJSON(filepath.Join(dir, "genesis.json"), genesis); err != nil {
+		t.Fatal(err)
+	}
+	if genesis.Config == nil || genesis.Config.Election == nil || len(genesis.Config.Election.Validators) != 4 {
+		t.Fatalf("genesis has no election of the committee: %v", genesis.Config)
+	}
+
+	ports := make(map[uint32]bool)
+	for i, m := range committee.Members {
+		key, err := crypto.LoadECDSA(filepath.Join(nodeDir(dir, i), "nodekey"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(m.PubKey, crypto.FromECDSAPub(&key.PublicKey)) {
+			t.Errorf("node%d: committee key differs from its node key", i)
+		}
+		if !bytes.Equal(genesis.Config.Election.Validators[i], m.PubKey) {
+			t.Errorf("node%d: genesis validator differs from the committee", i)
+		}
+		blsKey, err := loadBLSKey(filepath.Join(nodeDir(dir, i), "blskey"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(m.BLSPubKey, blsKey.PubKey().Bytes()) {
+			t.Errorf("node%d: committee BLS key differs from its BLS key", i)
+		}
+		if ports[m.Port] || ports[m.Port2] {
+			t.Errorf("node%d: ports %d/%d already taken", i, m.Port, m.Port2)
+		}
+		ports[m.Port], ports[m.Port2] = true, true
+
+		conf := new(nodeConfig)
+		if err := readJSON(filepath.Join(nodeDir(dir, i), "config.json"), conf); err != nil {
+			t.Fatal(err)
+		}
+		if conf.Health == nil || !conf.Health.Evict {
+			t.Errorf("node%d: health policy not configured: %v", i, conf.Health)
+		}
+		if filepath.Dir(filepath.Dir(conf.HealthHistory)) != nodeDir(dir, i) {
+			t.Errorf("node%d: health history %s outside the node directory", i, conf.HealthHistory)
+		}
+	}
+}
+
+func TestTestnetInitInvalid(t *testing.T) {
+	if _, err := runTestnetInit(t, "--nodes", "2"); err == nil {
+		t.Error("testnet below the minimum committee size initialized")
+	}
+	if _, err := runTestnetInit(t, "--health.timeout", "0"); err == nil {
+		t.Error("testnet with a zero health timeout initialized")
+	}
+}
+
+func TestCommitteeFileInfo(t *testing.T) {
+	var members []*memberConfig
+	for i, pub := range testPubs(t, 3) {
+		bls := tcrypto.GenPrivKeyBLS()
+		proof, err := bls.ProofOfPossession()
+		if err != nil {
+			t.Fatal(err)
+		}
+		members = append(members, &memberConfig{
+			PubKey:    crypto.FromECDSAPub(pub),
+			BLSPubKey: bls.PubKey().Bytes(),
+			BLSProof:  proof,
+			Power:     uint64(i + 1),
+			IP:        "127.0.0.1",
+			Port:      uint32(30000 + 2*i),
+			Port2:     uint32(30001 + 2*i),
+		})
+	}
+	c := &committeeFile{ChainID: "tbft-test", ID: 7, Members: members}
+
+	info, nodes, err := c.info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Id.Uint64() != 7 || info.StartHeight.Cmp(big.NewInt(1)) != 0 {
+		t.Fatalf("committee %v starting at %v", info.Id, info.StartHeight)
+	}
+	if len(info.Members) != 3 || len(nodes) != 3 {
+		t.Fatalf("have %d members and %d nodes, want 3", len(info.Members), len(nodes))
+	}
+	for i, m := range members {
+		pub, _ := crypto.UnmarshalPubkey(m.PubKey)
+		addr := crypto.PubkeyToAddress(*pub)
+		im, node := info.Members[i], nodes[i]
+		if im.CommitteeBase != addr || im.Coinbase != addr || im.VotingPower != m.Power {
+			t.Errorf("member %d mismatch: %v", i, im)
+		}
+		if im.Flag != types.StateUsedFlag || im.MType != types.TypeWorked {
+			t.Errorf("member %d not working: flag %d type %d", i, im.Flag, im.MType)
+		}
+		if !bytes.Equal(im.BLSPublickey, m.BLSPubKey) || !bytes.Equal(im.BLSPossession, m.BLSProof) {
+			t.Errorf("member %d BLS key mismatch", i)
+		}
+		if node.Coinbase != addr || node.IP != m.IP || node.Port != m.Port || node.Port2 != m.Port2 {
+			t.Errorf("node %d mismatch: %v", i, node)
+		}
+	}
+
+	// an elected committee gets the BLS keys of the file
+	elected := &types.CommitteeInfo{Id: big.NewInt(7)}
+	for _, m := range info.Members {
+		elected.Members = append(elected.Members, &types.CommitteeMember{Publickey: m.Publickey, CommitteeBase: m.CommitteeBase})
+	}
+	if _, err := c.apply(elected); err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range elected.Members {
+		if !bytes.Equal(m.BLSPublickey, members[i].BLSPubKey) {
+			t.Errorf("elected member %d has no BLS key", i)
+		}
+	}
+
+	c.Members = append(c.Members, &memberConfig{PubKey: []byte{1, 2, 3}})
+	if _, _, err := c.info(); err == nil {
+		t.Error("member with invalid key accepted")
+	}
+}
+
+func TestStartNodeNoElection(t *testing.T) {
+	if _, err := startNode("", &core.Genesis{}, &committeeFile{}); err == nil {
+		t.Error("node started on a genesis without election")
+	}
+}
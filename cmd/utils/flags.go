@@ -55,6 +55,7 @@ import (
 	"ethereum/rpc-network/p2p/nat"
 	"ethereum/rpc-network/p2p/netutil"
 	"ethereum/rpc-network/params"
+	"ethereum/rpc-network/rpc"
 	whisper "ethereum/rpc-network/whisper/whisperv6"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/fdlimit"
@@ -554,6 +555,47 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	RPCJWTSecretFlag = cli.StringFlag{
+		Name:  "rpc.jwtsecret",
+		Usage: "Path to a hex encoded secret; HTTP and WS-RPC requests must then authenticate with a HS256 JWT signed with it",
+		Value: "",
+	}
+	RPCRateLimitFlag = cli.Float64Flag{
+		Name:  "rpc.ratelimit",
+		Usage: "Requests per second a HTTP or WS-RPC client may make (0 = no limit)",
+	}
+	RPCBatchLimitFlag = cli.IntFlag{
+		Name:  "rpc.batchlimit",
+		Usage: "Maximum number of requests in a HTTP or WS-RPC batch (0 = no limit)",
+	}
+	RPCConcurrencyLimitFlag = cli.IntFlag{
+		Name:  "rpc.concurrencylimit",
+		Usage: "Maximum number of calls a client runs at once over HTTP and WS-RPC (0 = no limit)",
+	}
+	RPCResponseLimitFlag = cli.IntFlag{
+		Name:  "rpc.responselimit",
+		Usage: "Maximum size in bytes of an RPC response (0 = no limit)",
+	}
+	RPCTLSCertFlag = cli.StringFlag{
+		Name:  "rpc.tlscert",
+		Usage: "Path to the PEM encoded TLS certificate of the HTTP and WS-RPC server (reloaded on SIGHUP)",
+	}
+	RPCTLSKeyFlag = cli.StringFlag{
+		Name:  "rpc.tlskey",
+		Usage: "Path to the PEM encoded TLS key of the HTTP and WS-RPC server",
+	}
+	RPCTLSClientCAFlag = cli.StringFlag{
+		Name:  "rpc.tlsclientca",
+		Usage: "Path to PEM encoded CA certificates; HTTP and WS-RPC clients must then present a certificate signed by one of them",
+	}
+	RPCCallTimeoutFlag = cli.DurationFlag{
+		Name:  "rpc.calltimeout",
+		Usage: "Maximum execution time of an RPC call, canceled afterwards (0 = no limit)",
+	}
+	RPCNamespaceTimeoutsFlag = cli.StringFlag{
+		Name:  "rpc.namespacetimeouts",
+		Usage: "Comma separated namespace=timeout pairs overriding --rpc.calltimeout (e.g. debug=1m,eth=10s)",
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
@@ -986,6 +1028,91 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// setRPCAuth applies the RPC authentication flags to the config, keeping the
+// static tokens of a config file.
+func setRPCAuth(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCJWTSecretFlag.Name) {
+		if cfg.RPCAuth == nil {
+			cfg.RPCAuth = new(node.RPCAuthConfig)
+		}
+		cfg.RPCAuth.JWTSecret = ctx.GlobalString(RPCJWTSecretFlag.Name)
+	}
+}
+
+// setRPCLimits applies the RPC limit flags to the config, keeping the method
+// costs of a config file.
+func setRPCLimits(ctx *cli.Context, cfg *node.Config) {
+	if !ctx.GlobalIsSet(RPCRateLimitFlag.Name) && !ctx.GlobalIsSet(RPCBatchLimitFlag.Name) &&
+		!ctx.GlobalIsSet(RPCConcurrencyLimitFlag.Name) && !ctx.GlobalIsSet(RPCResponseLimitFlag.Name) {
+		return
+	}
+	if cfg.RPCLimits == nil {
+		cfg.RPCLimits = new(rpc.LimitConfig)
+	}
+	if ctx.GlobalIsSet(RPCRateLimitFlag.Name) {
+		cfg.RPCLimits.Rate = ctx.GlobalFloat64(RPCRateLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCBatchLimitFlag.Name) {
+		cfg.RPCLimits.MaxBatch = ctx.GlobalInt(RPCBatchLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCConcurrencyLimitFlag.Name) {
+		cfg.RPCLimits.MaxConcurrent = ctx.GlobalInt(RPCConcurrencyLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCResponseLimitFlag.Name) {
+		cfg.RPCLimits.MaxResponseSize = ctx.GlobalInt(RPCResponseLimitFlag.Name)
+	}
+}
+
+// setRPCTLS applies the RPC TLS flags to the config, keeping the client
+// certificate permissions of a config file.
+func setRPCTLS(ctx *cli.Context, cfg *node.Config) {
+	if !ctx.GlobalIsSet(RPCTLSCertFlag.Name) && !ctx.GlobalIsSet(RPCTLSKeyFlag.Name) && !ctx.GlobalIsSet(RPCTLSClientCAFlag.Name) {
+		return
+	}
+	if cfg.RPCTLS == nil {
+		cfg.RPCTLS = new(node.RPCTLSConfig)
+	}
+	if ctx.GlobalIsSet(RPCTLSCertFlag.Name) {
+		cfg.RPCTLS.CertFile = ctx.GlobalString(RPCTLSCertFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTLSKeyFlag.Name) {
+		cfg.RPCTLS.KeyFile = ctx.GlobalString(RPCTLSKeyFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTLSClientCAFlag.Name) {
+		cfg.RPCTLS.ClientCAFile = ctx.GlobalString(RPCTLSClientCAFlag.Name)
+	}
+}
+
+// setRPCCallTimeouts applies the RPC call timeout flags to the config, keeping
+// the namespace timeouts of a config file not overridden.
+func setRPCCallTimeouts(ctx *cli.Context, cfg *node.Config) {
+	if !ctx.GlobalIsSet(RPCCallTimeoutFlag.Name) && !ctx.GlobalIsSet(RPCNamespaceTimeoutsFlag.Name) {
+		return
+	}
+	if cfg.RPCCallTimeouts == nil {
+		cfg.RPCCallTimeouts = new(rpc.CallTimeouts)
+	}
+	if ctx.GlobalIsSet(RPCCallTimeoutFlag.Name) {
+		cfg.RPCCallTimeouts.Default = ctx.GlobalDuration(RPCCallTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCNamespaceTimeoutsFlag.Name) {
+		if cfg.RPCCallTimeouts.Namespaces == nil {
+			cfg.RPCCallTimeouts.Namespaces = make(map[string]time.Duration)
+		}
+		for _, entry := range strings.Split(ctx.GlobalString(RPCNamespaceTimeoutsFlag.Name), ",") {
+			parts := strings.Split(strings.TrimSpace(entry), "=")
+			if len(parts) != 2 {
+				Fatalf("Invalid namespace timeout entry: %s", entry)
+			}
+			timeout, err := time.ParseDuration(parts[1])
+			if err != nil {
+				Fatalf("Invalid timeout of namespace %s: %v", parts[0], err)
+			}
+			cfg.RPCCallTimeouts.Namespaces[parts[0]] = timeout
+		}
+	}
+}
+
 // setIPC creates an IPC path configuration from the set command line flags,
 // returning an empty string if IPC was explicitly disabled, or the set path.
 func setIPC(ctx *cli.Context, cfg *node.Config) {
@@ -1204,6 +1331,10 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setHTTP(ctx, cfg)
 	setGraphQL(ctx, cfg)
 	setWS(ctx, cfg)
+	setRPCAuth(ctx, cfg)
+	setRPCLimits(ctx, cfg)
+	setRPCTLS(ctx, cfg)
+	setRPCCallTimeouts(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 	setDataDir(ctx, cfg)
 	setSmartCard(ctx, cfg)
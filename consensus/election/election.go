@@ -0,0 +1,315 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package election derives the tbft committees from the chain state and rotates
+// them on the running tbft node at epoch boundaries.
+//
+// Committee k finalizes the blocks [k*epoch+1, (k+1)*epoch]. It is elected from
+// the state of block (k-1)*epoch+1, the first block of the epoch before it (the
+// genesis state for the first committee). The next committee is therefore known
+// a whole epoch in advance, which leaves its members time to start up and connect
+// before they take over.
+package election
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	tcrypto "ethereum/rpc-network/consensus/tbft/crypto"
+	"ethereum/rpc-network/core"
+	"ethereum/rpc-network/core/state"
+	"ethereum/rpc-network/core/types"
+	"ethereum/rpc-network/params"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
+	chainHeadChanSize = 10
+
+	// committeeCacheLimit is the number of elected committees kept in memory.
+	committeeCacheLimit = 16
+)
+
+var (
+	// ErrElectionNotYet is returned when the block a committee is elected from is
+	// not in the chain yet.
+	ErrElectionNotYet = errors.New("committee not elected yet")
+
+	errNoCandidates = errors.New("no committee candidates")
+	errZeroEpoch    = errors.New("zero tbft epoch")
+)
+
+// Chain defines the small collection of methods needed to elect committees.
+type Chain interface {
+	// CurrentHeader retrieves the current head header of the chain.
+	CurrentHeader() *types.Header
+
+	// GetHeaderByNumber retrieves a canonical header by number.
+	GetHeaderByNumber(number uint64) *types.Header
+
+	// StateAt returns the state at the given root.
+	StateAt(root common.Hash) (*state.StateDB, error)
+
+	// SubscribeChainHeadEvent subscribes to new head notifications.
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// Election elects the tbft committees and hands them to the local tbft node.
+type Election struct {
+	config *params.ElectionConfig
+	chain  Chain
+	server types.PbftServerProxy
+	self   common.Address // Committee address of the local node, zero if it never joins
+
+	genesis []*types.CommitteeMember // Decoded genesis validators
+
+	lock       sync.Mutex
+	committees map[uint64]*types.CommitteeInfo // Elected committees by id
+	running    map[uint64]bool                 // Committees started on the server
+
+	headCh  chan core.ChainHeadEvent
+	headSub event.Subscription
+	quit    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// New creates an election on top of the chain. Committees the local node is a
+// member of are handed to server, self is the address of the node's consensus
+// key. The server may be nil if the election is only used to look up committees.
+func New(config *params.ElectionConfig, chain Chain, server types.PbftServerProxy, self common.Address) (*Election, error) {
+	if config.Epoch == 0 {
+		return nil, errZeroEpoch
+	}
+	e := &Election{
+		config:     config,
+		chain:      chain,
+		server:     server,
+		self:       self,
+		committees: make(map[uint64]*types.CommitteeInfo),
+		running:    make(map[uint64]bool),
+	}
+	if config.StakingContract == nil {
+		if len(config.Validators) == 0 {
+			return nil, errNoCandidates
+		}
+		for i, key := range config.Validators {
+			pub, err := crypto.UnmarshalPubkey(key)
+			if err != nil {
+				return nil, fmt.Errorf("genesis validator %d: %v", i, err)
+			}
+			e.genesis = append(e.genesis, types.NewCommitteeMember(crypto.PubkeyToAddress(*pub), key, types.StateUsedFlag, types.TypeWorked))
+		}
+	}
+	return e, nil
+}
+
+// EpochOf returns the id of the committee finalizing the given block.
+func (e *Election) EpochOf(number uint64) uint64 {
+	if number == 0 {
+		return 0
+	}
+	return (number - 1) / e.config.Epoch
+}
+
+// electionHeight returns the number of the block whose state elects the committee.
+func (e *Election) electionHeight(id uint64) uint64 {
+	if id == 0 {
+		return 0
+	}
+	return (id-1)*e.config.Epoch + 1
+}
+
+// GetCommittee returns the committee with the given id. ErrElectionNotYet is
+// returned if the committee can't be elected from the current chain yet.
+func (e *Election) GetCommittee(id uint64) (*types.CommitteeInfo, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	return e.committee(id)
+}
+
+// GetCommitteeByNumber returns the committee finalizing the given block.
+func (e *Election) GetCommitteeByNumber(number uint64) (*types.CommitteeInfo, error) {
+	return e.GetCommittee(e.EpochOf(number))
+}
+
+// VerifyHeader checks the header was finalized by its committee.
+func (e *Election) VerifyHeader(header *types.Header) error {
+	committee, err := e.GetCommitteeByNumber(header.Number.Uint64())
+	if err != nil {
+		return err
+	}
+	return committee.VerifyHeader(header, tcrypto.VerifyPossessionBLS)
+}
+
+// committee returns the cached committee or elects it, the lock must be held.
+func (e *Election) committee(id uint64) (*types.CommitteeInfo, error) {
+	if info, ok := e.committees[id]; ok {
+		return info, nil
+	}
+	header := e.chain.GetHeaderByNumber(e.electionHeight(id))
+	if header == nil {
+		return nil, ErrElectionNotYet
+	}
+	candidates := e.genesis
+	if e.config.StakingContract != nil {
+		statedb, err := e.chain.StateAt(header.Root)
+		if err != nil {
+			return nil, err
+		}
+		if candidates, err = ReadStakingCandidates(statedb, *e.config.StakingContract); err != nil {
+			return nil, err
+		}
+		sortByStake(candidates)
+	}
+	if len(candidates) == 0 {
+		return nil, errNoCandidates
+	}
+	info := &types.CommitteeInfo{
+		Id:          new(big.Int).SetUint64(id),
+		StartHeight: new(big.Int).SetUint64(id*e.config.Epoch + 1),
+		EndHeight:   new(big.Int).SetUint64((id + 1) * e.config.Epoch),
+	}
+	for i, c := range candidates {
+		member := *c
+		if e.config.CommitteeSize == 0 || uint64(i) < e.config.CommitteeSize {
+			member.Flag, member.MType = types.StateUsedFlag, types.TypeWorked
+			info.Members = append(info.Members, &member)
+		} else {
+			member.Flag, member.MType = types.StateUnusedFlag, types.TypeBack
+			info.BackMembers = append(info.BackMembers, &member)
+		}
+	}
+	for old := range e.committees {
+		if old+committeeCacheLimit <= id {
+			delete(e.committees, old)
+		}
+	}
+	e.committees[id] = info
+	log.Debug("Elected tbft committee", "id", id, "start", info.StartHeight, "end", info.EndHeight,
+		"members", len(info.Members), "backups", len(info.BackMembers))
+	return info, nil
+}
+
+// sortByStake orders staking candidates by descending stake, so that the ones with
+// the highest stake become the working members, whatever their order in storage.
+// Candidates with the same stake are ordered by address.
+func sortByStake(candidates []*types.CommitteeMember) {
+	sort.Slice(candidates, func(i, j int) bool {
+		if si, sj := candidates[i].VotingPower, candidates[j].VotingPower; si != sj {
+			return si > sj
+		}
+		return bytes.Compare(candidates[i].CommitteeBase[:], candidates[j].CommitteeBase[:]) < 0
+	})
+}
+
+// Start hands the committees of the current head to the server and follows the
+// chain to rotate them.
+func (e *Election) Start() {
+	e.headCh = make(chan core.ChainHeadEvent, chainHeadChanSize)
+	e.headSub = e.chain.SubscribeChainHeadEvent(e.headCh)
+	e.quit = make(chan struct{})
+
+	e.rotate(e.chain.CurrentHeader().Number.Uint64())
+
+	e.wg.Add(1)
+	go e.loop()
+}
+
+// Stop stops following the chain. Running committees are left to the server.
+func (e *Election) Stop() {
+	e.headSub.Unsubscribe()
+	close(e.quit)
+	e.wg.Wait()
+}
+
+func (e *Election) loop() {
+	defer e.wg.Done()
+
+	for {
+		select {
+		case ev := <-e.headCh:
+			e.rotate(ev.Block.NumberU64())
+		case <-e.headSub.Err():
+			return
+		case <-e.quit:
+			return
+		}
+	}
+}
+
+// rotate stops the committees whose epoch is over and starts the current and the
+// next committee as soon as they are elected, if the local node is a member.
+func (e *Election) rotate(head uint64) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	current := e.EpochOf(head + 1)
+	for id := range e.running {
+		if id >= current {
+			continue
+		}
+		log.Info("Stopping tbft committee", "id", id, "head", head)
+		if err := e.server.Notify(new(big.Int).SetUint64(id), types.CommitteeStop); err != nil {
+			log.Warn("Failed to stop tbft committee", "id", id, "err", err)
+		}
+		delete(e.running, id)
+	}
+	if e.server == nil || e.self == (common.Address{}) {
+		return
+	}
+	for id := current; id <= current+1 && e.electionHeight(id) <= head; id++ {
+		if e.running[id] {
+			continue
+		}
+		info, err := e.committee(id)
+		if err != nil {
+			log.Warn("Failed to elect tbft committee", "id", id, "err", err)
+			continue
+		}
+		if !e.isMember(info) {
+			continue
+		}
+		log.Info("Starting tbft committee", "id", id, "start", info.StartHeight, "end", info.EndHeight, "head", head)
+		if err := e.server.PutCommittee(info); err != nil {
+			log.Warn("Failed to put tbft committee", "id", id, "err", err)
+			continue
+		}
+		if err := e.server.Notify(info.Id, types.CommitteeStart); err != nil {
+			log.Warn("Failed to start tbft committee", "id", id, "err", err)
+			continue
+		}
+		e.running[id] = true
+	}
+}
+
+// isMember reports whether the local node works or backs up in the committee.
+func (e *Election) isMember(info *types.CommitteeInfo) bool {
+	for _, m := range info.GetAllMembers() {
+		if m.CommitteeBase == e.self {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,282 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package election
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"ethereum/rpc-network/consensus/ethash"
+	"ethereum/rpc-network/core"
+	"ethereum/rpc-network/core/rawdb"
+	"ethereum/rpc-network/core/state"
+	"ethereum/rpc-network/core/types"
+	"ethereum/rpc-network/core/vm"
+	"ethereum/rpc-network/params"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// testServer records the committee actions of the election.
+type testServer struct {
+	actions chan string
+}
+
+func (s *testServer) PutCommittee(info *types.CommitteeInfo) error {
+	s.actions <- fmt.Sprintf("put %v [%v,%v]", info.Id, info.StartHeight, info.EndHeight)
+	return nil
+}
+
+func (s *testServer) Notify(id *big.Int, action int) error {
+	switch action {
+	case types.CommitteeStart:
+		s.actions <- fmt.Sprintf("start %v", id)
+	case types.CommitteeStop:
+		s.actions <- fmt.Sprintf("stop %v", id)
+	}
+	return nil
+}
+
+func (s *testServer) UpdateCommittee(info *types.CommitteeInfo) error                { return nil }
+func (s *testServer) PutNodes(id *big.Int, nodes []*types.CommitteeNode) error       { return nil }
+func (s *testServer) SetCommitteeStop(committeeId *big.Int, stop uint64) error       { return nil }
+func (s *testServer) GetCommitteeStatus(committeeID *big.Int) map[string]interface{} { return nil }
+func (s *testServer) IsLeader(committeeID *big.Int) bool                             { return false }
+
+// stakingCode stores the second calldata word in the slot given by the first one,
+// standing in for the staking contract.
+var stakingCode = common.FromHex("0x6020356000355500")
+
+func newTestKeys(t *testing.T, n int) ([]*ecdsa.PrivateKey, []*types.CommitteeMember) {
+	keys := make([]*ecdsa.PrivateKey, n)
+	members := make([]*types.CommitteeMember, n)
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = key
+		members[i] = types.NewCommitteeMember(crypto.PubkeyToAddress(key.PublicKey), crypto.FromECDSAPub(&key.PublicKey),
+			types.StateUsedFlag, types.TypeWorked)
+	}
+	return keys, members
+}
+
+func TestGenesisValidators(t *testing.T) {
+	_, members := newTestKeys(t, 5)
+	config := &params.ElectionConfig{Epoch: 10, CommitteeSize: 4}
+	for _, m := range members {
+		config.Validators = append(config.Validators, hexutil.Bytes(m.Publickey))
+	}
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		genesis = (&core.Genesis{Config: params.TestChainConfig}).MustCommit(db)
+	)
+	chain, _ := core.NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer chain.Stop()
+
+	election, err := New(config, chain, nil, common.Address{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := election.GetCommitteeByNumber(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Id.Uint64() != 0 || info.StartHeight.Uint64() != 1 || info.EndHeight.Uint64() != 10 {
+		t.Fatalf("committee range mismatch: %v", info)
+	}
+	if len(info.Members) != 4 || len(info.BackMembers) != 1 {
+		t.Fatalf("committee size mismatch: have %d+%d, want 4+1", len(info.Members), len(info.BackMembers))
+	}
+	if info.BackMembers[0].CommitteeBase != members[4].CommitteeBase || info.BackMembers[0].MType != types.TypeBack {
+		t.Fatalf("backup mismatch: %v", info.BackMembers[0])
+	}
+	// The second committee is elected from block 1, which doesn't exist yet
+	if _, err := election.GetCommitteeByNumber(11); err != ErrElectionNotYet {
+		t.Fatalf("future committee error mismatch: have %v, want %v", err, ErrElectionNotYet)
+	}
+	blocks, _ := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 1, nil)
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+	if info, err = election.GetCommitteeByNumber(11); err != nil || info.Id.Uint64() != 1 {
+		t.Fatalf("failed to elect the second committee: %v %v", info, err)
+	}
+}
+
+func TestStakingVotingPower(t *testing.T) {
+	_, members := newTestKeys(t, 3)
+	members[0].VotingPower = 10
+	members[2].VotingPower = 1 << 62
+
+	var (
+		contract   = common.HexToAddress("0x1000000000000000000000000000000000000001")
+		statedb, _ = state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	)
+	for slot, value := range StakingStorage(members) {
+		statedb.SetState(contract, slot, value)
+	}
+	candidates, err := ReadStakingCandidates(statedb, contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []int64{10, 1, types.MaxMemberPower} {
+		if candidates[i].CommitteeBase != members[i].CommitteeBase {
+			t.Fatalf("candidate %d mismatch: %v", i, candidates[i])
+		}
+		if have := candidates[i].Power(); have != want {
+			t.Fatalf("candidate %d power mismatch: have %d, want %d", i, have, want)
+		}
+	}
+}
+
+func TestStakingElectionOrder(t *testing.T) {
+	_, members := newTestKeys(t, 5)
+	for i, stake := range []uint64{1, 5, 3, 5, 0} {
+		members[i].VotingPower = stake
+	}
+	// Of the two candidates with the highest stake, the lower address comes first
+	first, second := members[1], members[3]
+	if bytes.Compare(first.CommitteeBase[:], second.CommitteeBase[:]) > 0 {
+		first, second = second, first
+	}
+	var (
+		staking = common.HexToAddress("0x1000000000000000000000000000000000000001")
+		config  = &params.ElectionConfig{Epoch: 10, CommitteeSize: 3, StakingContract: &staking}
+		db      = rawdb.NewMemoryDatabase()
+		gspec   = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{staking: {Balance: new(big.Int), Code: stakingCode, Storage: StakingStorage(members)}},
+		}
+	)
+	gspec.MustCommit(db)
+	chain, _ := core.NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer chain.Stop()
+
+	election, err := New(config, chain, nil, common.Address{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := election.GetCommitteeByNumber(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*types.CommitteeMember{first, second, members[2], members[0], members[4]}
+	have := append(info.Members, info.BackMembers...)
+	if len(info.Members) != 3 || len(have) != len(want) {
+		t.Fatalf("committee size mismatch: have %d+%d, want 3+2", len(info.Members), len(info.BackMembers))
+	}
+	for i := range want {
+		if have[i].CommitteeBase != want[i].CommitteeBase {
+			t.Errorf("member %d mismatch: have stake %d, want stake %d", i, have[i].VotingPower, want[i].VotingPower)
+		}
+	}
+}
+
+func TestCommitteeRotation(t *testing.T) {
+	var (
+		keys, members = newTestKeys(t, 6)
+		funds, _      = crypto.GenerateKey()
+		staking       = common.HexToAddress("0x1000000000000000000000000000000000000001")
+		config        = &params.ElectionConfig{Epoch: 4, StakingContract: &staking}
+		db            = rawdb.NewMemoryDatabase()
+		signer        = types.NewEIP155Signer(params.TestChainConfig.ChainID)
+	)
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			crypto.PubkeyToAddress(funds.PublicKey): {Balance: big.NewInt(1000000000000000000)},
+			staking:                                 {Balance: new(big.Int), Code: stakingCode, Storage: StakingStorage(members[:4])},
+		},
+	}
+	genesis := gspec.MustCommit(db)
+
+	// In block 3 the first two candidates are replaced by the last two
+	update := StakingStorage([]*types.CommitteeMember{members[4], members[5], members[2], members[3]})
+	blocks, _ := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 12, func(i int, b *core.BlockGen) {
+		if i != 2 {
+			return
+		}
+		for slot, value := range update {
+			tx := types.NewTransaction(b.TxNonce(crypto.PubkeyToAddress(funds.PublicKey)), staking, new(big.Int), 100000,
+				big.NewInt(1), append(slot.Bytes(), value.Bytes()...))
+			tx, _ = types.SignTx(tx, signer, funds)
+			b.AddTx(tx)
+		}
+	})
+	chain, _ := core.NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer chain.Stop()
+
+	server := &testServer{actions: make(chan string, 16)}
+	election, err := New(config, chain, server, crypto.PubkeyToAddress(keys[0].PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	election.Start()
+	defer election.Stop()
+
+	for _, block := range blocks {
+		if _, err := chain.InsertChain(types.Blocks{block}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{
+		"put 0 [1,4]", "start 0", // elected from genesis
+		"put 1 [5,8]", "start 1", // elected from block 1, a whole epoch ahead
+		"stop 0", // block 4 ends the first epoch
+		"stop 1", // the third committee from block 5 no longer holds the local node
+	}
+	for i, action := range want {
+		select {
+		case have := <-server.actions:
+			if have != action {
+				t.Fatalf("action %d mismatch: have %q, want %q", i, have, action)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("action %d timeout, want %q", i, action)
+		}
+	}
+	select {
+	case have := <-server.actions:
+		t.Fatalf("unexpected action %q", have)
+	case <-time.After(100 * time.Millisecond):
+	}
+	for id, want := range [][]*types.CommitteeMember{members[:4], members[:4], {members[4], members[5], members[2], members[3]}} {
+		// Candidates of equal stake are elected in the order of their addresses
+		want = append([]*types.CommitteeMember{}, want...)
+		sortByStake(want)
+
+		info, err := election.GetCommittee(uint64(id))
+		if err != nil {
+			t.Fatalf("committee %d: %v", id, err)
+		}
+		if len(info.Members) != len(want) {
+			t.Fatalf("committee %d size mismatch: have %d, want %d", id, len(info.Members), len(want))
+		}
+		for i := range want {
+			if !info.Members[i].Compared(want[i]) {
+				t.Errorf("committee %d member %d mismatch: have %v, want %v", id, i, info.Members[i], want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package election
+
+import (
+	"fmt"
+	"math/big"
+
+	"ethereum/rpc-network/core/state"
+	"ethereum/rpc-network/core/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// The staking system contract keeps its candidates in a plain storage array:
+//
+//	slot 0                      number of candidates
+//	keccak256(slot 0) + 3*i     coinbase of candidate i
+//	keccak256(slot 0) + 3*i + 1 first half of the public key of candidate i
+//	keccak256(slot 0) + 3*i + 2 second half of the public key of candidate i
+//	keccak256(slot 1) + i       stake of candidate i
+//
+// Public keys are stored uncompressed without the leading 0x04 byte. The stake
+// becomes the voting power of the candidate, a zero stake counts as one.
+const (
+	stakingWordsPerCandidate = 3
+
+	// maxStakingCandidates caps the candidate count read from the contract, so a
+	// corrupted length slot can't make the election walk the whole storage.
+	maxStakingCandidates = 1024
+)
+
+var (
+	stakingLengthSlot = common.Hash{}
+	stakingStakeSlot  = common.BigToHash(big.NewInt(1))
+)
+
+// stakingSlot returns the storage slot of word j of candidate i.
+func stakingSlot(i, j uint64) common.Hash {
+	base := new(big.Int).SetBytes(crypto.Keccak256(stakingLengthSlot[:]))
+	base.Add(base, new(big.Int).SetUint64(i*stakingWordsPerCandidate+j))
+	return common.BigToHash(base)
+}
+
+// stakeSlot returns the storage slot of the stake of candidate i.
+func stakeSlot(i uint64) common.Hash {
+	base := new(big.Int).SetBytes(crypto.Keccak256(stakingStakeSlot[:]))
+	base.Add(base, new(big.Int).SetUint64(i))
+	return common.BigToHash(base)
+}
+
+// ReadStakingCandidates reads the candidates from the staking contract storage.
+func ReadStakingCandidates(statedb *state.StateDB, contract common.Address) ([]*types.CommitteeMember, error) {
+	length := statedb.GetState(contract, stakingLengthSlot).Big()
+	if !length.IsUint64() || length.Uint64() > maxStakingCandidates {
+		return nil, fmt.Errorf("too many staking candidates: %v", length)
+	}
+	candidates := make([]*types.CommitteeMember, 0, length.Uint64())
+	for i := uint64(0); i < length.Uint64(); i++ {
+		var (
+			coinbase = common.BytesToAddress(statedb.GetState(contract, stakingSlot(i, 0)).Bytes())
+			pubkey   = make([]byte, 1, 65)
+		)
+		pubkey[0] = 0x04
+		pubkey = append(pubkey, statedb.GetState(contract, stakingSlot(i, 1)).Bytes()...)
+		pubkey = append(pubkey, statedb.GetState(contract, stakingSlot(i, 2)).Bytes()...)
+		if _, err := crypto.UnmarshalPubkey(pubkey); err != nil {
+			return nil, fmt.Errorf("staking candidate %d: %v", i, err)
+		}
+		member := types.NewCommitteeMember(coinbase, pubkey, types.StateUsedFlag, types.TypeWorked)
+		if stake := statedb.GetState(contract, stakeSlot(i)).Big(); stake.IsUint64() {
+			member.VotingPower = stake.Uint64()
+		} else {
+			member.VotingPower = types.MaxMemberPower
+		}
+		candidates = append(candidates, member)
+	}
+	return candidates, nil
+}
+
+// StakingStorage returns the contract storage holding the given candidates, to be
+// used in the genesis allocation of the staking contract or when updating it.
+func StakingStorage(candidates []*types.CommitteeMember) map[common.Hash]common.Hash {
+	storage := map[common.Hash]common.Hash{
+		stakingLengthSlot: common.BigToHash(big.NewInt(int64(len(candidates)))),
+	}
+	for i, c := range candidates {
+		storage[stakingSlot(uint64(i), 0)] = common.BytesToHash(c.Coinbase[:])
+		storage[stakingSlot(uint64(i), 1)] = common.BytesToHash(c.Publickey[1:33])
+		storage[stakingSlot(uint64(i), 2)] = common.BytesToHash(c.Publickey[33:65])
+		if c.VotingPower != 0 {
+			storage[stakeSlot(uint64(i))] = common.BigToHash(new(big.Int).SetUint64(c.VotingPower))
+		}
+	}
+	return storage
+}
@@ -0,0 +1,242 @@
+package tbft
+
+import (
+	"context"
+	"errors"
+
+	"ethereum/rpc-network/consensus/tbft/tp2p"
+	ttypes "ethereum/rpc-network/consensus/tbft/types"
+	"ethereum/rpc-network/rpc"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var errUnknownCommittee = errors.New("unknown committee")
+
+// eventKeys are the EventBus events streamed by tbft_subscribe("events", ...)
+var eventKeys = []string{ttypes.EventNewRound, ttypes.EventPolka, ttypes.EventLock, ttypes.EventVote}
+
+// APIs returns the tbft RPC services
+func (n *Node) APIs() []rpc.API {
+	return []rpc.API{{
+		Namespace: "tbft",
+		Version:   "1.0",
+		Service:   &PublicTbftAPI{node: n},
+		Public:    true,
+	}}
+}
+
+// PublicTbftAPI exposes the consensus state of the running committees
+type PublicTbftAPI struct {
+	node *Node
+}
+
+// RoundStateInfo is the height/round/step of a committee
+type RoundStateInfo struct {
+	Height uint64 `json:"height"`
+	Round  uint   `json:"round"`
+	Step   string `json:"step"`
+}
+
+// ValidatorInfo is the RPC view of a validator
+type ValidatorInfo struct {
+	Address     hexutil.Bytes `json:"address"`
+	PubKey      hexutil.Bytes `json:"pubKey"`
+	VotingPower int64         `json:"votingPower"`
+	Accum       int64         `json:"accum"`
+}
+
+// SwitchInfo is the RPC view of a pending switch-validator proposal
+type SwitchInfo struct {
+	ID        uint64  `json:"id"`
+	Reason    string  `json:"reason"`
+	From      int     `json:"from"`
+	Round     int     `json:"round"`
+	DoorCount int     `json:"doorCount"`
+	Remove    tp2p.ID `json:"remove"`
+	Add       tp2p.ID `json:"add"`
+}
+
+// EventInfo is one streamed EventBus event
+type EventInfo struct {
+	Type   string       `json:"type"`
+	Height uint64       `json:"height"`
+	Round  uint         `json:"round"`
+	Step   string       `json:"step,omitempty"`
+	Vote   *ttypes.Vote `json:"vote,omitempty"`
+}
+
+func newValidatorInfo(v *ttypes.Validator) *ValidatorInfo {
+	if v == nil {
+		return nil
+	}
+	return &ValidatorInfo{
+		Address:     hexutil.Bytes(v.Address),
+		PubKey:      v.PubKey.Bytes(),
+		VotingPower: v.VotingPower,
+		Accum:       v.Accum,
+	}
+}
+
+func (api *PublicTbftAPI) service(committeeID uint64) (*service, error) {
+	s := getCommittee(api.node, committeeID)
+	if s == nil {
+		return nil, errUnknownCommittee
+	}
+	return s, nil
+}
+
+// RoundState returns the current height, round and step of a committee
+func (api *PublicTbftAPI) RoundState(committeeID uint64) (*RoundStateInfo, error) {
+	s, err := api.service(committeeID)
+	if err != nil {
+		return nil, err
+	}
+	rs := s.consensusState.GetRoundState()
+	return &RoundStateInfo{Height: rs.Height, Round: rs.Round, Step: rs.Step.String()}, nil
+}
+
+// Validators returns the validator set of a committee at the current height
+func (api *PublicTbftAPI) Validators(committeeID uint64) ([]*ValidatorInfo, error) {
+	s, err := api.service(committeeID)
+	if err != nil {
+		return nil, err
+	}
+	vals := s.consensusState.GetRoundState().Validators
+	if vals == nil {
+		return nil, nil
+	}
+	infos := make([]*ValidatorInfo, 0, vals.Size())
+	vals.Iterate(func(_ int, v *ttypes.Validator) bool {
+		infos = append(infos, newValidatorInfo(v))
+		return false
+	})
+	return infos, nil
+}
+
+// Proposer returns the proposer of the current round
+func (api *PublicTbftAPI) Proposer(committeeID uint64) (*ValidatorInfo, error) {
+	s, err := api.service(committeeID)
+	if err != nil {
+		return nil, err
+	}
+	vals := s.consensusState.GetRoundState().Validators
+	if vals == nil {
+		return nil, nil
+	}
+	return newValidatorInfo(vals.GetProposer()), nil
+}
+
+// Peers returns the round state each connected peer has announced
+func (api *PublicTbftAPI) Peers(committeeID uint64) (map[tp2p.ID]*ttypes.PeerRoundState, error) {
+	s, err := api.service(committeeID)
+	if err != nil {
+		return nil, err
+	}
+	peers := make(map[tp2p.ID]*ttypes.PeerRoundState)
+	for _, peer := range s.sw.Peers().List() {
+		if ps, ok := peer.Get(ttypes.PeerStateKey).(*PeerState); ok {
+			peers[peer.ID()] = ps.GetRoundState()
+		}
+	}
+	return peers, nil
+}
+
+// HealthTicks returns the health tick of every committee member
+func (api *PublicTbftAPI) HealthTicks(committeeID uint64) (map[tp2p.ID]int32, error) {
+	s, err := api.service(committeeID)
+	if err != nil {
+		return nil, err
+	}
+	return s.healthMgr.HealthTicks(), nil
+}
+
+// HealthPolicy returns the policy deciding when members of a committee are switched
+func (api *PublicTbftAPI) HealthPolicy(committeeID uint64) (*ttypes.HealthPolicy, error) {
+	s, err := api.service(committeeID)
+	if err != nil {
+		return nil, err
+	}
+	policy := s.healthMgr.Policy()
+	return &policy, nil
+}
+
+// HealthHistory returns the last limit health transitions and switch decisions
+// of a committee, all the ones kept if limit is 0
+func (api *PublicTbftAPI) HealthHistory(committeeID uint64, limit int) ([]*ttypes.HealthEvent, error) {
+	s, err := api.service(committeeID)
+	if err != nil {
+		return nil, err
+	}
+	return s.healthMgr.History(limit), nil
+}
+
+// PendingSwitch returns the switch-validator proposal in progress, nil if none
+func (api *PublicTbftAPI) PendingSwitch(committeeID uint64) (*SwitchInfo, error) {
+	s, err := api.service(committeeID)
+	if err != nil {
+		return nil, err
+	}
+	sv := s.healthMgr.CurrentSwitch()
+	if sv == nil {
+		return nil, nil
+	}
+	info := &SwitchInfo{ID: sv.ID, Reason: sv.Resion, From: sv.From, Round: sv.Round, DoorCount: sv.DoorCount}
+	if sv.Remove != nil {
+		info.Remove = sv.Remove.ID
+	}
+	if sv.Add != nil {
+		info.Add = sv.Add.ID
+	}
+	return info, nil
+}
+
+// Events streams the NewRound, Polka, Lock and Vote events of a committee
+func (api *PublicTbftAPI) Events(ctx context.Context, committeeID uint64) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	s, err := api.service(committeeID)
+	if err != nil {
+		return nil, err
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	// The bus drops the subscription once the buffer overflows, the client
+	// is told by the end of its subscription
+	events := make(chan interface{}, 256)
+	sub := s.EventBus().Subscribe(events, eventKeys...)
+	go func() {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case ev := <-events:
+				switch data := ev.(type) {
+				case ttypes.EventDataCommon:
+					notifier.Notify(rpcSub.ID, &EventInfo{
+						Type:   data.Key,
+						Height: data.Data.Height,
+						Round:  data.Data.Round,
+						Step:   data.Data.Step,
+					})
+				case ttypes.EventDataVote:
+					notifier.Notify(rpcSub.ID, &EventInfo{
+						Type:   ttypes.EventVote,
+						Height: data.Vote.Height,
+						Round:  data.Vote.Round,
+						Vote:   data.Vote,
+					})
+				}
+			case err := <-sub.Err():
+				log.Debug("Consensus event subscription ended", "id", rpcSub.ID, "err", err)
+				return
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
@@ -0,0 +1,508 @@
+package tbft
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"ethereum/rpc-network/consensus/tbft/help"
+	"ethereum/rpc-network/consensus/tbft/tp2p"
+	ttypes "ethereum/rpc-network/consensus/tbft/types"
+	ctypes "ethereum/rpc-network/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/tendermint/go-amino"
+)
+
+const (
+	//BlockSyncChannel is channel for committed blocks
+	BlockSyncChannel = byte(0x40)
+
+	maxBlocksPerRequest = 16  // heights asked from a single peer at once
+	maxPendingBlocks    = 128 // heights requested or downloaded ahead of the chain
+
+	blockRequestTimeout       = 15 * time.Second
+	maxPeerTimeouts           = 3 // timed out requests before a peer is evicted
+	peerEvictionTime          = 2 * time.Minute
+	trySyncInterval           = 50 * time.Millisecond
+	statusUpdateInterval      = 10 * time.Second
+	switchToConsensusInterval = 1 * time.Second
+	// noPeersSyncTimeout is how long to wait for a peer status before starting
+	// consensus anyway
+	noPeersSyncTimeout = 10 * time.Second
+)
+
+var errBlockSyncCommit = errors.New("block doesn't match its commit")
+
+// blockRequest is a height requested from a peer, and the block once it arrived.
+type blockRequest struct {
+	peer   tp2p.ID
+	sent   time.Time
+	block  *ctypes.Block
+	commit *ttypes.Commit
+}
+
+// BlockSyncReactor lets a lagging validator download the committed blocks with
+// their commits from its peers, and switches the consensus reactor to consensus
+// mode once it caught up. Peers serve the blocks kept in their block store.
+type BlockSyncReactor struct {
+	tp2p.BaseReactor
+
+	state ttypes.StateAgent
+	store *ttypes.BlockStore
+	conR  *ConsensusReactor
+
+	mtx         sync.Mutex
+	syncing     bool
+	startTime   time.Time
+	peerHeights map[tp2p.ID]uint64       // last block height reported by each peer
+	timeouts    map[tp2p.ID]int          // requests timed out since the last block of a peer
+	evicted     map[tp2p.ID]time.Time    // peers ignored until the given time
+	requests    map[uint64]*blockRequest // heights in flight or downloaded
+	synced      uint64
+}
+
+// NewBlockSyncReactor returns a new BlockSyncReactor, it syncs before handing
+// over to conR if fastSync is set and only serves blocks otherwise
+func NewBlockSyncReactor(state ttypes.StateAgent, store *ttypes.BlockStore, conR *ConsensusReactor, fastSync bool) *BlockSyncReactor {
+	bcR := &BlockSyncReactor{
+		state:       state,
+		store:       store,
+		conR:        conR,
+		syncing:     fastSync,
+		peerHeights: make(map[tp2p.ID]uint64),
+		timeouts:    make(map[tp2p.ID]int),
+		evicted:     make(map[tp2p.ID]time.Time),
+		requests:    make(map[uint64]*blockRequest),
+	}
+	bcR.BaseReactor = *tp2p.NewBaseReactor("BlockSyncReactor", bcR)
+	return bcR
+}
+
+// OnStart implements BaseService by starting the sync routine if we're syncing.
+func (bcR *BlockSyncReactor) OnStart() error {
+	if bcR.Syncing() {
+		bcR.mtx.Lock()
+		bcR.startTime = time.Now()
+		bcR.mtx.Unlock()
+		go bcR.poolRoutine()
+	}
+	return nil
+}
+
+// GetChannels implements Reactor
+func (bcR *BlockSyncReactor) GetChannels() []*tp2p.ChannelDescriptor {
+	return []*tp2p.ChannelDescriptor{
+		{
+			ID:                  BlockSyncChannel,
+			Priority:            5,
+			SendQueueCapacity:   1000,
+			RecvBufferCapacity:  50 * 4096,
+			RecvMessageCapacity: maxMsgSize,
+		},
+	}
+}
+
+// AddPeer implements Reactor by exchanging the block heights with the peer.
+func (bcR *BlockSyncReactor) AddPeer(peer tp2p.Peer) {
+	peer.TrySend(BlockSyncChannel, cdc.MustMarshalBinaryBare(&BlockStatusResponseMessage{Height: bcR.store.MaxBlockHeight()}))
+	if bcR.Syncing() {
+		peer.TrySend(BlockSyncChannel, cdc.MustMarshalBinaryBare(&BlockStatusRequestMessage{}))
+	}
+}
+
+// RemovePeer implements Reactor by forgetting the peer and its pending requests.
+func (bcR *BlockSyncReactor) RemovePeer(peer tp2p.Peer, reason interface{}) {
+	bcR.mtx.Lock()
+	defer bcR.mtx.Unlock()
+	bcR.dropPeer(peer.ID())
+	delete(bcR.evicted, peer.ID())
+}
+
+// dropPeer removes the peer and its pending requests, the lock must be held.
+func (bcR *BlockSyncReactor) dropPeer(id tp2p.ID) {
+	delete(bcR.peerHeights, id)
+	delete(bcR.timeouts, id)
+	for height, req := range bcR.requests {
+		if req.peer == id {
+			delete(bcR.requests, height)
+		}
+	}
+}
+
+// evictPeer stops syncing from a peer not delivering the blocks it announced.
+// Its status is ignored for peerEvictionTime, the lock must be held.
+func (bcR *BlockSyncReactor) evictPeer(id tp2p.ID, now time.Time) {
+	log.Info("Evicting unresponsive block sync peer", "peer", id, "timeouts", bcR.timeouts[id])
+	bcR.dropPeer(id)
+	bcR.evicted[id] = now.Add(peerEvictionTime)
+}
+
+// Syncing returns whether the reactor is still downloading blocks.
+func (bcR *BlockSyncReactor) Syncing() bool {
+	bcR.mtx.Lock()
+	defer bcR.mtx.Unlock()
+	return bcR.syncing
+}
+
+// Receive implements Reactor
+func (bcR *BlockSyncReactor) Receive(chID byte, src tp2p.Peer, msgBytes []byte) {
+	msg, err := decodeBlockSyncMsg(msgBytes)
+	if err != nil {
+		log.Debug("Error decoding block sync message", "src", src, "chId", chID, "err", err)
+		bcR.Switch.StopPeerForError(src, err)
+		return
+	}
+	switch msg := msg.(type) {
+	case *BlockStatusRequestMessage:
+		src.TrySend(BlockSyncChannel, cdc.MustMarshalBinaryBare(&BlockStatusResponseMessage{Height: bcR.store.MaxBlockHeight()}))
+	case *BlockStatusResponseMessage:
+		bcR.mtx.Lock()
+		if until, ok := bcR.evicted[src.ID()]; !ok || time.Now().After(until) {
+			delete(bcR.evicted, src.ID())
+			bcR.peerHeights[src.ID()] = msg.Height
+		}
+		bcR.mtx.Unlock()
+	case *BlockRequestMessage:
+		if msg.To < msg.From || msg.To-msg.From >= maxBlocksPerRequest {
+			bcR.Switch.StopPeerForError(src, fmt.Errorf("invalid block request [%d,%d]", msg.From, msg.To))
+			return
+		}
+		bcR.respondToPeer(msg, src)
+	case *BlockResponseMessage:
+		block := new(ctypes.Block)
+		if err := rlp.DecodeBytes(msg.Block, block); err != nil {
+			bcR.Switch.StopPeerForError(src, err)
+			return
+		}
+		bcR.mtx.Lock()
+		if req, ok := bcR.requests[block.NumberU64()]; ok && req.peer == src.ID() && req.block == nil {
+			req.block, req.commit = block, msg.Commit
+			delete(bcR.timeouts, src.ID())
+		} else {
+			log.Debug("Unsolicited block", "peer", src.ID(), "height", block.NumberU64())
+		}
+		bcR.mtx.Unlock()
+	case *NoBlockResponseMessage:
+		bcR.mtx.Lock()
+		// Ask the height again, maybe from another peer
+		if req, ok := bcR.requests[msg.Height]; ok && req.peer == src.ID() && req.block == nil {
+			delete(bcR.requests, msg.Height)
+		}
+		if height, ok := bcR.peerHeights[src.ID()]; ok && height >= msg.Height && msg.Height > 0 {
+			bcR.peerHeights[src.ID()] = msg.Height - 1
+		}
+		bcR.mtx.Unlock()
+	default:
+		log.Debug(fmt.Sprintf("Unknown message type %v", reflect.TypeOf(msg)))
+	}
+}
+
+// respondToPeer sends the requested blocks with their commits, or tells the peer
+// the blocks left the store.
+func (bcR *BlockSyncReactor) respondToPeer(msg *BlockRequestMessage, src tp2p.Peer) {
+	for height := msg.From; height <= msg.To; height++ {
+		meta := bcR.store.LoadBlockMeta(height)
+		if meta == nil || meta.Block == nil || meta.SeenCommit == nil {
+			src.TrySend(BlockSyncChannel, cdc.MustMarshalBinaryBare(&NoBlockResponseMessage{Height: height}))
+			continue
+		}
+		bz, err := rlp.EncodeToBytes(meta.Block)
+		if err != nil {
+			log.Error("Failed to encode block", "height", height, "err", err)
+			return
+		}
+		if !src.Send(BlockSyncChannel, cdc.MustMarshalBinaryBare(&BlockResponseMessage{Block: bz, Commit: meta.SeenCommit})) {
+			return
+		}
+	}
+}
+
+// poolRoutine downloads and applies blocks until we caught up with the peers.
+func (bcR *BlockSyncReactor) poolRoutine() {
+	trySyncTicker := time.NewTicker(trySyncInterval)
+	statusUpdateTicker := time.NewTicker(statusUpdateInterval)
+	switchToConsensusTicker := time.NewTicker(switchToConsensusInterval)
+	defer trySyncTicker.Stop()
+	defer statusUpdateTicker.Stop()
+	defer switchToConsensusTicker.Stop()
+
+	bcR.Switch.Broadcast(BlockSyncChannel, cdc.MustMarshalBinaryBare(&BlockStatusRequestMessage{}))
+	for {
+		select {
+		case <-trySyncTicker.C:
+			bcR.trySync()
+		case <-statusUpdateTicker.C:
+			bcR.Switch.Broadcast(BlockSyncChannel, cdc.MustMarshalBinaryBare(&BlockStatusRequestMessage{}))
+		case <-switchToConsensusTicker.C:
+			if !bcR.isCaughtUp() {
+				continue
+			}
+			bcR.mtx.Lock()
+			bcR.syncing = false
+			bcR.requests = make(map[uint64]*blockRequest)
+			synced := bcR.synced
+			bcR.mtx.Unlock()
+			log.Info("Block sync caught up, switching to consensus", "height", bcR.state.GetLastBlockHeight(), "synced", synced)
+			if bcR.conR != nil {
+				bcR.conR.SwitchToConsensus()
+			}
+			return
+		case <-bcR.Quit():
+			return
+		}
+	}
+}
+
+// trySync applies the downloaded blocks in order and requests the next ones.
+func (bcR *BlockSyncReactor) trySync() {
+	next := bcR.state.GetLastBlockHeight() + 1
+	for {
+		bcR.mtx.Lock()
+		req, ok := bcR.requests[next]
+		bcR.mtx.Unlock()
+		if !ok || req.block == nil {
+			break
+		}
+		parts, err := bcR.verifyBlock(req.block, req.commit)
+		if err == ttypes.ErrHeightOutOfCommittee {
+			// Another committee signed it, leave it to that one
+			break
+		}
+		if err != nil {
+			log.Warn("Invalid synced block", "peer", req.peer, "height", next, "err", err)
+			bcR.mtx.Lock()
+			bcR.dropPeer(req.peer)
+			bcR.mtx.Unlock()
+			if peer := bcR.Switch.Peers().Get(req.peer); peer != nil {
+				bcR.Switch.StopPeerForError(peer, err)
+			}
+			break
+		}
+		// The block is committed by the validators, a failure is ours and the
+		// block is tried again on the next tick
+		if err := bcR.state.ConsensusCommit(req.block); err != nil {
+			log.Warn("Failed to commit synced block", "height", next, "err", err)
+			break
+		}
+		if bcR.store.MaxBlockHeight() < next {
+			bcR.store.SaveBlock(req.block, parts, req.commit, nil)
+		}
+		bcR.mtx.Lock()
+		delete(bcR.requests, next)
+		bcR.synced++
+		bcR.mtx.Unlock()
+		next++
+	}
+	bcR.makeRequests(next)
+}
+
+// verifyBlock checks the block was committed by +2/3 of the validators of its
+// height and returns the part set it was proposed with.
+func (bcR *BlockSyncReactor) verifyBlock(block *ctypes.Block, commit *ttypes.Commit) (*ttypes.PartSet, error) {
+	vals, err := bcR.state.GetValidatorAt(block.NumberU64())
+	if err != nil {
+		return nil, err
+	}
+	if vals == nil {
+		return nil, fmt.Errorf("no validators for height %d", block.NumberU64())
+	}
+	if commit == nil || !commit.IsCommit() {
+		return nil, errBlockSyncCommit
+	}
+	hash := block.Hash()
+	if !help.EqualHashes(hash[:], commit.BlockID.Hash) {
+		return nil, errBlockSyncCommit
+	}
+	if err := vals.VerifyCommit(bcR.state.GetChainID(), commit.BlockID, block.NumberU64(), commit); err != nil {
+		return nil, err
+	}
+	// The block was proposed before the committee signs were attached
+	header := block.Header()
+	header.Signs = nil
+	parts, err := ttypes.MakePartSet(ttypes.BlockPartSizeBytes, block.WithSeal(header))
+	if err != nil {
+		return nil, err
+	}
+	if !parts.HasHeader(commit.BlockID.PartsHeader) {
+		return nil, errBlockSyncCommit
+	}
+	return parts, nil
+}
+
+// makeRequests spreads the missing heights of the committee ahead of next over
+// the peers. Peers timing out maxPeerTimeouts times in a row are evicted.
+func (bcR *BlockSyncReactor) makeRequests(next uint64) {
+	end := next + maxPendingBlocks
+	for end > next {
+		if _, err := bcR.state.GetValidatorAt(end - 1); err != ttypes.ErrHeightOutOfCommittee {
+			break
+		}
+		end--
+	}
+
+	bcR.mtx.Lock()
+	defer bcR.mtx.Unlock()
+
+	now := time.Now()
+	timedOut := make(map[tp2p.ID]bool)
+	for height, req := range bcR.requests {
+		switch {
+		case height < next:
+			delete(bcR.requests, height)
+		case req.block == nil && now.Sub(req.sent) > blockRequestTimeout:
+			log.Debug("Block request timed out", "peer", req.peer, "height", height)
+			delete(bcR.requests, height)
+			timedOut[req.peer] = true
+		}
+	}
+	// a request spans several heights, count it once
+	for id := range timedOut {
+		if bcR.timeouts[id]++; bcR.timeouts[id] >= maxPeerTimeouts {
+			bcR.evictPeer(id, now)
+		}
+	}
+	pending := make(map[tp2p.ID]int)
+	for _, req := range bcR.requests {
+		pending[req.peer]++
+	}
+	for from := next; from < end; from++ {
+		if _, ok := bcR.requests[from]; ok {
+			continue
+		}
+		// Pick the least busy peer having the height
+		var (
+			id     tp2p.ID
+			height uint64
+			found  bool
+		)
+		for pid, h := range bcR.peerHeights {
+			if h >= from && (!found || pending[pid] < pending[id]) {
+				id, height, found = pid, h, true
+			}
+		}
+		if !found {
+			return
+		}
+		to := from
+		for to+1 < end && to+1 <= height && to+1-from < maxBlocksPerRequest {
+			if _, ok := bcR.requests[to+1]; ok {
+				break
+			}
+			to++
+		}
+		peer := bcR.Switch.Peers().Get(id)
+		if peer == nil {
+			delete(bcR.peerHeights, id)
+			continue
+		}
+		if !peer.TrySend(BlockSyncChannel, cdc.MustMarshalBinaryBare(&BlockRequestMessage{From: from, To: to})) {
+			return
+		}
+		for h := from; h <= to; h++ {
+			bcR.requests[h] = &blockRequest{peer: id, sent: now}
+		}
+		pending[id] += int(to - from + 1)
+		from = to
+	}
+}
+
+// isCaughtUp reports whether no peer is ahead of us, or the next height isn't
+// committed by our committee. Evicted peers don't count. Without any peer we start consensus
+// after noPeersSyncTimeout.
+func (bcR *BlockSyncReactor) isCaughtUp() bool {
+	height := bcR.state.GetLastBlockHeight()
+	if _, err := bcR.state.GetValidatorAt(height + 1); err == ttypes.ErrHeightOutOfCommittee {
+		return true
+	}
+
+	bcR.mtx.Lock()
+	defer bcR.mtx.Unlock()
+	if len(bcR.peerHeights) == 0 {
+		return time.Since(bcR.startTime) > noPeersSyncTimeout
+	}
+	for _, h := range bcR.peerHeights {
+		if h > height {
+			return false
+		}
+	}
+	return true
+}
+
+//-------------------------------------
+
+// BlockSyncMessage is a message sent or received by the BlockSyncReactor.
+type BlockSyncMessage interface{}
+
+// RegisterBlockSyncMessages registers the block sync messages on the codec.
+func RegisterBlockSyncMessages(cdc *amino.Codec) {
+	cdc.RegisterInterface((*BlockSyncMessage)(nil), nil)
+	cdc.RegisterConcrete(&BlockStatusRequestMessage{}, "true/BlockStatusRequest", nil)
+	cdc.RegisterConcrete(&BlockStatusResponseMessage{}, "true/BlockStatusResponse", nil)
+	cdc.RegisterConcrete(&BlockRequestMessage{}, "true/BlockRequest", nil)
+	cdc.RegisterConcrete(&BlockResponseMessage{}, "true/BlockResponse", nil)
+	cdc.RegisterConcrete(&NoBlockResponseMessage{}, "true/NoBlockResponse", nil)
+}
+
+func decodeBlockSyncMsg(bz []byte) (msg BlockSyncMessage, err error) {
+	if len(bz) > maxMsgSize {
+		return msg, fmt.Errorf("msg exceeds max size (%d > %d)", len(bz), maxMsgSize)
+	}
+	err = cdc.UnmarshalBinaryBare(bz, &msg)
+	return
+}
+
+// BlockStatusRequestMessage asks a peer for the height of its last block.
+type BlockStatusRequestMessage struct{}
+
+// String returns a string representation.
+func (m *BlockStatusRequestMessage) String() string {
+	return "[BlockStatusRequest]"
+}
+
+// BlockStatusResponseMessage reports the height of the last stored block.
+type BlockStatusResponseMessage struct {
+	Height uint64
+}
+
+// String returns a string representation.
+func (m *BlockStatusResponseMessage) String() string {
+	return fmt.Sprintf("[BlockStatusResponse %v]", m.Height)
+}
+
+// BlockRequestMessage asks a peer for the blocks From to To inclusive.
+type BlockRequestMessage struct {
+	From uint64
+	To   uint64
+}
+
+// String returns a string representation.
+func (m *BlockRequestMessage) String() string {
+	return fmt.Sprintf("[BlockRequest %v-%v]", m.From, m.To)
+}
+
+// BlockResponseMessage carries a RLP encoded block and the commit finalizing it.
+type BlockResponseMessage struct {
+	Block  []byte
+	Commit *ttypes.Commit
+}
+
+// String returns a string representation.
+func (m *BlockResponseMessage) String() string {
+	if m.Commit == nil {
+		return "[BlockResponse nil-Commit]"
+	}
+	return fmt.Sprintf("[BlockResponse %v]", m.Commit.Height())
+}
+
+// NoBlockResponseMessage tells the peer the block isn't available.
+type NoBlockResponseMessage struct {
+	Height uint64
+}
+
+// String returns a string representation.
+func (m *NoBlockResponseMessage) String() string {
+	return fmt.Sprintf("[NoBlockResponse %v]", m.Height)
+}
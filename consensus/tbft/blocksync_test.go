@@ -0,0 +1,335 @@
+package tbft
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	tcrypto "ethereum/rpc-network/consensus/tbft/crypto"
+	"ethereum/rpc-network/consensus/tbft/help"
+	"ethereum/rpc-network/consensus/tbft/tp2p"
+	"ethereum/rpc-network/consensus/tbft/tp2p/dummy"
+	ttypes "ethereum/rpc-network/consensus/tbft/types"
+	"ethereum/rpc-network/core/types"
+	"ethereum/rpc-network/crypto"
+	cfg "ethereum/rpc-network/params"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const syncChainID = "tbft-sync"
+
+// syncNet is a committee whose first member lags behind and syncs the blocks
+// the others store.
+type syncNet struct {
+	clock     *help.VirtualClock
+	net       *dummy.Network
+	keys      []*ecdsa.PrivateKey
+	committee *types.CommitteeInfo
+	vals      *ttypes.ValidatorSet
+	blocks    []*types.Block
+	syncer    *BlockSyncReactor
+	state     *ttypes.StateAgentImpl
+	servers   []*BlockSyncReactor
+}
+
+func newSyncNet(t *testing.T, n int, blocks int) *syncNet {
+	sn := &syncNet{
+		clock:     help.NewVirtualClock(),
+		committee: &types.CommitteeInfo{Id: common.Big1, StartHeight: common.Big1},
+	}
+	sn.net = dummy.NewNetwork(sn.clock, 1)
+	for i := 0; i < n; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sn.keys = append(sn.keys, key)
+		sn.committee.Members = append(sn.committee.Members, &types.CommitteeMember{
+			CommitteeBase: crypto.PubkeyToAddress(key.PublicKey),
+			Publickey:     crypto.FromECDSAPub(&key.PublicKey),
+			Flag:          types.StateUsedFlag,
+			MType:         types.TypeWorked,
+		})
+	}
+	sn.vals = MakeValidators(sn.committee)
+
+	var parent common.Hash
+	for h := 1; h <= blocks; h++ {
+		block := types.NewBlockWithHeader(&types.Header{
+			ParentHash: parent,
+			Number:     big.NewInt(int64(h)),
+			Difficulty: common.Big1,
+			Time:       uint64(h),
+		})
+		sn.blocks = append(sn.blocks, block)
+		parent = block.Hash()
+	}
+	return sn
+}
+
+// commit returns the block at height h with the precommits of the given keys.
+func (sn *syncNet) commit(t *testing.T, h int, vals *ttypes.ValidatorSet, keys []*ecdsa.PrivateKey) (*types.Block, *ttypes.PartSet, *ttypes.Commit) {
+	block := sn.blocks[h-1]
+	parts, err := ttypes.MakePartSet(ttypes.BlockPartSizeBytes, block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := block.Hash()
+	blockID := ttypes.BlockID{Hash: hash[:], PartsHeader: parts.Header()}
+	voteSet := ttypes.NewVoteSet(syncChainID, uint64(h), 0, ttypes.VoteTypePrecommit, vals)
+	for _, key := range keys {
+		priv := ttypes.NewPrivValidator(*key)
+		index, _ := vals.GetByAddress(priv.GetAddress())
+		vote := &ttypes.Vote{
+			ValidatorAddress: priv.GetAddress(),
+			ValidatorIndex:   uint(index),
+			Height:           uint64(h),
+			Result:           types.VoteAgree,
+			Timestamp:        time.Now(),
+			Type:             ttypes.VoteTypePrecommit,
+			BlockID:          blockID,
+		}
+		if err := priv.SignVote(syncChainID, vote); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := voteSet.AddVote(vote); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return block, parts, voteSet.MakeCommit()
+}
+
+// addServer adds a member storing the first height blocks, committed by the
+// given validators.
+func (sn *syncNet) addServer(t *testing.T, i, height int, vals *ttypes.ValidatorSet, keys []*ecdsa.PrivateKey) *BlockSyncReactor {
+	store := ttypes.NewBlockStore()
+	for h := 1; h <= height; h++ {
+		block, parts, commit := sn.commit(t, h, vals, keys)
+		store.SaveBlock(block, parts, commit, nil)
+	}
+	sa := ttypes.NewStateAgent(&simAgent{key: sn.keys[i]}, syncChainID, sn.vals, 1, 1)
+	bcR := NewBlockSyncReactor(sa, store, nil, false)
+	sn.addSwitch(t, sn.keys[i], sa, bcR)
+	sn.servers = append(sn.servers, bcR)
+	return bcR
+}
+
+func (sn *syncNet) addSwitch(t *testing.T, key *ecdsa.PrivateKey, sa *ttypes.StateAgentImpl, bcR *BlockSyncReactor) {
+	sw := tp2p.NewSwitch(&cfg.P2PConfig{}, sa)
+	sw.AddReactor("BLOCKSYNC", bcR)
+	sn.net.AddSwitch(tp2p.PubKeyToID(tcrypto.PubKeyTrue(key.PublicKey)), sw)
+	if err := sw.Start(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// start adds the lagging member and connects everyone. The sync routine isn't
+// run, the tests drive the reactor step by step.
+func (sn *syncNet) start(t *testing.T) {
+	sn.state = ttypes.NewStateAgent(&simAgent{key: sn.keys[0]}, syncChainID, sn.vals, 1, 1)
+	sn.syncer = NewBlockSyncReactor(sn.state, ttypes.NewBlockStore(), nil, false)
+	sn.addSwitch(t, sn.keys[0], sn.state, sn.syncer)
+	sn.syncer.syncing = true
+	sn.syncer.startTime = time.Now()
+	if err := sn.net.ConnectAll(); err != nil {
+		t.Fatal(err)
+	}
+	sn.clock.Advance(time.Millisecond)
+}
+
+func (sn *syncNet) stop() {
+	for _, bcR := range append(sn.servers, sn.syncer) {
+		bcR.Switch.Stop()
+	}
+}
+
+// step requests and applies blocks, then delivers the messages sent.
+func (sn *syncNet) step() {
+	sn.syncer.trySync()
+	sn.clock.Advance(time.Millisecond)
+	sn.syncer.trySync()
+}
+
+// expire lets the pending requests of the syncer time out.
+func (sn *syncNet) expire() {
+	sn.syncer.mtx.Lock()
+	defer sn.syncer.mtx.Unlock()
+	for _, req := range sn.syncer.requests {
+		if req.block == nil {
+			req.sent = req.sent.Add(-blockRequestTimeout - time.Second)
+		}
+	}
+}
+
+// verify checks the block at height h with the precommits of the given keys.
+func (sn *syncNet) verify(t *testing.T, h int, vals *ttypes.ValidatorSet, keys []*ecdsa.PrivateKey) error {
+	block, _, commit := sn.commit(t, h, vals, keys)
+	_, err := sn.syncer.verifyBlock(block, commit)
+	return err
+}
+
+func (sn *syncNet) height() uint64 {
+	return sn.state.GetLastBlockHeight()
+}
+
+func TestBlockSync(t *testing.T) {
+	sn := newSyncNet(t, 4, 40)
+	for i := 1; i < 4; i++ {
+		sn.addServer(t, i, 40, sn.vals, sn.keys[:3])
+	}
+	sn.start(t)
+	defer sn.stop()
+
+	if sn.syncer.isCaughtUp() {
+		t.Fatal("caught up before syncing")
+	}
+	for i := 0; i < 10 && sn.height() < 40; i++ {
+		sn.step()
+	}
+	if sn.height() != 40 {
+		t.Fatalf("synced to %d, want 40", sn.height())
+	}
+	if !sn.syncer.isCaughtUp() {
+		t.Fatal("not caught up after syncing")
+	}
+	if sn.syncer.store.MaxBlockHeight() != 40 {
+		t.Fatalf("stored up to %d, want 40", sn.syncer.store.MaxBlockHeight())
+	}
+}
+
+func TestBlockSyncTimeout(t *testing.T) {
+	sn := newSyncNet(t, 4, 20)
+	sn.addServer(t, 1, 10, sn.vals, sn.keys[:3])
+	// the second server announces more blocks than the first but never sends them
+	silent := sn.addServer(t, 2, 20, sn.vals, sn.keys[:3])
+	silentID := tp2p.PubKeyToID(tcrypto.PubKeyTrue(sn.keys[2].PublicKey))
+	sn.start(t)
+	defer sn.stop()
+
+	sn.net.AddFilter(func(from, to tp2p.ID, chID byte, msg []byte) bool {
+		return from != silentID
+	})
+	for i := 0; i < maxPeerTimeouts; i++ {
+		sn.step()
+		if sn.syncer.isCaughtUp() {
+			t.Fatalf("caught up at %d while the silent peer is in", sn.height())
+		}
+		sn.expire()
+	}
+	sn.step()
+	sn.syncer.mtx.Lock()
+	_, known := sn.syncer.peerHeights[silentID]
+	sn.syncer.mtx.Unlock()
+	if known {
+		t.Fatal("silent peer not evicted")
+	}
+	if sn.height() != 10 {
+		t.Fatalf("synced to %d, want 10", sn.height())
+	}
+	if !sn.syncer.isCaughtUp() {
+		t.Fatal("not caught up with the responding peer")
+	}
+
+	// the status of the evicted peer is ignored
+	peer := sn.syncer.Switch.Peers().Get(silentID)
+	if peer == nil {
+		t.Fatal("silent peer disconnected")
+	}
+	sn.syncer.Receive(BlockSyncChannel, peer, cdc.MustMarshalBinaryBare(&BlockStatusResponseMessage{Height: silent.store.MaxBlockHeight()}))
+	if !sn.syncer.isCaughtUp() {
+		t.Fatal("evicted peer status counted")
+	}
+}
+
+func TestBlockSyncBadPeer(t *testing.T) {
+	sn := newSyncNet(t, 4, 20)
+	// the first server's commits are signed by keys outside the committee
+	var others []*ecdsa.PrivateKey
+	other := &types.CommitteeInfo{Id: common.Big1}
+	for i := 0; i < 3; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		others = append(others, key)
+		other.Members = append(other.Members, &types.CommitteeMember{
+			Publickey: crypto.FromECDSAPub(&key.PublicKey),
+			Flag:      types.StateUsedFlag,
+		})
+	}
+	sn.addServer(t, 1, 20, MakeValidators(other), others)
+	sn.addServer(t, 2, 20, sn.vals, sn.keys[:3])
+	badID := tp2p.PubKeyToID(tcrypto.PubKeyTrue(sn.keys[1].PublicKey))
+	sn.start(t)
+	defer sn.stop()
+
+	for i := 0; i < 10 && sn.height() < 20; i++ {
+		sn.step()
+	}
+	if sn.height() != 20 {
+		t.Fatalf("synced to %d, want 20", sn.height())
+	}
+	if sn.syncer.Switch.Peers().Has(badID) {
+		t.Fatal("peer serving invalid commits still connected")
+	}
+}
+
+func TestBlockSyncCommitteeRange(t *testing.T) {
+	sn := newSyncNet(t, 4, 20)
+	for i := 1; i < 4; i++ {
+		sn.addServer(t, i, 20, sn.vals, sn.keys[:3])
+	}
+	sn.start(t)
+	defer sn.stop()
+	// the committee ends at 12, the blocks after are signed by the next one
+	sn.state.SetEndHeight(12)
+
+	for i := 0; i < 10; i++ {
+		sn.step()
+	}
+	if sn.height() != 12 {
+		t.Fatalf("synced to %d, want 12", sn.height())
+	}
+	if !sn.syncer.isCaughtUp() {
+		t.Fatal("not caught up at the end of the committee")
+	}
+	if err := sn.verify(t, 13, sn.vals, sn.keys[:3]); err != ttypes.ErrHeightOutOfCommittee {
+		t.Fatalf("block after the committee: have %v, want %v", err, ttypes.ErrHeightOutOfCommittee)
+	}
+}
+
+func TestBlockSyncValidatorUpdate(t *testing.T) {
+	sn := newSyncNet(t, 4, 20)
+	sn.start(t)
+	defer sn.stop()
+
+	// the whole committee commits up to 5, the last two members afterwards
+	for h := 1; h <= 5; h++ {
+		block, _, _ := sn.commit(t, h, sn.vals, sn.keys[:3])
+		if err := sn.state.ConsensusCommit(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	shrunk := &types.CommitteeInfo{Id: common.Big1, Members: sn.committee.Members[2:]}
+	newVals := MakeValidators(shrunk)
+	if err := sn.state.UpdateValidator(newVals, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sn.verify(t, 5, sn.vals, sn.keys[:3]); err != nil {
+		t.Fatalf("block of the old members rejected: %v", err)
+	}
+	if err := sn.verify(t, 6, newVals, sn.keys[2:]); err != nil {
+		t.Fatalf("block of the new members rejected: %v", err)
+	}
+	if err := sn.verify(t, 6, sn.vals, sn.keys[:3]); err == nil {
+		t.Fatal("block of the old members accepted after the update")
+	}
+}
+
+func TestBlockResponseMessageString(t *testing.T) {
+	if s := (&BlockResponseMessage{}).String(); s == "" {
+		t.Fatal("empty string")
+	}
+}
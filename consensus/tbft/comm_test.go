@@ -4,6 +4,7 @@ import (
 	"fmt"
 	types2 "ethereum/rpc-network/consensus/tbft/types"
 	"ethereum/rpc-network/core/types"
+	"github.com/ethereum/go-ethereum/trie"
 	"math/big"
 	"testing"
 	"time"
@@ -35,9 +36,8 @@ func TestReader(t *testing.T) {
 	}
 
 	var re []*types.Receipt
-	var si []*types.PbftSign
 
-	bTmp := types.NewBlock(header, tr, re, si, nil)
+	bTmp := types.NewBlock(header, tr, nil, re, new(trie.Trie))
 
 	ps, _ := types2.MakePartSet(64*1024, bTmp)
 	pe := types2.NewPartSetFromHeader(ps.Header())
@@ -47,7 +47,7 @@ func TestReader(t *testing.T) {
 	}
 
 	header2 := &types.Header{}
-	n, e := cdc.UnmarshalBinaryReader(pe.GetReader(), &header2, 37502)
+	n, e := cdc.UnmarshalBinaryLengthPrefixedReader(pe.GetReader(), &header2, 37502)
 	fmt.Println(n, e)
 	fmt.Println(header)
 	fmt.Println(header2)
@@ -0,0 +1,332 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"ethereum/rpc-network/consensus/tbft/help"
+	tcrypyo "ethereum/rpc-network/crypto"
+	"ethereum/rpc-network/crypto/bls12381"
+)
+
+const (
+	BLSPrivKeyAminoRoute = "true/PrivKeyBLS"
+	BLSPubKeyAminoRoute  = "true/PubKeyBLS"
+	// PubKeyBLSSize is the size of an uncompressed G1 point
+	PubKeyBLSSize = 96
+	// SignatureBLSSize is the size of an uncompressed G2 point
+	SignatureBLSSize = 192
+)
+
+var (
+	// signature and proof of possession domain separation tags, see the IETF
+	// BLS signature draft, proof of possession scheme.
+	blsSignatureDST = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+	blsPopDST       = []byte("BLS_POP_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+
+	// blsFieldModulus is the base field modulus p of BLS12-381
+	blsFieldModulus, _ = new(big.Int).SetString("1a0111ea397fe69a4b1ba7b6434bacd764774b84f38512bf6730d2a0f6b0f6241eabfffeb153ffffb9feffffffffaaab", 16)
+
+	errBLSInfinity = errors.New("bls point at infinity")
+)
+
+func init() {
+	cdc.RegisterConcrete(PubKeyBLS{},
+		BLSPubKeyAminoRoute, nil)
+	cdc.RegisterConcrete(PrivKeyBLS{},
+		BLSPrivKeyAminoRoute, nil)
+}
+
+//-------------------------------------
+
+// PrivKeyBLS implements PrivKey for BLS signatures on BLS12-381, with public keys
+// in G1 and signatures in G2. Signatures on the same message can be aggregated.
+type PrivKeyBLS [32]byte
+
+// GenPrivKeyBLS generates a new BLS private key.
+func GenPrivKeyBLS() PrivKeyBLS {
+	sk, err := rand.Int(rand.Reader, bls12381.NewG1().Q())
+	if err != nil {
+		panic(err)
+	}
+	if sk.Sign() == 0 {
+		sk.SetUint64(1)
+	}
+	var priv PrivKeyBLS
+	sk.FillBytes(priv[:])
+	return priv
+}
+
+func (priv PrivKeyBLS) scalar() *big.Int {
+	return new(big.Int).SetBytes(priv[:])
+}
+
+// Bytes marshals the privkey using amino encoding.
+func (priv PrivKeyBLS) Bytes() []byte {
+	return cdc.MustMarshalBinaryBare(priv)
+}
+
+// Sign produces a signature on the provided message.
+func (priv PrivKeyBLS) Sign(msg []byte) ([]byte, error) {
+	return priv.sign(msg, blsSignatureDST)
+}
+
+// ProofOfPossession signs the public key itself, it must be checked with
+// PubKeyBLS.VerifyPossession before the key is admitted to a validator set.
+func (priv PrivKeyBLS) ProofOfPossession() ([]byte, error) {
+	return priv.sign(priv.PubKey().Bytes(), blsPopDST)
+}
+
+func (priv PrivKeyBLS) sign(msg, dst []byte) ([]byte, error) {
+	h, err := hashToG2(msg, dst)
+	if err != nil {
+		return nil, err
+	}
+	g2 := bls12381.NewG2()
+	return g2.ToBytes(g2.MulScalar(g2.New(), h, priv.scalar())), nil
+}
+
+// PubKey gets the corresponding public key from the private key.
+func (priv PrivKeyBLS) PubKey() PubKey {
+	g1 := bls12381.NewG1()
+	var pub PubKeyBLS
+	copy(pub[:], g1.ToBytes(g1.MulScalar(g1.New(), g1.One(), priv.scalar())))
+	return pub
+}
+
+// Equals is comp private key
+func (priv PrivKeyBLS) Equals(other PrivKey) bool {
+	if otherBLS, ok := other.(PrivKeyBLS); ok {
+		return bytes.Equal(priv[:], otherBLS[:])
+	}
+	return false
+}
+
+//-------------------------------------
+
+// PubKeyBLS implements PubKey for the BLS signature scheme.
+type PubKeyBLS [PubKeyBLSSize]byte
+
+// PubKeyBLSFromBytes returns the public key in pub if proof shows possession of
+// its private key. Keys without a valid proof are rejected, they could be chosen
+// to cancel out the keys of other signers of an aggregate.
+func PubKeyBLSFromBytes(pub, proof []byte) (PubKeyBLS, error) {
+	var key PubKeyBLS
+	if len(pub) != PubKeyBLSSize {
+		return key, fmt.Errorf("invalid bls public key length %d", len(pub))
+	}
+	copy(key[:], pub)
+	if len(proof) == 0 {
+		return key, errors.New("missing bls proof of possession")
+	}
+	if !key.VerifyPossession(proof) {
+		return key, errors.New("invalid bls proof of possession")
+	}
+	return key, nil
+}
+
+// VerifyPossessionBLS checks that proof shows possession of the private key of the
+// public key pub, like PubKeyBLSFromBytes.
+func VerifyPossessionBLS(pub, proof []byte) error {
+	_, err := PubKeyBLSFromBytes(pub, proof)
+	return err
+}
+
+// Address is the last 20 bytes of the Keccak256 of the raw pubkey bytes.
+func (pub PubKeyBLS) Address() help.Address {
+	return help.Address(tcrypyo.Keccak256(pub[:])[12:])
+}
+
+// Bytes returns the uncompressed G1 point.
+func (pub PubKeyBLS) Bytes() []byte {
+	return pub[:]
+}
+
+func (pub PubKeyBLS) point() (*bls12381.PointG1, error) {
+	g1 := bls12381.NewG1()
+	p, err := g1.FromBytes(pub[:])
+	if err != nil {
+		return nil, err
+	}
+	if g1.IsZero(p) {
+		return nil, errBLSInfinity
+	}
+	if !g1.InCorrectSubgroup(p) {
+		return nil, errors.New("bls public key not in subgroup")
+	}
+	return p, nil
+}
+
+// VerifyBytes is check msg
+func (pub PubKeyBLS) VerifyBytes(msg []byte, sig []byte) bool {
+	p, err := pub.point()
+	if err != nil {
+		return false
+	}
+	return verifyBLS(p, msg, sig, blsSignatureDST)
+}
+
+// VerifyPossession checks a proof of possession made by PrivKeyBLS.ProofOfPossession.
+func (pub PubKeyBLS) VerifyPossession(proof []byte) bool {
+	p, err := pub.point()
+	if err != nil {
+		return false
+	}
+	return verifyBLS(p, pub[:], proof, blsPopDST)
+}
+
+func (pub PubKeyBLS) String() string {
+	return fmt.Sprintf("PubKeyBLS{%X}", pub[:])
+}
+
+// Equals is comp public key
+func (pub PubKeyBLS) Equals(other PubKey) bool {
+	if otherBLS, ok := other.(PubKeyBLS); ok {
+		return bytes.Equal(pub[:], otherBLS[:])
+	}
+	return false
+}
+
+//-------------------------------------
+
+// AggregateSignaturesBLS sums up BLS signatures into a single one.
+func AggregateSignaturesBLS(sigs [][]byte) ([]byte, error) {
+	if len(sigs) == 0 {
+		return nil, errors.New("no signatures to aggregate")
+	}
+	g2 := bls12381.NewG2()
+	agg := g2.Zero()
+	for i, sig := range sigs {
+		p, err := decodeSignatureBLS(sig)
+		if err != nil {
+			return nil, fmt.Errorf("signature %d: %v", i, err)
+		}
+		g2.Add(agg, agg, p)
+	}
+	return g2.ToBytes(agg), nil
+}
+
+// VerifyAggregateBLS checks an aggregate signature of the given keys over the
+// same message. The keys must have proven possession, see PubKeyBLSFromBytes,
+// otherwise a rogue key can forge the aggregate.
+func VerifyAggregateBLS(pubs []PubKeyBLS, msg []byte, sig []byte) bool {
+	if len(pubs) == 0 {
+		return false
+	}
+	g1 := bls12381.NewG1()
+	agg := g1.Zero()
+	for _, pub := range pubs {
+		p, err := pub.point()
+		if err != nil {
+			return false
+		}
+		g1.Add(agg, agg, p)
+	}
+	if g1.IsZero(agg) {
+		return false
+	}
+	return verifyBLS(agg, msg, sig, blsSignatureDST)
+}
+
+func decodeSignatureBLS(sig []byte) (*bls12381.PointG2, error) {
+	g2 := bls12381.NewG2()
+	p, err := g2.FromBytes(sig)
+	if err != nil {
+		return nil, err
+	}
+	if g2.IsZero(p) {
+		return nil, errBLSInfinity
+	}
+	if !g2.InCorrectSubgroup(p) {
+		return nil, errors.New("bls signature not in subgroup")
+	}
+	return p, nil
+}
+
+// verifyBLS checks e(pub, H(msg)) == e(g1, sig).
+func verifyBLS(pub *bls12381.PointG1, msg, sig, dst []byte) bool {
+	s, err := decodeSignatureBLS(sig)
+	if err != nil {
+		return false
+	}
+	h, err := hashToG2(msg, dst)
+	if err != nil {
+		return false
+	}
+	engine := bls12381.NewPairingEngine()
+	engine.AddPair(pub, h)
+	engine.AddPairInv(engine.G1.One(), s)
+	return engine.Check()
+}
+
+// hashToG2 hashes the message to a G2 point, following hash_to_curve with
+// expand_message_xmd (SHA-256) and the simplified SWU map.
+func hashToG2(msg, dst []byte) (*bls12381.PointG2, error) {
+	uniform, err := expandMsgXMD(msg, dst, 256)
+	if err != nil {
+		return nil, err
+	}
+	g2 := bls12381.NewG2()
+	sum := g2.Zero()
+	for i := 0; i < 2; i++ {
+		c0 := reduceFieldElement(uniform[i*128 : i*128+64])
+		c1 := reduceFieldElement(uniform[i*128+64 : i*128+128])
+		// the fp2 element is encoded as c1 || c0
+		p, err := g2.MapToCurve(append(c1, c0...))
+		if err != nil {
+			return nil, err
+		}
+		g2.Add(sum, sum, p)
+	}
+	return g2.Affine(sum), nil
+}
+
+// reduceFieldElement reduces 64 uniform bytes into a 48 byte field element.
+func reduceFieldElement(in []byte) []byte {
+	e := new(big.Int).SetBytes(in)
+	e.Mod(e, blsFieldModulus)
+	return e.FillBytes(make([]byte, 48))
+}
+
+// expandMsgXMD implements expand_message_xmd with SHA-256.
+func expandMsgXMD(msg, dst []byte, outLen int) ([]byte, error) {
+	const hashLen = sha256.Size
+	ell := (outLen + hashLen - 1) / hashLen
+	if ell > 255 || len(dst) > 255 {
+		return nil, errors.New("expand message: invalid length")
+	}
+	dstPrime := append(append([]byte{}, dst...), byte(len(dst)))
+
+	h := sha256.New()
+	h.Write(make([]byte, sha256.BlockSize))
+	h.Write(msg)
+	h.Write([]byte{byte(outLen >> 8), byte(outLen), 0})
+	h.Write(dstPrime)
+	b0 := h.Sum(nil)
+
+	h.Reset()
+	h.Write(b0)
+	h.Write([]byte{1})
+	h.Write(dstPrime)
+	bi := h.Sum(nil)
+
+	out := make([]byte, 0, ell*hashLen)
+	out = append(out, bi...)
+	for i := 2; i <= ell; i++ {
+		tmp := make([]byte, hashLen)
+		for j := range tmp {
+			tmp[j] = b0[j] ^ bi[j]
+		}
+		h.Reset()
+		h.Write(tmp)
+		h.Write([]byte{byte(i)})
+		h.Write(dstPrime)
+		bi = h.Sum(nil)
+		out = append(out, bi...)
+	}
+	return out[:outLen], nil
+}
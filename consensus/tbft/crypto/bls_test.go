@@ -0,0 +1,116 @@
+package crypto
+
+import (
+	"testing"
+
+	"ethereum/rpc-network/crypto/bls12381"
+)
+
+func TestBLSSignVerify(t *testing.T) {
+	priv := GenPrivKeyBLS()
+	pub := priv.PubKey()
+	msg := []byte("tbft precommit")
+
+	sig, err := priv.Sign(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sig) != SignatureBLSSize {
+		t.Fatalf("signature size mismatch: have %d, want %d", len(sig), SignatureBLSSize)
+	}
+	if !pub.VerifyBytes(msg, sig) {
+		t.Fatal("valid signature rejected")
+	}
+	if pub.VerifyBytes([]byte("other"), sig) {
+		t.Fatal("signature verified on another message")
+	}
+	if GenPrivKeyBLS().PubKey().VerifyBytes(msg, sig) {
+		t.Fatal("signature verified with another key")
+	}
+	proof, err := priv.ProofOfPossession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pub.(PubKeyBLS).VerifyPossession(proof) {
+		t.Fatal("valid proof of possession rejected")
+	}
+	// A plain signature on the key must not pass as a proof of possession
+	plain, _ := priv.Sign(pub.Bytes())
+	if pub.(PubKeyBLS).VerifyPossession(plain) {
+		t.Fatal("signature accepted as proof of possession")
+	}
+}
+
+func TestBLSAggregate(t *testing.T) {
+	msg := []byte("tbft commit")
+	var (
+		pubs []PubKeyBLS
+		sigs [][]byte
+	)
+	for i := 0; i < 4; i++ {
+		priv := GenPrivKeyBLS()
+		sig, err := priv.Sign(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pubs = append(pubs, priv.PubKey().(PubKeyBLS))
+		sigs = append(sigs, sig)
+	}
+	agg, err := AggregateSignaturesBLS(sigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyAggregateBLS(pubs, msg, agg) {
+		t.Fatal("valid aggregate rejected")
+	}
+	if VerifyAggregateBLS(pubs[:3], msg, agg) {
+		t.Fatal("aggregate verified with a missing signer")
+	}
+	if VerifyAggregateBLS(pubs, []byte("other"), agg) {
+		t.Fatal("aggregate verified on another message")
+	}
+	if _, err := AggregateSignaturesBLS([][]byte{sigs[0], make([]byte, SignatureBLSSize)}); err == nil {
+		t.Fatal("infinity signature aggregated")
+	}
+}
+
+func TestBLSRogueKey(t *testing.T) {
+	msg := []byte("tbft commit")
+	honest := GenPrivKeyBLS().PubKey().(PubKeyBLS)
+
+	// The attacker publishes x*g1 - honest, the aggregate of both keys is x*g1
+	// and a signature made with x alone verifies as signed by both.
+	attacker := GenPrivKeyBLS()
+	g1 := bls12381.NewG1()
+	honestPoint, err := honest.point()
+	if err != nil {
+		t.Fatal(err)
+	}
+	own := g1.MulScalar(g1.New(), g1.One(), attacker.scalar())
+	var rogue PubKeyBLS
+	copy(rogue[:], g1.ToBytes(g1.Sub(g1.New(), own, honestPoint)))
+
+	forged, err := attacker.Sign(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyAggregateBLS([]PubKeyBLS{honest, rogue}, msg, forged) {
+		t.Fatal("rogue key attack failed, test is broken")
+	}
+	// Without the private key of rogue no proof of possession can be made
+	proof, err := attacker.ProofOfPossession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PubKeyBLSFromBytes(rogue[:], proof); err == nil {
+		t.Fatal("rogue key admitted")
+	}
+	if _, err := PubKeyBLSFromBytes(rogue[:], nil); err == nil {
+		t.Fatal("key without proof admitted")
+	}
+	priv := GenPrivKeyBLS()
+	proof, _ = priv.ProofOfPossession()
+	if key, err := PubKeyBLSFromBytes(priv.PubKey().Bytes(), proof); err != nil || !key.Equals(priv.PubKey()) {
+		t.Fatalf("valid key rejected: %v", err)
+	}
+}
@@ -23,12 +23,16 @@ func RegisterAmino(cdc *amino.Codec) {
 		"true/PubKeyEd25519", nil)
 	cdc.RegisterConcrete(crypto.PubKeyTrue{},
 		"true/PubKeyTrue", nil)
+	cdc.RegisterConcrete(crypto.PubKeyBLS{},
+		crypto.BLSPubKeyAminoRoute, nil)
 
 	cdc.RegisterInterface((*crypto.PrivKey)(nil), nil)
 	cdc.RegisterConcrete(ed25519.PrivKeyEd25519{},
 		"true/PrivKeyEd25519", nil)
 	cdc.RegisterConcrete(crypto.PrivKeyTrue{},
 		"true/PrivKeyTrue", nil)
+	cdc.RegisterConcrete(crypto.PrivKeyBLS{},
+		crypto.BLSPrivKeyAminoRoute, nil)
 }
 
 func PrivKeyFromBytes(privKeyBytes []byte) (privKey crypto.PrivKey, err error) {
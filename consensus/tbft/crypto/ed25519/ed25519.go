@@ -2,6 +2,7 @@ package ed25519
 
 import (
 	"bytes"
+	stded25519 "crypto/ed25519"
 	"crypto/subtle"
 	"fmt"
 	"github.com/agl/ed25519"
@@ -70,7 +71,7 @@ func (privKey PrivKeyEd25519) PubKey() crypto.PubKey {
 		return PubKeyEd25519(pubkeyBytes)
 	}
 
-	pubBytes := *ed25519.MakePublicKey(&privKeyBytes)
+	pubBytes := *makePublicKey(&privKeyBytes)
 	return PubKeyEd25519(pubBytes)
 }
 
@@ -106,10 +107,10 @@ func genPrivKey(rand io.Reader) PrivKeyEd25519 {
 	if err != nil {
 		panic(err)
 	}
-	// ed25519.MakePublicKey(privKey) alters the last 32 bytes of privKey.
+	// makePublicKey(privKey) alters the last 32 bytes of privKey.
 	// It places the pubkey in the last 32 bytes of privKey, and returns the
 	// public key.
-	ed25519.MakePublicKey(privKey)
+	makePublicKey(privKey)
 	return PrivKeyEd25519(*privKey)
 }
 
@@ -121,13 +122,22 @@ func GenPrivKeyFromSecret(secret []byte) PrivKeyEd25519 {
 	privKey32 := crypto.Sha256(secret) // Not Ripemd160 because we want 32 bytes.
 	privKey := new([64]byte)
 	copy(privKey[:32], privKey32)
-	// ed25519.MakePublicKey(privKey) alters the last 32 bytes of privKey.
+	// makePublicKey(privKey) alters the last 32 bytes of privKey.
 	// It places the pubkey in the last 32 bytes of privKey, and returns the
 	// public key.
-	ed25519.MakePublicKey(privKey)
+	makePublicKey(privKey)
 	return PrivKeyEd25519(*privKey)
 }
 
+// makePublicKey derives the public key from the seed in the first 32 bytes of
+// privKey, places it in the last 32 bytes and returns it.
+func makePublicKey(privKey *[64]byte) *[PubKeyEd25519Size]byte {
+	copy(privKey[:], stded25519.NewKeyFromSeed(privKey[:32]))
+	pubKey := new([PubKeyEd25519Size]byte)
+	copy(pubKey[:], privKey[32:])
+	return pubKey
+}
+
 //-------------------------------------
 
 var _ crypto.PubKey = PubKeyEd25519{}
@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"math/big"
+	"os"
 	"path/filepath"
 	"sync"
 	"sync/atomic"
@@ -14,6 +15,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
 	"ethereum/rpc-network/consensus/tbft/help"
 	"ethereum/rpc-network/consensus/tbft/tp2p"
 	ttypes "ethereum/rpc-network/consensus/tbft/types"
@@ -37,11 +39,16 @@ var (
 	quit = false
 )
 
+// openLogDebug prints the logs up to lvl to stderr.
+func openLogDebug(lvl log.Lvl) {
+	log.Root().SetHandler(log.LvlFilterHandler(lvl, log.StreamHandler(os.Stderr, log.TerminalFormat(false))))
+}
+
 func makeBlock() *types.Block {
 	header := new(types.Header)
 	header.Number = common.Big1
-	header.Time = big.NewInt(time.Now().Unix())
-	block := types.NewBlock(header, nil, nil, nil, nil)
+	header.Time = uint64(time.Now().Unix())
+	block := types.NewBlock(header, nil, nil, nil, new(trie.Trie))
 	return block
 }
 
@@ -189,7 +196,7 @@ func checkResult(end chan<- int, out <-chan *ttypes.SwitchValidator) {
 }
 
 func TestWatch2(t *testing.T) {
-	//log.OpenLogDebug(3)
+	//openLogDebug(log.LvlInfo)
 	help.BeginWatchMgr()
 	defer help.EndWatchMgr()
 	defer fmt.Println("End WatchMgr...")
@@ -221,7 +228,7 @@ func TestWatch2(t *testing.T) {
 }
 
 func TestWatchFinishCount(t *testing.T) {
-	log.OpenLogDebug(3)
+	openLogDebug(log.LvlInfo)
 	help.BeginWatchMgr()
 	defer help.EndWatchMgr()
 	defer fmt.Println("End WatchMgr...")
@@ -355,8 +362,8 @@ func TestBlock(t *testing.T) {
 func TestRlpBlock(t *testing.T) {
 	header := new(types.Header)
 	header.Number = common.Big1
-	header.Time = big.NewInt(time.Now().Unix())
-	block := types.NewBlock(header, nil, nil, nil, nil)
+	header.Time = uint64(time.Now().Unix())
+	block := types.NewBlock(header, nil, nil, nil, new(trie.Trie))
 	bzs, err := rlp.EncodeToBytes(block)
 	if err != nil {
 		fmt.Println(err.Error())
@@ -371,7 +378,7 @@ func TestRlpBlock(t *testing.T) {
 }
 
 func TestPbftRunForHealth(t *testing.T) {
-	log.OpenLogDebug(3)
+	openLogDebug(log.LvlInfo)
 	IDCacheInit()
 	start := make(chan int)
 	pr1 := getPrivateKey(0)
@@ -399,6 +406,7 @@ func TestPbftRunForHealth(t *testing.T) {
 
 	n1, _ := NewNode(config1, "1", pr1, agent1)
 	n1.Start()
+	defer n1.Stop()
 
 	config2 := new(config.TbftConfig)
 	*config2 = *config.DefaultConfig()
@@ -415,6 +423,7 @@ func TestPbftRunForHealth(t *testing.T) {
 
 	n2, _ := NewNode(config2, "1", pr2, agent2)
 	n2.Start()
+	defer n2.Stop()
 
 	config3 := new(config.TbftConfig)
 	*config3 = *config.DefaultConfig()
@@ -431,6 +440,7 @@ func TestPbftRunForHealth(t *testing.T) {
 
 	n3, _ := NewNode(config3, "1", pr3, agent3)
 	n3.Start()
+	defer n3.Stop()
 
 	config4 := new(config.TbftConfig)
 	*config4 = *config.DefaultConfig()
@@ -447,6 +457,7 @@ func TestPbftRunForHealth(t *testing.T) {
 
 	n4, _ := NewNode(config4, "1", pr4, agent4)
 	n4.Start()
+	defer n4.Stop()
 
 	c1 := new(types.CommitteeInfo)
 	c1.Id = big.NewInt(1)
@@ -518,7 +529,7 @@ func CloseStart(c chan int) {
 }
 
 func TestRunPbftChange1(t *testing.T) {
-	log.OpenLogDebug(3)
+	openLogDebug(log.LvlInfo)
 	IDCacheInit()
 	start := make(chan int)
 	pr1 := getPrivateKey(0)
@@ -586,6 +597,7 @@ func TestRunPbftChange1(t *testing.T) {
 	cn = append(cn, &types.CommitteeNode{IP: "127.0.0.1", Port: 28898, Port2: 28899, Coinbase: m5.Coinbase, Publickey: m5.Publickey})
 
 	n1.Start()
+	defer n1.Stop()
 	n1.PutCommittee(c1)
 	n1.PutNodes(common.Big1, cn)
 	n1.Notify(c1.Id, Start)
@@ -605,7 +617,7 @@ func TestRunPbftChange1(t *testing.T) {
 }
 
 func TestRunPbftChange2(t *testing.T) {
-	log.OpenLogDebug(3)
+	openLogDebug(log.LvlInfo)
 	IDCacheInit()
 	start := make(chan int)
 	pr1 := getPrivateKey(0)
@@ -673,6 +685,7 @@ func TestRunPbftChange2(t *testing.T) {
 	cn = append(cn, &types.CommitteeNode{IP: "127.0.0.1", Port: 28898, Port2: 28899, Coinbase: m5.Coinbase, Publickey: m5.Publickey})
 
 	n2.Start()
+	defer n2.Stop()
 	n2.PutCommittee(c1)
 	n2.Notify(c1.Id, Start)
 	n2.PutNodes(common.Big1, cn)
@@ -692,7 +705,7 @@ func TestRunPbftChange2(t *testing.T) {
 }
 
 func TestRunPbftChange3(t *testing.T) {
-	log.OpenLogDebug(3)
+	openLogDebug(log.LvlInfo)
 	IDCacheInit()
 	start := make(chan int)
 	pr1 := getPrivateKey(0)
@@ -760,6 +773,7 @@ func TestRunPbftChange3(t *testing.T) {
 	cn = append(cn, &types.CommitteeNode{IP: "127.0.0.1", Port: 28898, Port2: 28899, Coinbase: m5.Coinbase, Publickey: m5.Publickey})
 
 	n3.Start()
+	defer n3.Stop()
 	n3.PutCommittee(c1)
 	n3.Notify(c1.Id, Start)
 	n3.PutNodes(common.Big1, cn)
@@ -780,7 +794,7 @@ func TestRunPbftChange3(t *testing.T) {
 
 func TestRunPbftChange4(t *testing.T) {
 
-	log.OpenLogDebug(3)
+	openLogDebug(log.LvlInfo)
 	IDCacheInit()
 	start := make(chan int)
 	pr1 := getPrivateKey(0)
@@ -848,6 +862,7 @@ func TestRunPbftChange4(t *testing.T) {
 	cn = append(cn, &types.CommitteeNode{IP: "127.0.0.1", Port: 28898, Port2: 28899, Coinbase: m5.Coinbase, Publickey: m5.Publickey})
 
 	n4.Start()
+	defer n4.Stop()
 	n4.PutCommittee(c1)
 	n4.Notify(c1.Id, Start)
 	n4.PutNodes(common.Big1, cn)
@@ -869,7 +884,7 @@ func TestRunPbftChange4(t *testing.T) {
 var Tbft5Start = big.NewInt(9)
 
 func TestRunPbftChange5(t *testing.T) {
-	log.OpenLogDebug(3)
+	openLogDebug(log.LvlInfo)
 	IDCacheInit()
 	start := make(chan int)
 	pr1 := getPrivateKey(0)
@@ -937,6 +952,7 @@ func TestRunPbftChange5(t *testing.T) {
 	cn = append(cn, &types.CommitteeNode{IP: "127.0.0.1", Port: 28898, Port2: 28899, Coinbase: m5.Coinbase, Publickey: m5.Publickey})
 
 	n4.Start()
+	defer n4.Stop()
 	n4.PutCommittee(c1)
 	n4.Notify(c1.Id, Start)
 	n4.PutNodes(common.Big1, cn)
@@ -3,11 +3,9 @@ package help
 import (
 	"encoding/binary"
 	"fmt"
-	"math/rand"
 	"regexp"
 	"strings"
 	"sync"
-	"time"
 )
 
 // BitArray is a thread-safe implementation of a bit array.
@@ -241,7 +239,7 @@ func (bA *BitArray) IsFull() bool {
 }
 
 // PickRandom returns a random index in the bit array, and its value.
-// It uses the global randomness in `random.go` to get this index.
+// It uses the global randomness of the Rand functions to get this index.
 func (bA *BitArray) PickRandom() (uint, bool) {
 	if bA == nil {
 		return 0, false
@@ -253,14 +251,12 @@ func (bA *BitArray) PickRandom() (uint, bool) {
 	if length == 0 {
 		return 0, false
 	}
-	// md random tmp by iceming
-	random := rand.New(rand.NewSource(time.Now().Unix()))
-	randElemStart := random.Intn(length)
+	randElemStart := RandIntn(length)
 	for i := 0; i < length; i++ {
 		elemIdx := ((i + randElemStart) % length)
 		if elemIdx < length-1 {
 			if bA.Elems[elemIdx] > 0 {
-				randBitStart := random.Intn(64)
+				randBitStart := RandIntn(64)
 				for j := 0; j < 64; j++ {
 					bitIdx := ((j + randBitStart) % 64)
 					if (bA.Elems[elemIdx] & (uint64(1) << uint(bitIdx))) > 0 {
@@ -277,7 +273,7 @@ func (bA *BitArray) PickRandom() (uint, bool) {
 			if elemBits == 0 {
 				elemBits = 64
 			}
-			randBitStart := random.Intn(int(elemBits))
+			randBitStart := RandIntn(int(elemBits))
 			for j := 0; j < int(elemBits); j++ {
 				bitIdx := ((j + randBitStart) % int(elemBits))
 				if (bA.Elems[elemIdx] & (uint64(1) << uint(bitIdx))) > 0 {
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"os"
 	"io"
+	"sync"
 	"syscall"
 	"golang.org/x/crypto/sha3"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -235,31 +236,50 @@ func EncodeUvarint(w io.Writer, u uint64) (err error) {
 	return
 }
 //-----------------------------------------------------------------------------
+// rng backs the Rand functions. It is seeded once, a source seeded from the
+// clock on every call returns the same numbers for a whole second.
+var rng = struct {
+	sync.Mutex
+	*rand.Rand
+}{Rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+// RandSeed reseeds the Rand functions, so a simulation drawing from a
+// single goroutine can be replayed.
+func RandSeed(seed int64) {
+	rng.Lock()
+	defer rng.Unlock()
+	rng.Seed(seed)
+}
+
 func RandInt() int {
-	random := rand.New(rand.NewSource(time.Now().Unix()))
-	return random.Int()
+	rng.Lock()
+	defer rng.Unlock()
+	return rng.Int()
 }
 func RandIntn(n int) int {
-	random := rand.New(rand.NewSource(time.Now().Unix()))
-	return random.Intn(n)
+	rng.Lock()
+	defer rng.Unlock()
+	return rng.Intn(n)
 }
-func RandFloat64() float64  {
-	random := rand.New(rand.NewSource(time.Now().Unix()))
-	return random.Float64()
+func RandFloat64() float64 {
+	rng.Lock()
+	defer rng.Unlock()
+	return rng.Float64()
 }
 func RandInt31n(n int32) int32 {
-	random := rand.New(rand.NewSource(time.Now().Unix()))
-	return random.Int31n(n)
+	rng.Lock()
+	defer rng.Unlock()
+	return rng.Int31n(n)
 }
 func RandPerm(n int) []int {
-	random := rand.New(rand.NewSource(time.Now().Unix()))
-	perm := random.Perm(n)
-	return perm
+	rng.Lock()
+	defer rng.Unlock()
+	return rng.Perm(n)
 }
 func RandInt63n(n int64) int64 {
-	random := rand.New(rand.NewSource(time.Now().Unix()))
-	i63n := random.Int63n(n)
-	return i63n
+	rng.Lock()
+	defer rng.Unlock()
+	return rng.Int63n(n)
 }
 
 //-----------------------------------------------------------------------------
@@ -0,0 +1,107 @@
+package help
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// VirtualClock is a manually advanced clock for simulations. Timers scheduled
+// on it fire in (deadline, schedule order) on the goroutine calling Advance,
+// so the firing order only depends on the scheduled durations.
+type VirtualClock struct {
+	mtx    sync.Mutex
+	now    time.Duration
+	seq    uint64
+	timers []*VirtualTimer
+}
+
+// VirtualTimer is a pending callback on a VirtualClock.
+type VirtualTimer struct {
+	clock *VirtualClock
+	when  time.Duration
+	seq   uint64
+	fn    func()
+}
+
+// NewVirtualClock returns a clock starting at zero.
+func NewVirtualClock() *VirtualClock {
+	return &VirtualClock{}
+}
+
+// Now returns the time elapsed since the clock was created.
+func (c *VirtualClock) Now() time.Duration {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.now
+}
+
+// AfterFunc calls fn once the clock has advanced by d. Non-positive durations
+// fire on the next Advance.
+func (c *VirtualClock) AfterFunc(d time.Duration, fn func()) *VirtualTimer {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if d < 0 {
+		d = 0
+	}
+	c.seq++
+	t := &VirtualTimer{clock: c, when: c.now + d, seq: c.seq, fn: fn}
+	i := sort.Search(len(c.timers), func(i int) bool { return t.before(c.timers[i]) })
+	c.timers = append(c.timers, nil)
+	copy(c.timers[i+1:], c.timers[i:])
+	c.timers[i] = t
+	return t
+}
+
+// Next returns the deadline of the earliest pending timer.
+func (c *VirtualClock) Next() (time.Duration, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if len(c.timers) == 0 {
+		return 0, false
+	}
+	return c.timers[0].when, true
+}
+
+// Advance moves the clock forward by d, firing every timer that falls due,
+// including the ones scheduled by fired callbacks.
+func (c *VirtualClock) Advance(d time.Duration) {
+	c.mtx.Lock()
+	end := c.now + d
+	c.mtx.Unlock()
+	for {
+		c.mtx.Lock()
+		if len(c.timers) == 0 || c.timers[0].when > end {
+			c.now = end
+			c.mtx.Unlock()
+			return
+		}
+		t := c.timers[0]
+		c.timers = c.timers[1:]
+		c.now = t.when
+		c.mtx.Unlock()
+		t.fn()
+	}
+}
+
+// Stop prevents the timer from firing. It returns false if the timer already
+// fired or was stopped.
+func (t *VirtualTimer) Stop() bool {
+	c := t.clock
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for i, v := range c.timers {
+		if v == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (t *VirtualTimer) before(o *VirtualTimer) bool {
+	if t.when != o.when {
+		return t.when < o.when
+	}
+	return t.seq < o.seq
+}
@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"fmt"
 	"github.com/ethereum/go-ethereum/metrics"
 	"time"
 )
@@ -25,6 +26,22 @@ var (
 
 	//FetchFastBlock rounds count statistics
 	TBftFetchFastBlockRoundTime = metrics.NewRegisteredTimer("consensus/tbft/count/FetchFastBlockRound", nil)
+
+	//Health statistics
+	TbftHealthTimeoutMeter = metrics.NewRegisteredMeter("consensus/tbft/health/timeout", nil)
+	TbftHealthRecoverMeter = metrics.NewRegisteredMeter("consensus/tbft/health/recover", nil)
+	TbftHealthStateMeter   = metrics.NewRegisteredMeter("consensus/tbft/health/state", nil)
+	TbftSwitchProposeMeter = metrics.NewRegisteredMeter("consensus/tbft/health/switch/propose", nil)
+	TbftSwitchRestoreMeter = metrics.NewRegisteredMeter("consensus/tbft/health/switch/restore", nil)
+	TbftSwitchApplyMeter   = metrics.NewRegisteredMeter("consensus/tbft/health/switch/apply", nil)
+	TbftSwitchRejectMeter  = metrics.NewRegisteredMeter("consensus/tbft/health/switch/reject", nil)
+
+	//Round statistics
+	TbftHeightRoundsHistogram = metrics.NewRegisteredHistogram("consensus/tbft/height/rounds", nil, metrics.NewExpDecaySample(1028, 0.015))
+
+	//Block part gossip statistics
+	TbftPartsReceivedMeter  = metrics.NewRegisteredMeter("consensus/tbft/parts/received", nil)
+	TbftPartsDuplicateMeter = metrics.NewRegisteredMeter("consensus/tbft/parts/duplicate", nil)
 )
 
 type ConsensusTime int
@@ -113,3 +130,68 @@ func MTimesCount(t TimesCount, d time.Duration) {
 		break
 	}
 }
+
+// MHealth marks a health event of the given kind.
+func MHealth(kind string) {
+	switch kind {
+	case "timeout":
+		TbftHealthTimeoutMeter.Mark(1)
+	case "recover":
+		TbftHealthRecoverMeter.Mark(1)
+	case "state":
+		TbftHealthStateMeter.Mark(1)
+	case "propose":
+		TbftSwitchProposeMeter.Mark(1)
+	case "restore":
+		TbftSwitchRestoreMeter.Mark(1)
+	case "apply":
+		TbftSwitchApplyMeter.Mark(1)
+	case "reject":
+		TbftSwitchRejectMeter.Mark(1)
+	}
+}
+
+// MRounds records the number of rounds a height needed to commit.
+func MRounds(rounds int) {
+	TbftHeightRoundsHistogram.Update(int64(rounds))
+}
+
+// MStep records the time spent in the round step named step.
+func MStep(step string, d time.Duration) {
+	metrics.GetOrRegisterTimer("consensus/tbft/step/"+step, nil).Update(d)
+}
+
+// voteMetric names the metric of kind about the votes of voteType cast by validator.
+func voteMetric(validator, voteType, kind string) string {
+	return fmt.Sprintf("consensus/tbft/validator/%s/%s/%s", validator, voteType, kind)
+}
+
+// MVoteLatency records how long after the proposal the vote of voteType cast
+// by validator arrived. Validators are registered on first use.
+func MVoteLatency(validator string, voteType string, d time.Duration) {
+	metrics.GetOrRegisterTimer(voteMetric(validator, voteType, "latency"), nil).Update(d)
+}
+
+// MVoteMissed marks a vote of voteType validator didn't cast in the round a
+// height committed in.
+func MVoteMissed(validator string, voteType string) {
+	metrics.GetOrRegisterMeter(voteMetric(validator, voteType, "missed"), nil).Mark(1)
+}
+
+// UnregisterValidator drops the vote metrics of validator, so the registry
+// doesn't keep growing with the members of past committees.
+func UnregisterValidator(validator string) {
+	for _, voteType := range []string{"prevote", "precommit"} {
+		metrics.Unregister(voteMetric(validator, voteType, "latency"))
+		metrics.Unregister(voteMetric(validator, voteType, "missed"))
+	}
+}
+
+// MBlockPart marks a block part received from gossip, duplicate if it was
+// known already.
+func MBlockPart(duplicate bool) {
+	TbftPartsReceivedMeter.Mark(1)
+	if duplicate {
+		TbftPartsDuplicateMeter.Mark(1)
+	}
+}
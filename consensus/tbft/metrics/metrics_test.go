@@ -3,6 +3,8 @@ package metrics
 import (
 	"testing"
 	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
 )
 
 func TestMTimes(t *testing.T) {
@@ -10,3 +12,35 @@ func TestMTimes(t *testing.T) {
 	time.Sleep(time.Second)
 	MTimes(PreVoteTime, true)
 }
+
+// metricCount returns the count of the timer or meter registered as name.
+func metricCount(name string) int64 {
+	if m, ok := metrics.DefaultRegistry.Get(name).(interface{ Count() int64 }); ok {
+		return m.Count()
+	}
+	return 0
+}
+
+func TestMVoteRegistered(t *testing.T) {
+	enabled := metrics.Enabled
+	metrics.Enabled = true
+	defer func() { metrics.Enabled = enabled }()
+
+	latency, missed := voteMetric("ab12", "prevote", "latency"), voteMetric("ab12", "precommit", "missed")
+	latency0, missed0 := metricCount(latency), metricCount(missed)
+	MVoteLatency("ab12", "prevote", time.Second)
+	MVoteMissed("ab12", "precommit")
+	if d := metricCount(latency) - latency0; d != 1 {
+		t.Fatalf("vote latency counted %d times, want 1", d)
+	}
+	if d := metricCount(missed) - missed0; d != 1 {
+		t.Fatalf("missed vote counted %d times, want 1", d)
+	}
+
+	UnregisterValidator("ab12")
+	for _, name := range []string{latency, missed} {
+		if m := metrics.DefaultRegistry.Get(name); m != nil {
+			t.Errorf("%s still registered", name)
+		}
+	}
+}
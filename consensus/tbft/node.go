@@ -1,6 +1,7 @@
 package tbft
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"encoding/hex"
 	"errors"
@@ -16,11 +17,13 @@ import (
 
 	tcrypto "ethereum/rpc-network/consensus/tbft/crypto"
 	"ethereum/rpc-network/consensus/tbft/help"
+	"ethereum/rpc-network/consensus/tbft/privval"
 	"ethereum/rpc-network/consensus/tbft/tp2p"
 	"ethereum/rpc-network/consensus/tbft/tp2p/pex"
 	ttypes "ethereum/rpc-network/consensus/tbft/types"
 	"ethereum/rpc-network/core/types"
 	"ethereum/rpc-network/crypto"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/log"
 	cfg "ethereum/rpc-network/params"
 )
@@ -29,6 +32,7 @@ type service struct {
 	sw               *tp2p.Switch
 	consensusState   *ConsensusState   // latest consensus state
 	consensusReactor *ConsensusReactor // for participating in the consensus
+	blockSyncReactor *BlockSyncReactor // for catching up with the committed blocks
 	sa               *ttypes.StateAgentImpl
 	nodeTable        map[tp2p.ID]*nodeInfo
 	lock             *sync.Mutex
@@ -70,7 +74,7 @@ func newNodeService(p2pcfg *cfg.P2PConfig, cscfg *cfg.ConsensusConfig, state *tt
 		// nodeTable:      make(map[p2p.ID]*nodeInfo),
 		lock:       new(sync.Mutex),
 		updateChan: make(chan bool, 2),
-		//eventBus:   ttypes.NewEventBus(),
+		eventBus:   ttypes.NewEventBus(),
 		// If PEX is on, it should handle dialing the seeds. Otherwise the switch does it.
 		// Note we currently use the addrBook regardless at least for AddOurAddress
 		addrBook:  pex.NewAddrBook(p2pcfg.AddrBookFile(), p2pcfg.AddrBookStrict),
@@ -91,15 +95,14 @@ func (s *service) setNodes(nodes map[tp2p.ID]*nodeInfo) {
 	s.nodeTable = nodes
 }
 func (s *service) start(cid *big.Int, node *Node) error {
-	//err := s.eventBus.Start()
-	//if err != nil {
-	//	return err
-	//}
 	// Create & add listener
 	if s.sw.IsRunning() {
 		log.Warn("service is running")
 		return errors.New("service is running")
 	}
+	if err := s.eventBus.Start(); err != nil {
+		return err
+	}
 
 	lstr := node.config.P2P.ListenAddress2
 	if cid.Uint64()%2 == 0 {
@@ -116,6 +119,7 @@ func (s *service) start(cid *big.Int, node *Node) error {
 
 	s.sw.SetNodeInfo(nodeinfo)
 	s.sw.SetNodeKey(&node.nodekey)
+	s.sw.SetRLPxPeers(node.rlpxPeers)
 	l := tp2p.NewDefaultListener(
 		lstr,
 		node.config.P2P.ExternalAddress,
@@ -123,9 +127,11 @@ func (s *service) start(cid *big.Int, node *Node) error {
 		log.New("p2p", "self"))
 	s.sw.AddListener(l)
 
-	privValidator := ttypes.NewPrivValidator(*node.priv)
-	s.consensusState.SetPrivValidator(privValidator)
-	s.sa.SetPrivValidator(privValidator)
+	// observers run without a validator, they never sign
+	if privValidator := node.privValidator(s.sa.GetValidator()); privValidator != nil {
+		s.consensusState.SetPrivValidator(privValidator)
+		s.sa.SetPrivValidator(privValidator)
+	}
 	// Start the switch (the P2P server).
 	help.CheckAndPrintError(s.healthMgr.OnStart())
 	err := s.sw.Start()
@@ -154,7 +160,7 @@ func (s *service) stop() error {
 		s.updateChan <- false
 		s.healthMgr.OnStop()
 		help.CheckAndPrintError(s.sw.Stop())
-		//help.CheckAndPrintError(s.eventBus.Stop())
+		help.CheckAndPrintError(s.eventBus.Stop())
 		log.Info("end service stop")
 	}
 	return nil
@@ -321,12 +327,20 @@ type Node struct {
 	// configt
 	config *cfg.TbftConfig
 	Agent  types.PbftAgentProxy
-	priv   *ecdsa.PrivateKey // local node's validator key
+	priv   *ecdsa.PrivateKey     // local node's validator key, nil for an observer
+	signer *privval.RemoteSigner // signs in place of priv when the key is held remotely
+	blsKey *tcrypto.PrivKeyBLS   // votes in the committees registering its public key
+
+	observers []string // observer nodes admitted to our committees
+
+	healthPolicy  ttypes.HealthPolicy   // policy of the health mgr of every committee
+	healthHistory *ttypes.HealthHistory // health events of all committees
 
 	// services
 	services   map[uint64]*service
 	nodekey    tp2p.NodeKey
 	nodeinfo   tp2p.NodeInfo
+	rlpxPeers  *help.CMap // peers dialed over RLPx, kept across committees
 	chainID    string
 	lock       *sync.Mutex
 	servicePre uint64
@@ -346,12 +360,16 @@ func NewNode(config *cfg.TbftConfig, chainID string, priv *ecdsa.PrivateKey,
 	// services which will be publishing and/or subscribing for messages (events)
 	// consensusReactor will set it on consensusState and blockExecutor
 	node := &Node{
-		config:   config,
-		priv:     priv,
-		chainID:  chainID,
-		Agent:    agent,
-		lock:     new(sync.Mutex),
-		services: make(map[uint64]*service),
+		config:    config,
+		priv:      priv,
+		chainID:   chainID,
+		Agent:     agent,
+		lock:      new(sync.Mutex),
+		services:  make(map[uint64]*service),
+		rlpxPeers: help.NewCMap(),
+
+		healthPolicy:  ttypes.DefaultHealthPolicy(),
+		healthHistory: ttypes.NewHealthHistory(ttypes.DefaultHealthHistoryLimit),
 		nodekey: tp2p.NodeKey{
 			PrivKey: tcrypto.PrivKeyTrue(*priv),
 		},
@@ -360,6 +378,108 @@ func NewNode(config *cfg.TbftConfig, chainID string, priv *ecdsa.PrivateKey,
 	return node, nil
 }
 
+// NewObserverNode returns a Node following the committees without a validator
+// key. It receives the proposals, block parts and votes, verifies the commits
+// and feeds the committed blocks to the agent, but never signs. nodeKey only
+// identifies the node on the p2p network, committee members have to admit it
+// with AddObservers.
+func NewObserverNode(config *cfg.TbftConfig, chainID string, nodeKey *ecdsa.PrivateKey,
+	agent types.PbftAgentProxy) (*Node, error) {
+	node, err := NewNode(config, chainID, nodeKey, agent)
+	if err != nil {
+		return nil, err
+	}
+	node.priv = nil
+	return node, nil
+}
+
+// NewRemoteSignerNode returns a Node whose validator key is held by a remote
+// signer. The key signs the votes, the proposals and the p2p handshakes through
+// the signer and never enters the memory of the node.
+func NewRemoteSignerNode(config *cfg.TbftConfig, chainID string, signer *privval.RemoteSigner,
+	agent types.PbftAgentProxy) (*Node, error) {
+	node := &Node{
+		config:    config,
+		signer:    signer,
+		chainID:   chainID,
+		Agent:     agent,
+		lock:      new(sync.Mutex),
+		services:  make(map[uint64]*service),
+		rlpxPeers: help.NewCMap(),
+		nodekey:   tp2p.NodeKey{PrivKey: signer.NodeKey()},
+
+		healthPolicy:  ttypes.DefaultHealthPolicy(),
+		healthHistory: ttypes.NewHealthHistory(ttypes.DefaultHealthHistoryLimit),
+	}
+	node.BaseService = *help.NewBaseService("Node", node)
+	return node, nil
+}
+
+// SetHealthPolicy sets the policy of the health mgr of the committees put after.
+func (n *Node) SetHealthPolicy(policy ttypes.HealthPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.healthPolicy = policy
+	return nil
+}
+
+// SetHealthHistory sets the history recording the health events of the
+// committees put after, e.g. one opened with ttypes.OpenHealthHistory to keep
+// it across restarts.
+func (n *Node) SetHealthHistory(history *ttypes.HealthHistory) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.healthHistory = history
+}
+
+// IsObserver returns true if the node follows the committees without signing.
+func (n *Node) IsObserver() bool {
+	return n.priv == nil && n.signer == nil
+}
+
+// SetBLSKey sets the BLS key the node votes with in the committees put after
+// whose member entry of the node registers its public key. The votes of such
+// committees are aggregated, other committees get votes of the validator key.
+func (n *Node) SetBLSKey(key tcrypto.PrivKeyBLS) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.blsKey = &key
+}
+
+// privValidator returns a fresh signer of votes and proposals for a committee
+// of the validators vals, nil for an observer.
+func (n *Node) privValidator(vals *ttypes.ValidatorSet) ttypes.PrivValidator {
+	switch {
+	case n.signer != nil:
+		return n.signer
+	case n.priv != nil:
+		if n.blsKey != nil && vals != nil {
+			addr := tcrypto.PubKeyTrue(n.priv.PublicKey).Address()
+			if _, val := vals.GetByAddress(addr); val != nil && val.PubKey.Equals(n.blsKey.PubKey()) {
+				return ttypes.NewBLSPrivValidator(addr, *n.blsKey)
+			}
+		}
+		return ttypes.NewPrivValidator(*n.priv)
+	}
+	return nil
+}
+
+// AddObservers admits the given observer nodes to the committees of this node,
+// the running ones and the ones put later.
+func (n *Node) AddObservers(ids ...tp2p.ID) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	for _, id := range ids {
+		n.observers = append(n.observers, string(id))
+	}
+	for _, s := range n.services {
+		s.sa.AddObservers(n.observers...)
+	}
+}
+
 // OnStart starts the Node. It implements help.Service.
 func (n *Node) OnStart() error {
 	n.nodeinfo = n.makeNodeInfo()
@@ -405,6 +525,9 @@ func (n *Node) makeNodeInfo() tp2p.NodeInfo {
 			fmt.Sprintf("consensus_version=%v", "0.1.0"),
 		},
 	}
+	if n.nodekey.SupportsRLPx() {
+		nodeInfo.Other = append(nodeInfo.Other, tp2p.TransportsEntry(tp2p.TransportSecret, tp2p.TransportRLPx))
+	}
 	// Split protocol, address, and port.
 	_, lAddr := help.ProtocolAndAddress(n.config.P2P.ListenAddress1)
 	lAddrIP, lAddrPort := tp2p.SplitHostPort(lAddr)
@@ -474,6 +597,7 @@ func (n *Node) PutCommittee(committeeInfo *types.CommitteeInfo) error {
 	if state == nil {
 		return errors.New("make the nil state")
 	}
+	state.AddObservers(n.observers...)
 	if committeeInfo.EndHeight != nil && committeeInfo.EndHeight.Cmp(committeeInfo.StartHeight) > 0 {
 		state.SetEndHeight(committeeInfo.EndHeight.Uint64())
 	}
@@ -485,6 +609,8 @@ func (n *Node) PutCommittee(committeeInfo *types.CommitteeInfo) error {
 		return fmt.Errorf("members len is error :want big to %d get %d", cfg.MinimumCommitteeNumber, len(committeeInfo.Members))
 	}
 
+	help.CheckAndPrintError(service.healthMgr.SetPolicy(n.healthPolicy))
+	service.healthMgr.SetHistory(n.healthHistory)
 	n.AddHealthForCommittee(service.healthMgr, committeeInfo)
 
 	service.consensusState.SetHealthMgr(service.healthMgr)
@@ -498,11 +624,22 @@ func (n *Node) PutCommittee(committeeInfo *types.CommitteeInfo) error {
 
 	service.setNodes(nodeInfo)
 	service.sa = state
-	service.consensusReactor = NewConsensusReactor(service.consensusState, false)
+	// Catch up with the committed blocks before joining the consensus
+	service.consensusReactor = NewConsensusReactor(service.consensusState, true)
 	service.sw.AddReactor("CONSENSUS", service.consensusReactor)
+	service.blockSyncReactor = NewBlockSyncReactor(state, store, service.consensusReactor, true)
+	service.sw.AddReactor("BLOCKSYNC", service.blockSyncReactor)
 	service.sw.SetAddrBook(service.addrBook)
+	// Exchange addresses so backups and observers find the committee on their own
+	if n.config.P2P.PexReactor {
+		pexReactor := pex.NewPEXReactor(service.addrBook, &pex.PEXReactorConfig{
+			Seeds:    help.SplitAndTrim(n.config.P2P.Seeds, ",", " "),
+			SeedMode: n.config.P2P.SeedMode,
+		})
+		service.sw.AddReactor("PEX", pexReactor)
+	}
 	service.consensusReactor.SetHealthMgr(service.healthMgr)
-	//service.consensusReactor.SetEventBus(service.eventBus)
+	service.consensusReactor.SetEventBus(service.eventBus)
 	service.selfID = n.nodekey.ID()
 	n.services[id.Uint64()] = service
 	return nil
@@ -567,7 +704,7 @@ func (n *Node) checkValidatorSet(service *service, info *types.CommitteeInfo) (s
 			if e != nil {
 				log.Debug("checkValidatorSet pk error", "pk", v.Publickey)
 			}
-			if service.consensusState.state.GetPubKey().Equals(tcrypto.PubKeyTrue(*pk)) {
+			if self := service.consensusState.state.GetAddress(); self != nil && bytes.Equal(self, tcrypto.PubKeyTrue(*pk).Address()) {
 				selfStop = true
 			}
 			remove = append(remove, v)
@@ -625,29 +762,32 @@ func (n *Node) UpdateCommittee(info *types.CommitteeInfo) error {
 	return errors.New("service not found")
 }
 
-//MakeValidators is make CommitteeInfo to ValidatorSet
+//MakeValidators is make CommitteeInfo to ValidatorSet. The validators are the
+//voting members of the committee, see CommitteeInfo.VotingMembers
 func MakeValidators(cmm *types.CommitteeInfo) *ttypes.ValidatorSet {
 	id := cmm.Id
-	members := append(cmm.Members, cmm.BackMembers...)
-	if id == nil || len(members) <= 0 {
+	if id == nil || len(cmm.GetAllMembers()) <= 0 {
 		return nil
 	}
-	vals := make([]*ttypes.Validator, 0, 0)
-	var power int64 = 1
-	for i, m := range members {
-		if m.Flag != types.StateUsedFlag {
-			continue
-		}
-		if i == 0 {
-			power = 1
-		} else {
-			power = 1
+	verifyBLS := func(pub, proof []byte) error {
+		err := tcrypto.VerifyPossessionBLS(pub, proof)
+		if err != nil {
+			log.Warn("MakeValidators rejected member", "blskey", hexutil.Encode(pub), "err", err)
 		}
+		return err
+	}
+	vals := make([]*ttypes.Validator, 0, 0)
+	for _, m := range cmm.VotingMembers(verifyBLS) {
 		pk, e := crypto.UnmarshalPubkey(m.Publickey)
 		if e != nil {
 			log.Debug("MakeValidators pk error", "pk", m.Publickey)
 		}
-		v := ttypes.NewValidator(tcrypto.PubKeyTrue(*pk), power)
+		v := ttypes.NewValidator(tcrypto.PubKeyTrue(*pk), m.Power())
+		if len(m.BLSPublickey) > 0 {
+			var blsKey tcrypto.PubKeyBLS
+			copy(blsKey[:], m.BLSPublickey)
+			v = ttypes.NewBLSValidator(v.Address, blsKey, m.Power())
+		}
 		vals = append(vals, v)
 	}
 	return ttypes.NewValidatorSet(vals)
@@ -0,0 +1,151 @@
+package privval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	tcrypto "ethereum/rpc-network/consensus/tbft/crypto"
+	"ethereum/rpc-network/consensus/tbft/help"
+	"ethereum/rpc-network/consensus/tbft/tp2p/conn"
+	ttypes "ethereum/rpc-network/consensus/tbft/types"
+	"ethereum/rpc-network/crypto"
+	"ethereum/rpc-network/rpc"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// signTimeout bounds a signing round trip, consensus can't wait for a signer
+// which went away.
+const signTimeout = 3 * time.Second
+
+var (
+	errBadSignature = errors.New("invalid signature from remote signer")
+	errRemoteKey    = errors.New("remote key only signs handshake challenges")
+)
+
+// RemoteSigner is a PrivValidator whose key is held by a Signer reached over
+// RPC, so the key never enters the memory of the node.
+type RemoteSigner struct {
+	client *rpc.Client
+	pubKey tcrypto.PubKeyTrue
+}
+
+var _ ttypes.PrivValidator = (*RemoteSigner)(nil)
+
+// NewRemoteSigner connects to the signer served at endpoint.
+func NewRemoteSigner(endpoint string) (*RemoteSigner, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return NewRemoteSignerWithClient(client)
+}
+
+// NewRemoteSignerWithClient returns a RemoteSigner talking through client.
+func NewRemoteSignerWithClient(client *rpc.Client) (*RemoteSigner, error) {
+	s := &RemoteSigner{client: client}
+	var pub hexutil.Bytes
+	if err := s.call(&pub, "pubKey"); err != nil {
+		return nil, err
+	}
+	key, err := crypto.UnmarshalPubkey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signer public key: %v", err)
+	}
+	s.pubKey = tcrypto.PubKeyTrue(*key)
+	return s, nil
+}
+
+// Close closes the connection to the signer.
+func (s *RemoteSigner) Close() {
+	s.client.Close()
+}
+
+func (s *RemoteSigner) call(result interface{}, method string, args ...interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), signTimeout)
+	defer cancel()
+	return s.client.CallContext(ctx, result, Namespace+"_"+method, args...)
+}
+
+// GetAddress returns the address of the validator.
+func (s *RemoteSigner) GetAddress() help.Address {
+	return s.pubKey.Address()
+}
+
+// GetPubKey returns the public key of the validator.
+func (s *RemoteSigner) GetPubKey() tcrypto.PubKey {
+	return s.pubKey
+}
+
+// SignVote has the signer sign vote. Implements PrivValidator.
+func (s *RemoteSigner) SignVote(chainID string, vote *ttypes.Vote) error {
+	var signed ttypes.Vote
+	if err := s.call(&signed, "signVote", chainID, vote); err != nil {
+		return fmt.Errorf("error signing vote: %v", err)
+	}
+	// the signer may only reuse the timestamp of a vote it signed before
+	check := vote.Copy()
+	check.Timestamp = signed.Timestamp
+	if !s.pubKey.VerifyBytes(check.SignBytes(chainID), signed.Signature) {
+		return fmt.Errorf("error signing vote: %v", errBadSignature)
+	}
+	vote.Timestamp, vote.Signature = signed.Timestamp, signed.Signature
+	return nil
+}
+
+// SignProposal has the signer sign proposal. Implements PrivValidator.
+func (s *RemoteSigner) SignProposal(chainID string, proposal *ttypes.Proposal) error {
+	var signed ttypes.Proposal
+	if err := s.call(&signed, "signProposal", chainID, proposal); err != nil {
+		return fmt.Errorf("error signing proposal: %v", err)
+	}
+	check := *proposal
+	check.Timestamp = signed.Timestamp
+	if !s.pubKey.VerifyBytes(check.SignBytes(chainID), signed.Signature) {
+		return fmt.Errorf("error signing proposal: %v", errBadSignature)
+	}
+	proposal.Timestamp, proposal.Signature = signed.Timestamp, signed.Signature
+	return nil
+}
+
+// NodeKey returns the p2p key of the validator. It authenticates the node in
+// the secret connection handshake through the signer and signs nothing else.
+func (s *RemoteSigner) NodeKey() tcrypto.PrivKey {
+	return &remoteKey{s}
+}
+
+// remoteKey is the validator key as a p2p node key.
+type remoteKey struct {
+	s *RemoteSigner
+}
+
+var _ conn.ChallengeSigner = (*remoteKey)(nil)
+
+// Bytes returns nil, the key never leaves the signer.
+func (k *remoteKey) Bytes() []byte {
+	return nil
+}
+
+// Sign always fails, a node key only signs the handshake challenge.
+func (k *remoteKey) Sign(msg []byte) ([]byte, error) {
+	return nil, errRemoteKey
+}
+
+func (k *remoteKey) PubKey() tcrypto.PubKey {
+	return k.s.pubKey
+}
+
+func (k *remoteKey) Equals(other tcrypto.PrivKey) bool {
+	o, ok := other.(*remoteKey)
+	return ok && k.s.pubKey.Equals(o.s.pubKey)
+}
+
+// SignChallenge implements conn.ChallengeSigner.
+func (k *remoteKey) SignChallenge(dhSecret *[32]byte) ([]byte, error) {
+	var sig hexutil.Bytes
+	if err := k.s.call(&sig, "signChallenge", hexutil.Bytes(dhSecret[:])); err != nil {
+		return nil, err
+	}
+	return sig, nil
+}
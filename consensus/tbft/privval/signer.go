@@ -0,0 +1,84 @@
+package privval
+
+import (
+	"crypto/ecdsa"
+	"errors"
+
+	tcrypto "ethereum/rpc-network/consensus/tbft/crypto"
+	"ethereum/rpc-network/consensus/tbft/tp2p/conn"
+	ttypes "ethereum/rpc-network/consensus/tbft/types"
+	"ethereum/rpc-network/rpc"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Namespace is the RPC namespace served by a Signer.
+const Namespace = "tbftsigner"
+
+var errBadSecret = errors.New("dh secret must be 32 bytes")
+
+// Signer holds a validator key on the signing host and serves the signatures
+// tbft nodes ask for. The double sign checks run here, so a compromised node
+// can't get two conflicting votes or proposals signed.
+type Signer struct {
+	key tcrypto.PrivKeyTrue
+	pv  ttypes.PrivValidator
+}
+
+// NewSigner returns a signer for key.
+func NewSigner(key *ecdsa.PrivateKey) *Signer {
+	return &Signer{
+		key: tcrypto.PrivKeyTrue(*key),
+		pv:  ttypes.NewPrivValidator(*key),
+	}
+}
+
+// APIs returns the RPC services of the signer. They must only be served on an
+// endpoint the validator node alone can reach, e.g. an IPC socket.
+func (s *Signer) APIs() []rpc.API {
+	return []rpc.API{{
+		Namespace: Namespace,
+		Version:   "1.0",
+		Service:   &SignerAPI{s},
+	}}
+}
+
+// SignerAPI is the RPC service of a Signer.
+type SignerAPI struct {
+	s *Signer
+}
+
+// PubKey returns the uncompressed public key of the validator.
+func (api *SignerAPI) PubKey() hexutil.Bytes {
+	return api.s.pv.GetPubKey().Bytes()
+}
+
+// SignVote signs vote unless it conflicts with a vote signed before.
+func (api *SignerAPI) SignVote(chainID string, vote *ttypes.Vote) (*ttypes.Vote, error) {
+	if err := api.s.pv.SignVote(chainID, vote); err != nil {
+		log.Warn("Refused to sign vote", "height", vote.Height, "round", vote.Round, "type", vote.Type, "err", err)
+		return nil, err
+	}
+	return vote, nil
+}
+
+// SignProposal signs proposal unless it conflicts with a proposal signed before.
+func (api *SignerAPI) SignProposal(chainID string, proposal *ttypes.Proposal) (*ttypes.Proposal, error) {
+	if err := api.s.pv.SignProposal(chainID, proposal); err != nil {
+		log.Warn("Refused to sign proposal", "height", proposal.Height, "round", proposal.Round, "err", err)
+		return nil, err
+	}
+	return proposal, nil
+}
+
+// SignChallenge signs the p2p handshake challenge derived from dhSecret, which
+// proves the validator identity to the peers of the node.
+func (api *SignerAPI) SignChallenge(dhSecret hexutil.Bytes) (hexutil.Bytes, error) {
+	if len(dhSecret) != 32 {
+		return nil, errBadSecret
+	}
+	var secret [32]byte
+	copy(secret[:], dhSecret)
+	challenge := conn.DeriveChallenge(&secret)
+	return api.s.key.Sign(challenge[:])
+}
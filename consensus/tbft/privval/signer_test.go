@@ -0,0 +1,147 @@
+package privval
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tcrypto "ethereum/rpc-network/consensus/tbft/crypto"
+	"ethereum/rpc-network/consensus/tbft/tp2p/conn"
+	ttypes "ethereum/rpc-network/consensus/tbft/types"
+	"ethereum/rpc-network/crypto"
+	"ethereum/rpc-network/rpc"
+)
+
+const testChainID = "tbft-test"
+
+// startSigner serves a signer on an IPC socket, standing in for the signer
+// process of a validator.
+func startSigner(t *testing.T) (*RemoteSigner, tcrypto.PubKeyTrue, func()) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "tbft-signer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoint := filepath.Join(dir, "signer.ipc")
+	listener, server, err := rpc.StartIPCEndpoint(endpoint, NewSigner(key).APIs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := NewRemoteSigner(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer, tcrypto.PubKeyTrue(key.PublicKey), func() {
+		signer.Close()
+		server.Stop()
+		listener.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func newTestVote(addr []byte, height uint64, round uint, hash byte) *ttypes.Vote {
+	return &ttypes.Vote{
+		ValidatorAddress: addr,
+		Height:           height,
+		Round:            round,
+		Timestamp:        time.Now().UTC(),
+		Type:             ttypes.VoteTypePrevote,
+		BlockID:          ttypes.BlockID{Hash: bytes.Repeat([]byte{hash}, 32)},
+	}
+}
+
+func TestRemoteSignVote(t *testing.T) {
+	signer, pub, stop := startSigner(t)
+	defer stop()
+
+	if !bytes.Equal(signer.GetAddress(), pub.Address()) || !signer.GetPubKey().Equals(pub) {
+		t.Fatalf("signer key mismatch: have %x, want %x", signer.GetAddress(), pub.Address())
+	}
+	vote := newTestVote(signer.GetAddress(), 1, 0, 0x01)
+	if err := signer.SignVote(testChainID, vote); err != nil {
+		t.Fatal(err)
+	}
+	if !pub.VerifyBytes(vote.SignBytes(testChainID), vote.Signature) {
+		t.Fatal("vote signature does not verify")
+	}
+	// signing the same vote again is fine, the signer hands out the same signature
+	again := vote.Copy()
+	again.Signature = nil
+	if err := signer.SignVote(testChainID, again); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again.Signature, vote.Signature) {
+		t.Fatal("repeated vote got a different signature")
+	}
+	// a prevote for another block in the same round is a double sign
+	if err := signer.SignVote(testChainID, newTestVote(signer.GetAddress(), 1, 0, 0x02)); err == nil {
+		t.Fatal("signer signed a conflicting vote")
+	}
+	// going back in height is refused as well
+	if err := signer.SignVote(testChainID, newTestVote(signer.GetAddress(), 0, 0, 0x01)); err == nil {
+		t.Fatal("signer signed a vote for an older height")
+	}
+	if err := signer.SignVote(testChainID, newTestVote(signer.GetAddress(), 1, 1, 0x02)); err != nil {
+		t.Fatalf("vote for the next round refused: %v", err)
+	}
+}
+
+func TestRemoteSignProposal(t *testing.T) {
+	signer, pub, stop := startSigner(t)
+	defer stop()
+
+	proposal := ttypes.NewProposal(3, 0, ttypes.PartSetHeader{Total: 1, Hash: []byte{0x01}}, 0, ttypes.BlockID{})
+	if err := signer.SignProposal(testChainID, proposal); err != nil {
+		t.Fatal(err)
+	}
+	if !pub.VerifyBytes(proposal.SignBytes(testChainID), proposal.Signature) {
+		t.Fatal("proposal signature does not verify")
+	}
+	conflict := ttypes.NewProposal(3, 0, ttypes.PartSetHeader{Total: 1, Hash: []byte{0x02}}, 0, ttypes.BlockID{})
+	if err := signer.SignProposal(testChainID, conflict); err == nil {
+		t.Fatal("signer signed a conflicting proposal")
+	}
+}
+
+func TestRemoteNodeKeyHandshake(t *testing.T) {
+	signer, pub, stop := startSigner(t)
+	defer stop()
+
+	nodeKey := signer.NodeKey()
+	if _, err := nodeKey.Sign(make([]byte, 32)); err == nil {
+		t.Fatal("remote node key signed an arbitrary hash")
+	}
+	peerKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	errc := make(chan error, 1)
+	go func() {
+		sc, err := conn.MakeSecretConnection(b, tcrypto.PrivKeyTrue(*peerKey))
+		if err == nil && !sc.RemotePubKey().Equals(pub) {
+			err = errRemoteKey
+		}
+		errc <- err
+	}()
+	sc, err := conn.MakeSecretConnection(a, nodeKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sc.RemotePubKey().Equals(tcrypto.PubKeyTrue(peerKey.PublicKey)) {
+		t.Fatal("wrong remote key on the validator side")
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("peer did not authenticate the validator: %v", err)
+	}
+}
@@ -40,6 +40,10 @@ type ConsensusReactor struct {
 	fastSync bool
 	eventBus *ttypes.EventBus
 	hm       *ttypes.HealthMgr
+
+	// afterFunc runs the gossip steps and broadcasts on the caller's clock
+	// instead of their own goroutines, for simulations
+	afterFunc func(d time.Duration, f func())
 }
 
 // NewConsensusReactor returns a new ConsensusReactor with the given
@@ -62,14 +66,35 @@ func (conR *ConsensusReactor) OnStart() error {
 	}
 	conR.subscribeToBroadcastEvents()
 
-	err := conR.conS.Start()
-	if err != nil {
-		return err
+	// The block sync reactor starts the state once we caught up
+	if !conR.FastSync() {
+		err := conR.conS.Start()
+		if err != nil {
+			return err
+		}
 	}
 	log.Debug("End ConsensusReactor start")
 	return nil
 }
 
+// SwitchToConsensus switches from fast_sync mode to consensus mode.
+// It rebuilds the last commit from the synced blocks and starts the state.
+func (conR *ConsensusReactor) SwitchToConsensus() {
+	log.Info("SwitchToConsensus", "height", conR.conS.state.GetLastBlockHeight())
+	conR.conS.reconstructLastCommit()
+
+	conR.mtx.Lock()
+	conR.fastSync = false
+	conR.mtx.Unlock()
+
+	if err := conR.conS.Start(); err != nil {
+		log.Error("Error starting consensus state", "err", err)
+		return
+	}
+	// Peers ignored our round state while syncing
+	conR.broadcastNewRoundStepMessages(conR.conS.GetRoundState())
+}
+
 // OnStop implements BaseService by unsubscribing from events and stopping
 // state.
 func (conR *ConsensusReactor) OnStop() {
@@ -128,9 +153,16 @@ func (conR *ConsensusReactor) AddPeer(peer tp2p.Peer) {
 	peer.Set(ttypes.PeerStateKey, peerState)
 
 	// Begin routines for this peer.
-	go conR.gossipDataRoutine(peer, peerState)
-	go conR.gossipVotesRoutine(peer, peerState)
-	go conR.queryMaj23Routine(peer, peerState)
+	conR.runGossip("gossipDataRoutine", peer, func() time.Duration {
+		return conR.gossipData(peer, peerState)
+	})
+	sleeping := 0
+	conR.runGossip("gossipVotesRoutine", peer, func() time.Duration {
+		return conR.gossipVotes(peer, peerState, &sleeping)
+	})
+	conR.runGossip("queryMaj23Routine", peer, func() time.Duration {
+		return conR.queryMaj23(peer, peerState)
+	})
 
 	// Send our state to peer.
 	// If we're fast_syncing, broadcast a RoundStepMessage later upon SwitchToConsensus().
@@ -347,14 +379,26 @@ func (conR *ConsensusReactor) unsubscribeFromBroadcastEvents() {
 	conR.conS.evsw.RemoveListener(subscriber)
 }
 
+// broadcast sends msg to every peer, in order of connection when the
+// reactor runs on an afterFunc.
+func (conR *ConsensusReactor) broadcast(chID byte, msg []byte) {
+	if conR.afterFunc == nil {
+		conR.Switch.Broadcast(chID, msg)
+		return
+	}
+	for _, peer := range conR.Switch.Peers().List() {
+		peer.Send(chID, msg)
+	}
+}
+
 func (conR *ConsensusReactor) broadcastNewRoundStepMessages(rs *ttypes.RoundState) {
 	log.Trace("broadcastNewRoundStepMessages", "makeRoundStepMessages", "in")
-	nrsMsg, csMsg := makeRoundStepMessages(rs)
+	nrsMsg, csMsg := makeRoundStepMessages(rs, conR.conS.now())
 	if nrsMsg != nil {
-		conR.Switch.Broadcast(StateChannel, cdc.MustMarshalBinaryBare(nrsMsg))
+		conR.broadcast(StateChannel, cdc.MustMarshalBinaryBare(nrsMsg))
 	}
 	if csMsg != nil {
-		conR.Switch.Broadcast(StateChannel, cdc.MustMarshalBinaryBare(csMsg))
+		conR.broadcast(StateChannel, cdc.MustMarshalBinaryBare(csMsg))
 	}
 }
 
@@ -366,7 +410,7 @@ func (conR *ConsensusReactor) broadcastHasVoteMessage(vote *ttypes.Vote) {
 		Type:   vote.Type,
 		Index:  vote.ValidatorIndex,
 	}
-	conR.Switch.Broadcast(StateChannel, cdc.MustMarshalBinaryBare(msg))
+	conR.broadcast(StateChannel, cdc.MustMarshalBinaryBare(msg))
 	/*
 		// TODO: Make this broadcast more selective.
 		for _, peer := range conR.Switch.Peers().List() {
@@ -384,12 +428,12 @@ func (conR *ConsensusReactor) broadcastHasVoteMessage(vote *ttypes.Vote) {
 	*/
 }
 
-func makeRoundStepMessages(rs *ttypes.RoundState) (nrsMsg *NewRoundStepMessage, csMsg *CommitStepMessage) {
+func makeRoundStepMessages(rs *ttypes.RoundState, now time.Time) (nrsMsg *NewRoundStepMessage, csMsg *CommitStepMessage) {
 	nrsMsg = &NewRoundStepMessage{
 		Height:                rs.Height,
 		Round:                 uint(rs.Round),
 		Step:                  rs.Step,
-		SecondsSinceStartTime: uint(time.Since(rs.StartTime).Seconds()),
+		SecondsSinceStartTime: uint(now.Sub(rs.StartTime).Seconds()),
 		LastCommitRound:       uint(rs.LastCommit.Round()),
 	}
 	if rs.Step == ttypes.RoundStepCommit && rs.ProposalBlockParts != nil {
@@ -405,7 +449,7 @@ func makeRoundStepMessages(rs *ttypes.RoundState) (nrsMsg *NewRoundStepMessage,
 func (conR *ConsensusReactor) sendNewRoundStepMessages(peer tp2p.Peer) {
 	log.Trace("sendNewRoundStepMessages", "makeRoundStepMessages", "in")
 	rs := conR.conS.GetRoundState()
-	nrsMsg, csMsg := makeRoundStepMessages(rs)
+	nrsMsg, csMsg := makeRoundStepMessages(rs, conR.conS.now())
 	if nrsMsg != nil {
 		peer.Send(StateChannel, cdc.MustMarshalBinaryBare(nrsMsg))
 	}
@@ -414,111 +458,122 @@ func (conR *ConsensusReactor) sendNewRoundStepMessages(peer tp2p.Peer) {
 	}
 }
 
-func (conR *ConsensusReactor) gossipDataRoutine(peer tp2p.Peer, ps *PeerState) {
-	//logger := log.With("peer", peer)
-
-outerLoop:
-	for {
-		// Manage disconnects from self or peer.
-		if !peer.IsRunning() || !conR.IsRunning() {
-			log.Trace("Stopping gossipDataRoutine for peer")
-			return
+// runGossip calls step until the peer or the reactor stops, waiting for the
+// duration step returns in between.
+func (conR *ConsensusReactor) runGossip(name string, peer tp2p.Peer, step func() time.Duration) {
+	if conR.afterFunc != nil {
+		var next func()
+		next = func() {
+			if !peer.IsRunning() || !conR.IsRunning() {
+				return
+			}
+			conR.afterFunc(step(), next)
 		}
-		rs := conR.conS.GetRoundState()
-		prs := ps.GetRoundState()
-
-		// Send proposal Block parts?
-		if rs.ProposalBlockParts != nil && rs.ProposalBlockParts.HasHeader(prs.ProposalBlockPartsHeader) {
-			if index, ok := rs.ProposalBlockParts.BitArray().Sub(prs.ProposalBlockParts.Copy()).PickRandom(); ok {
-				part := rs.ProposalBlockParts.GetPart(index)
-				if part != nil {
-					msg := &BlockPartMessage{
-						Height: rs.Height,      // This tells peer that this part applies to us.
-						Round:  uint(rs.Round), // This tells peer that this part applies to us.
-						Part:   part,
-					}
-					log.Trace("Sending block part", "height", prs.Height, "round", prs.Round)
-					if peer.Send(DataChannel, cdc.MustMarshalBinaryBare(msg)) {
-						ps.SetHasProposalBlockPart(prs.Height, uint(prs.Round), index)
-					}
-					continue outerLoop
-				}
+		conR.afterFunc(0, next)
+		return
+	}
+	go func() {
+		for {
+			// Manage disconnects from self or peer.
+			if !peer.IsRunning() || !conR.IsRunning() {
+				log.Trace("Stopping gossip routine for peer", "routine", name)
+				return
+			}
+			if d := step(); d > 0 {
+				time.Sleep(d)
 			}
 		}
+	}()
+}
 
-		// If the peer is on a previous height, help catch up.
-		if (0 < prs.Height) && (prs.Height < rs.Height) {
-			//heightLogger := logger.With("height", prs.Height)
-
-			// if we never received the commit message from the peer, the block parts wont be initialized
-			if prs.ProposalBlockParts == nil {
-				blockMeta := conR.conS.blockStore.LoadBlockMeta(prs.Height)
-				if blockMeta == nil {
-					// help.PanicSanity(fmt.Sprintf("Failed to load block %d when blockStore is at %d",
-					// 	prs.Height, conR.conS.blockStore.MaxBlockHeight()))
-					time.Sleep(conR.conS.config.PeerGossipSleep())
-				} else {
-					ps.InitProposalBlockParts(blockMeta.BlockID.PartsHeader)
+// gossipData sends the peer one piece of the proposal it misses and returns
+// how long to wait before the next one.
+func (conR *ConsensusReactor) gossipData(peer tp2p.Peer, ps *PeerState) time.Duration {
+	rs := conR.conS.GetRoundState()
+	prs := ps.GetRoundState()
+
+	// Send proposal Block parts?
+	if rs.ProposalBlockParts != nil && rs.ProposalBlockParts.HasHeader(prs.ProposalBlockPartsHeader) {
+		if index, ok := rs.ProposalBlockParts.BitArray().Sub(prs.ProposalBlockParts.Copy()).PickRandom(); ok {
+			part := rs.ProposalBlockParts.GetPart(index)
+			if part != nil {
+				msg := &BlockPartMessage{
+					Height: rs.Height,      // This tells peer that this part applies to us.
+					Round:  uint(rs.Round), // This tells peer that this part applies to us.
+					Part:   part,
+				}
+				log.Trace("Sending block part", "height", prs.Height, "round", prs.Round)
+				if peer.Send(DataChannel, cdc.MustMarshalBinaryBare(msg)) {
+					ps.SetHasProposalBlockPart(prs.Height, uint(prs.Round), index)
 				}
-				// continue the loop since prs is a copy and not effected by this initialization
-				continue outerLoop
+				return 0
 			}
-			conR.gossipDataForCatchup(rs, prs, ps, peer)
-			continue outerLoop
 		}
+	}
 
-		// If height and round don't match, sleep.
-		if (rs.Height != prs.Height) || (rs.Round != prs.Round) {
-			//logger.Info("Peer Height|Round mismatch, sleeping", "peerHeight", prs.Height, "peerRound", prs.Round, "peer", peer)
-			time.Sleep(conR.conS.config.PeerGossipSleep())
-			continue outerLoop
+	// If the peer is on a previous height, help catch up.
+	if (0 < prs.Height) && (prs.Height < rs.Height) {
+		// if we never received the commit message from the peer, the block parts wont be initialized
+		if prs.ProposalBlockParts == nil {
+			blockMeta := conR.conS.blockStore.LoadBlockMeta(prs.Height)
+			if blockMeta == nil {
+				return conR.conS.config.PeerGossipSleep()
+			}
+			ps.InitProposalBlockParts(blockMeta.BlockID.PartsHeader)
+			// continue the loop since prs is a copy and not effected by this initialization
+			return 0
 		}
+		return conR.gossipDataForCatchup(rs, prs, ps, peer)
+	}
 
-		// By here, height and round match.
-		// Proposal block parts were already matched and sent if any were wanted.
-		// (These can match on hash so the round doesn't matter)
-		// Now consider sending other things, like the Proposal itself.
-
-		// Send Proposal && ProposalPOL BitArray?
-		if rs.Proposal != nil && !prs.Proposal {
-			// Proposal: share the proposal metadata with peer.
-			{
-				msg := &ProposalMessage{Proposal: rs.Proposal}
-				log.Trace("Sending proposal", "height", prs.Height, "round", prs.Round)
-				if peer.Send(DataChannel, cdc.MustMarshalBinaryBare(msg)) {
-					ps.SetHasProposal(rs.Proposal)
-				}
+	// If height and round don't match, sleep.
+	if (rs.Height != prs.Height) || (rs.Round != prs.Round) {
+		return conR.conS.config.PeerGossipSleep()
+	}
+
+	// By here, height and round match.
+	// Proposal block parts were already matched and sent if any were wanted.
+	// (These can match on hash so the round doesn't matter)
+	// Now consider sending other things, like the Proposal itself.
+
+	// Send Proposal && ProposalPOL BitArray?
+	if rs.Proposal != nil && !prs.Proposal {
+		// Proposal: share the proposal metadata with peer.
+		{
+			msg := &ProposalMessage{Proposal: rs.Proposal}
+			log.Trace("Sending proposal", "height", prs.Height, "round", prs.Round)
+			if peer.Send(DataChannel, cdc.MustMarshalBinaryBare(msg)) {
+				ps.SetHasProposal(rs.Proposal)
 			}
-			// ProposalPOL: lets peer know which POL votes we have so far.
-			// Peer must receive ProposalMessage first.
-			// rs.Proposal was validated, so rs.Proposal.POLRound <= rs.Round,
-			// so we definitely have rs.Votes.Prevotes(rs.Proposal.POLRound).
-			if 0 <= int(rs.Proposal.POLRound) {
-				msg := &ProposalPOLMessage{
-					Height:           rs.Height,
-					ProposalPOLRound: rs.Proposal.POLRound,
-					ProposalPOL:      rs.Votes.Prevotes(int(rs.Proposal.POLRound)).BitArray(),
-				}
-				log.Trace("Sending POL", "height", prs.Height, "round", prs.Round)
-				peer.Send(DataChannel, cdc.MustMarshalBinaryBare(msg))
+		}
+		// ProposalPOL: lets peer know which POL votes we have so far.
+		// Peer must receive ProposalMessage first.
+		// rs.Proposal was validated, so rs.Proposal.POLRound <= rs.Round,
+		// so we definitely have rs.Votes.Prevotes(rs.Proposal.POLRound).
+		if 0 <= int(rs.Proposal.POLRound) {
+			msg := &ProposalPOLMessage{
+				Height:           rs.Height,
+				ProposalPOLRound: rs.Proposal.POLRound,
+				ProposalPOL:      rs.Votes.Prevotes(int(rs.Proposal.POLRound)).BitArray(),
 			}
-			continue outerLoop
+			log.Trace("Sending POL", "height", prs.Height, "round", prs.Round)
+			peer.Send(DataChannel, cdc.MustMarshalBinaryBare(msg))
 		}
-
-		// Nothing to do. Sleep.
-		time.Sleep(conR.conS.config.PeerGossipSleep())
-		continue outerLoop
+		return 0
 	}
+
+	// Nothing to do. Sleep.
+	return conR.conS.config.PeerGossipSleep()
 }
 
 func (conR *ConsensusReactor) gossipDataForCatchup(rs *ttypes.RoundState,
-	prs *ttypes.PeerRoundState, ps *PeerState, peer tp2p.Peer) {
+	prs *ttypes.PeerRoundState, ps *PeerState, peer tp2p.Peer) time.Duration {
 
 	if !prs.Proposal {
 		blockMeta := conR.conS.blockStore.LoadBlockMeta(prs.Height)
 		if blockMeta == nil || blockMeta.Proposal == nil {
 			log.Debug("Failed to load block meta", "Height", prs.Height, "maxHeight", conR.conS.blockStore.MaxBlockHeight())
-			return
+			return 0
 		}
 		msg := &ProposalMessage{Proposal: blockMeta.Proposal}
 		log.Trace("Sending proposal", "height", prs.Height, "round", prs.Round)
@@ -533,21 +588,18 @@ func (conR *ConsensusReactor) gossipDataForCatchup(rs *ttypes.RoundState,
 		if blockMeta == nil {
 			log.Debug("Failed to load block meta",
 				"ourHeight", rs.Height, "blockstoreHeight", conR.conS.blockStore.MaxBlockHeight())
-			time.Sleep(conR.conS.config.PeerGossipSleep())
-			return
+			return conR.conS.config.PeerGossipSleep()
 		} else if !blockMeta.BlockID.PartsHeader.Equals(prs.ProposalBlockPartsHeader) {
 			log.Debug("Peer ProposalBlockPartsHeader mismatch, sleeping",
 				"blockPartsHeader", blockMeta.BlockID.PartsHeader, "peerBlockPartsHeader", prs.ProposalBlockPartsHeader)
-			time.Sleep(conR.conS.config.PeerGossipSleep())
-			return
+			return conR.conS.config.PeerGossipSleep()
 		}
 		// Load the part
 		part := conR.conS.blockStore.LoadBlockPart(prs.Height, index)
 		if part == nil {
 			log.Debug("Could not load part", "index", index,
 				"blockPartsHeader", blockMeta.BlockID.PartsHeader, "peerBlockPartsHeader", prs.ProposalBlockPartsHeader)
-			time.Sleep(conR.conS.config.PeerGossipSleep())
-			return
+			return conR.conS.config.PeerGossipSleep()
 		}
 		// Send the part
 		msg := &BlockPartMessage{
@@ -561,80 +613,64 @@ func (conR *ConsensusReactor) gossipDataForCatchup(rs *ttypes.RoundState,
 		} else {
 			log.Trace("Sending block part for catchup failed")
 		}
-		return
+		return 0
 	}
 	//logger.Info("No parts to send in catch-up, sleeping")
-	time.Sleep(conR.conS.config.PeerGossipSleep())
+	return conR.conS.config.PeerGossipSleep()
 }
 
-func (conR *ConsensusReactor) gossipVotesRoutine(peer tp2p.Peer, ps *PeerState) {
-	//logger := conR.Logger.With("peer", peer)
-
-	// Simple hack to throttle logs upon sleep.
-	var sleeping = 0
-
-outerLoop:
-	for {
-		// Manage disconnects from self or peer.
-		if !peer.IsRunning() || !conR.IsRunning() {
-			log.Trace("Stopping gossipVotesRoutine for peer")
-			return
-		}
-		rs := conR.conS.GetRoundState()
-		prs := ps.GetRoundState()
-
-		switch sleeping {
-		case 1: // First sleep
-			sleeping = 2
-		case 2: // No more sleep
-			sleeping = 0
-		}
+// gossipVotes sends the peer one vote it misses and returns how long to wait
+// before the next one. sleeping throttles the logs between calls.
+func (conR *ConsensusReactor) gossipVotes(peer tp2p.Peer, ps *PeerState, sleeping *int) time.Duration {
+	rs := conR.conS.GetRoundState()
+	prs := ps.GetRoundState()
 
-		//logger.Debug("gossipVotesRoutine", "rsHeight", rs.Height, "rsRound", rs.Round,
-		//	"prsHeight", prs.Height, "prsRound", prs.Round, "prsStep", prs.Step)
+	switch *sleeping {
+	case 1: // First sleep
+		*sleeping = 2
+	case 2: // No more sleep
+		*sleeping = 0
+	}
 
-		// If height matches, then send LastCommit, Prevotes, Precommits.
-		if rs.Height == prs.Height {
-			//heightLogger := logger.With("height", prs.Height)
-			if conR.gossipVotesForHeight(rs, prs, ps) {
-				continue outerLoop
-			}
+	// If height matches, then send LastCommit, Prevotes, Precommits.
+	if rs.Height == prs.Height {
+		if conR.gossipVotesForHeight(rs, prs, ps) {
+			return 0
 		}
+	}
 
-		// Special catchup logic.
-		// If peer is lagging by height 1, send LastCommit.
-		if prs.Height != 0 && rs.Height == prs.Height+1 {
-			if ps.PickSendVote(rs.LastCommit) {
-				log.Trace("Picked rs.LastCommit to send", "height", prs.Height)
-				continue outerLoop
-			}
+	// Special catchup logic.
+	// If peer is lagging by height 1, send LastCommit.
+	if prs.Height != 0 && rs.Height == prs.Height+1 {
+		if ps.PickSendVote(rs.LastCommit) {
+			log.Trace("Picked rs.LastCommit to send", "height", prs.Height)
+			return 0
 		}
+	}
 
-		// Catchup logic
-		// If peer is lagging by more than 1, send Commit.
-		if prs.Height != 0 && rs.Height >= prs.Height+2 {
-			// Load the block commit for prs.Height,
-			// which contains precommit signatures for prs.Height.
-			commit := conR.conS.blockStore.LoadBlockCommit(prs.Height)
-			if commit != nil && ps.PickSendVote(commit) {
-				log.Trace("Picked Catchup commit to send", "height", prs.Height)
-				continue outerLoop
-			}
+	// Catchup logic
+	// If peer is lagging by more than 1, send Commit.
+	if prs.Height != 0 && rs.Height >= prs.Height+2 {
+		// Load the block commit for prs.Height,
+		// which contains precommit signatures for prs.Height.
+		commit := conR.conS.blockStore.LoadBlockCommit(prs.Height)
+		if commit != nil && ps.PickSendVote(commit) {
+			log.Trace("Picked Catchup commit to send", "height", prs.Height)
+			return 0
 		}
+	}
 
-		if sleeping == 0 {
-			// We sent nothing. Sleep...
-			sleeping = 1
-			log.Trace("No votes to send, sleeping", "rs.Height", rs.Height, "prs.Height", prs.Height,
-				"localPV", rs.Votes.Prevotes(int(rs.Round)).BitArray(), "peerPV", prs.Prevotes,
-				"localPC", rs.Votes.Precommits(int(rs.Round)).BitArray(), "peerPC", prs.Precommits)
-		} else if sleeping == 2 {
-			// Continued sleep...
-			sleeping = 1
-		}
-		time.Sleep(conR.conS.config.PeerGossipSleep())
-		continue outerLoop
+	if *sleeping == 0 {
+		// We sent nothing. Sleep...
+		*sleeping = 1
+		log.Trace("No votes to send, sleeping", "rs.Height", rs.Height, "prs.Height", prs.Height,
+			"localPV", rs.Votes.Prevotes(int(rs.Round)).BitArray(), "peerPV", prs.Prevotes,
+			"localPC", rs.Votes.Precommits(int(rs.Round)).BitArray(), "peerPC", prs.Precommits)
+	} else if *sleeping == 2 {
+		// Continued sleep...
+		*sleeping = 1
 	}
+	return conR.conS.config.PeerGossipSleep()
 }
 
 func (conR *ConsensusReactor) gossipVotesForHeight(rs *ttypes.RoundState, prs *ttypes.PeerRoundState, ps *PeerState) bool {
@@ -689,96 +725,85 @@ func (conR *ConsensusReactor) gossipVotesForHeight(rs *ttypes.RoundState, prs *t
 	return false
 }
 
-// NOTE: `queryMaj23Routine` has a simple crude design since it only comes
-// into play for liveness when there's a signature DDoS attack happening.
-func (conR *ConsensusReactor) queryMaj23Routine(peer tp2p.Peer, ps *PeerState) {
-	//logger := conR.Logger.With("peer", peer)
-
-outerLoop:
-	for {
-		// Manage disconnects from self or peer.
-		if !peer.IsRunning() || !conR.IsRunning() {
-			log.Debug("Stopping queryMaj23Routine for peer")
-			return
-		}
+// queryMaj23 tells the peer about the +2/3 majorities we have seen and returns
+// how long to wait before doing it again.
+// NOTE: it has a simple crude design since it only comes into play for
+// liveness when there's a signature DDoS attack happening.
+func (conR *ConsensusReactor) queryMaj23(peer tp2p.Peer, ps *PeerState) time.Duration {
+	sleep := conR.conS.config.PeerQueryMaj23Sleep()
+	wait := sleep
 
-		// Maybe send Height/Round/Prevotes
-		{
-			rs := conR.conS.GetRoundState()
-			prs := ps.GetRoundState()
-			if rs.Height == prs.Height {
-				if maj23, ok := rs.Votes.Prevotes(int(prs.Round)).TwoThirdsMajority(); ok {
-					peer.TrySend(StateChannel, cdc.MustMarshalBinaryBare(&VoteSetMaj23Message{
-						Height:  prs.Height,
-						Round:   uint(prs.Round),
-						Type:    ttypes.VoteTypePrevote,
-						BlockID: maj23,
-					}))
-					time.Sleep(conR.conS.config.PeerQueryMaj23Sleep())
-				}
+	// Maybe send Height/Round/Prevotes
+	{
+		rs := conR.conS.GetRoundState()
+		prs := ps.GetRoundState()
+		if rs.Height == prs.Height {
+			if maj23, ok := rs.Votes.Prevotes(int(prs.Round)).TwoThirdsMajority(); ok {
+				peer.TrySend(StateChannel, cdc.MustMarshalBinaryBare(&VoteSetMaj23Message{
+					Height:  prs.Height,
+					Round:   uint(prs.Round),
+					Type:    ttypes.VoteTypePrevote,
+					BlockID: maj23,
+				}))
+				wait += sleep
 			}
 		}
+	}
 
-		// Maybe send Height/Round/Precommits
-		{
-			rs := conR.conS.GetRoundState()
-			prs := ps.GetRoundState()
-			if rs.Height == prs.Height {
-				if maj23, ok := rs.Votes.Precommits(int(prs.Round)).TwoThirdsMajority(); ok {
-					peer.TrySend(StateChannel, cdc.MustMarshalBinaryBare(&VoteSetMaj23Message{
-						Height:  prs.Height,
-						Round:   uint(prs.Round),
-						Type:    ttypes.VoteTypePrecommit,
-						BlockID: maj23,
-					}))
-					time.Sleep(conR.conS.config.PeerQueryMaj23Sleep())
-				}
+	// Maybe send Height/Round/Precommits
+	{
+		rs := conR.conS.GetRoundState()
+		prs := ps.GetRoundState()
+		if rs.Height == prs.Height {
+			if maj23, ok := rs.Votes.Precommits(int(prs.Round)).TwoThirdsMajority(); ok {
+				peer.TrySend(StateChannel, cdc.MustMarshalBinaryBare(&VoteSetMaj23Message{
+					Height:  prs.Height,
+					Round:   uint(prs.Round),
+					Type:    ttypes.VoteTypePrecommit,
+					BlockID: maj23,
+				}))
+				wait += sleep
 			}
 		}
+	}
 
-		// Maybe send Height/Round/ProposalPOL
-		{
-			rs := conR.conS.GetRoundState()
-			prs := ps.GetRoundState()
-			if rs.Height == prs.Height && int(prs.ProposalPOLRound) >= 0 {
-				if maj23, ok := rs.Votes.Prevotes(int(prs.ProposalPOLRound)).TwoThirdsMajority(); ok {
-					peer.TrySend(StateChannel, cdc.MustMarshalBinaryBare(&VoteSetMaj23Message{
-						Height:  prs.Height,
-						Round:   uint(prs.ProposalPOLRound),
-						Type:    ttypes.VoteTypePrevote,
-						BlockID: maj23,
-					}))
-					time.Sleep(conR.conS.config.PeerQueryMaj23Sleep())
-				}
+	// Maybe send Height/Round/ProposalPOL
+	{
+		rs := conR.conS.GetRoundState()
+		prs := ps.GetRoundState()
+		if rs.Height == prs.Height && int(prs.ProposalPOLRound) >= 0 {
+			if maj23, ok := rs.Votes.Prevotes(int(prs.ProposalPOLRound)).TwoThirdsMajority(); ok {
+				peer.TrySend(StateChannel, cdc.MustMarshalBinaryBare(&VoteSetMaj23Message{
+					Height:  prs.Height,
+					Round:   uint(prs.ProposalPOLRound),
+					Type:    ttypes.VoteTypePrevote,
+					BlockID: maj23,
+				}))
+				wait += sleep
 			}
 		}
+	}
 
-		// Little point sending LastCommitRound/LastCommit,
-		// These are fleeting and non-blocking.
+	// Little point sending LastCommitRound/LastCommit,
+	// These are fleeting and non-blocking.
 
-		// Maybe send Height/CatchupCommitRound/CatchupCommit.
-		{
-			prs := ps.GetRoundState()
-			if prs.CatchupCommitRound != -1 && 0 < prs.Height && prs.Height <= conR.conS.blockStore.MaxBlockHeight() {
-				// update by iceming
-				// commit := conR.conS.LoadCommit(prs.Height)
-				commit := conR.conS.blockStore.LoadBlockCommit(prs.Height)
-				if commit != nil {
-					peer.TrySend(StateChannel, cdc.MustMarshalBinaryBare(&VoteSetMaj23Message{
-						Height:  prs.Height,
-						Round:   uint(commit.Round()),
-						Type:    ttypes.VoteTypePrecommit,
-						BlockID: commit.BlockID,
-					}))
-				}
-				time.Sleep(conR.conS.config.PeerQueryMaj23Sleep())
+	// Maybe send Height/CatchupCommitRound/CatchupCommit.
+	{
+		prs := ps.GetRoundState()
+		if prs.CatchupCommitRound != -1 && 0 < prs.Height && prs.Height <= conR.conS.blockStore.MaxBlockHeight() {
+			commit := conR.conS.blockStore.LoadBlockCommit(prs.Height)
+			if commit != nil {
+				peer.TrySend(StateChannel, cdc.MustMarshalBinaryBare(&VoteSetMaj23Message{
+					Height:  prs.Height,
+					Round:   uint(commit.Round()),
+					Type:    ttypes.VoteTypePrecommit,
+					BlockID: commit.BlockID,
+				}))
 			}
+			wait += sleep
 		}
-
-		time.Sleep(conR.conS.config.PeerQueryMaj23Sleep())
-
-		continue outerLoop
 	}
+	return wait
 }
 
 // String returns a string representation of the ConsensusReactor.
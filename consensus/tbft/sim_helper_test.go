@@ -0,0 +1,509 @@
+package tbft
+
+import (
+	"crypto/ecdsa"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	tcrypto "ethereum/rpc-network/consensus/tbft/crypto"
+	"ethereum/rpc-network/consensus/tbft/help"
+	"ethereum/rpc-network/consensus/tbft/tp2p"
+	"ethereum/rpc-network/consensus/tbft/tp2p/dummy"
+	ttypes "ethereum/rpc-network/consensus/tbft/types"
+	"ethereum/rpc-network/core/types"
+	"ethereum/rpc-network/crypto"
+	cfg "ethereum/rpc-network/params"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	simChainID = "tbft-sim"
+	// simStep is how far the virtual clock moves between two checks of RunUntil
+	simStep = 10 * time.Millisecond
+	// simMinGossipSleep keeps a gossip routine whose sends fail from spinning
+	// at one instant of the virtual clock
+	simMinGossipSleep = time.Millisecond
+)
+
+// simEpoch is the wall clock time of a simulation at zero. Proposals still
+// carry real timestamps, a virtual time before them keeps the catch up start
+// of a new height out of the way.
+var simEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Simulation runs a committee of validators in one process on an in-memory
+// network and a virtual clock. Every message, gossip step and timeout is
+// handled on the goroutine advancing the clock, so a run only depends on the
+// seed and a failing one can be replayed.
+type Simulation struct {
+	Clock *help.VirtualClock
+	Net   *dummy.Network
+	Nodes []*SimNode
+
+	seed      int64
+	config    *cfg.ConsensusConfig
+	committee *types.CommitteeInfo
+}
+
+// SimNode is one validator or observer of a Simulation.
+type SimNode struct {
+	ID       tp2p.ID
+	Index    int
+	Key      *ecdsa.PrivateKey
+	State    *ConsensusState
+	Reactor  *ConsensusReactor
+	Switch   *tp2p.Switch
+	Observer bool
+	agent    *simAgent
+	sa       *ttypes.StateAgentImpl
+}
+
+// Byzantine alters the behavior of a node before the simulation starts.
+type Byzantine func(n *SimNode)
+
+// NewSimulation builds n validators whose keys and network randomness derive
+// from seed. byzantine maps node indexes to the misbehavior they run.
+func NewSimulation(n int, seed int64, config *cfg.ConsensusConfig, byzantine map[int]Byzantine) (*Simulation, error) {
+	if n < cfg.MinimumCommitteeNumber {
+		return nil, fmt.Errorf("need at least %d validators, got %d", cfg.MinimumCommitteeNumber, n)
+	}
+	help.RandSeed(seed)
+	clock := help.NewVirtualClock()
+	sim := &Simulation{
+		Clock:     clock,
+		Net:       dummy.NewNetwork(clock, seed),
+		seed:      seed,
+		config:    config,
+		committee: &types.CommitteeInfo{Id: common.Big1, StartHeight: common.Big1},
+	}
+	keys := make([]*ecdsa.PrivateKey, n)
+	for i := range keys {
+		key, err := sim.key(i)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+		sim.committee.Members = append(sim.committee.Members, &types.CommitteeMember{
+			Coinbase:      crypto.PubkeyToAddress(key.PublicKey),
+			CommitteeBase: crypto.PubkeyToAddress(key.PublicKey),
+			Publickey:     crypto.FromECDSAPub(&key.PublicKey),
+			Flag:          types.StateUsedFlag,
+			MType:         types.TypeWorked,
+		})
+	}
+	for _, key := range keys {
+		sim.addNode(key, false)
+	}
+	for i, b := range byzantine {
+		if i < 0 || i >= n {
+			return nil, fmt.Errorf("byzantine node %d out of range", i)
+		}
+		b(sim.Nodes[i])
+	}
+	return sim, nil
+}
+
+// AddObserver adds a node following the committee without a validator key.
+// It has to be called before Start.
+func (s *Simulation) AddObserver() (*SimNode, error) {
+	key, err := s.key(len(s.Nodes))
+	if err != nil {
+		return nil, err
+	}
+	node := s.addNode(key, true)
+	for _, n := range s.Nodes {
+		n.sa.AddObservers(string(node.ID))
+	}
+	return node, nil
+}
+
+// key derives the key of node i from the seed.
+func (s *Simulation) key(i int) (*ecdsa.PrivateKey, error) {
+	var buf [16]byte
+	binary.BigEndian.PutUint64(buf[:8], uint64(s.seed))
+	binary.BigEndian.PutUint64(buf[8:], uint64(i))
+	return crypto.ToECDSA(crypto.Keccak256(buf[:]))
+}
+
+func (s *Simulation) addNode(key *ecdsa.PrivateKey, observer bool) *SimNode {
+	node := &SimNode{
+		ID:       tp2p.PubKeyToID(tcrypto.PubKeyTrue(key.PublicKey)),
+		Index:    len(s.Nodes),
+		Key:      key,
+		Observer: observer,
+		agent:    &simAgent{key: key},
+	}
+	node.sa = ttypes.NewStateAgent(node.agent, simChainID, MakeValidators(s.committee), 1, s.committee.Id.Uint64())
+
+	hm := ttypes.NewHealthMgr(s.committee.Id.Uint64())
+	node.State = NewConsensusState(s.config, node.sa, ttypes.NewBlockStore(), WithClock(s.now))
+	node.State.driven = true
+	node.State.SetTimeoutTicker(newVirtualTicker("TimeoutTicker", s.Clock))
+	node.State.SetTimeoutTask(newVirtualTicker("TimeoutTask", s.Clock))
+	if !observer {
+		priv := ttypes.NewPrivValidator(*key)
+		node.sa.SetPrivValidator(priv)
+		node.State.SetPrivValidator(priv)
+	}
+	node.State.SetHealthMgr(hm)
+	node.State.SetCommitteeInfo(s.committee)
+
+	node.Reactor = NewConsensusReactor(node.State, false)
+	node.Reactor.SetHealthMgr(hm)
+	node.Reactor.afterFunc = func(d time.Duration, f func()) {
+		if d < simMinGossipSleep {
+			d = simMinGossipSleep
+		}
+		s.Clock.AfterFunc(d, f)
+	}
+	node.Switch = tp2p.NewSwitch(&cfg.P2PConfig{}, node.sa)
+	node.Switch.AddReactor("CONSENSUS", node.Reactor)
+	s.Net.AddSwitch(node.ID, node.Switch)
+	s.Nodes = append(s.Nodes, node)
+	return node
+}
+
+// Start starts every node and connects them all to each other.
+func (s *Simulation) Start() error {
+	for _, n := range s.Nodes {
+		if err := n.Switch.Start(); err != nil {
+			return err
+		}
+	}
+	return s.Net.ConnectAll()
+}
+
+// Stop stops every node.
+func (s *Simulation) Stop() {
+	for _, n := range s.Nodes {
+		help.CheckAndPrintError(n.Switch.Stop())
+	}
+}
+
+func (s *Simulation) now() time.Time {
+	return simEpoch.Add(s.Clock.Now())
+}
+
+// Run advances the virtual clock by d.
+func (s *Simulation) Run(d time.Duration) {
+	s.advance(d)
+}
+
+// RunUntil runs until cond holds or max virtual time passed, reporting whether cond held.
+func (s *Simulation) RunUntil(cond func() bool, max time.Duration) bool {
+	for elapsed := time.Duration(0); elapsed < max; elapsed += simStep {
+		if cond() {
+			return true
+		}
+		s.advance(simStep)
+	}
+	return cond()
+}
+
+// advance moves the clock forward by d one due instant at a time, the nodes
+// handle what the fired timers queued before the clock moves on.
+func (s *Simulation) advance(d time.Duration) {
+	end := s.Clock.Now() + d
+	for {
+		s.drain()
+		next, ok := s.Clock.Next()
+		if !ok || next > end {
+			break
+		}
+		s.Clock.Advance(next - s.Clock.Now())
+	}
+	s.Clock.Advance(end - s.Clock.Now())
+}
+
+// drain lets the nodes handle their queues in turn until all are empty.
+func (s *Simulation) drain() {
+	for busy := true; busy; {
+		busy = false
+		for _, n := range s.Nodes {
+			for n.State.IsRunning() && n.State.handleQueued() {
+				busy = true
+			}
+		}
+	}
+}
+
+// Height returns the lowest height committed by the given nodes, all if none given.
+func (s *Simulation) Height(nodes ...int) uint64 {
+	if len(nodes) == 0 {
+		for i := range s.Nodes {
+			nodes = append(nodes, i)
+		}
+	}
+	var min uint64
+	for i, idx := range nodes {
+		if h := uint64(len(s.Nodes[idx].Blocks())); i == 0 || h < min {
+			min = h
+		}
+	}
+	return min
+}
+
+// CheckSafety returns an error if two nodes committed different blocks at the same height.
+func (s *Simulation) CheckSafety() error {
+	committed := make(map[int]common.Hash)
+	for _, n := range s.Nodes {
+		for i, b := range n.Blocks() {
+			if h, ok := committed[i]; ok && h != b.Hash() {
+				return fmt.Errorf("fork at height %d: %s committed %x, another node %x", i+1, n.ID, b.Hash(), h)
+			}
+			committed[i] = b.Hash()
+		}
+	}
+	return nil
+}
+
+// Blocks returns the blocks committed by the node.
+func (n *SimNode) Blocks() []*types.Block {
+	n.agent.mtx.Lock()
+	defer n.agent.mtx.Unlock()
+	return append([]*types.Block(nil), n.agent.blocks...)
+}
+
+//-----------------------------------------------------------------------------
+
+var errSimInvalidBlock = errors.New("block does not extend the chain")
+
+// simAgent is the chain of a simulated node, it builds empty blocks on top of
+// what was committed.
+type simAgent struct {
+	key    *ecdsa.PrivateKey
+	mtx    sync.Mutex
+	blocks []*types.Block
+}
+
+func (a *simAgent) head() (uint64, common.Hash) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	if len(a.blocks) == 0 {
+		return 0, common.Hash{}
+	}
+	last := a.blocks[len(a.blocks)-1]
+	return last.NumberU64(), last.Hash()
+}
+
+func (a *simAgent) FetchFastBlock(committeeID *big.Int, infos []*types.CommitteeMember) (*types.Block, error) {
+	number, parent := a.head()
+	return types.NewBlockWithHeader(&types.Header{
+		ParentHash: parent,
+		Number:     new(big.Int).SetUint64(number + 1),
+		Difficulty: common.Big1,
+		Time:       number + 1,
+		Coinbase:   crypto.PubkeyToAddress(a.key.PublicKey),
+	}), nil
+}
+
+func (a *simAgent) VerifyFastBlock(block *types.Block, result bool) (*types.PbftSign, error) {
+	number, parent := a.head()
+	if block.NumberU64() != number+1 || block.ParentHash() != parent {
+		return nil, errSimInvalidBlock
+	}
+	sign := &types.PbftSign{
+		Result:     types.VoteAgree,
+		FastHeight: block.Number(),
+		FastHash:   block.Hash(),
+	}
+	var err error
+	sign.Sign, err = crypto.Sign(sign.HashWithNoSign().Bytes(), a.key)
+	return sign, err
+}
+
+func (a *simAgent) BroadcastConsensus(block *types.Block) error {
+	if number, _ := a.head(); block.NumberU64() != number+1 {
+		return errSimInvalidBlock
+	}
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.blocks = append(a.blocks, block)
+	return nil
+}
+
+func (a *simAgent) GetCurrentHeight() *big.Int {
+	number, _ := a.head()
+	return new(big.Int).SetUint64(number)
+}
+
+func (a *simAgent) GetSeedMember() []*types.CommitteeMember {
+	return nil
+}
+
+func (a *simAgent) GetFastLastProposer() common.Address {
+	return common.Address{}
+}
+
+//-----------------------------------------------------------------------------
+
+// handleQueued handles one queued message or timeout of a driven state. The
+// queues are read in a fixed order, a select would pick among the ready ones
+// at random.
+func (cs *ConsensusState) handleQueued() bool {
+	rs := cs.RoundState
+	select {
+	case mi := <-cs.internalMsgQueue:
+		cs.handleMsg(mi)
+		return true
+	default:
+	}
+	select {
+	case mi := <-cs.peerMsgQueue:
+		cs.handleMsg(mi)
+		return true
+	default:
+	}
+	select {
+	case ti := <-cs.timeoutTicker.Chan():
+		cs.handleTimeout(ti, rs)
+		return true
+	default:
+	}
+	select {
+	case ti := <-cs.timeoutTask.Chan():
+		cs.handleTimeoutForTask(ti, rs)
+		return true
+	default:
+	}
+	return false
+}
+
+// virtualTicker is a TimeoutTicker firing on a help.VirtualClock.
+type virtualTicker struct {
+	help.BaseService
+
+	clock    *help.VirtualClock
+	mtx      sync.Mutex
+	ti       timeoutInfo
+	timer    *help.VirtualTimer
+	tockChan chan timeoutInfo
+}
+
+func newVirtualTicker(name string, clock *help.VirtualClock) TimeoutTicker {
+	t := &virtualTicker{
+		clock:    clock,
+		tockChan: make(chan timeoutInfo, 1),
+	}
+	t.BaseService = *help.NewBaseService(name, t)
+	return t
+}
+
+func (t *virtualTicker) OnStop() {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+}
+
+func (t *virtualTicker) Chan() <-chan timeoutInfo {
+	return t.tockChan
+}
+
+// ScheduleTimeout replaces the pending timeout unless ti is for an older
+// height/round/step. A fired timeout the state didn't handle yet is replaced
+// too, it is older than the new one.
+func (t *virtualTicker) ScheduleTimeout(ti timeoutInfo) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if isStaleTimeout(ti, t.ti) {
+		return
+	}
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	t.ti = ti
+	t.timer = t.clock.AfterFunc(ti.Duration, func() {
+		if !t.IsRunning() {
+			return
+		}
+		select {
+		case <-t.tockChan:
+		default:
+		}
+		t.tockChan <- ti
+	})
+}
+
+//-----------------------------------------------------------------------------
+
+// Equivocate makes the node sign two conflicting proposals in every round it
+// leads and send each to one half of its peers.
+func Equivocate(n *SimNode) {
+	cs := n.State
+	cs.decideProposal = func(height uint64, round int, blk *types.Block, parts *ttypes.PartSet) {
+		if blk == nil || parts == nil {
+			return
+		}
+		header := blk.Header()
+		header.Extra = []byte("equivocation")
+		other := types.NewBlockWithHeader(header)
+		otherParts, err := cs.state.MakePartSet(ttypes.BlockPartSizeBytes, other)
+		if err != nil {
+			return
+		}
+		polRound, polBlockID := cs.Votes.POLInfo()
+		// a fresh signer, ours refuses to sign twice for one height/round
+		signer := ttypes.NewPrivValidator(*n.Key)
+		proposals := []*ttypes.Proposal{
+			ttypes.NewProposal(height, round, parts.Header(), uint(polRound), polBlockID),
+			ttypes.NewProposal(height, round, otherParts.Header(), uint(polRound), polBlockID),
+		}
+		if cs.privValidator.SignProposal(cs.state.GetChainID(), proposals[0]) != nil ||
+			signer.SignProposal(cs.state.GetChainID(), proposals[1]) != nil {
+			return
+		}
+		cs.sendInternalMessage(msgInfo{&ProposalMessage{proposals[0]}, ""})
+		for i := uint(0); i < parts.Total(); i++ {
+			cs.sendInternalMessage(msgInfo{&BlockPartMessage{height, uint(round), parts.GetPart(i)}, ""})
+		}
+		for i, peer := range n.Switch.Peers().List() {
+			proposal, ps := proposals[i%2], parts
+			if i%2 == 1 {
+				ps = otherParts
+			}
+			peer.Send(DataChannel, cdc.MustMarshalBinaryBare(&ProposalMessage{proposal}))
+			for j := uint(0); j < ps.Total(); j++ {
+				peer.Send(DataChannel, cdc.MustMarshalBinaryBare(&BlockPartMessage{height, uint(round), ps.GetPart(j)}))
+			}
+		}
+	}
+}
+
+// WithholdVotes makes the node never sign a vote.
+func WithholdVotes(n *SimNode) {
+	n.State.SetPrivValidator(&withholdingValidator{n.State.privValidator})
+}
+
+// ProposeInvalidBlock makes the node propose blocks which do not extend the chain.
+func ProposeInvalidBlock(n *SimNode) {
+	cs := n.State
+	cs.decideProposal = func(height uint64, round int, blk *types.Block, parts *ttypes.PartSet) {
+		if blk == nil {
+			cs.defaultDecideProposal(height, round, blk, parts)
+			return
+		}
+		header := blk.Header()
+		header.ParentHash = common.BytesToHash([]byte("invalid"))
+		invalid := types.NewBlockWithHeader(header)
+		invalidParts, err := cs.state.MakePartSet(ttypes.BlockPartSizeBytes, invalid)
+		if err != nil {
+			return
+		}
+		cs.defaultDecideProposal(height, round, invalid, invalidParts)
+	}
+}
+
+var errVoteWithheld = errors.New("vote withheld")
+
+type withholdingValidator struct {
+	ttypes.PrivValidator
+}
+
+func (v *withholdingValidator) SignVote(chainID string, vote *ttypes.Vote) error {
+	return errVoteWithheld
+}
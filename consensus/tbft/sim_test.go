@@ -0,0 +1,244 @@
+package tbft
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	tcrypto "ethereum/rpc-network/consensus/tbft/crypto"
+	"ethereum/rpc-network/consensus/tbft/tp2p"
+	ttypes "ethereum/rpc-network/consensus/tbft/types"
+	"ethereum/rpc-network/crypto"
+	"ethereum/rpc-network/event"
+	config "ethereum/rpc-network/params"
+	"github.com/ethereum/go-ethereum/common"
+	gometrics "github.com/ethereum/go-ethereum/metrics"
+)
+
+func simConfig() *config.ConsensusConfig {
+	return &config.ConsensusConfig{
+		TimeoutPropose:              300 * time.Millisecond,
+		TimeoutProposeDelta:         100 * time.Millisecond,
+		TimeoutPrevote:              100 * time.Millisecond,
+		TimeoutPrevoteDelta:         50 * time.Millisecond,
+		TimeoutPrecommit:            100 * time.Millisecond,
+		TimeoutPrecommitDelta:       50 * time.Millisecond,
+		TimeoutCommit:               100 * time.Millisecond,
+		PeerGossipSleepDuration:     5 * time.Millisecond,
+		PeerQueryMaj23SleepDuration: 50 * time.Millisecond,
+	}
+}
+
+func runSim(t *testing.T, n int, byzantine map[int]Byzantine, setup func(*Simulation)) *Simulation {
+	sim, err := NewSimulation(n, 1, simConfig(), byzantine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if setup != nil {
+		setup(sim)
+	}
+	if err := sim.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return sim
+}
+
+func TestSimLiveness(t *testing.T) {
+	sim := runSim(t, 4, nil, func(s *Simulation) {
+		s.Net.SetLatency(5*time.Millisecond, 20*time.Millisecond)
+	})
+	defer sim.Stop()
+
+	if !sim.RunUntil(func() bool { return sim.Height() >= 3 }, 30*time.Second) {
+		t.Fatalf("committee stalled at height %d", sim.Height())
+	}
+	if err := sim.CheckSafety(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSimReplay(t *testing.T) {
+	run := func() (hashes [][]common.Hash, at time.Duration) {
+		sim := runSim(t, 4, nil, func(s *Simulation) {
+			s.Net.SetLatency(5*time.Millisecond, 20*time.Millisecond)
+			s.Net.SetDropRate(0.05)
+		})
+		defer sim.Stop()
+
+		if !sim.RunUntil(func() bool { return sim.Height() >= 3 }, 30*time.Second) {
+			t.Fatalf("committee stalled at height %d", sim.Height())
+		}
+		sim.Run(time.Second)
+		for _, n := range sim.Nodes {
+			var chain []common.Hash
+			for _, b := range n.Blocks() {
+				chain = append(chain, b.Hash())
+			}
+			hashes = append(hashes, chain)
+		}
+		return hashes, sim.Clock.Now()
+	}
+	first, firstAt := run()
+	second, secondAt := run()
+	if firstAt != secondAt {
+		t.Fatalf("runs took %v and %v", firstAt, secondAt)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Fatalf("runs committed different chains:\n%x\n%x", first, second)
+	}
+}
+
+func TestSimPartition(t *testing.T) {
+	sim := runSim(t, 4, nil, nil)
+	defer sim.Stop()
+
+	// neither half holds +2/3 of the votes
+	nodes := sim.Nodes
+	sim.Net.Partition([]tp2p.ID{nodes[0].ID, nodes[1].ID}, []tp2p.ID{nodes[2].ID, nodes[3].ID})
+	sim.Run(5 * time.Second)
+	stalled := sim.Height()
+	sim.Run(5 * time.Second)
+	if h := sim.Height(); h != stalled {
+		t.Fatalf("partitioned committee committed height %d", h)
+	}
+	if err := sim.Net.Heal(); err != nil {
+		t.Fatal(err)
+	}
+	if !sim.RunUntil(func() bool { return sim.Height() >= stalled+2 }, 60*time.Second) {
+		t.Fatalf("committee did not recover from partition, height %d", sim.Height())
+	}
+	if err := sim.CheckSafety(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSimByzantine(t *testing.T) {
+	tests := []struct {
+		name string
+		b    Byzantine
+	}{
+		{"equivocate", Equivocate},
+		{"withhold-votes", WithholdVotes},
+		{"invalid-proposal", ProposeInvalidBlock},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := runSim(t, 4, map[int]Byzantine{0: tt.b}, nil)
+			defer sim.Stop()
+
+			honest := []int{1, 2, 3}
+			if !sim.RunUntil(func() bool { return sim.Height(honest...) >= 4 }, 60*time.Second) {
+				t.Fatalf("honest nodes stalled at height %d", sim.Height(honest...))
+			}
+			if err := sim.CheckSafety(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestSimObserver(t *testing.T) {
+	var observer *SimNode
+	sim := runSim(t, 4, nil, func(s *Simulation) {
+		var err error
+		if observer, err = s.AddObserver(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	defer sim.Stop()
+
+	if !sim.RunUntil(func() bool { return sim.Height(observer.Index) >= 3 }, 30*time.Second) {
+		t.Fatalf("observer stalled at height %d", sim.Height(observer.Index))
+	}
+	if err := sim.CheckSafety(); err != nil {
+		t.Fatal(err)
+	}
+	// the observer must not have voted
+	for _, b := range observer.Blocks() {
+		for _, sign := range b.Signs() {
+			if signer, _ := sign.Signer(); signer == crypto.PubkeyToAddress(observer.Key.PublicKey) {
+				t.Fatalf("observer signed block %d", b.NumberU64())
+			}
+		}
+	}
+}
+
+func TestSimStalledEventSubscriber(t *testing.T) {
+	var subs []event.Subscription
+	sim := runSim(t, 4, nil, func(s *Simulation) {
+		for _, n := range s.Nodes {
+			bus := ttypes.NewEventBus()
+			if err := bus.Start(); err != nil {
+				t.Fatal(err)
+			}
+			n.State.SetEventBus(bus)
+			// nobody reads the channel
+			subs = append(subs, bus.Subscribe(make(chan interface{}), eventKeys...))
+		}
+	})
+	defer sim.Stop()
+
+	if !sim.RunUntil(func() bool { return sim.Height() >= 3 }, 30*time.Second) {
+		t.Fatalf("committee stalled at height %d", sim.Height())
+	}
+	for i, sub := range subs {
+		select {
+		case err := <-sub.Err():
+			if err != ttypes.ErrEventSubscriberTooSlow {
+				t.Errorf("node %d: subscription ended with %v", i, err)
+			}
+		default:
+			t.Errorf("node %d: stalled subscription not dropped", i)
+		}
+	}
+}
+
+func TestSimVoteMetrics(t *testing.T) {
+	enabled := gometrics.Enabled
+	gometrics.Enabled = true
+	defer func() { gometrics.Enabled = enabled }()
+
+	sim := runSim(t, 4, map[int]Byzantine{0: WithholdVotes}, nil)
+	addrs := make([]string, len(sim.Nodes))
+	for i, n := range sim.Nodes {
+		addrs[i] = fmt.Sprintf("%x", tcrypto.PubKeyTrue(n.Key.PublicKey).Address())
+	}
+	count := func(i int, voteType, kind string) int64 {
+		name := fmt.Sprintf("consensus/tbft/validator/%s/%s/%s", addrs[i], voteType, kind)
+		if m, ok := gometrics.DefaultRegistry.Get(name).(interface{ Count() int64 }); ok {
+			return m.Count()
+		}
+		return 0
+	}
+	missed := count(0, "precommit", "missed")
+
+	honest := []int{1, 2, 3}
+	if !sim.RunUntil(func() bool { return sim.Height(honest...) >= 3 }, 60*time.Second) {
+		t.Fatalf("honest nodes stalled at height %d", sim.Height(honest...))
+	}
+	// every commit of every node counts the precommit the silent validator missed
+	var commits int64
+	for _, n := range sim.Nodes {
+		commits += int64(len(n.Blocks()))
+	}
+	if d := count(0, "precommit", "missed") - missed; d != commits {
+		t.Errorf("counted %d missed precommits for %d commits", d, commits)
+	}
+	if count(1, "prevote", "latency") == 0 || count(1, "precommit", "latency") == 0 {
+		t.Error("vote latency of an honest validator not recorded")
+	}
+
+	// stopping the committee drops the metrics of its validators
+	sim.Stop()
+	for i := range sim.Nodes {
+		for _, voteType := range []string{"prevote", "precommit"} {
+			for _, kind := range []string{"latency", "missed"} {
+				name := fmt.Sprintf("consensus/tbft/validator/%s/%s/%s", addrs[i], voteType, kind)
+				if gometrics.DefaultRegistry.Get(name) != nil {
+					t.Errorf("%s still registered", name)
+				}
+			}
+		}
+	}
+}
@@ -90,6 +90,11 @@ type ConsensusState struct {
 
 	// for tests where we want to limit the number of transitions the state makes
 	nSteps int
+	// driven states don't run the receiveRoutine, their owner handles the
+	// queues and timeouts on its own goroutine
+	driven bool
+	// now reads the wall clock, simulations replace it
+	now func() time.Time
 
 	// some functions can be overwritten for testing
 	decideProposal func(height uint64, round int, blk *types.Block, parts *ttypes.PartSet)
@@ -105,11 +110,22 @@ type ConsensusState struct {
 	svs  []*ttypes.SwitchValidator
 	hm   *ttypes.HealthMgr
 	cm   *types.CommitteeInfo
+
+	// for the round metrics
+	stepStart    time.Time
+	proposalTime time.Time
 }
 
 // CSOption sets an optional parameter on the ConsensusState.
 type CSOption func(*ConsensusState)
 
+// WithClock makes the state read the time from now instead of the wall clock.
+func WithClock(now func() time.Time) CSOption {
+	return func(cs *ConsensusState) {
+		cs.now = now
+	}
+}
+
 // NewConsensusState returns a new ConsensusState.
 func NewConsensusState(
 	config *cfg.ConsensusConfig,
@@ -128,12 +144,17 @@ func NewConsensusState(
 		state:            state,
 		evsw:             ttypes.NewEventSwitch(),
 		svs:              make([]*ttypes.SwitchValidator, 0, 0),
+		now:              time.Now,
 	}
 	// set function defaults (may be overwritten before calling Start)
 	cs.decideProposal = cs.defaultDecideProposal
 	cs.doPrevote = cs.defaultDoPrevote
 	cs.setProposal = cs.defaultSetProposal
 	cs.taskTimeOut = config.Propose(0)
+	// options apply before updateToState reads the clock
+	for _, option := range options {
+		option(cs)
+	}
 
 	cs.updateToState(state)
 	log.Debug("NewConsensusState", "Height", cs.Height)
@@ -141,9 +162,6 @@ func NewConsensusState(
 	// We do that upon Start().
 	cs.reconstructLastCommit()
 	cs.BaseService = *help.NewBaseService("ConsensusState", cs)
-	for _, option := range options {
-		option(cs)
-	}
 	return cs
 }
 
@@ -214,6 +232,13 @@ func (cs *ConsensusState) SetTimeoutTicker(timeoutTicker TimeoutTicker) {
 	cs.timeoutTicker = timeoutTicker
 }
 
+// SetTimeoutTask sets the block sync timer. It may be useful to overwrite for testing.
+func (cs *ConsensusState) SetTimeoutTask(timeoutTask TimeoutTicker) {
+	cs.mtx.Lock()
+	defer cs.mtx.Unlock()
+	cs.timeoutTask = timeoutTask
+}
+
 // OnStart implements help.Service.
 // It loads the latest state via the WAL, and starts the timeout and receive routines.
 func (cs *ConsensusState) OnStart() error {
@@ -237,7 +262,9 @@ func (cs *ConsensusState) OnStart() error {
 	}
 	cs.updateToState(cs.state)
 	// now start the receiveRoutine
-	go cs.receiveRoutine(0)
+	if !cs.driven {
+		go cs.receiveRoutine(0)
+	}
 
 	// schedule the first round!
 	// use GetRoundState so we don't race the receiveRoutine for access
@@ -267,6 +294,10 @@ func (cs *ConsensusState) OnStop() {
 	help.CheckAndPrintError(cs.evsw.Stop())
 	help.CheckAndPrintError(cs.timeoutTicker.Stop())
 	help.CheckAndPrintError(cs.timeoutTask.Stop())
+	if cs.driven {
+		close(cs.done)
+	}
+	cs.unregisterMetrics()
 	log.Info("End ConsensusState finish")
 }
 
@@ -351,13 +382,18 @@ func (cs *ConsensusState) updateHeight(height uint64) {
 }
 
 func (cs *ConsensusState) updateRoundStep(round int, step ttypes.RoundStepType) {
+	now := time.Now()
+	if !cs.stepStart.IsZero() {
+		metrics.MStep(cs.Step.String(), now.Sub(cs.stepStart))
+	}
+	cs.stepStart = now
 	cs.Round = uint(round)
 	cs.Step = step
 }
 
 // enterNewRound(height, 0) at cs.StartTime.
 func (cs *ConsensusState) scheduleRound0(rs *ttypes.RoundState) {
-	sleepDuration := rs.StartTime.Sub(time.Now()) // nolint: gotype, gosimple
+	sleepDuration := rs.StartTime.Sub(cs.now()) // nolint: gotype, gosimple
 	cs.scheduleTimeout(sleepDuration, rs.Height, 0, ttypes.RoundStepNewHeight)
 	var d = cs.taskTimeOut
 	cs.timeoutTask.ScheduleTimeout(timeoutInfo{d, rs.Height, uint(rs.Round), ttypes.RoundStepBlockSync, 0})
@@ -454,10 +490,10 @@ func (cs *ConsensusState) updateToState(state ttypes.StateAgent) {
 		// to be gathered for the first block.
 		// And alternative solution that relies on clocks:
 		//  cs.StartTime = state.LastBlockTime.Add(timeoutCommit)
-		cs.StartTime = cs.config.Commit(time.Now())
+		cs.StartTime = cs.config.Commit(cs.now())
 	} else {
 		if cs.Proposal != nil && cs.StartTime.After(cs.config.CatchupTime(time.Unix(cs.Proposal.Timestamp.Unix(), 0))) {
-			cs.StartTime = time.Now()
+			cs.StartTime = cs.now()
 		} else {
 			cs.StartTime = cs.config.Commit(cs.CommitTime)
 		}
@@ -485,13 +521,12 @@ func (cs *ConsensusState) updateToState(state ttypes.StateAgent) {
 }
 
 func (cs *ConsensusState) newStep() {
-	//rs := cs.RoundStateEvent()
 	cs.nSteps++
 	// newStep is called by updateToState in NewConsensusState before the eventBus is set!
-	//if cs.eventBus != nil {
-	//help.CheckAndPrintError(cs.eventBus.PublishEventNewRoundStep(rs))
+	if cs.eventBus != nil {
+		help.CheckAndPrintError(cs.eventBus.PublishEventNewRoundStep(cs.RoundStateEvent()))
+	}
 	cs.evsw.FireEvent(ttypes.EventNewRoundStep, &cs.RoundState)
-	//}
 }
 func (cs *ConsensusState) validatorUpdate(msg *ValidatorUpdateMessage) {
 	log.Trace("ValidatorUpdate", "uHeight", msg.uHeight, "eHeight", msg.eHeight, "cHeight", cs.Height, "Round", cs.Round)
@@ -500,6 +535,10 @@ func (cs *ConsensusState) validatorUpdate(msg *ValidatorUpdateMessage) {
 	if len(cs.svs) > 0 {
 		cs.svs = append(cs.svs[:0], cs.svs[1:]...)
 	}
+	// keep the proposer rotation of the members staying in the committee
+	if msg.vset != nil {
+		msg.vset.CarryAccum(cs.Validators)
+	}
 	help.CheckAndPrintError(cs.state.UpdateValidator(msg.vset, true))
 	cs.updateToState(cs.state)
 	cs.state.PrivReset()
@@ -662,13 +701,13 @@ func (cs *ConsensusState) handleTimeout(ti timeoutInfo, rs ttypes.RoundState) {
 	case ttypes.RoundStepNewRound:
 		cs.tryEnterProposal(ti.Height, 0, ti.Wait)
 	case ttypes.RoundStepPropose:
-		//help.CheckAndPrintError(cs.eventBus.PublishEventTimeoutPropose(cs.RoundStateEvent()))
+		help.CheckAndPrintError(cs.eventBus.PublishEventTimeoutPropose(cs.RoundStateEvent()))
 		cs.enterPrevote(ti.Height, int(ti.Round))
 	case ttypes.RoundStepPrevoteWait:
-		//help.CheckAndPrintError(cs.eventBus.PublishEventTimeoutWait(cs.RoundStateEvent()))
+		help.CheckAndPrintError(cs.eventBus.PublishEventTimeoutWait(cs.RoundStateEvent()))
 		cs.enterPrecommit(ti.Height, int(ti.Round))
 	case ttypes.RoundStepPrecommitWait:
-		//help.CheckAndPrintError(cs.eventBus.PublishEventTimeoutWait(cs.RoundStateEvent()))
+		help.CheckAndPrintError(cs.eventBus.PublishEventTimeoutWait(cs.RoundStateEvent()))
 		cs.enterNewRound(ti.Height, int(ti.Round)+1)
 	default:
 		panic(fmt.Sprintf("Invalid timeout step: %v", ti.Step))
@@ -700,7 +739,7 @@ func (cs *ConsensusState) enterNewRound(height uint64, round int) {
 		return
 	}
 
-	if now := time.Now(); cs.StartTime.After(now) {
+	if now := cs.now(); cs.StartTime.After(now) {
 		log.Debug("Need to set a buffer and log message here for sanity.", "startTime", cs.StartTime, "now", now)
 	}
 
@@ -732,7 +771,7 @@ func (cs *ConsensusState) enterNewRound(height uint64, round int) {
 	}
 	cs.Votes.SetRound(round + 1) // also track next round (round+1) to allow round-skipping
 
-	//help.CheckAndPrintError(cs.eventBus.PublishEventNewRound(cs.RoundStateEvent()))
+	help.CheckAndPrintError(cs.eventBus.PublishEventNewRound(cs.RoundStateEvent()))
 	// cs.metrics.Rounds.Set(float64(round))
 	cs.tryEnterProposal(height, round, 1)
 }
@@ -959,7 +998,7 @@ func (cs *ConsensusState) enterPrevote(height uint64, round int) {
 
 	// fire event for how we got here
 	if cs.isProposalComplete() {
-		//help.CheckAndPrintError(cs.eventBus.PublishEventCompleteProposal(cs.RoundStateEvent()))
+		help.CheckAndPrintError(cs.eventBus.PublishEventCompleteProposal(cs.RoundStateEvent()))
 	} else {
 		// we received +2/3 prevotes for a future round
 		// TODO: catchup event?
@@ -1075,7 +1114,7 @@ func (cs *ConsensusState) enterPrecommit(height uint64, round int) {
 	}
 
 	// At this point +2/3 prevoted for a particular block or nil.
-	//help.CheckAndPrintError(cs.eventBus.PublishEventPolka(cs.RoundStateEvent()))
+	help.CheckAndPrintError(cs.eventBus.PublishEventPolka(cs.RoundStateEvent()))
 
 	// the latest POLRound should be this round.
 	polRound, _ := cs.Votes.POLInfo()
@@ -1091,7 +1130,7 @@ func (cs *ConsensusState) enterPrecommit(height uint64, round int) {
 			log.Debug("enterPrecommit: +2/3 prevoted for nil. Unlocking")
 			cs.LockedRound, cs.LockedBlock = 0, nil
 			cs.LockedBlockParts, cs.proposalForCatchup = nil, nil
-			//help.CheckAndPrintError(cs.eventBus.PublishEventUnlock(cs.RoundStateEvent()))
+			help.CheckAndPrintError(cs.eventBus.PublishEventUnlock(cs.RoundStateEvent()))
 		}
 		cs.signAddVote(ttypes.VoteTypePrecommit, nil, ttypes.PartSetHeader{}, nil)
 		return
@@ -1106,7 +1145,7 @@ func (cs *ConsensusState) enterPrecommit(height uint64, round int) {
 	}() {
 		log.Debug("enterPrecommit: +2/3 prevoted locked block. Relocking")
 		cs.LockedRound = uint(round)
-		//help.CheckAndPrintError(cs.eventBus.PublishEventRelock(cs.RoundStateEvent()))
+		help.CheckAndPrintError(cs.eventBus.PublishEventRelock(cs.RoundStateEvent()))
 		ksign, err := cs.validateBlock(cs.LockedBlock)
 		if err != nil {
 			log.Debug("ValidateBlock faild will vote VoteAgreeAgainst", "hash", hexutil.Encode(blockID.Hash), "err", err)
@@ -1137,7 +1176,7 @@ func (cs *ConsensusState) enterPrecommit(height uint64, round int) {
 				cs.LockedRound, cs.LockedBlock = uint(round), cs.ProposalBlock
 				cs.LockedBlockParts, cs.proposalForCatchup = cs.ProposalBlockParts, cs.Proposal
 			}
-			//help.CheckAndPrintError(cs.eventBus.PublishEventLock(cs.RoundStateEvent()))
+			help.CheckAndPrintError(cs.eventBus.PublishEventLock(cs.RoundStateEvent()))
 			cs.signAddVote(ttypes.VoteTypePrecommit, blockID.Hash, blockID.PartsHeader, ksign)
 		} else {
 			cs.signAddVote(ttypes.VoteTypePrecommit, nil, ttypes.PartSetHeader{}, nil)
@@ -1155,7 +1194,7 @@ func (cs *ConsensusState) enterPrecommit(height uint64, round int) {
 		cs.ProposalBlock = nil
 		cs.ProposalBlockParts = ttypes.NewPartSetFromHeader(blockID.PartsHeader)
 	}
-	//help.CheckAndPrintError(cs.eventBus.PublishEventUnlock(cs.RoundStateEvent()))
+	help.CheckAndPrintError(cs.eventBus.PublishEventUnlock(cs.RoundStateEvent()))
 	cs.signAddVote(ttypes.VoteTypePrecommit, nil, ttypes.PartSetHeader{}, nil)
 }
 
@@ -1198,7 +1237,7 @@ func (cs *ConsensusState) enterCommit(height uint64, commitRound int) {
 		// keep cs.Round the same, commitRound points to the right Precommits set.
 		cs.updateRoundStep(int(cs.Round), ttypes.RoundStepCommit)
 		cs.CommitRound = uint(commitRound)
-		cs.CommitTime = time.Now()
+		cs.CommitTime = cs.now()
 		cs.newStep()
 
 		// Maybe finalize immediately.
@@ -1270,6 +1309,45 @@ func (cs *ConsensusState) tryFinalizeCommit(height uint64) {
 	cs.finalizeCommit(height)
 }
 
+// recordCommitMetrics records the rounds the height needed and the votes of
+// the commit round missing from each validator.
+func (cs *ConsensusState) recordCommitMetrics() {
+	metrics.MRounds(int(cs.CommitRound) + 1)
+	prevotes := cs.Votes.Prevotes(int(cs.CommitRound))
+	precommits := cs.Votes.Precommits(int(cs.CommitRound))
+	cs.Validators.Iterate(func(index int, val *ttypes.Validator) bool {
+		if prevotes == nil || prevotes.GetByIndex(uint(index)) == nil {
+			metrics.MVoteMissed(fmt.Sprintf("%x", val.Address), voteTypeName(ttypes.VoteTypePrevote))
+		}
+		if precommits == nil || precommits.GetByIndex(uint(index)) == nil {
+			metrics.MVoteMissed(fmt.Sprintf("%x", val.Address), voteTypeName(ttypes.VoteTypePrecommit))
+		}
+		return false
+	})
+}
+
+// unregisterMetrics drops the vote metrics of the validators of the committee.
+func (cs *ConsensusState) unregisterMetrics() {
+	cs.mtx.RLock()
+	validators := cs.Validators
+	cs.mtx.RUnlock()
+	if validators == nil {
+		return
+	}
+	validators.Iterate(func(index int, val *ttypes.Validator) bool {
+		metrics.UnregisterValidator(fmt.Sprintf("%x", val.Address))
+		return false
+	})
+}
+
+// voteTypeName names the vote types in metrics.
+func voteTypeName(voteType byte) string {
+	if voteType == ttypes.VoteTypePrevote {
+		return "prevote"
+	}
+	return "precommit"
+}
+
 // Increment height and goto ttypes.RoundStepNewHeight
 func (cs *ConsensusState) finalizeCommit(height uint64) {
 	if cs.Height != height || cs.Step != ttypes.RoundStepCommit {
@@ -1300,6 +1378,14 @@ func (cs *ConsensusState) finalizeCommit(height uint64) {
 
 	// Execute and commit the block, update and save the state, and update the mempool.
 	// NOTE The block.AppHash wont reflect these txs until the next block.
+	// An observer has no key of its own, it only hands blocks to the chain
+	// whose commit checks out against the validator set.
+	if cs.privValidator == nil {
+		if err := cs.Validators.VerifyCommit(cs.state.GetChainID(), blockID, height, voteset.MakeCommit()); err != nil {
+			log.Error("Observer rejected the commit", "height", height, "err", err)
+			return
+		}
+	}
 	var err error
 	block.SetSign(signs)
 
@@ -1320,8 +1406,10 @@ func (cs *ConsensusState) finalizeCommit(height uint64) {
 			proposal = cs.proposalForCatchup
 		}
 		cs.blockStore.SaveBlock(block, blockParts, seenCommit, cs.Proposal)
+		cs.recordCommitMetrics()
 	} else {
-		// Happens during replay if we already saved the block but didn't commit
+		// Happens during replay if we already saved the block but didn't commit,
+		// its votes were counted the first time
 		log.Debug("Calling finalizeCommit on already stored block", "height", block.NumberU64())
 	}
 
@@ -1373,6 +1461,7 @@ func (cs *ConsensusState) defaultSetProposal(proposal *ttypes.Proposal) error {
 
 	cs.Proposal = proposal
 	cs.ProposalBlockParts = ttypes.NewPartSetFromHeader(proposal.BlockPartsHeader)
+	cs.proposalTime = time.Now()
 	log.Debug("Received proposal", "proposal", proposal)
 	return nil
 }
@@ -1401,6 +1490,7 @@ func (cs *ConsensusState) addProposalBlockPart(msg *BlockPartMessage, peerID str
 	if err != nil {
 		return added, err
 	}
+	metrics.MBlockPart(!added)
 	if added && cs.ProposalBlockParts.IsComplete() {
 		// Added and completed!
 		cs.ProposalBlock, err = ttypes.MakeBlockFromPartSet(cs.ProposalBlockParts)
@@ -1452,7 +1542,7 @@ func (cs *ConsensusState) tryAddVote(vote *ttypes.Vote, peerID string) error {
 			return err
 		}
 		if err == ttypes.ErrVoteConflictingVotes {
-			if bytes.Equal(vote.ValidatorAddress, cs.privValidator.GetAddress()) {
+			if cs.privValidator != nil && bytes.Equal(vote.ValidatorAddress, cs.privValidator.GetAddress()) {
 				log.Debug("Found conflicting vote from ourselves. Did you unsafe_reset a validator?", "height", vote.Height, "round", vote.Round, "type", vote.Type)
 				return err
 			}
@@ -1488,7 +1578,7 @@ func (cs *ConsensusState) addVote(vote *ttypes.Vote, peerID string) (added bool,
 			}
 			log.Debug(fmt.Sprintf("Added to lastPrecommits: %v", cs.LastCommit.StringShort()))
 		}
-		//help.CheckAndPrintError(cs.eventBus.PublishEventVote(ttypes.EventDataVote{Vote: vote}))
+		help.CheckAndPrintError(cs.eventBus.PublishEventVote(ttypes.EventDataVote{Vote: vote}))
 		cs.evsw.FireEvent(ttypes.EventVote, vote)
 
 		// if we can skip timeoutCommit and have all the votes now,
@@ -1514,8 +1604,11 @@ func (cs *ConsensusState) addVote(vote *ttypes.Vote, peerID string) (added bool,
 		// Either duplicate, or error upon cs.Votes.AddByIndex()
 		return
 	}
+	if cs.Proposal != nil && cs.Proposal.Round == vote.Round {
+		metrics.MVoteLatency(fmt.Sprintf("%x", vote.ValidatorAddress), voteTypeName(vote.Type), time.Since(cs.proposalTime))
+	}
 
-	//help.CheckAndPrintError(cs.eventBus.PublishEventVote(ttypes.EventDataVote{Vote: vote}))
+	help.CheckAndPrintError(cs.eventBus.PublishEventVote(ttypes.EventDataVote{Vote: vote}))
 	cs.evsw.FireEvent(ttypes.EventVote, vote)
 
 	switch vote.Type {
@@ -1545,7 +1638,7 @@ func (cs *ConsensusState) addVote(vote *ttypes.Vote, peerID string) (added bool,
 				cs.LockedBlock = nil
 				cs.LockedBlockParts = nil
 				cs.proposalForCatchup = nil
-				//help.CheckAndPrintError(cs.eventBus.PublishEventUnlock(cs.RoundStateEvent()))
+				help.CheckAndPrintError(cs.eventBus.PublishEventUnlock(cs.RoundStateEvent()))
 			}
 
 			// Update Valid* if we can.
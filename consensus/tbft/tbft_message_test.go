@@ -22,8 +22,8 @@ func BinaryBareTest(o interface{}, o2 interface{}) {
 }
 
 func BinaryTest(o interface{}, o2 interface{}) {
-	byte2, _ := cdc.MarshalBinary(o)
-	if err := cdc.UnmarshalBinary(byte2, o2); err == nil {
+	byte2, _ := cdc.MarshalBinaryLengthPrefixed(o)
+	if err := cdc.UnmarshalBinaryLengthPrefixed(byte2, o2); err == nil {
 		fmt.Println(o)
 		fmt.Println(o2)
 	} else {
@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"math/big"
+	"net"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -16,6 +17,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"ethereum/rpc-network/crypto"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/trie"
 	tcrypto "ethereum/rpc-network/consensus/tbft/crypto"
 	"ethereum/rpc-network/consensus/tbft/help"
 	ttypes "ethereum/rpc-network/consensus/tbft/types"
@@ -75,9 +77,9 @@ func (pap *PbftAgentProxyImp) FetchFastBlock(committeeID *big.Int, infos []*type
 	header := new(types.Header)
 	header.Number = getIDForCache(pap.Name) //getID()
 	fmt.Println(pap.Name, header.Number)
-	header.Time = big.NewInt(time.Now().Unix())
+	header.Time = uint64(time.Now().Unix())
 	println("[AGENT]", pap.Name, "++++++++", "FetchFastBlock", "Number:", header.Number.Uint64())
-	return types.NewBlock(header, nil, nil, nil, infos), nil
+	return types.NewBlock(header, nil, nil, nil, new(trie.Trie)).WithSwitchInfos(infos), nil
 }
 
 func (agent *PbftAgentProxyImp) GetFastLastProposer() common.Address {
@@ -201,7 +203,7 @@ func TestPbftRunForOne(t *testing.T) {
 }
 
 func TestPbftRunFor2(t *testing.T) {
-	//log.OpenLogDebug(3)
+	//openLogDebug(log.LvlInfo)
 	IDCacheInit()
 	start := make(chan int)
 	pr1 := getPrivateKey(0)
@@ -225,6 +227,7 @@ func TestPbftRunFor2(t *testing.T) {
 
 	n1, _ := NewNode(config1, "1", pr1, agent1)
 	n1.Start()
+	defer n1.Stop()
 
 	config2 := new(config.TbftConfig)
 	*config2 = *config.TestConfig()
@@ -241,6 +244,7 @@ func TestPbftRunFor2(t *testing.T) {
 
 	n2, _ := NewNode(config2, "1", pr2, agent2)
 	n2.Start()
+	defer n2.Stop()
 
 	c1 := new(types.CommitteeInfo)
 	c1.Id = big.NewInt(1)
@@ -270,7 +274,7 @@ func TestPbftRunFor2(t *testing.T) {
 }
 
 func TestPbftRunFor4(t *testing.T) {
-	log.OpenLogDebug(3)
+	openLogDebug(log.LvlInfo)
 	IDCacheInit()
 	start := make(chan int)
 	pr1 := getPrivateKey(0)
@@ -298,6 +302,7 @@ func TestPbftRunFor4(t *testing.T) {
 
 	n1, _ := NewNode(config1, "1", pr1, agent1)
 	n1.Start()
+	defer n1.Stop()
 
 	config2 := new(config.TbftConfig)
 	*config2 = *config.DefaultConfig()
@@ -314,6 +319,7 @@ func TestPbftRunFor4(t *testing.T) {
 
 	n2, _ := NewNode(config2, "1", pr2, agent2)
 	n2.Start()
+	defer n2.Stop()
 
 	config3 := new(config.TbftConfig)
 	*config3 = *config.DefaultConfig()
@@ -330,6 +336,7 @@ func TestPbftRunFor4(t *testing.T) {
 
 	n3, _ := NewNode(config3, "1", pr3, agent3)
 	n3.Start()
+	defer n3.Stop()
 
 	config4 := new(config.TbftConfig)
 	*config4 = *config.DefaultConfig()
@@ -346,6 +353,7 @@ func TestPbftRunFor4(t *testing.T) {
 
 	n4, _ := NewNode(config4, "1", pr4, agent4)
 	n4.Start()
+	defer n4.Stop()
 
 	c1 := new(types.CommitteeInfo)
 	c1.Id = big.NewInt(1)
@@ -402,7 +410,7 @@ func TestPbftRunFor4(t *testing.T) {
 }
 
 func TestPbftRunFor4AndChange(t *testing.T) {
-	//log.OpenLogDebug(3)
+	//openLogDebug(log.LvlInfo)
 	IDCacheInit()
 	start := make(chan int)
 	pr1 := getPrivateKey(0)
@@ -430,6 +438,7 @@ func TestPbftRunFor4AndChange(t *testing.T) {
 
 	n1, _ := NewNode(config1, "1", pr1, agent1)
 	n1.Start()
+	defer n1.Stop()
 
 	config2 := new(config.TbftConfig)
 	*config2 = *config.TestConfig()
@@ -446,6 +455,7 @@ func TestPbftRunFor4AndChange(t *testing.T) {
 
 	n2, _ := NewNode(config2, "1", pr2, agent2)
 	n2.Start()
+	defer n2.Stop()
 
 	config3 := new(config.TbftConfig)
 	*config3 = *config.TestConfig()
@@ -462,6 +472,7 @@ func TestPbftRunFor4AndChange(t *testing.T) {
 
 	n3, _ := NewNode(config3, "1", pr3, agent3)
 	n3.Start()
+	defer n3.Stop()
 
 	config4 := new(config.TbftConfig)
 	*config4 = *config.TestConfig()
@@ -478,6 +489,7 @@ func TestPbftRunFor4AndChange(t *testing.T) {
 
 	n4, _ := NewNode(config4, "1", pr4, agent4)
 	n4.Start()
+	defer n4.Stop()
 
 	c1 := new(types.CommitteeInfo)
 	c1.Id = big.NewInt(1)
@@ -582,6 +594,7 @@ func TestPbftRunFor5(t *testing.T) {
 
 	n1, _ := NewNode(config1, "1", pr1, agent1)
 	n1.Start()
+	defer n1.Stop()
 
 	config2 := new(config.TbftConfig)
 	*config2 = *config.TestConfig()
@@ -598,6 +611,7 @@ func TestPbftRunFor5(t *testing.T) {
 
 	n2, _ := NewNode(config2, "1", pr2, agent2)
 	n2.Start()
+	defer n2.Stop()
 
 	config3 := new(config.TbftConfig)
 	*config3 = *config.TestConfig()
@@ -614,6 +628,7 @@ func TestPbftRunFor5(t *testing.T) {
 
 	n3, _ := NewNode(config3, "1", pr3, agent3)
 	n3.Start()
+	defer n3.Stop()
 
 	config4 := new(config.TbftConfig)
 	*config4 = *config.TestConfig()
@@ -630,6 +645,7 @@ func TestPbftRunFor5(t *testing.T) {
 
 	n4, _ := NewNode(config4, "1", pr4, agent4)
 	n4.Start()
+	defer n4.Stop()
 
 	config5 := new(config.TbftConfig)
 	*config5 = *config.TestConfig()
@@ -646,6 +662,7 @@ func TestPbftRunFor5(t *testing.T) {
 
 	n5, _ := NewNode(config5, "1", pr5, agent5)
 	n5.Start()
+	defer n5.Stop()
 
 	c1 := new(types.CommitteeInfo)
 	c1.Id = big.NewInt(1)
@@ -705,7 +722,7 @@ func TestPbftRunFor5(t *testing.T) {
 }
 
 func TestRunPbft1(t *testing.T) {
-	log.OpenLogDebug(3)
+	openLogDebug(log.LvlInfo)
 	IDCacheInit()
 	start := make(chan int)
 	pr1 := getPrivateKey(0)
@@ -757,6 +774,7 @@ func TestRunPbft1(t *testing.T) {
 	c1.EndHeight = big.NewInt(11111)
 
 	n1.Start()
+	defer n1.Stop()
 	n1.PutCommittee(c1)
 
 	cn := make([]*types.CommitteeNode, 0)
@@ -781,7 +799,7 @@ func TestRunPbft1(t *testing.T) {
 }
 
 func TestRunPbft2(t *testing.T) {
-	//log.OpenLogDebug(3)
+	//openLogDebug(log.LvlInfo)
 	IDCacheInit()
 	start := make(chan int)
 	pr1 := getPrivateKey(0)
@@ -833,6 +851,7 @@ func TestRunPbft2(t *testing.T) {
 	c1.EndHeight = big.NewInt(11111)
 
 	n2.Start()
+	defer n2.Stop()
 	n2.PutCommittee(c1)
 	n2.Notify(c1.Id, Start)
 
@@ -863,7 +882,7 @@ func getAddr() common.Address {
 }
 
 func TestRunPbft3(t *testing.T) {
-	log.OpenLogDebug(3)
+	openLogDebug(log.LvlInfo)
 	IDCacheInit()
 	start := make(chan int)
 	pr1 := getPrivateKey(0)
@@ -915,6 +934,7 @@ func TestRunPbft3(t *testing.T) {
 	c1.EndHeight = big.NewInt(11111)
 
 	n3.Start()
+	defer n3.Stop()
 	n3.PutCommittee(c1)
 	n3.Notify(c1.Id, Start)
 
@@ -939,7 +959,7 @@ func TestRunPbft3(t *testing.T) {
 }
 
 func TestRunPbft4(t *testing.T) {
-	//log.OpenLogDebug(3)
+	//openLogDebug(log.LvlInfo)
 	IDCacheInit()
 	start := make(chan int)
 	pr1 := getPrivateKey(0)
@@ -991,6 +1011,7 @@ func TestRunPbft4(t *testing.T) {
 	c1.EndHeight = big.NewInt(11111)
 
 	n4.Start()
+	defer n4.Stop()
 	n4.PutCommittee(c1)
 	n4.Notify(c1.Id, Start)
 
@@ -1180,6 +1201,13 @@ func TestPrivKey(t *testing.T) {
 }
 
 func TestPutNodes(t *testing.T) {
+	// the node listens on the address of the first testnet member
+	l, err := net.Listen("tcp", "39.98.44.213:30310")
+	if err != nil {
+		t.Skip("not running on the testnet host:", err)
+	}
+	l.Close()
+
 	IDCacheInit()
 	start := make(chan int)
 	pr1, _ := crypto.HexToECDSA("2ee9b9082e3eb19378d478f450e0e818e94cf7e3bf13ad5dd657ef2a35fbb0a8")
@@ -1203,6 +1231,7 @@ func TestPutNodes(t *testing.T) {
 
 	n1, _ := NewNode(config1, "1", pr1, agent1)
 	n1.Start()
+	defer n1.Stop()
 
 	c1 := new(types.CommitteeInfo)
 	c1.Id = big.NewInt(1)
@@ -1238,7 +1267,7 @@ func TestPutNodes(t *testing.T) {
 }
 
 func TestWatch(t *testing.T) {
-	log.OpenLogDebug(5)
+	openLogDebug(log.LvlTrace)
 	help.BeginWatchMgr()
 	w := help.NewTWatch(3, "111")
 	time.Sleep(time.Second * 70)
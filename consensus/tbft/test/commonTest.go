@@ -10,6 +10,7 @@ import (
 	amino "github.com/tendermint/go-amino"
 	types2 "ethereum/rpc-network/consensus/tbft/types"
 	"ethereum/rpc-network/core/types"
+	"github.com/ethereum/go-ethereum/trie"
 )
 
 var cdc = amino.NewCodec()
@@ -68,12 +69,12 @@ func TestMarshalBinary(t *testing.T) {
 	a := TStruct{Id: big.NewFloat(1.001), T2: t2}
 	var tOut TStruct
 	fmt.Println(a)
-	byte2, err := cdc.MarshalBinary(a)
+	byte2, err := cdc.MarshalBinaryLengthPrefixed(a)
 	if err != nil {
 		fmt.Println(err.Error())
 	}
 	fmt.Println(string(byte2))
-	if err := cdc.UnmarshalBinary(byte2, &tOut); err == nil {
+	if err := cdc.UnmarshalBinaryLengthPrefixed(byte2, &tOut); err == nil {
 		fmt.Println(tOut)
 	} else {
 		fmt.Println(err.Error())
@@ -82,9 +83,9 @@ func TestMarshalBinary(t *testing.T) {
 
 func TestReader(t *testing.T) {
 	header := &types.Header{}
-	header.Time = big.NewInt(time.Now().Unix())
+	header.Time = uint64(time.Now().Unix())
 	header.ParentHash = RandHexBytes()
-	header.Number = header.Time
+	header.Number = new(big.Int).SetUint64(header.Time)
 
 	var tr []*types.Transaction
 
@@ -94,16 +95,15 @@ func TestReader(t *testing.T) {
 	}
 
 	var re []*types.Receipt
-	var si []*types.PbftSign
 
-	bTmp := types.NewBlock(header, tr, re, si, nil)
+	bTmp := types.NewBlock(header, tr, nil, re, new(trie.Trie))
 
 	ps, _ := types2.MakePartSet(64*1024, bTmp)
 	pe := types2.NewPartSetFromHeader(ps.Header())
 
 	header2 := &types.Header{}
 
-	cdc.UnmarshalBinaryReader(pe.GetReader(), &header2, 1000)
+	cdc.UnmarshalBinaryLengthPrefixedReader(pe.GetReader(), &header2, 1000)
 
 	fmt.Println(header)
 	fmt.Println(header2)
@@ -103,18 +103,8 @@ func (t *timeoutTicker) timeoutRoutine() {
 			log.Trace("Received tick", "old_ti", ti, "new_ti", newti)
 
 			// ignore tickers for old height/round/step
-			if newti.Wait == 1 {
-				if newti.Height < ti.Height {
-					continue
-				} else if newti.Height == ti.Height {
-					if newti.Round < ti.Round {
-						continue
-					} else if newti.Round == ti.Round {
-						if ti.Step > 0 && newti.Step <= ti.Step {
-							continue
-						}
-					}
-				}
+			if isStaleTimeout(newti, ti) {
+				continue
 			}
 
 			// stop the last timer
@@ -140,3 +130,17 @@ func (t *timeoutTicker) timeoutRoutine() {
 		}
 	}
 }
+
+// isStaleTimeout reports whether newti is for an older height/round/step than ti.
+func isStaleTimeout(newti, ti timeoutInfo) bool {
+	if newti.Wait != 1 {
+		return false
+	}
+	if newti.Height != ti.Height {
+		return newti.Height < ti.Height
+	}
+	if newti.Round != ti.Round {
+		return newti.Round < ti.Round
+	}
+	return ti.Step > 0 && newti.Step <= ti.Step
+}
@@ -0,0 +1,181 @@
+package conn
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+
+	"ethereum/rpc-network/consensus/tbft/crypto"
+	"ethereum/rpc-network/p2p"
+)
+
+// RLPxVersion is the version of the RLPx transport, advertised by nodes
+// supporting it.
+const RLPxVersion = 1
+
+// rlpxMsgCode is the code of every message carrying tp2p data.
+const rlpxMsgCode = 0
+
+// rlpxMaxWrite bounds the data written in one RLPx message.
+const rlpxMaxWrite = 1 << 16
+
+var (
+	errRLPxKey     = errors.New("RLPx transport needs a secp256k1 node key")
+	errRLPxVersion = errors.New("unsupported RLPx transport version")
+)
+
+// The dialer of a RLPx connection writes a zero byte followed by the transport
+// version ahead of the handshake. The secret connection handshake starts with
+// the non-zero length prefix of the ephemeral key, so a listener tells the two
+// transports apart by the first byte.
+const rlpxPreamble = 0x00
+
+// RLPxConnection implements net.Conn on top of an RLPx connection authenticated
+// with the node key, the same key identifies the node on devp2p.
+type RLPxConnection struct {
+	net.Conn
+	rw         *p2p.RLPxConn
+	remPubKey  crypto.PubKey
+	recvBuffer []byte
+}
+
+// MakeRLPxConnection dials the RLPx transport on conn, authenticating the node
+// holding remPubKey.
+func MakeRLPxConnection(conn net.Conn, locPrivKey crypto.PrivKey, remPubKey crypto.PubKey) (*RLPxConnection, error) {
+	prv, err := rlpxPrivKey(locPrivKey)
+	if err != nil {
+		return nil, err
+	}
+	var pub ecdsa.PublicKey
+	switch k := remPubKey.(type) {
+	case crypto.PubKeyTrue:
+		pub = ecdsa.PublicKey(k)
+	case *crypto.PubKeyTrue:
+		pub = ecdsa.PublicKey(*k)
+	default:
+		return nil, errRLPxKey
+	}
+	if _, err := conn.Write([]byte{rlpxPreamble, RLPxVersion}); err != nil {
+		return nil, err
+	}
+	return handshakeRLPx(conn, prv, &pub)
+}
+
+// AcceptConnection runs the handshake of the transport the dialer of conn
+// picked, RLPx if allowed by rlpx or else the secret connection.
+func AcceptConnection(conn net.Conn, locPrivKey crypto.PrivKey, rlpx bool) (net.Conn, error) {
+	var first [1]byte
+	if _, err := io.ReadFull(conn, first[:]); err != nil {
+		return nil, err
+	}
+	if first[0] != rlpxPreamble {
+		return MakeSecretConnection(&prefixConn{Conn: conn, prefix: first[:]}, locPrivKey)
+	}
+	if !rlpx {
+		return nil, fmt.Errorf("RLPx transport disabled")
+	}
+	if _, err := io.ReadFull(conn, first[:]); err != nil {
+		return nil, err
+	}
+	if first[0] != RLPxVersion {
+		return nil, errRLPxVersion
+	}
+	prv, err := rlpxPrivKey(locPrivKey)
+	if err != nil {
+		return nil, err
+	}
+	return handshakeRLPx(conn, prv, nil)
+}
+
+// SupportsRLPx reports whether key can authenticate a RLPx connection. Keys
+// held by a remote signer can't, the handshake needs ECDH with the key itself.
+func SupportsRLPx(key crypto.PrivKey) bool {
+	_, err := rlpxPrivKey(key)
+	return err == nil
+}
+
+func rlpxPrivKey(key crypto.PrivKey) (*ecdsa.PrivateKey, error) {
+	switch k := key.(type) {
+	case crypto.PrivKeyTrue:
+		prv := ecdsa.PrivateKey(k)
+		return &prv, nil
+	case *crypto.PrivKeyTrue:
+		return (*ecdsa.PrivateKey)(k), nil
+	}
+	return nil, errRLPxKey
+}
+
+func handshakeRLPx(conn net.Conn, prv *ecdsa.PrivateKey, dial *ecdsa.PublicKey) (*RLPxConnection, error) {
+	rw := p2p.NewRLPxConn(conn)
+	remote, err := rw.Handshake(prv, dial)
+	if err != nil {
+		return nil, err
+	}
+	return &RLPxConnection{
+		Conn:      conn,
+		rw:        rw,
+		remPubKey: crypto.PubKeyTrue(*remote),
+	}, nil
+}
+
+// RemotePubKey returns authenticated remote pubkey
+func (rc *RLPxConnection) RemotePubKey() crypto.PubKey {
+	return rc.remPubKey
+}
+
+// Write writes data in messages of at most rlpxMaxWrite bytes.
+func (rc *RLPxConnection) Write(data []byte) (n int, err error) {
+	for len(data) > 0 {
+		chunk := data
+		if len(chunk) > rlpxMaxWrite {
+			chunk = chunk[:rlpxMaxWrite]
+		}
+		msg := p2p.Msg{Code: rlpxMsgCode, Size: uint32(len(chunk)), Payload: bytes.NewReader(chunk)}
+		if err := rc.rw.WriteMsg(msg); err != nil {
+			return n, err
+		}
+		n += len(chunk)
+		data = data[len(chunk):]
+	}
+	return n, nil
+}
+
+// Read reads data from the current message, reading the next one once it is
+// used up.
+func (rc *RLPxConnection) Read(data []byte) (n int, err error) {
+	for len(rc.recvBuffer) == 0 {
+		msg, err := rc.rw.ReadMsg()
+		if err != nil {
+			return 0, err
+		}
+		if msg.Code != rlpxMsgCode {
+			return 0, fmt.Errorf("unexpected RLPx message code %d", msg.Code)
+		}
+		if rc.recvBuffer, err = ioutil.ReadAll(msg.Payload); err != nil {
+			return 0, err
+		}
+	}
+	n = copy(data, rc.recvBuffer)
+	rc.recvBuffer = rc.recvBuffer[n:]
+	return n, nil
+}
+
+// prefixConn hands out prefix before reading from Conn, returning the bytes a
+// listener read to detect the transport.
+type prefixConn struct {
+	net.Conn
+	prefix []byte
+}
+
+func (pc *prefixConn) Read(data []byte) (int, error) {
+	if len(pc.prefix) > 0 {
+		n := copy(data, pc.prefix)
+		pc.prefix = pc.prefix[n:]
+		return n, nil
+	}
+	return pc.Conn.Read(data)
+}
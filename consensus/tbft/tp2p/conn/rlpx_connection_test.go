@@ -0,0 +1,108 @@
+package conn
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+
+	"ethereum/rpc-network/consensus/tbft/crypto"
+	ecrypto "ethereum/rpc-network/crypto"
+)
+
+func newTestKey(t *testing.T) crypto.PrivKeyTrue {
+	key, err := ecrypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return crypto.PrivKeyTrue(*key)
+}
+
+func pubKeyOf(key crypto.PrivKeyTrue) crypto.PubKey {
+	return crypto.PubKeyTrue(key.PublicKey)
+}
+
+// acceptAsync accepts a connection on conn in the background.
+func acceptAsync(conn net.Conn, key crypto.PrivKey, rlpx bool) <-chan net.Conn {
+	connc := make(chan net.Conn, 1)
+	go func() {
+		accepted, err := AcceptConnection(conn, key, rlpx)
+		if err != nil {
+			conn.Close()
+			accepted = nil
+		}
+		connc <- accepted
+	}()
+	return connc
+}
+
+func TestRLPxConnection(t *testing.T) {
+	dialKey, listenKey := newTestKey(t), newTestKey(t)
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	connc := acceptAsync(b, listenKey, true)
+	dialed, err := MakeRLPxConnection(a, dialKey, listenKey.PubKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	accepted := <-connc
+	if accepted == nil {
+		t.Fatal("listener failed the handshake")
+	}
+	if _, ok := accepted.(*RLPxConnection); !ok {
+		t.Fatalf("listener picked %T, want RLPx", accepted)
+	}
+	if !dialed.RemotePubKey().Equals(pubKeyOf(listenKey)) {
+		t.Fatal("wrong remote key on the dialer")
+	}
+	if !accepted.(*RLPxConnection).RemotePubKey().Equals(pubKeyOf(dialKey)) {
+		t.Fatal("wrong remote key on the listener")
+	}
+
+	// writes larger than a message arrive in one piece
+	data := bytes.Repeat([]byte{0x42}, 3*rlpxMaxWrite+7)
+	go dialed.Write(data)
+	got := make([]byte, len(data))
+	if _, err := io.ReadFull(accepted, got); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("data corrupted on the way")
+	}
+}
+
+func TestAcceptSecretConnection(t *testing.T) {
+	dialKey, listenKey := newTestKey(t), newTestKey(t)
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	connc := acceptAsync(b, listenKey, true)
+	dialed, err := MakeSecretConnection(a, dialKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accepted := <-connc
+	if _, ok := accepted.(*SecretConnection); !ok {
+		t.Fatalf("listener picked %T, want the secret connection", accepted)
+	}
+	if !dialed.RemotePubKey().Equals(pubKeyOf(listenKey)) {
+		t.Fatal("wrong remote key on the dialer")
+	}
+}
+
+func TestAcceptRLPxDisabled(t *testing.T) {
+	dialKey, listenKey := newTestKey(t), newTestKey(t)
+	a, b := net.Pipe()
+	defer a.Close()
+
+	connc := acceptAsync(b, listenKey, false)
+	if _, err := MakeRLPxConnection(a, dialKey, listenKey.PubKey()); err == nil {
+		t.Fatal("RLPx handshake succeeded with a listener not supporting it")
+	}
+	if accepted := <-connc; accepted != nil {
+		t.Fatal("listener accepted RLPx while disabled")
+	}
+}
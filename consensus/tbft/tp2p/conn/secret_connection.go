@@ -42,6 +42,20 @@ type SecretConnection struct {
 	remPubKey  crypto.PubKey
 }
 
+// ChallengeSigner is implemented by keys which must not sign arbitrary hashes,
+// like a validator key held by a remote signer. It derives the handshake
+// challenge from the DH secret itself, so the signature can't be asked for
+// anything but a handshake.
+type ChallengeSigner interface {
+	SignChallenge(dhSecret *[32]byte) ([]byte, error)
+}
+
+// DeriveChallenge returns the challenge signed in a handshake with the given DH secret.
+func DeriveChallenge(dhSecret *[32]byte) *[32]byte {
+	_, _, challenge := deriveSecretAndChallenge(dhSecret, true)
+	return challenge
+}
+
 // MakeSecretConnection performs handshake and returns a new authenticated
 // SecretConnection.
 // Returns nil if there is an error in handshake.
@@ -85,7 +99,14 @@ func MakeSecretConnection(conn io.ReadWriteCloser, locPrivKey crypto.PrivKey) (*
 	}
 
 	// Sign the challenge bytes for authentication.
-	locSignature := signChallenge(challenge, locPrivKey)
+	var locSignature []byte
+	if signer, ok := locPrivKey.(ChallengeSigner); ok {
+		if locSignature, err = signer.SignChallenge(dhSecret); err != nil {
+			return nil, err
+		}
+	} else {
+		locSignature = signChallenge(challenge, locPrivKey)
+	}
 
 	// Share (in secret) each other's pubkey & challenge signature
 	authSigMsg, err := shareAuthSignature(sc, locPubKey, locSignature)
@@ -216,7 +237,7 @@ func shareEphPubKey(conn io.ReadWriteCloser, locEphPub *[32]byte) (remEphPub *[3
 	// Send our pubkey and receive theirs in tandem.
 	var trs, _ = help.Parallel(
 		func(_ int) (val interface{}, err error, abort bool) {
-			var _, err1 = cdc.MarshalBinaryWriter(conn, locEphPub)
+			var _, err1 = cdc.MarshalBinaryLengthPrefixedWriter(conn, locEphPub)
 			if err1 != nil {
 				return nil, err1, true // abort
 			}
@@ -224,7 +245,7 @@ func shareEphPubKey(conn io.ReadWriteCloser, locEphPub *[32]byte) (remEphPub *[3
 		},
 		func(_ int) (val interface{}, err error, abort bool) {
 			var _remEphPub [32]byte
-			var _, err2 = cdc.UnmarshalBinaryReader(conn, &_remEphPub, 1024*1024) // TODO
+			var _, err2 = cdc.UnmarshalBinaryLengthPrefixedReader(conn, &_remEphPub, 1024*1024) // TODO
 			if err2 != nil {
 				return nil, err2, true // abort
 			}
@@ -311,7 +332,7 @@ func shareAuthSignature(sc *SecretConnection, pubKey crypto.PubKey, signature []
 	// Send our info and receive theirs in tandem.
 	var trs, _ = help.Parallel(
 		func(_ int) (val interface{}, err error, abort bool) {
-			var _, err1 = cdc.MarshalBinaryWriter(sc, authSigMessage{pubKey.Bytes(), signature})
+			var _, err1 = cdc.MarshalBinaryLengthPrefixedWriter(sc, authSigMessage{pubKey.Bytes(), signature})
 			if err1 != nil {
 				return nil, err1, true // abort
 			}
@@ -319,7 +340,7 @@ func shareAuthSignature(sc *SecretConnection, pubKey crypto.PubKey, signature []
 		},
 		func(_ int) (val interface{}, err error, abort bool) {
 			var _recvMsg authSigMessage
-			var _, err2 = cdc.UnmarshalBinaryReader(sc, &_recvMsg, 1024*1024) // TODO
+			var _, err2 = cdc.UnmarshalBinaryLengthPrefixedReader(sc, &_recvMsg, 1024*1024) // TODO
 			if err2 != nil {
 				return nil, err2, true // abort
 			}
@@ -0,0 +1,322 @@
+package dummy
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"ethereum/rpc-network/consensus/tbft/help"
+	tp2p "ethereum/rpc-network/consensus/tbft/tp2p"
+	tmconn "ethereum/rpc-network/consensus/tbft/tp2p/conn"
+)
+
+// Filter decides whether a message may travel from one node to another.
+// Returning false drops the message and fails the send.
+type Filter func(from, to tp2p.ID, chID byte, msg []byte) bool
+
+// Network is an in-memory transport between switches. Every message is
+// delivered on a virtual clock after the configured latency, so a network
+// built from the same seed delays and drops the same messages.
+type Network struct {
+	mtx      sync.Mutex
+	clock    *help.VirtualClock
+	rand     *rand.Rand
+	latency  time.Duration
+	jitter   time.Duration
+	dropRate float64
+	groups   map[tp2p.ID]int
+	filters  []Filter
+	switches map[tp2p.ID]*tp2p.Switch
+	addrs    map[tp2p.ID]string
+	links    []*memPeer // the outbound end of every connected pair
+	cut      [][2]tp2p.ID
+}
+
+// NewNetwork creates a network on clock whose random delays and drops come from seed.
+func NewNetwork(clock *help.VirtualClock, seed int64) *Network {
+	return &Network{
+		clock:    clock,
+		rand:     rand.New(rand.NewSource(seed)),
+		groups:   make(map[tp2p.ID]int),
+		switches: make(map[tp2p.ID]*tp2p.Switch),
+		addrs:    make(map[tp2p.ID]string),
+	}
+}
+
+// SetLatency delays every message by latency plus a random share of jitter.
+func (n *Network) SetLatency(latency, jitter time.Duration) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	n.latency, n.jitter = latency, jitter
+}
+
+// SetDropRate drops the given fraction of messages at random.
+func (n *Network) SetDropRate(rate float64) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	n.dropRate = rate
+}
+
+// AddFilter installs a filter every message has to pass.
+func (n *Network) AddFilter(f Filter) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	n.filters = append(n.filters, f)
+}
+
+// Partition splits the nodes into groups which can not reach each other,
+// disconnecting the peers across groups. Nodes left out of every group still
+// reach everyone.
+func (n *Network) Partition(groups ...[]tp2p.ID) {
+	n.mtx.Lock()
+	n.groups = make(map[tp2p.ID]int)
+	for i, g := range groups {
+		for _, id := range g {
+			n.groups[id] = i + 1
+		}
+	}
+	var drop []*memPeer
+	links := n.links[:0]
+	for _, p := range n.links {
+		if n.blocked(p.self, p.id) {
+			drop = append(drop, p)
+			n.cut = append(n.cut, [2]tp2p.ID{p.self, p.id})
+		} else {
+			links = append(links, p)
+		}
+	}
+	n.links = links
+	n.mtx.Unlock()
+
+	for _, p := range drop {
+		n.disconnect(p)
+	}
+}
+
+// Heal removes the partition and reconnects the peers it cut.
+func (n *Network) Heal() error {
+	n.mtx.Lock()
+	n.groups = make(map[tp2p.ID]int)
+	cut := n.cut
+	n.cut = nil
+	n.mtx.Unlock()
+
+	for _, c := range cut {
+		if err := n.Connect(c[0], c[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *Network) blocked(a, b tp2p.ID) bool {
+	ga, gb := n.groups[a], n.groups[b]
+	return ga != 0 && gb != 0 && ga != gb
+}
+
+func (n *Network) disconnect(p *memPeer) {
+	n.mtx.Lock()
+	swa, swb := n.switches[p.self], n.switches[p.id]
+	n.mtx.Unlock()
+	swa.StopPeerGracefully(p)
+	swb.StopPeerGracefully(p.remote)
+}
+
+// AddSwitch registers sw under id so it can be connected to others.
+// id has to be a well formed node ID.
+func (n *Network) AddSwitch(id tp2p.ID, sw *tp2p.Switch) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	n.switches[id] = sw
+	n.addrs[id] = fmt.Sprintf("127.0.0.1:%d", 26656+len(n.addrs))
+}
+
+// Connect links the switches of a and b with a pair of in-memory peers.
+func (n *Network) Connect(a, b tp2p.ID) error {
+	n.mtx.Lock()
+	swa, swb := n.switches[a], n.switches[b]
+	n.mtx.Unlock()
+
+	pa := newMemPeer(n, a, b, true)  // b as seen by a
+	pb := newMemPeer(n, b, a, false) // a as seen by b
+	pa.remote, pb.remote = pb, pa
+	pa.reactors, pb.reactors = reactorsByChannel(swa), reactorsByChannel(swb)
+
+	if err := swa.AddPeer(pa); err != nil {
+		return err
+	}
+	if err := swb.AddPeer(pb); err != nil {
+		return err
+	}
+	n.mtx.Lock()
+	n.links = append(n.links, pa)
+	n.mtx.Unlock()
+	return nil
+}
+
+// ConnectAll links every pair of registered switches.
+func (n *Network) ConnectAll() error {
+	n.mtx.Lock()
+	ids := make([]tp2p.ID, 0, len(n.switches))
+	for id := range n.switches {
+		ids = append(ids, id)
+	}
+	n.mtx.Unlock()
+	// connect in a fixed order, map iteration is random
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for i := range ids {
+		for j := i + 1; j < len(ids); j++ {
+			if err := n.Connect(ids[i], ids[j]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func reactorsByChannel(sw *tp2p.Switch) map[byte]tp2p.Reactor {
+	reactors := make(map[byte]tp2p.Reactor)
+	for _, r := range sw.Reactors() {
+		for _, ch := range r.GetChannels() {
+			reactors[ch.ID] = r
+		}
+	}
+	return reactors
+}
+
+// send schedules the delivery of msg over the link of p.
+func (n *Network) send(p *memPeer, chID byte, msg []byte) bool {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	// dropped messages report a failed send, like a congested connection,
+	// so the reactors try again later
+	from, to := p.self, p.id
+	if n.blocked(from, to) {
+		return false
+	}
+	for _, f := range n.filters {
+		if !f(from, to, chID, msg) {
+			return false
+		}
+	}
+	if n.dropRate > 0 && n.rand.Float64() < n.dropRate {
+		return false
+	}
+	delay := n.latency
+	if n.jitter > 0 {
+		delay += time.Duration(n.rand.Int63n(int64(n.jitter)))
+	}
+	bz := make([]byte, len(msg))
+	copy(bz, msg)
+	n.clock.AfterFunc(delay, func() { p.remote.receive(chID, bz) })
+	return true
+}
+
+//-----------------------------------------------------------------------------
+
+// memPeer is one end of an in-memory link. id is the remote node, self the
+// node owning the switch the peer was added to.
+type memPeer struct {
+	help.BaseService
+	net      *Network
+	id       tp2p.ID
+	self     tp2p.ID
+	outbound bool
+	remote   *memPeer
+	reactors map[byte]tp2p.Reactor // reactors of the owning switch
+
+	mtx sync.Mutex
+	kv  map[string]interface{}
+}
+
+var _ tp2p.Peer = (*memPeer)(nil)
+
+func newMemPeer(n *Network, self, id tp2p.ID, outbound bool) *memPeer {
+	p := &memPeer{
+		net:      n,
+		id:       id,
+		self:     self,
+		outbound: outbound,
+		kv:       make(map[string]interface{}),
+	}
+	p.BaseService = *help.NewBaseService("memPeer", p)
+	return p
+}
+
+// receive hands a message sent by the remote end to the reactors of our switch.
+func (p *memPeer) receive(chID byte, msg []byte) {
+	if !p.IsRunning() || !p.remote.IsRunning() {
+		return
+	}
+	if r, ok := p.reactors[chID]; ok {
+		r.Receive(chID, p, msg)
+	}
+}
+
+// ID returns the remote node ID.
+func (p *memPeer) ID() tp2p.ID {
+	return p.id
+}
+
+// IsOutbound returns true on the side which initiated Connect.
+func (p *memPeer) IsOutbound() bool {
+	return p.outbound
+}
+
+// IsPersistent always returns false.
+func (p *memPeer) IsPersistent() bool {
+	return false
+}
+
+// NodeInfo returns node info carrying the remote ID and simulated address.
+func (p *memPeer) NodeInfo() tp2p.NodeInfo {
+	p.net.mtx.Lock()
+	defer p.net.mtx.Unlock()
+	return tp2p.NodeInfo{ID: p.id, ListenAddr: p.net.addrs[p.id], Moniker: string(p.id)}
+}
+
+// RemoteIP always returns localhost.
+func (p *memPeer) RemoteIP() net.IP {
+	return net.ParseIP("127.0.0.1")
+}
+
+// Status always returns empty connection status.
+func (p *memPeer) Status() tmconn.ConnectionStatus {
+	return tmconn.ConnectionStatus{}
+}
+
+// Send queues msg on the simulated link.
+func (p *memPeer) Send(chID byte, msg []byte) bool {
+	if !p.IsRunning() {
+		return false
+	}
+	return p.net.send(p, chID, msg)
+}
+
+// TrySend queues msg on the simulated link, links never fill up.
+func (p *memPeer) TrySend(chID byte, msg []byte) bool {
+	return p.Send(chID, msg)
+}
+
+// Set records value under key specified in the map.
+func (p *memPeer) Set(key string, value interface{}) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.kv[key] = value
+}
+
+// Get returns a value associated with the key. Nil is returned if no value
+// found.
+func (p *memPeer) Get(key string) interface{} {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.kv[key]
+}
+
+// OriginalAddr always returns nil.
+func (p *memPeer) OriginalAddr() *tp2p.NetAddress {
+	return nil
+}
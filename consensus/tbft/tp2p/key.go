@@ -3,6 +3,7 @@ package tp2p
 import (
 	"encoding/hex"
 	"ethereum/rpc-network/consensus/tbft/crypto"
+	"ethereum/rpc-network/consensus/tbft/tp2p/conn"
 )
 
 // ID is a hex-encoded crypto.Address
@@ -32,6 +33,12 @@ func (nodeKey *NodeKey) PubKey() crypto.PubKey {
 	return nodeKey.PrivKey.PubKey()
 }
 
+// SupportsRLPx reports whether the key can authenticate the RLPx transport,
+// which makes the peer ID the same key as the devp2p enode.
+func (nodeKey *NodeKey) SupportsRLPx() bool {
+	return conn.SupportsRLPx(nodeKey.PrivKey)
+}
+
 // PubKeyToID returns the ID corresponding to the given PubKey.
 // It's the hex-encoding of the pubKey.Address().
 func PubKeyToID(pubKey crypto.PubKey) ID {
@@ -11,6 +11,22 @@ const (
 	maxNumChannels  = 16    // plenty of room for upgrades, for now
 )
 
+// Transports a connection between two nodes can use. The secret connection is
+// supported by every node, RLPx by nodes holding their secp256k1 node key.
+const (
+	TransportSecret = "secret/1"
+	TransportRLPx   = "rlpx/1"
+)
+
+// transportsKey prefixes the entry of NodeInfo.Other listing the transports of
+// a node, e.g. "p2p_transports=secret/1,rlpx/1".
+const transportsKey = "p2p_transports="
+
+// TransportsEntry returns the NodeInfo.Other entry advertising transports.
+func TransportsEntry(transports ...string) string {
+	return transportsKey + strings.Join(transports, ",")
+}
+
 // Max size of the NodeInfo struct
 func MaxNodeInfoSize() int {
 	return maxNodeInfoSize
@@ -145,6 +161,23 @@ func (info NodeInfo) NetAddress() *NetAddress {
 	return netAddr
 }
 
+// SupportsTransport reports whether the node advertised transport. Nodes
+// advertising nothing only support the secret connection.
+func (info NodeInfo) SupportsTransport(transport string) bool {
+	for _, other := range info.Other {
+		if !strings.HasPrefix(other, transportsKey) {
+			continue
+		}
+		for _, t := range strings.Split(strings.TrimPrefix(other, transportsKey), ",") {
+			if t == transport {
+				return true
+			}
+		}
+		return false
+	}
+	return transport == TransportSecret
+}
+
 func (info NodeInfo) String() string {
 	return fmt.Sprintf("NodeInfo{id: %v, moniker: %v, network: %v [listen %v], version: %v (%v)}",
 		info.ID, info.Moniker, info.Network, info.ListenAddr, info.Version, info.Other)
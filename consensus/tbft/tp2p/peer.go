@@ -49,10 +49,25 @@ type peerConn struct {
 	originalAddr *NetAddress // nil for inbound connections
 }
 
-// ID only exists for SecretConnection.
-// NOTE: Will panic if conn is not *SecretConnection.
+// authenticatedConn is a connection which authenticated the key of the remote
+// node, a SecretConnection or a RLPxConnection.
+type authenticatedConn interface {
+	net.Conn
+	RemotePubKey() crypto.PubKey
+}
+
+// ID only exists for authenticated connections.
+// NOTE: Will panic if conn is not an authenticatedConn.
 func (pc peerConn) ID() ID {
-	return PubKeyToID(pc.conn.(*tmconn.SecretConnection).RemotePubKey())
+	return PubKeyToID(pc.conn.(authenticatedConn).RemotePubKey())
+}
+
+// Transport returns the transport of the connection.
+func (pc peerConn) Transport() string {
+	if _, ok := pc.conn.(*tmconn.RLPxConnection); ok {
+		return TransportRLPx
+	}
+	return TransportSecret
 }
 
 // Return the IP from the connection RemoteAddr
@@ -137,13 +152,21 @@ func newOutboundPeerConn(
 	config *params.P2PConfig,
 	persistent bool,
 	ourNodePrivKey crypto.PrivKey,
+	remPubKey crypto.PubKey,
 ) (peerConn, error) {
 	conn, err := dial(addr, config)
 	if err != nil {
 		return peerConn{}, errors.New(fmt.Sprint(err, "dail Error creating peer"))
 	}
 
-	pc, err := newPeerConn(conn, config, true, persistent, ourNodePrivKey, addr)
+	// Dial the RLPx transport if we know the key of the peer
+	handshake := func(conn net.Conn) (net.Conn, error) {
+		if remPubKey != nil {
+			return tmconn.MakeRLPxConnection(conn, ourNodePrivKey, remPubKey)
+		}
+		return tmconn.MakeSecretConnection(conn, ourNodePrivKey)
+	}
+	pc, err := newPeerConn(conn, config, true, persistent, handshake, addr)
 	if err != nil {
 		testlog.AddLog("newPeerConnError", err.Error())
 		if cerr := conn.Close(); cerr != nil {
@@ -167,18 +190,22 @@ func newInboundPeerConn(
 	conn net.Conn,
 	config *params.P2PConfig,
 	ourNodePrivKey crypto.PrivKey,
+	rlpx bool,
 ) (peerConn, error) {
 
 	// TODO: issue PoW challenge
 
-	return newPeerConn(conn, config, false, false, ourNodePrivKey, nil)
+	handshake := func(conn net.Conn) (net.Conn, error) {
+		return tmconn.AcceptConnection(conn, ourNodePrivKey, rlpx)
+	}
+	return newPeerConn(conn, config, false, false, handshake, nil)
 }
 
 func newPeerConn(
 	rawConn net.Conn,
 	cfg *params.P2PConfig,
 	outbound, persistent bool,
-	ourNodePrivKey crypto.PrivKey,
+	handshake func(net.Conn) (net.Conn, error),
 	originalAddr *NetAddress,
 ) (pc peerConn, err error) {
 	conn := rawConn
@@ -197,7 +224,7 @@ func newPeerConn(
 	}
 
 	// Encrypt connection
-	conn, err = tmconn.MakeSecretConnection(conn, ourNodePrivKey)
+	conn, err = handshake(conn)
 	if err != nil {
 		return pc, errors.New(fmt.Sprint(err, "Error creating peer"))
 	}
@@ -355,11 +382,11 @@ func (pc *peerConn) HandshakeTimeout(
 
 	var trs, _ = help.Parallel(
 		func(_ int) (val interface{}, err error, abort bool) {
-			_, err = cdc.MarshalBinaryWriter(pc.conn, ourNodeInfo)
+			_, err = cdc.MarshalBinaryLengthPrefixedWriter(pc.conn, ourNodeInfo)
 			return
 		},
 		func(_ int) (val interface{}, err error, abort bool) {
-			_, err = cdc.UnmarshalBinaryReader(
+			_, err = cdc.UnmarshalBinaryLengthPrefixedReader(
 				pc.conn,
 				&peerNodeInfo,
 				int64(MaxNodeInfoSize()),
@@ -0,0 +1,720 @@
+package pex
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"ethereum/rpc-network/consensus/tbft/help"
+	"ethereum/rpc-network/consensus/tbft/tp2p"
+	"ethereum/rpc-network/consensus/tbft/tp2p/conn"
+	"github.com/ethereum/go-ethereum/log"
+	amino "github.com/tendermint/go-amino"
+)
+
+type Peer = tp2p.Peer
+
+const (
+	// PexChannel is a channel for PEX messages
+	PexChannel = byte(0x00)
+
+	// over-estimate of max NetAddress size
+	// hexID (40) + IP (16) + Port (2) + Name (100) ...
+	maxAddressSize = 256
+
+	// a small request results in up to maxMsgSize response, which is why the
+	// requests of every peer are rate limited
+	maxMsgSize = maxAddressSize * maxGetSelection
+
+	// ensure we have enough peers
+	defaultEnsurePeersPeriod   = 30 * time.Second
+	defaultMinNumOutboundPeers = tp2p.DefaultMinNumOutboundPeers
+
+	// Seed/Crawler constants
+
+	// We want seeds to only advertise good peers. Therefore they should wait at
+	// least as long as we expect it to take for a peer to become good before
+	// disconnecting.
+	// see consensus/tbft/reactor.go: blocksToContributeToBecomeGoodPeer
+	defaultSeedDisconnectWaitPeriod = 28 * time.Hour
+
+	// a crawling seed waits before dialing an address again
+	defaultCrawlPeerInterval = 2 * time.Minute
+	defaultCrawlPeersPeriod  = 30 * time.Second
+	// a seed keeps the peers it sent addresses to until the addresses went out
+	seedServeWait = 5 * time.Second
+
+	maxAttemptsToDial = 16 // ~ 35h in total (last attempt - 18h)
+
+	// if the peer is in the address book but we can't dial it, the backoff is
+	// 1s, 2s, 4s, ... capped at maxBackoffDurationForPeer
+	maxBackoffDurationForPeer = 1 * time.Hour
+
+	// percentage of new addresses a seed hands out, the rest are vetted ones
+	biasToSelectNewPeers = 30
+)
+
+// PEXReactor handles PEX (peer exchange) and ensures that an
+// adequate number of peers are connected to the switch.
+//
+// It uses `AddrBook` (address book) to store `NetAddress`es of the peers.
+//
+// ## Preventing abuse
+//
+// Only accept pexAddrsMessage from peers we sent a corresponding pexRequestMessage to.
+// Only accept one pexRequestMessage every third of the ensure peers period.
+type PEXReactor struct {
+	tp2p.BaseReactor
+
+	book              AddrBook
+	config            *PEXReactorConfig
+	ensurePeersPeriod time.Duration
+
+	// maps to prevent abuse
+	requestsSent         *help.CMap // ID->struct{}: unanswered send requests
+	lastReceivedRequests *help.CMap // ID->time.Time: last time peer requested from us
+	served               *help.CMap // ID->time.Time: when a seed sent its addresses to the peer
+
+	seedAddrs []*tp2p.NetAddress
+
+	attemptsToDial sync.Map // address (string) -> {number of attempts (int), last time dialed (time.Time)}
+}
+
+// PEXReactorConfig holds reactor specific configuration data.
+type PEXReactorConfig struct {
+	// Seed/Crawler mode
+	SeedMode bool
+
+	// Seeds is a list of addresses reactor may use
+	// if it can't connect to peers in the addrbook.
+	Seeds []string
+}
+
+type _attemptsToDial struct {
+	number     int
+	lastDialed time.Time
+}
+
+// NewPEXReactor creates new PEX reactor.
+func NewPEXReactor(b AddrBook, config *PEXReactorConfig) *PEXReactor {
+	r := &PEXReactor{
+		book:                 b,
+		config:               config,
+		ensurePeersPeriod:    defaultEnsurePeersPeriod,
+		requestsSent:         help.NewCMap(),
+		lastReceivedRequests: help.NewCMap(),
+		served:               help.NewCMap(),
+	}
+	r.BaseReactor = *tp2p.NewBaseReactor("PEXReactor", r)
+	return r
+}
+
+// OnStart implements BaseService
+func (r *PEXReactor) OnStart() error {
+	err := r.book.Start()
+	if err != nil && err != help.ErrAlreadyStarted {
+		return err
+	}
+
+	numOnline, seedAddrs, err := r.checkSeeds()
+	if err != nil {
+		return err
+	} else if numOnline == 0 && r.book.Empty() {
+		log.Warn("Address book is empty, and could not connect to any of the seeds")
+	}
+	r.seedAddrs = seedAddrs
+
+	// Check if this node should run
+	// in seed/crawler mode
+	if r.config.SeedMode {
+		go r.crawlPeersRoutine()
+	} else {
+		go r.ensurePeersRoutine()
+	}
+	return nil
+}
+
+// OnStop implements BaseService
+func (r *PEXReactor) OnStop() {
+	help.CheckAndPrintError(r.book.Stop())
+}
+
+// GetChannels implements Reactor
+func (r *PEXReactor) GetChannels() []*conn.ChannelDescriptor {
+	return []*conn.ChannelDescriptor{
+		{
+			ID:                PexChannel,
+			Priority:          1,
+			SendQueueCapacity: 10,
+		},
+	}
+}
+
+// AddPeer implements Reactor by adding peer to the address book (if inbound)
+// or by requesting more addresses (if outbound).
+func (r *PEXReactor) AddPeer(p Peer) {
+	if p.IsOutbound() {
+		// For outbound peers, the address is already in the books -
+		// either via DialPeersAsync or r.Receive.
+		// Ask it for more peers if we need.
+		if r.book.NeedMoreAddrs() {
+			r.RequestAddrs(p)
+		}
+	} else {
+		// inbound peer is its own source
+		addr := p.NodeInfo().NetAddress()
+		src := addr
+
+		// add to book. dont RequestAddrs right away because
+		// we don't trust inbound as much - let ensurePeersRoutine handle it.
+		err := r.book.AddAddress(addr, src)
+		r.logErrAddrBook(err)
+	}
+}
+
+func (r *PEXReactor) logErrAddrBook(err error) {
+	if err != nil {
+		switch err.(type) {
+		case ErrAddrBookNilAddr:
+			log.Error("Failed to add new address", "err", err)
+		default:
+			// non-routable, self, full book, private, etc.
+			log.Debug("Failed to add new address", "err", err)
+		}
+	}
+}
+
+// RemovePeer implements Reactor.
+func (r *PEXReactor) RemovePeer(p Peer, reason interface{}) {
+	id := string(p.ID())
+	r.requestsSent.Delete(id)
+	r.lastReceivedRequests.Delete(id)
+	r.served.Delete(id)
+}
+
+// Receive implements Reactor by handling incoming PEX messages.
+func (r *PEXReactor) Receive(chID byte, src Peer, msgBytes []byte) {
+	msg, err := decodeMsg(msgBytes)
+	if err != nil {
+		log.Error("Error decoding message", "src", src, "chId", chID, "msg", msg, "err", err, "bytes", msgBytes)
+		r.Switch.StopPeerForError(src, err)
+		return
+	}
+	log.Trace("Received message", "src", src, "chId", chID, "msg", msg)
+
+	switch msg := msg.(type) {
+	case *pexRequestMessage:
+		// Check we're not receiving too many requests
+		if err := r.receiveRequest(src); err != nil {
+			r.Switch.StopPeerForError(src, err)
+			return
+		}
+
+		// Seeds hand out a batch of addresses and disconnect on their next
+		// crawl, once the message went out
+		if r.config.SeedMode {
+			r.SendAddrs(src, r.book.GetSelectionWithBias(biasToSelectNewPeers))
+			r.served.Set(string(src.ID()), time.Now())
+		} else {
+			r.SendAddrs(src, r.book.GetSelection())
+		}
+
+	case *pexAddrsMessage:
+		// If we asked for addresses, add them to the book
+		if err := r.ReceiveAddrs(msg.Addrs, src); err != nil {
+			r.Switch.StopPeerForError(src, err)
+			return
+		}
+	default:
+		log.Error(fmt.Sprintf("Unknown message type %v", reflect.TypeOf(msg)))
+	}
+}
+
+// enforces a minimum amount of time between requests
+func (r *PEXReactor) receiveRequest(src Peer) error {
+	id := string(src.ID())
+	v := r.lastReceivedRequests.Get(id)
+	if v == nil {
+		// initialize with empty time
+		lastReceived := time.Time{}
+		r.lastReceivedRequests.Set(id, lastReceived)
+		return nil
+	}
+
+	lastReceived := v.(time.Time)
+	if lastReceived.Equal(time.Time{}) {
+		// first time gets a free pass. then we start tracking the time
+		lastReceived = time.Now()
+		r.lastReceivedRequests.Set(id, lastReceived)
+		return nil
+	}
+
+	now := time.Now()
+	minInterval := r.minReceiveRequestInterval()
+	if now.Sub(lastReceived) < minInterval {
+		return fmt.Errorf("Peer (%v) sent next PEX request too soon. lastReceived: %v, now: %v, minInterval: %v. Disconnecting",
+			src.ID(),
+			lastReceived,
+			now,
+			minInterval,
+		)
+	}
+	r.lastReceivedRequests.Set(id, now)
+	return nil
+}
+
+// RequestAddrs asks peer for more addresses if we do not already
+// have a request out for this peer.
+func (r *PEXReactor) RequestAddrs(p Peer) {
+	log.Debug("Request addrs", "from", p)
+	id := string(p.ID())
+	if r.requestsSent.Has(id) {
+		return
+	}
+	r.requestsSent.Set(id, struct{}{})
+	p.Send(PexChannel, cdc.MustMarshalBinaryBare(&pexRequestMessage{}))
+}
+
+// ReceiveAddrs adds the given addrs to the addrbook if theres an open
+// request for this peer and deletes the open request.
+// If there's no open request for the src peer, it returns an error.
+func (r *PEXReactor) ReceiveAddrs(addrs []*tp2p.NetAddress, src Peer) error {
+	id := string(src.ID())
+	if !r.requestsSent.Has(id) {
+		return fmt.Errorf("unsolicited pexAddrsMessage")
+	}
+	r.requestsSent.Delete(id)
+
+	srcAddr := src.NodeInfo().NetAddress()
+	added := false
+	for _, netAddr := range addrs {
+		// Validate netAddr. Disconnect from a peer if it sends us invalid data.
+		if netAddr == nil {
+			return fmt.Errorf("received nil addr")
+		}
+		if err := netAddr.Valid(); !err {
+			return fmt.Errorf("received invalid addr %v", netAddr)
+		}
+
+		// The book refuses addresses it can't use, like private or
+		// non-routable ones. Peers may well know those, that's no reason to
+		// disconnect them.
+		if err := r.book.AddAddress(netAddr, srcAddr); err != nil {
+			r.logErrAddrBook(err)
+			continue
+		}
+		added = true
+	}
+
+	// If the addresses came from a seed node, try to connect to them without
+	// waiting.
+	if added && !r.config.SeedMode && r.isSeed(srcAddr) {
+		r.ensurePeers()
+	}
+	return nil
+}
+
+func (r *PEXReactor) isSeed(addr *tp2p.NetAddress) bool {
+	for _, seedAddr := range r.seedAddrs {
+		if seedAddr.Equals(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// SendAddrs sends addrs to the peer.
+func (r *PEXReactor) SendAddrs(p Peer, netAddrs []*tp2p.NetAddress) {
+	p.Send(PexChannel, cdc.MustMarshalBinaryBare(&pexAddrsMessage{Addrs: netAddrs}))
+}
+
+// SetEnsurePeersPeriod sets period to ensure peers connected.
+func (r *PEXReactor) SetEnsurePeersPeriod(d time.Duration) {
+	r.ensurePeersPeriod = d
+}
+
+// Ensures that sufficient peers are connected. (continuous)
+func (r *PEXReactor) ensurePeersRoutine() {
+	jitter := help.RandInt63n(r.ensurePeersPeriod.Nanoseconds())
+
+	// Randomize first round of communication to avoid thundering herd.
+	// If no potential peers are present directly start connecting so we guarantee
+	// swift setup with the help of configured seeds.
+	if r.hasPotentialPeers() {
+		time.Sleep(time.Duration(jitter))
+	}
+
+	// fire once immediately.
+	// ensures we dial the seeds right away if the book is empty
+	r.ensurePeers()
+
+	// fire periodically
+	ticker := time.NewTicker(r.ensurePeersPeriod)
+	for {
+		select {
+		case <-ticker.C:
+			r.ensurePeers()
+		case <-r.Quit():
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+// ensurePeers dials addresses from the book until enough outbound peers are
+// connected or being dialed, and asks a peer for more addresses if the book
+// runs low. (once)
+func (r *PEXReactor) ensurePeers() {
+	var (
+		out, in, dial = r.Switch.NumPeers()
+		numToDial     = defaultMinNumOutboundPeers - (out + dial)
+	)
+	log.Debug(
+		"Ensure peers",
+		"numOutPeers", out,
+		"numInPeers", in,
+		"numDialing", dial,
+		"numToDial", numToDial,
+	)
+
+	if numToDial <= 0 {
+		return
+	}
+
+	// prefer vetted addresses while we have few connections, the bias
+	// towards new ones grows from 10% to 90% with the outbound peers
+	newBias := help.MinInt(out, 8)*10 + 10
+
+	toDial := make(map[tp2p.ID]*tp2p.NetAddress)
+	// Try maxAttempts times to pick numToDial addresses to dial
+	maxAttempts := numToDial * 3
+
+	for i := 0; i < maxAttempts && len(toDial) < numToDial; i++ {
+		try := r.book.PickAddress(newBias)
+		if try == nil {
+			continue
+		}
+		if _, selected := toDial[try.ID]; selected {
+			continue
+		}
+		if dialling := r.Switch.IsDialing(try.ID); dialling {
+			continue
+		}
+		if connected := r.Switch.Peers().Has(try.ID); connected {
+			continue
+		}
+		// Skip addresses failing too often or still backing off, so that
+		// another address takes their slot
+		if attempts, _ := r.dialAttemptsInfo(try); attempts > maxAttemptsToDial {
+			log.Debug("Reached max attempts to dial", "addr", try, "attempts", attempts)
+			r.book.MarkBad(try)
+			r.attemptsToDial.Delete(try.DialString())
+			continue
+		}
+		if r.tooEarlyToDial(try) {
+			continue
+		}
+		log.Debug("Will dial address", "addr", try)
+		toDial[try.ID] = try
+	}
+
+	// Dial picked addresses
+	for _, addr := range toDial {
+		go r.dialPeer(addr)
+	}
+
+	// If we need more addresses, pick a random peer and ask for more.
+	if r.book.NeedMoreAddrs() {
+		peers := r.Switch.Peers().List()
+		peersCount := len(peers)
+		if peersCount > 0 {
+			peer := peers[help.RandInt()%peersCount]
+			log.Info("We need more addresses. Sending pexRequest to random peer", "peer", peer)
+			r.RequestAddrs(peer)
+		}
+	}
+
+	// If we are not connected to nor dialing anybody, fallback to dialing a seed.
+	if out+in+dial+len(toDial) == 0 {
+		log.Info("No addresses to dial nor connected peers. Falling back to seeds")
+		r.dialSeeds()
+	}
+}
+
+func (r *PEXReactor) dialAttemptsInfo(addr *tp2p.NetAddress) (attempts int, lastDialed time.Time) {
+	_attempts, ok := r.attemptsToDial.Load(addr.DialString())
+	if !ok {
+		return
+	}
+	atd := _attempts.(_attemptsToDial)
+	return atd.number, atd.lastDialed
+}
+
+// tooEarlyToDial reports whether addr failed recently. The wait doubles with
+// every failed attempt, from 2s up to maxBackoffDurationForPeer.
+func (r *PEXReactor) tooEarlyToDial(addr *tp2p.NetAddress) bool {
+	attempts, lastDialed := r.dialAttemptsInfo(addr)
+	if attempts == 0 {
+		return false
+	}
+	jitter := time.Duration(help.RandFloat64() * float64(time.Second))
+	backoff := jitter + (1<<uint(attempts))*time.Second
+	if backoff > maxBackoffDurationForPeer {
+		backoff = maxBackoffDurationForPeer
+	}
+	return time.Since(lastDialed) < backoff
+}
+
+func (r *PEXReactor) dialPeer(addr *tp2p.NetAddress) {
+	err := r.Switch.DialPeerWithAddress(addr, false)
+	if err == nil {
+		r.attemptsToDial.Delete(addr.DialString())
+		return
+	}
+	attempts, _ := r.dialAttemptsInfo(addr)
+	log.Debug("Dialing failed", "addr", addr, "err", err, "attempts", attempts)
+
+	switch err.(type) {
+	case tp2p.ErrSwitchAuthenticationFailure:
+		// someone else answers at the address
+		r.book.MarkBad(addr)
+		r.attemptsToDial.Delete(addr.DialString())
+	case tp2p.ErrSwitchConnectToSelf:
+		r.book.RemoveAddress(addr)
+		r.book.AddOurAddress(addr)
+		r.attemptsToDial.Delete(addr.DialString())
+	case tp2p.ErrSwitchDuplicatePeerID, tp2p.ErrSwitchDuplicatePeerIP:
+		// connected meanwhile, the address works
+	default:
+		r.book.MarkAttempt(addr)
+		// the book may drop addresses failing too often, don't keep their
+		// attempts around
+		if !r.book.HasAddress(addr) {
+			r.attemptsToDial.Delete(addr.DialString())
+			return
+		}
+		r.attemptsToDial.Store(addr.DialString(), _attemptsToDial{attempts + 1, time.Now()})
+	}
+}
+
+// check seed addresses are well formed
+func (r *PEXReactor) checkSeeds() (numOnline int, netAddrs []*tp2p.NetAddress, err error) {
+	lSeeds := len(r.config.Seeds)
+	if lSeeds == 0 {
+		return 0, nil, nil
+	}
+	netAddrs, errs := tp2p.NewNetAddressStrings(r.config.Seeds)
+	numOnline = lSeeds - len(errs)
+	for _, err := range errs {
+		switch e := err.(type) {
+		case tp2p.ErrNetAddressLookup:
+			log.Error("Connecting to seed failed", "err", e)
+		default:
+			return 0, nil, fmt.Errorf("seed node configuration has error: %v", e)
+		}
+	}
+	return
+}
+
+// randomly dial seeds until we connect to one or exhaust them
+func (r *PEXReactor) dialSeeds() {
+	perm := help.RandPerm(len(r.seedAddrs))
+	for _, i := range perm {
+		// dial a random seed
+		seedAddr := r.seedAddrs[i]
+		err := r.Switch.DialPeerWithAddress(seedAddr, false)
+		if err == nil {
+			return
+		}
+		log.Error("Error dialing seed", "err", err, "seed", seedAddr)
+	}
+	if len(r.seedAddrs) > 0 {
+		log.Error("Couldn't connect to any seeds")
+	}
+}
+
+// AttemptsToDial returns the number of attempts to dial specific address. It
+// returns 0 if never attempted or successfully connected.
+func (r *PEXReactor) AttemptsToDial(addr *tp2p.NetAddress) int {
+	lAttempts, attempted := r.attemptsToDial.Load(addr.DialString())
+	if attempted {
+		return lAttempts.(_attemptsToDial).number
+	}
+	return 0
+}
+
+//----------------------------------------------------------
+
+// Explores the network searching for more peers. (continuous)
+// Seed/Crawler Mode causes this node to quickly disconnect
+// from peers, except other seed nodes.
+func (r *PEXReactor) crawlPeersRoutine() {
+	// Do an initial crawl
+	r.crawlPeers()
+
+	// Fire periodically
+	ticker := time.NewTicker(defaultCrawlPeersPeriod)
+
+	for {
+		select {
+		case <-ticker.C:
+			r.attemptDisconnects()
+			r.crawlPeers()
+		case <-r.Quit():
+			return
+		}
+	}
+}
+
+// hasPotentialPeers indicates if there is a potential peer to connect to, by
+// consulting the Switch as well as the AddrBook.
+func (r *PEXReactor) hasPotentialPeers() bool {
+	out, in, dial := r.Switch.NumPeers()
+
+	return out+in+dial > 0 && len(r.book.ListOfKnownAddresses()) > 0
+}
+
+// crawlPeerInfo handles temporary data needed for the
+// network crawling performed during seed/crawler mode.
+type crawlPeerInfo struct {
+	// The listening address of a potential peer we learned about
+	Addr *tp2p.NetAddress
+
+	// The last time we attempt to reach this address
+	LastAttempt time.Time
+
+	// The last time we successfully reached this address
+	LastSuccess time.Time
+}
+
+// oldestFirst implements sort.Interface for []crawlPeerInfo
+// based on the LastAttempt field.
+type oldestFirst []crawlPeerInfo
+
+func (of oldestFirst) Len() int           { return len(of) }
+func (of oldestFirst) Swap(i, j int)      { of[i], of[j] = of[j], of[i] }
+func (of oldestFirst) Less(i, j int) bool { return of[i].LastAttempt.Before(of[j].LastAttempt) }
+
+// getPeersToCrawl returns the addresses of the peers we aren't connected to,
+// the ones attempted longest ago first.
+func (r *PEXReactor) getPeersToCrawl() []crawlPeerInfo {
+	var of oldestFirst
+
+	addrs := r.book.ListOfKnownAddresses()
+	for _, addr := range addrs {
+		if len(addr.ID()) == 0 {
+			continue // dont use peers without id
+		}
+		if r.Switch.Peers().Has(addr.ID()) || r.Switch.IsDialing(addr.ID()) {
+			continue
+		}
+
+		of = append(of, crawlPeerInfo{
+			Addr:        addr.Addr,
+			LastAttempt: addr.LastAttempt,
+			LastSuccess: addr.LastSuccess,
+		})
+	}
+	sort.Sort(of)
+	return of
+}
+
+// crawlPeers asks the connected peers for addresses and dials the known ones
+// not dialed recently, to ask them too. (once)
+func (r *PEXReactor) crawlPeers() {
+	for _, peer := range r.Switch.Peers().List() {
+		if !r.served.Has(string(peer.ID())) {
+			r.RequestAddrs(peer)
+		}
+	}
+
+	peerInfos := r.getPeersToCrawl()
+
+	now := time.Now()
+	// Use addresses we know of to reach additional peers
+	for _, pi := range peerInfos {
+		// Do not attempt to connect with peers we recently dialed
+		if now.Sub(pi.LastAttempt) < defaultCrawlPeerInterval {
+			continue
+		}
+		// Otherwise, attempt to connect with the known address
+		err := r.Switch.DialPeerWithAddress(pi.Addr, false)
+		if err != nil {
+			r.book.MarkAttempt(pi.Addr)
+			continue
+		}
+		// Ask for more addresses
+		peer := r.Switch.Peers().Get(pi.Addr.ID)
+		if peer != nil {
+			r.RequestAddrs(peer)
+		}
+	}
+}
+
+// attemptDisconnects disconnects the peers we sent addresses to, and the ones
+// connected for long enough to be advertised.
+func (r *PEXReactor) attemptDisconnects() {
+	for _, peer := range r.Switch.Peers().List() {
+		if peer.IsPersistent() {
+			continue
+		}
+		served := r.served.Get(string(peer.ID()))
+		if served != nil && time.Since(served.(time.Time)) >= seedServeWait {
+			r.Switch.StopPeerGracefully(peer)
+			continue
+		}
+		if peer.Status().Duration >= defaultSeedDisconnectWaitPeriod {
+			r.Switch.StopPeerGracefully(peer)
+		}
+	}
+}
+
+// minReceiveRequestInterval is the minimum time a peer must wait between
+// requests, a third of the period at which peers ask when they need more.
+func (r *PEXReactor) minReceiveRequestInterval() time.Duration {
+	return r.ensurePeersPeriod / 3
+}
+
+//-----------------------------------------------------------------------------
+// Messages
+
+// PexMessage is a primary type for PEX messages. Underneath, it could contain
+// either pexRequestMessage, or pexAddrsMessage messages.
+type PexMessage interface{}
+
+func RegisterPexMessage(cdc *amino.Codec) {
+	cdc.RegisterInterface((*PexMessage)(nil), nil)
+	cdc.RegisterConcrete(&pexRequestMessage{}, "true/Pex/PexRequestMessage", nil)
+	cdc.RegisterConcrete(&pexAddrsMessage{}, "true/Pex/PexAddrsMessage", nil)
+}
+
+func decodeMsg(bz []byte) (msg PexMessage, err error) {
+	if len(bz) > maxMsgSize {
+		return msg, fmt.Errorf("Msg exceeds max size (%d > %d)", len(bz), maxMsgSize)
+	}
+	err = cdc.UnmarshalBinaryBare(bz, &msg)
+	return
+}
+
+/*
+A pexRequestMessage requests additional peer addresses.
+*/
+type pexRequestMessage struct {
+}
+
+func (m *pexRequestMessage) String() string {
+	return "[pexRequest]"
+}
+
+/*
+A message with announced peer addresses.
+*/
+type pexAddrsMessage struct {
+	Addrs []*tp2p.NetAddress
+}
+
+func (m *pexAddrsMessage) String() string {
+	return fmt.Sprintf("[pexAddrs %v]", m.Addrs)
+}
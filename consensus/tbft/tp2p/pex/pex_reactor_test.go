@@ -0,0 +1,229 @@
+package pex
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	tcrypto "ethereum/rpc-network/consensus/tbft/crypto"
+	"ethereum/rpc-network/consensus/tbft/help"
+	"ethereum/rpc-network/consensus/tbft/tp2p"
+	"ethereum/rpc-network/consensus/tbft/tp2p/conn"
+	"ethereum/rpc-network/consensus/tbft/tp2p/dummy"
+	"ethereum/rpc-network/crypto"
+	"ethereum/rpc-network/params"
+)
+
+// recorder stands in for the PEX reactor of a remote node, it records the
+// messages it receives.
+type recorder struct {
+	tp2p.BaseReactor
+	mtx  sync.Mutex
+	msgs []PexMessage
+}
+
+func newRecorder() *recorder {
+	r := &recorder{}
+	r.BaseReactor = *tp2p.NewBaseReactor("recorder", r)
+	return r
+}
+
+func (r *recorder) GetChannels() []*conn.ChannelDescriptor {
+	return []*conn.ChannelDescriptor{{ID: PexChannel, Priority: 1}}
+}
+
+func (r *recorder) Receive(chID byte, src tp2p.Peer, msgBytes []byte) {
+	msg, err := decodeMsg(msgBytes)
+	if err != nil {
+		panic(err)
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.msgs = append(r.msgs, msg)
+}
+
+// count returns the number of requests and address messages received.
+func (r *recorder) count() (requests, addrs int) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	for _, msg := range r.msgs {
+		switch msg.(type) {
+		case *pexRequestMessage:
+			requests++
+		case *pexAddrsMessage:
+			addrs++
+		}
+	}
+	return
+}
+
+// pexNet connects a PEX reactor to a recorder. The switches aren't started, so
+// the reactor doesn't run its routines and the tests drive it.
+type pexNet struct {
+	clock    *help.VirtualClock
+	net      *dummy.Network
+	pex      *PEXReactor
+	recorder *recorder
+	pexID    tp2p.ID
+	remoteID tp2p.ID
+}
+
+func newPexNet(t *testing.T, config *PEXReactorConfig) *pexNet {
+	dir, err := ioutil.TempDir("", "pex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	pn := &pexNet{clock: help.NewVirtualClock(), recorder: newRecorder()}
+	pn.net = dummy.NewNetwork(pn.clock, 1)
+	pn.pex = NewPEXReactor(NewAddrBook(filepath.Join(dir, "addrbook.json"), false), config)
+	pn.pexID = pn.addSwitch(t, pn.pex)
+	pn.remoteID = pn.addSwitch(t, pn.recorder)
+	if err := pn.net.Connect(pn.pexID, pn.remoteID); err != nil {
+		t.Fatal(err)
+	}
+	for _, sw := range []*tp2p.Switch{pn.pex.Switch, pn.recorder.Switch} {
+		for _, peer := range sw.Peers().List() {
+			if err := peer.Start(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return pn
+}
+
+func (pn *pexNet) addSwitch(t *testing.T, r tp2p.Reactor) tp2p.ID {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := tp2p.PubKeyToID(tcrypto.PubKeyTrue(key.PublicKey))
+	sw := tp2p.NewSwitch(&params.P2PConfig{}, nil)
+	sw.AddReactor("PEX", r)
+	pn.net.AddSwitch(id, sw)
+	return id
+}
+
+// send delivers msg from the recorder to the PEX reactor.
+func (pn *pexNet) send(msg PexMessage) {
+	pn.recorder.Switch.Peers().Get(pn.pexID).Send(PexChannel, cdc.MustMarshalBinaryBare(msg))
+	pn.clock.Advance(time.Millisecond)
+}
+
+func (pn *pexNet) connected() bool {
+	return pn.pex.Switch.Peers().Has(pn.remoteID)
+}
+
+func testAddrs(t *testing.T, n int) []*tp2p.NetAddress {
+	addrs := make([]*tp2p.NetAddress, n)
+	for i := range addrs {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := tp2p.PubKeyToID(tcrypto.PubKeyTrue(key.PublicKey))
+		addr, err := tp2p.NewNetAddressString(fmt.Sprintf("%s@1.2.3.%d:26656", id, i+1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		addrs[i] = addr
+	}
+	return addrs
+}
+
+func TestPEXReactorRequestRateLimit(t *testing.T) {
+	pn := newPexNet(t, &PEXReactorConfig{})
+	addr := testAddrs(t, 1)[0]
+	pn.pex.book.AddAddress(addr, addr)
+
+	// the first two requests are answered, right after connecting and with
+	// the first ensure peers round
+	pn.send(&pexRequestMessage{})
+	pn.send(&pexRequestMessage{})
+	if _, addrs := pn.recorder.count(); addrs != 2 {
+		t.Fatalf("answered %d requests, want 2", addrs)
+	}
+	// another one is fine after the minimum interval
+	pn.pex.lastReceivedRequests.Set(string(pn.remoteID), time.Now().Add(-pn.pex.minReceiveRequestInterval()))
+	pn.send(&pexRequestMessage{})
+	if _, addrs := pn.recorder.count(); addrs != 3 || !pn.connected() {
+		t.Fatalf("answered %d requests, connected %v", addrs, pn.connected())
+	}
+	// but not right away
+	pn.send(&pexRequestMessage{})
+	if pn.connected() {
+		t.Fatal("peer requesting too often still connected")
+	}
+	if _, addrs := pn.recorder.count(); addrs != 3 {
+		t.Fatalf("answered %d requests, want 3", addrs)
+	}
+}
+
+func TestPEXReactorAddrsExchange(t *testing.T) {
+	pn := newPexNet(t, &PEXReactorConfig{})
+	addrs := testAddrs(t, 3)
+
+	pn.pex.RequestAddrs(pn.pex.Switch.Peers().Get(pn.remoteID))
+	pn.clock.Advance(time.Millisecond)
+	if requests, _ := pn.recorder.count(); requests != 1 {
+		t.Fatalf("sent %d requests, want 1", requests)
+	}
+	// a pending request isn't sent twice
+	pn.pex.RequestAddrs(pn.pex.Switch.Peers().Get(pn.remoteID))
+	pn.clock.Advance(time.Millisecond)
+	if requests, _ := pn.recorder.count(); requests != 1 {
+		t.Fatalf("sent %d requests, want 1", requests)
+	}
+
+	// private and invalid addresses are skipped without disconnecting
+	local, err := tp2p.NewNetAddressString(fmt.Sprintf("%s@127.0.0.1:26656", pn.remoteID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pn.pex.book.AddOurAddress(local)
+	pn.send(&pexAddrsMessage{Addrs: append(addrs, local)})
+	if !pn.connected() {
+		t.Fatal("peer disconnected after answering")
+	}
+	for _, addr := range addrs {
+		if !pn.pex.book.HasAddress(addr) {
+			t.Errorf("address %v not added", addr)
+		}
+	}
+	if pn.pex.book.HasAddress(local) {
+		t.Error("our own address added")
+	}
+
+	// addresses nobody asked for are refused
+	pn.send(&pexAddrsMessage{Addrs: testAddrs(t, 1)})
+	if pn.connected() {
+		t.Fatal("peer sending unsolicited addresses still connected")
+	}
+}
+
+func TestPEXReactorSeedDisconnect(t *testing.T) {
+	pn := newPexNet(t, &PEXReactorConfig{SeedMode: true})
+	for _, addr := range testAddrs(t, 5) {
+		pn.pex.book.AddAddress(addr, addr)
+	}
+
+	pn.send(&pexRequestMessage{})
+	if _, addrs := pn.recorder.count(); addrs != 1 {
+		t.Fatalf("answered %d requests, want 1", addrs)
+	}
+	// the seed keeps the peer until the addresses went out
+	pn.pex.attemptDisconnects()
+	if !pn.connected() {
+		t.Fatal("peer disconnected right after the answer")
+	}
+	pn.pex.served.Set(string(pn.remoteID), time.Now().Add(-seedServeWait))
+	pn.pex.attemptDisconnects()
+	if pn.connected() {
+		t.Fatal("served peer still connected")
+	}
+}
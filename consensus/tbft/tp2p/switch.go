@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	"ethereum/rpc-network/consensus/tbft/crypto"
 	"ethereum/rpc-network/consensus/tbft/help"
 	"ethereum/rpc-network/consensus/tbft/testlog"
 	"ethereum/rpc-network/consensus/tbft/tp2p/conn"
@@ -76,6 +77,10 @@ type Switch struct {
 	filterConnByID   func(ID) error
 	hasPeer          help.PeerInValidators
 
+	// keys of the peers which advertised the RLPx transport, they are dialed
+	// over RLPx from then on
+	rlpxPeers *help.CMap
+
 	mConfig conn.MConnConfig
 }
 
@@ -93,6 +98,7 @@ func NewSwitch(cfg *config.P2PConfig, hasPeer help.PeerInValidators, options ...
 		hasPeer:      hasPeer,
 		dialing:      help.NewCMap(),
 		reconnecting: help.NewCMap(),
+		rlpxPeers:    help.NewCMap(),
 	}
 
 	// Ensure we have a completely undeterministic PRNG.
@@ -205,6 +211,18 @@ func (sw *Switch) SetNodeKey(nodeKey *NodeKey) {
 	sw.nodeKey = nodeKey
 }
 
+// SetRLPxPeers sets the table of peers known to support the RLPx transport,
+// which lets a node keep it across switches.
+// NOTE: Not goroutine safe.
+func (sw *Switch) SetRLPxPeers(peers *help.CMap) {
+	sw.rlpxPeers = peers
+}
+
+// rlpx reports whether the node key can authenticate RLPx connections.
+func (sw *Switch) rlpx() bool {
+	return sw.nodeKey != nil && sw.nodeKey.SupportsRLPx()
+}
+
 //---------------------------------------------------------------------
 // Service start/stop
 
@@ -567,7 +585,7 @@ func (sw *Switch) addInboundPeerWithConfig(
 	config *config.P2PConfig,
 ) error {
 	testlog.AddLog("addPeer in", conn.RemoteAddr().String())
-	peerConn, err := newInboundPeerConn(conn, config, sw.nodeKey.PrivKey)
+	peerConn, err := newInboundPeerConn(conn, config, sw.nodeKey.PrivKey, sw.rlpx())
 	if err != nil {
 		testlog.AddLog("newPeerConnError", err.Error())
 		help.CheckAndPrintError(conn.Close()) // peer is nil
@@ -593,15 +611,25 @@ func (sw *Switch) addOutboundPeerWithConfig(
 	config *config.P2PConfig,
 	persistent bool,
 ) error {
-	log.Info("Dialing peer out", "address", addr)
+	var remPubKey crypto.PubKey
+	if key, ok := sw.rlpxPeers.Get(string(addr.ID)).(crypto.PubKey); ok && sw.rlpx() {
+		remPubKey = key
+	}
+	log.Info("Dialing peer out", "address", addr, "rlpx", remPubKey != nil)
 	peerConn, err := newOutboundPeerConn(
 		addr,
 		config,
 		persistent,
 		sw.nodeKey.PrivKey,
+		remPubKey,
 	)
 	testlog.AddLog("newOutboundPeerConnError", err)
 	if err != nil {
+		if remPubKey != nil {
+			// fall back to the secret connection on the next attempt
+			log.Debug("RLPx handshake failed", "peer", addr.ID, "err", err)
+			sw.rlpxPeers.Delete(string(addr.ID))
+		}
 		if persistent {
 			go sw.reconnectToPeer(addr)
 		}
@@ -693,10 +721,14 @@ func (sw *Switch) addPeer(pc peerConn) error {
 		return err
 	}
 
+	if sw.rlpx() && peerNodeInfo.SupportsTransport(TransportRLPx) {
+		sw.rlpxPeers.Set(string(peerID), pc.conn.(authenticatedConn).RemotePubKey())
+	}
+
 	peer := newPeer(pc, sw.mConfig, peerNodeInfo, sw.reactorsByCh, sw.chDescs, sw.StopPeerForError)
 	//peer.SetLogger(sw.Logger.With("peer", addr))
 
-	log.Info("Successful handshake with peer", "peerNodeInfo", peerNodeInfo)
+	log.Info("Successful handshake with peer", "peerNodeInfo", peerNodeInfo, "transport", pc.Transport())
 
 	// All good. Start peer
 	if sw.IsRunning() {
@@ -714,7 +746,21 @@ func (sw *Switch) addPeer(pc peerConn) error {
 	return nil
 }
 
-func (sw *Switch) startInitPeer(peer *peer) error {
+// AddPeer adds a peer whose connection is already established, e.g. an
+// in-memory peer of a simulated network, skipping the handshake.
+func (sw *Switch) AddPeer(peer Peer) error {
+	if sw.peers.Has(peer.ID()) {
+		return ErrSwitchDuplicatePeerID{peer.ID()}
+	}
+	if sw.IsRunning() {
+		if err := sw.startInitPeer(peer); err != nil {
+			return err
+		}
+	}
+	return sw.peers.Add(peer)
+}
+
+func (sw *Switch) startInitPeer(peer Peer) error {
 	err := peer.Start() // spawn send/recv routines
 	if err != nil {
 		// Should never happen
@@ -204,7 +204,7 @@ func getServiceURL(rootURL string) (url, urnDomain string, err error) {
 	defer r.Body.Close() // nolint: errcheck
 
 	if r.StatusCode >= 400 {
-		err = errors.New(string(r.StatusCode))
+		err = errors.New(strconv.Itoa(r.StatusCode))
 		return
 	}
 	var root Root
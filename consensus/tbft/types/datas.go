@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"ethereum/rpc-network/consensus/tbft/crypto"
 	"ethereum/rpc-network/consensus/tbft/help"
 	ctypes "ethereum/rpc-network/core/types"
 	"strings"
@@ -29,7 +30,7 @@ var (
 
 // 	// We prefix the byte length, so that unmarshaling
 // 	// can easily happen via a reader.
-// 	bz, err := cdc.MarshalBinary(b)
+// 	bz, err := cdc.MarshalBinaryLengthPrefixed(b)
 // 	if err != nil {
 // 		panic(err)
 // 	}
@@ -160,6 +161,34 @@ func (commit *Commit) ValidateBasic() error {
 	return nil
 }
 
+// Aggregate compresses the agreeing precommits of BLS validators into an
+// AggregateCommit. Precommits for other blocks or against the block are dropped.
+func (commit *Commit) Aggregate() (*AggregateCommit, error) {
+	if !commit.IsCommit() {
+		return nil, errors.New("no precommits in commit")
+	}
+	signers := help.NewBitArray(uint(len(commit.Precommits)))
+	sigs := make([][]byte, 0, len(commit.Precommits))
+	for i, precommit := range commit.Precommits {
+		if precommit == nil || precommit.Result != ctypes.VoteAgree || !commit.BlockID.Equals(precommit.BlockID) {
+			continue
+		}
+		signers.SetIndex(uint(i), true)
+		sigs = append(sigs, precommit.Signature)
+	}
+	sig, err := crypto.AggregateSignaturesBLS(sigs)
+	if err != nil {
+		return nil, err
+	}
+	return &AggregateCommit{
+		BlockID:   commit.BlockID,
+		Height:    commit.Height(),
+		Round:     uint(commit.Round()),
+		Signers:   signers,
+		Signature: sig,
+	}, nil
+}
+
 // Hash returns the hash of the commit
 func (commit *Commit) Hash() help.HexBytes {
 	if commit == nil {
@@ -350,3 +379,28 @@ func (b *BlockStore) SaveBlock(block *ctypes.Block, blockParts *PartSet, seenCom
 		}
 	}
 }
+
+//-------------------------------------
+
+// AggregateCommit is the compact form of a Commit of BLS validators: a single
+// aggregate signature over the agreeing precommits and the bit array of their
+// validator indexes.
+type AggregateCommit struct {
+	BlockID   BlockID        `json:"block_id"`
+	Height    uint64         `json:"height"`
+	Round     uint           `json:"round"`
+	Signers   *help.BitArray `json:"signers"`
+	Signature []byte         `json:"signature"`
+}
+
+// SignBytes returns the message signed by every signer of the commit.
+func (commit *AggregateCommit) SignBytes(chainID string) []byte {
+	vote := &Vote{
+		Height:  commit.Height,
+		Round:   commit.Round,
+		Result:  ctypes.VoteAgree,
+		Type:    VoteTypePrecommit,
+		BlockID: commit.BlockID,
+	}
+	return vote.AggregateSignBytes(chainID)
+}
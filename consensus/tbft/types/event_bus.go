@@ -2,11 +2,16 @@ package types
 
 import (
 	"errors"
+	"sync"
 	"github.com/ethereum/go-ethereum/log"
 	"ethereum/rpc-network/consensus/tbft/help"
 	"ethereum/rpc-network/event"
 )
 
+// ErrEventSubscriberTooSlow ends a subscription whose channel was full when an
+// event was published.
+var ErrEventSubscriberTooSlow = errors.New("event subscriber too slow")
+
 //const defaultCapacity = 0
 
 // type EventBusSubscriber interface {
@@ -15,22 +20,59 @@ import (
 // 	UnsubscribeAll(ctx context.Context, subscriber string) error
 // }
 
-// EventBus is a common bus for all events going through the system. All calls
-// are proxied to underlying pubsub server. All events must be published using
-// EventBus to ensure correct data types.
+// EventBus is a common bus for all events going through the system. All events
+// must be published using EventBus to ensure correct data types.
+//
+// Events are published from the consensus routine, which never waits for a
+// subscriber. A subscriber whose channel is full is dropped, its subscription
+// fails with ErrEventSubscriberTooSlow.
 type EventBus struct {
 	help.BaseService
-	scope event.SubscriptionScope
-	subs  map[string]*event.Feed
+	lock sync.RWMutex
+	subs map[string][]*eventSub // replaced on change, publishers use a snapshot
+	all  map[*eventSub]struct{}
 }
 
 // NewEventBus returns a new event bus.
 func NewEventBus() *EventBus {
-	b := &EventBus{subs: make(map[string]*event.Feed)}
+	b := &EventBus{
+		subs: make(map[string][]*eventSub),
+		all:  make(map[*eventSub]struct{}),
+	}
 	b.BaseService = *help.NewBaseService("EventBus", b)
 	return b
 }
 
+// eventSub receives the events of some keys on out.
+type eventSub struct {
+	bus  *EventBus
+	keys []string
+	out  chan<- interface{}
+	err  chan error
+	once sync.Once
+}
+
+// Err implements event.Subscription.
+func (s *eventSub) Err() <-chan error {
+	return s.err
+}
+
+// Unsubscribe implements event.Subscription.
+func (s *eventSub) Unsubscribe() {
+	s.close(nil)
+}
+
+// close removes the subscriber from the bus and ends the subscription with err.
+func (s *eventSub) close(err error) {
+	s.once.Do(func() {
+		s.bus.remove(s)
+		if err != nil {
+			s.err <- err
+		}
+		close(s.err)
+	})
+}
+
 // func (b *EventBus) SetLogger(l log.Logger) {
 // 	b.BaseService.SetLogger(l)
 // 	b.pubsub.SetLogger(l.With("module", "pubsub"))
@@ -41,18 +83,72 @@ func (b *EventBus) OnStart() error {
 	return nil
 }
 
-//OnStop eventBus stop
+//OnStop eventBus stop, ends all subscriptions
 func (b *EventBus) OnStop() {
-	b.scope.Close()
+	b.lock.RLock()
+	subs := make([]*eventSub, 0, len(b.all))
+	for sub := range b.all {
+		subs = append(subs, sub)
+	}
+	b.lock.RUnlock()
+	for _, sub := range subs {
+		sub.Unsubscribe()
+	}
+}
+
+// Subscribe delivers the events of the given keys on out, several subscribers
+// may share one key. The channel needs a buffer for the events published while
+// the subscriber is busy, the subscription fails once it overflows.
+func (b *EventBus) Subscribe(out chan<- interface{}, keys ...string) event.Subscription {
+	sub := &eventSub{bus: b, keys: keys, out: out, err: make(chan error, 1)}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for _, key := range keys {
+		subs := make([]*eventSub, len(b.subs[key]), len(b.subs[key])+1)
+		copy(subs, b.subs[key])
+		b.subs[key] = append(subs, sub)
+	}
+	b.all[sub] = struct{}{}
+	return sub
 }
 
-//Subscribe chan<- interface{}
-func (b *EventBus) Subscribe(key string, out chan<- interface{}) event.Subscription {
-	if _, ok := b.subs[key]; !ok {
-		var feed event.Feed
-		s := b.scope.Track(feed.Subscribe(out))
-		b.subs[key] = &feed
-		return s
+func (b *EventBus) remove(sub *eventSub) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for _, key := range sub.keys {
+		subs := make([]*eventSub, 0, len(b.subs[key]))
+		for _, s := range b.subs[key] {
+			if s != sub {
+				subs = append(subs, s)
+			}
+		}
+		if len(subs) == 0 {
+			delete(b.subs, key)
+		} else {
+			b.subs[key] = subs
+		}
+	}
+	delete(b.all, sub)
+}
+
+// publish hands data to the subscribers of key without blocking.
+func (b *EventBus) publish(key string, data interface{}) error {
+	if b == nil {
+		return errors.New(EventMsgNotFound)
+	}
+	b.lock.RLock()
+	subs := b.subs[key]
+	b.lock.RUnlock()
+	if len(subs) == 0 {
+		return errors.New(EventMsgNotFound)
+	}
+	for _, sub := range subs {
+		select {
+		case sub.out <- data:
+		default:
+			log.Warn("Dropping slow event subscriber", "key", key)
+			sub.close(ErrEventSubscriberTooSlow)
+		}
 	}
 	return nil
 }
@@ -77,7 +173,7 @@ func (b *EventBus) Subscribe(key string, out chan<- interface{}) event.Subscript
 
 // func (b *EventBus) PublishEventNewBlock(event EventDataNewBlock) error {
 // 	if v,ok := b.subs[EventNewBlock];ok {
-// 		v.Send(event)
+// 		v.SendSync(event)
 // 		return nil
 // 	}
 // 	return errors.New(EventMsgNotFound)
@@ -85,7 +181,7 @@ func (b *EventBus) Subscribe(key string, out chan<- interface{}) event.Subscript
 
 // func (b *EventBus) PublishEventNewBlockHeader(event EventDataNewBlockHeader) error {
 // 	if v,ok := b.subs[EventNewBlockHeader];ok {
-// 		v.Send(event)
+// 		v.SendSync(event)
 // 		return nil
 // 	}
 // 	return errors.New(EventMsgNotFound)
@@ -93,11 +189,7 @@ func (b *EventBus) Subscribe(key string, out chan<- interface{}) event.Subscript
 
 //PublishEventVote send event data common EventVote
 func (b *EventBus) PublishEventVote(event EventDataVote) error {
-	if v, ok := b.subs[EventVote]; ok {
-		v.Send(event)
-		return nil
-	}
-	return errors.New(EventMsgNotFound)
+	return b.publish(EventVote, event)
 }
 
 // PublishEventTx publishes tx event with tags from Result. Note it will add
@@ -137,110 +229,74 @@ func (b *EventBus) PublishEventVote(event EventDataVote) error {
 
 //PublishEventNewRoundStep send event data common EventNewRoundStep
 func (b *EventBus) PublishEventNewRoundStep(event EventDataRoundState) error {
-	if v, ok := b.subs[EventNewRoundStep]; ok {
-		v.Send(EventDataCommon{
-			Key:  EventNewRoundStep,
-			Data: event,
-		})
-		return nil
-	}
-	return errors.New(EventMsgNotFound)
+	return b.publish(EventNewRoundStep, EventDataCommon{
+		Key:  EventNewRoundStep,
+		Data: event,
+	})
 }
 
 //PublishEventTimeoutPropose send event data common EventTimeoutPropose
 func (b *EventBus) PublishEventTimeoutPropose(event EventDataRoundState) error {
-	if v, ok := b.subs[EventTimeoutPropose]; ok {
-		v.Send(EventDataCommon{
-			Key:  EventTimeoutPropose,
-			Data: event,
-		})
-		return nil
-	}
-	return errors.New(EventMsgNotFound)
+	return b.publish(EventTimeoutPropose, EventDataCommon{
+		Key:  EventTimeoutPropose,
+		Data: event,
+	})
 }
 
 //PublishEventTimeoutWait send event data common EventTimeoutWait
 func (b *EventBus) PublishEventTimeoutWait(event EventDataRoundState) error {
-	if v, ok := b.subs[EventTimeoutWait]; ok {
-		v.Send(EventDataCommon{
-			Key:  EventTimeoutWait,
-			Data: event,
-		})
-		return nil
-	}
-	return errors.New(EventMsgNotFound)
+	return b.publish(EventTimeoutWait, EventDataCommon{
+		Key:  EventTimeoutWait,
+		Data: event,
+	})
 }
 
 //PublishEventNewRound send event data common EventNewRound
 func (b *EventBus) PublishEventNewRound(event EventDataRoundState) error {
-	if v, ok := b.subs[EventNewRound]; ok {
-		v.Send(EventDataCommon{
-			Key:  EventNewRound,
-			Data: event,
-		})
-		return nil
-	}
-	return errors.New(EventMsgNotFound)
+	return b.publish(EventNewRound, EventDataCommon{
+		Key:  EventNewRound,
+		Data: event,
+	})
 }
 
 //PublishEventCompleteProposal send event data common EventCompleteProposal
 func (b *EventBus) PublishEventCompleteProposal(event EventDataRoundState) error {
-	if v, ok := b.subs[EventCompleteProposal]; ok {
-		v.Send(EventDataCommon{
-			Key:  EventCompleteProposal,
-			Data: event,
-		})
-		return nil
-	}
-	return errors.New(EventMsgNotFound)
+	return b.publish(EventCompleteProposal, EventDataCommon{
+		Key:  EventCompleteProposal,
+		Data: event,
+	})
 }
 
 //PublishEventPolka send event data common EventPolka
 func (b *EventBus) PublishEventPolka(event EventDataRoundState) error {
-	if v, ok := b.subs[EventPolka]; ok {
-		v.Send(EventDataCommon{
-			Key:  EventPolka,
-			Data: event,
-		})
-		return nil
-	}
-	return errors.New(EventMsgNotFound)
+	return b.publish(EventPolka, EventDataCommon{
+		Key:  EventPolka,
+		Data: event,
+	})
 }
 
 //PublishEventUnlock send event data common unlock
 func (b *EventBus) PublishEventUnlock(event EventDataRoundState) error {
-	if v, ok := b.subs[EventUnlock]; ok {
-		v.Send(EventDataCommon{
-			Key:  EventUnlock,
-			Data: event,
-		})
-		return nil
-	}
-	return errors.New(EventMsgNotFound)
+	return b.publish(EventUnlock, EventDataCommon{
+		Key:  EventUnlock,
+		Data: event,
+	})
 }
 
 //PublishEventRelock send event data common relock
 func (b *EventBus) PublishEventRelock(event EventDataRoundState) error {
-	if v, ok := b.subs[EventRelock]; ok {
-		v.Send(EventDataCommon{
-			Key:  EventRelock,
-			Data: event,
-		})
-		return nil
-	}
-	return errors.New(EventMsgNotFound)
+	return b.publish(EventRelock, EventDataCommon{
+		Key:  EventRelock,
+		Data: event,
+	})
 }
 
 //PublishEventLock send event data common
 func (b *EventBus) PublishEventLock(event EventDataRoundState) error {
-	if v, ok := b.subs[EventLock]; ok {
-		v.Send(EventDataCommon{
-			Key:  EventLock,
-			Data: event,
-		})
-		return nil
-	}
-	return errors.New(EventMsgNotFound)
+	return b.publish(EventLock, EventDataCommon{
+		Key:  EventLock,
+		Data: event,
+	})
 }
 
 func logIfTagExists(tag string, tags map[string]string, logger log.Logger) {
@@ -0,0 +1,84 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventBusSlowSubscriber(t *testing.T) {
+	bus := NewEventBus()
+	if err := bus.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Stop()
+
+	stalled := bus.Subscribe(make(chan interface{}), EventNewRound)
+	fast := make(chan interface{}, 4)
+	bus.Subscribe(fast, EventNewRound, EventVote)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 3; i++ {
+			if err := bus.PublishEventNewRound(EventDataRoundState{Height: uint64(i)}); err != nil {
+				t.Error(err)
+			}
+		}
+		if err := bus.PublishEventVote(EventDataVote{}); err != nil {
+			t.Error(err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("publishing blocked on a stalled subscriber")
+	}
+
+	if err := <-stalled.Err(); err != ErrEventSubscriberTooSlow {
+		t.Fatalf("stalled subscription ended with %v, want %v", err, ErrEventSubscriberTooSlow)
+	}
+	if len(fast) != 4 {
+		t.Fatalf("fast subscriber received %d events, want 4", len(fast))
+	}
+	for i := 0; i < 3; i++ {
+		ev := (<-fast).(EventDataCommon)
+		if ev.Key != EventNewRound || ev.Data.Height != uint64(i) {
+			t.Errorf("event %d: %+v", i, ev)
+		}
+	}
+	if _, ok := (<-fast).(EventDataVote); !ok {
+		t.Error("vote not received")
+	}
+}
+
+func TestEventBusUnsubscribe(t *testing.T) {
+	bus := NewEventBus()
+	if err := bus.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan interface{}, 1)
+	sub := bus.Subscribe(events, EventLock)
+	sub.Unsubscribe()
+	if _, ok := <-sub.Err(); ok {
+		t.Fatal("error channel open after unsubscribe")
+	}
+	if err := bus.PublishEventLock(EventDataRoundState{}); err == nil {
+		t.Fatal("event published without subscribers")
+	}
+	if len(events) != 0 {
+		t.Fatal("event delivered after unsubscribe")
+	}
+
+	// stopping the bus ends the remaining subscriptions
+	sub = bus.Subscribe(events, EventLock)
+	bus.Stop()
+	select {
+	case _, ok := <-sub.Err():
+		if ok {
+			t.Fatal("subscription failed on stop")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription not ended on stop")
+	}
+}
@@ -14,7 +14,6 @@ import (
 	"ethereum/rpc-network/consensus/tbft/tp2p"
 	ctypes "ethereum/rpc-network/core/types"
 	"github.com/ethereum/go-ethereum/log"
-	"ethereum/rpc-network/params"
 )
 
 const (
@@ -170,6 +169,9 @@ type HealthMgr struct {
 	cid            uint64
 	uid            uint64
 	lock           *sync.Mutex
+	policy         HealthPolicy
+	history        *HealthHistory
+	restored       uint64 // switch whose restore was recorded
 }
 
 //NewHealthMgr func
@@ -185,6 +187,8 @@ func NewHealthMgr(cid uint64) *HealthMgr {
 		cid:            cid,
 		lock:           new(sync.Mutex),
 		healthTick:     nil,
+		policy:         DefaultHealthPolicy(),
+		history:        NewHealthHistory(DefaultHealthHistoryLimit),
 	}
 	h.BaseService = *help.NewBaseService("HealthMgr", h)
 	hi, lo := cid<<32, uint64(100)
@@ -193,6 +197,79 @@ func NewHealthMgr(cid uint64) *HealthMgr {
 	return h
 }
 
+//SetPolicy sets the health policy, must be called before the mgr is started
+func (h *HealthMgr) SetPolicy(policy HealthPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.policy = policy
+	return nil
+}
+
+//Policy returns the health policy
+func (h *HealthMgr) Policy() HealthPolicy {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.policy
+}
+
+//SetHistory sets the history recording the health events, which may be shared
+//by the committees of a node
+func (h *HealthMgr) SetHistory(history *HealthHistory) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.history = history
+}
+
+func (h *HealthMgr) getHistory() *HealthHistory {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.history
+}
+
+//History returns the last limit health events of the committee, all of them if limit is 0
+func (h *HealthMgr) History(limit int) []*HealthEvent {
+	return h.getHistory().Events(h.cid, limit)
+}
+
+// enabled reports whether the mgr proposes and votes for switches
+func (h *HealthMgr) enabled() bool {
+	return EnableHealthMgr && h.policy.Evict
+}
+
+// record adds an event about v to the history
+func (h *HealthMgr) record(kind string, v *Health, reason string) {
+	h.getHistory().Add(&HealthEvent{
+		Committee: h.cid,
+		Kind:      kind,
+		ID:        v.ID,
+		Address:   hexutil.Bytes(v.Val.Address),
+		Tick:      atomic.LoadInt32(&v.Tick),
+		State:     atomic.LoadUint32(&v.State),
+		Reason:    reason,
+	})
+}
+
+// recordSwitch adds an event about sv to the history
+func (h *HealthMgr) recordSwitch(kind string, sv *SwitchValidator, reason string) {
+	ev := &HealthEvent{
+		Committee: h.cid,
+		Kind:      kind,
+		Switch:    sv.ID,
+		Reason:    reason,
+	}
+	if sv.Remove != nil {
+		ev.ID, ev.Address = sv.Remove.ID, hexutil.Bytes(sv.Remove.Val.Address)
+		ev.Tick, ev.State = atomic.LoadInt32(&sv.Remove.Tick), atomic.LoadUint32(&sv.Remove.State)
+	}
+	if sv.Add != nil {
+		ev.Add = sv.Add.ID
+	}
+	h.getHistory().Add(ev)
+}
+
 // Sum invoke in the testing, after mgr start
 func (h *HealthMgr) Sum() int {
 	return len(h.Work) + len(h.Back) + len(h.seed)
@@ -235,6 +312,26 @@ func (h *HealthMgr) GetHealthTick(pid tp2p.ID) int32 {
 	return -1
 }
 
+//HealthTicks returns the current tick of every work, back and seed peer
+func (h *HealthMgr) HealthTicks() map[tp2p.ID]int32 {
+	ticks := make(map[tp2p.ID]int32, h.Sum())
+	for _, v := range h.Work {
+		ticks[v.ID] = atomic.LoadInt32(&v.Tick)
+	}
+	for _, v := range h.Back {
+		ticks[v.ID] = atomic.LoadInt32(&v.Tick)
+	}
+	for _, v := range h.seed {
+		ticks[v.ID] = atomic.LoadInt32(&v.Tick)
+	}
+	return ticks
+}
+
+//CurrentSwitch returns the switch-validator proposal in progress, nil if none
+func (h *HealthMgr) CurrentSwitch() *SwitchValidator {
+	return h.getCurSV()
+}
+
 //UpdataHealthInfo update one health
 func (h *HealthMgr) UpdataHealthInfo(id tp2p.ID, ip string, port uint32, pk []byte) {
 	enter := h.GetHealth(pk)
@@ -256,8 +353,7 @@ func (h *HealthMgr) ChanTo() chan *SwitchValidator {
 
 //OnStart mgr start
 func (h *HealthMgr) OnStart() error {
-	EnableHealthMgr = false
-	if h.healthTick == nil && EnableHealthMgr {
+	if h.healthTick == nil && h.enabled() {
 		h.healthTick = time.NewTicker(1 * time.Second)
 		go h.healthGoroutine()
 	}
@@ -267,7 +363,6 @@ func (h *HealthMgr) OnStart() error {
 //OnStop mgr stop
 func (h *HealthMgr) OnStop() {
 	log.Info("Begin HealthMgr finish")
-	EnableHealthMgr = false
 	if h.healthTick != nil {
 		h.healthTick.Stop()
 	}
@@ -325,7 +420,7 @@ func (h *HealthMgr) healthGoroutine() {
 	}
 }
 func (h *HealthMgr) work(sshift bool) {
-	if !EnableHealthMgr {
+	if !h.enabled() {
 		return
 	}
 	for _, v := range h.Work {
@@ -341,13 +436,18 @@ func (h *HealthMgr) checkSwitchValidator(v *Health, sshift bool) {
 
 		val := atomic.AddInt32(&v.Tick, 1)
 		log.Debug("Health", "id", v.ID, "val", val)
-		if sshift && (val > HealthOut+60) && v.State == ctypes.StateUsedFlag && !v.Self {
+		if val == h.policy.Timeout {
+			h.record(HealthEventTimeout, v, fmt.Sprintf("no message for %d ticks", val))
+		}
+		if sshift && (val > h.policy.Timeout+h.policy.Grace) && v.State == ctypes.StateUsedFlag && !v.Self {
 			if sv0 := h.getCurSV(); sv0 == nil {
 				log.Warn("Health", "id", v.ID, "val", val)
 				back := h.pickUnuseValidator()
-				cur := h.makeSwitchValidators(v, back, "Switch", 0)
+				reason := fmt.Sprintf("no message for %d ticks", val)
+				cur := h.makeSwitchValidators(v, back, reason, 0)
 				atomic.StoreUint32(&v.State, ctypes.StateSwitchingFlag)
 				h.setCurSV(cur)
+				h.recordSwitch(HealthEventPropose, cur, reason)
 				log.Warn("CheckSwitchValidator(remove,add)", "info:", cur, "cid", h.cid)
 				go h.Switch(cur)
 			}
@@ -355,10 +455,15 @@ func (h *HealthMgr) checkSwitchValidator(v *Health, sshift bool) {
 	}
 	if sv0 := h.getCurSV(); sv0 != nil {
 		val0 := atomic.LoadInt32(&sv0.Remove.Tick)
-		if val0 < HealthOut && sv0.From == 0 {
+		if val0 < h.policy.Timeout && sv0.From == 0 {
 			sv1 := *sv0
 			sv1.From = 1
 			log.Info("Restore SwitchValidator", "info", sv1, "cid", h.cid)
+			if h.restored != sv1.ID {
+				// the restore is sent on every tick until the committee acts on it
+				h.restored = sv1.ID
+				h.recordSwitch(HealthEventRestore, &sv1, fmt.Sprintf("member recovered at tick %d", val0))
+			}
 			go h.Switch(&sv1)
 		}
 	}
@@ -408,12 +513,12 @@ func (h *HealthMgr) isShiftSV() (bool, int) {
 			cnt++
 		}
 	}
-	return cnt > params.MinimumCommitteeNumber, cnt
+	return cnt > h.policy.MinMembers, cnt
 }
 
 //switchResult handle the sv after consensus and the result removed from self
 func (h *HealthMgr) switchResult(res *SwitchValidator) {
-	if !EnableHealthMgr {
+	if !h.enabled() {
 		return
 	}
 	ss := "failed"
@@ -449,6 +554,7 @@ func (h *HealthMgr) switchResult(res *SwitchValidator) {
 				atomic.StoreUint32(&remove.State, ctypes.StateRemovedFlag)
 				atomic.StoreInt32(&remove.Tick, 0) // issues for the sv was in another proposal queue
 				ss += "Success"
+				h.recordSwitch(HealthEventApply, res, res.Resion)
 			}
 			if add != nil {
 
@@ -481,20 +587,27 @@ func (h *HealthMgr) pickUnuseValidator() *Health {
 func (h *HealthMgr) Update(id tp2p.ID) {
 	if v, ok := h.Work[id]; ok {
 		if v.HType != ctypes.TypeFixed {
-			atomic.StoreInt32(&v.Tick, 0)
+			h.resetTick(v)
 			return
 		}
 	}
 	for _, v := range h.Back {
 		if v.ID == id {
 			if v.HType != ctypes.TypeFixed {
-				atomic.StoreInt32(&v.Tick, 0)
+				h.resetTick(v)
 			}
 			return
 		}
 	}
 }
 
+// resetTick resets the tick of v, recording its recovery if it timed out
+func (h *HealthMgr) resetTick(v *Health) {
+	if old := atomic.SwapInt32(&v.Tick, 0); old >= h.policy.Timeout {
+		h.record(HealthEventRecover, v, fmt.Sprintf("message after %d ticks", old))
+	}
+}
+
 func (h *HealthMgr) getHealthFromPart(address []byte, part int) *Health {
 	if part == SwitchPartBack { // back
 		for _, v := range h.Back {
@@ -537,7 +650,7 @@ func (h *HealthMgr) GetHealth(adress []byte) *Health {
 
 //VerifySwitch verify remove and add switchEnter
 func (h *HealthMgr) VerifySwitch(sv *SwitchValidator) error {
-	if !EnableHealthMgr {
+	if !h.enabled() {
 		err := fmt.Errorf("healthMgr not enable")
 		log.Debug("VerifySwitch", "err", err)
 		return err
@@ -548,7 +661,11 @@ func (h *HealthMgr) VerifySwitch(sv *SwitchValidator) error {
 			return nil // proposal is self?
 		}
 	}
-	return h.verifySwitchEnter(sv.Remove, sv.Add)
+	if err := h.verifySwitchEnter(sv.Remove, sv.Add); err != nil {
+		h.recordSwitch(HealthEventReject, sv, err.Error())
+		return err
+	}
+	return nil
 }
 
 func (h *HealthMgr) verifySwitchEnter(remove, add *Health) error {
@@ -561,7 +678,7 @@ func (h *HealthMgr) verifySwitchEnter(remove, add *Health) error {
 	rTick := atomic.LoadInt32(&remove.Tick)
 
 	rState := atomic.LoadUint32(&remove.State)
-	if rState >= ctypes.StateUsedFlag && rState <= ctypes.StateSwitchingFlag && rTick >= HealthOut {
+	if rState >= ctypes.StateUsedFlag && rState <= ctypes.StateSwitchingFlag && rTick >= h.policy.Timeout {
 		rRes = true
 	}
 	res := remove.SimpleString()
@@ -587,7 +704,7 @@ func (h *HealthMgr) UpdateFromCommittee(member, backMember ctypes.CommitteeMembe
 	for _, v := range member {
 		for k, v2 := range h.Work {
 			if bytes.Equal(v.CommitteeBase.Bytes(), v2.Val.Address) {
-				atomic.StoreUint32(&h.Work[k].State, v.Flag)
+				h.updateState(h.Work[k], v.Flag)
 				break
 			}
 		}
@@ -596,14 +713,14 @@ func (h *HealthMgr) UpdateFromCommittee(member, backMember ctypes.CommitteeMembe
 		if v.MType == ctypes.TypeBack {
 			for k, v2 := range h.Back {
 				if bytes.Equal(v.CommitteeBase.Bytes(), v2.Val.Address) {
-					atomic.StoreUint32(&h.Back[k].State, v.Flag)
+					h.updateState(h.Back[k], v.Flag)
 					break
 				}
 			}
 		} else if v.MType == ctypes.TypeFixed {
 			for k, v2 := range h.seed {
 				if bytes.Equal(v.CommitteeBase.Bytes(), v2.Val.Address) {
-					atomic.StoreUint32(&h.seed[k].State, v.Flag)
+					h.updateState(h.seed[k], v.Flag)
 					break
 				}
 			}
@@ -613,6 +730,13 @@ func (h *HealthMgr) UpdateFromCommittee(member, backMember ctypes.CommitteeMembe
 	h.checkSaveSwitchValidator(append(member, backMember...))
 }
 
+// updateState sets the state the committee reported for v, recording a change
+func (h *HealthMgr) updateState(v *Health, state uint32) {
+	if old := atomic.SwapUint32(&v.State, state); old != state {
+		h.record(HealthEventState, v, fmt.Sprintf("state %d to %d", old, state))
+	}
+}
+
 func (h *HealthMgr) checkSaveSwitchValidator(members ctypes.CommitteeMembers) {
 	h.lock.Lock()
 	defer h.lock.Unlock()
@@ -0,0 +1,233 @@
+package types
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"ethereum/rpc-network/consensus/tbft/metrics"
+	"ethereum/rpc-network/consensus/tbft/tp2p"
+	"ethereum/rpc-network/params"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// DefaultHealthHistoryLimit is the number of events kept by a health history.
+const DefaultHealthHistoryLimit = 1024
+
+// HealthPolicy decides when a committee member counts as down and is rotated
+// out for a back member. Ticks are seconds without a message from the member.
+type HealthPolicy struct {
+	Evict      bool  `json:"evict"`      // propose switches for members which are down
+	Timeout    int32 `json:"timeout"`    // ticks after which a member is down and a switch removing it is accepted
+	Grace      int32 `json:"grace"`      // further ticks before the node proposes the switch itself
+	MinMembers int   `json:"minMembers"` // switches are only proposed while more members are in use
+}
+
+// DefaultHealthPolicy returns the policy of a committee unless configured
+// otherwise. Eviction is off, members are only rotated by the election.
+func DefaultHealthPolicy() HealthPolicy {
+	return HealthPolicy{
+		Evict:      false,
+		Timeout:    HealthOut,
+		Grace:      60,
+		MinMembers: params.MinimumCommitteeNumber,
+	}
+}
+
+// Validate checks the policy can't stall the committee.
+func (p HealthPolicy) Validate() error {
+	if p.Timeout <= 0 {
+		return errors.New("health timeout must be positive")
+	}
+	if p.Grace < 0 {
+		return errors.New("health grace must not be negative")
+	}
+	if p.MinMembers < params.MinimumCommitteeNumber {
+		return fmt.Errorf("health policy must keep at least %d members", params.MinimumCommitteeNumber)
+	}
+	return nil
+}
+
+// Kinds of health events.
+const (
+	HealthEventTimeout = "timeout" // a member went quiet for the policy timeout
+	HealthEventRecover = "recover" // a member which timed out was heard from again
+	HealthEventState   = "state"   // the committee changed the state of a member
+	HealthEventPropose = "propose" // the node proposed a switch
+	HealthEventRestore = "restore" // the node asked to restore a switch as the member recovered
+	HealthEventApply   = "apply"   // the committee applied a switch
+	HealthEventReject  = "reject"  // the node refused to vote for a switch
+)
+
+// HealthEvent is an entry of the health history.
+type HealthEvent struct {
+	Time      time.Time     `json:"time"`
+	Committee uint64        `json:"committee"`
+	Kind      string        `json:"kind"`
+	ID        tp2p.ID       `json:"id,omitempty"`
+	Address   hexutil.Bytes `json:"address,omitempty"`
+	Tick      int32         `json:"tick"`
+	State     uint32        `json:"state"`
+	Switch    uint64        `json:"switch,omitempty"` // ID of the switch validator proposal
+	Add       tp2p.ID       `json:"add,omitempty"`    // member taking the place of the removed one
+	Reason    string        `json:"reason,omitempty"`
+}
+
+func (e *HealthEvent) String() string {
+	return fmt.Sprintf("HealthEvent{%s cid:%d id:%s tick:%d state:%d switch:%d add:%s reason:%s}",
+		e.Kind, e.Committee, e.ID, e.Tick, e.State, e.Switch, e.Add, e.Reason)
+}
+
+// HealthHistory keeps the latest health events of all committees of a node,
+// optionally journaled to a file so they survive a restart.
+type HealthHistory struct {
+	mu     sync.Mutex
+	events []*HealthEvent
+	limit  int
+	path   string
+	file   *os.File
+	lines  int // events in the journal, it is compacted at twice the limit
+}
+
+// NewHealthHistory returns a history keeping the last limit events in memory.
+func NewHealthHistory(limit int) *HealthHistory {
+	if limit <= 0 {
+		limit = DefaultHealthHistoryLimit
+	}
+	return &HealthHistory{limit: limit}
+}
+
+// OpenHealthHistory returns a history journaled to the file at path. The events
+// already in the file are loaded, and the file is compacted to the last limit.
+func OpenHealthHistory(path string, limit int) (*HealthHistory, error) {
+	hh := NewHealthHistory(limit)
+	hh.path = path
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			ev := new(HealthEvent)
+			if err := json.Unmarshal(scanner.Bytes(), ev); err != nil {
+				log.Warn("Skipping bad health history entry", "path", path, "err", err)
+				continue
+			}
+			hh.append(ev)
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err := hh.compact(); err != nil {
+		return nil, err
+	}
+	return hh, nil
+}
+
+// compact replaces the journal with one holding the events kept in memory.
+// The new journal is written aside and renamed over the old one, so a crash
+// leaves either of them.
+func (hh *HealthHistory) compact() error {
+	tmp := hh.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	for _, ev := range hh.events {
+		if err := writeHealthEvent(f, ev); err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, hh.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if hh.file != nil {
+		hh.file.Close()
+	}
+	hh.file, err = os.OpenFile(hh.path, os.O_WRONLY|os.O_APPEND, 0600)
+	hh.lines = len(hh.events)
+	return err
+}
+
+func writeHealthEvent(f *os.File, ev *HealthEvent) error {
+	blob, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(blob, '\n'))
+	return err
+}
+
+func (hh *HealthHistory) append(ev *HealthEvent) {
+	hh.events = append(hh.events, ev)
+	if len(hh.events) > hh.limit {
+		hh.events = append(hh.events[:0], hh.events[len(hh.events)-hh.limit:]...)
+	}
+}
+
+// Add records ev.
+func (hh *HealthHistory) Add(ev *HealthEvent) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	metrics.MHealth(ev.Kind)
+	log.Debug("Health event", "event", ev)
+
+	hh.mu.Lock()
+	defer hh.mu.Unlock()
+	hh.append(ev)
+	if hh.file == nil {
+		return
+	}
+	if err := writeHealthEvent(hh.file, ev); err != nil {
+		log.Warn("Failed to journal health event", "err", err)
+		return
+	}
+	if hh.lines++; hh.lines >= 2*hh.limit {
+		if err := hh.compact(); err != nil {
+			log.Warn("Failed to compact health history", "path", hh.path, "err", err)
+		}
+	}
+}
+
+// Events returns the last limit events of the committee cid, oldest first.
+// A limit of zero returns all of them.
+func (hh *HealthHistory) Events(cid uint64, limit int) []*HealthEvent {
+	hh.mu.Lock()
+	defer hh.mu.Unlock()
+	var events []*HealthEvent
+	for i := len(hh.events) - 1; i >= 0 && (limit <= 0 || len(events) < limit); i-- {
+		if hh.events[i].Committee == cid {
+			events = append(events, hh.events[i])
+		}
+	}
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	return events
+}
+
+// Close closes the journal.
+func (hh *HealthHistory) Close() error {
+	hh.mu.Lock()
+	defer hh.mu.Unlock()
+	if hh.file == nil {
+		return nil
+	}
+	err := hh.file.Close()
+	hh.file = nil
+	return err
+}
@@ -0,0 +1,203 @@
+package types
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ethereum/rpc-network/consensus/tbft/tp2p"
+	ctypes "ethereum/rpc-network/core/types"
+)
+
+func newTestHealthMgr(t *testing.T, work, back int, policy HealthPolicy) (*HealthMgr, []*Health) {
+	mgr := NewHealthMgr(1)
+	if err := mgr.SetPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+	powers := make([]int64, work+back)
+	for i := range powers {
+		powers[i] = 1
+	}
+	var healths []*Health
+	for i, val := range newTestValidators(t, powers...) {
+		id := tp2p.ID(fmt.Sprintf("%x", val.Address))
+		if i < work {
+			h := NewHealth(id, ctypes.TypeWorked, ctypes.StateUsedFlag, val, false)
+			mgr.PutWorkHealth(h)
+			healths = append(healths, h)
+		} else {
+			h := NewHealth(id, ctypes.TypeBack, ctypes.StateUnusedFlag, val, false)
+			mgr.PutBackHealth(h)
+			healths = append(healths, h)
+		}
+	}
+	return mgr, healths
+}
+
+// tick advances the mgr by one tick, in which every member but the down one is heard from.
+func tick(mgr *HealthMgr, healths []*Health, down *Health) {
+	sshift, _ := mgr.isShiftSV()
+	mgr.work(sshift)
+	for _, h := range healths {
+		if h != down {
+			mgr.Update(h.ID)
+		}
+	}
+}
+
+func historyKinds(events []*HealthEvent) []string {
+	kinds := make([]string, len(events))
+	for i, ev := range events {
+		kinds[i] = ev.Kind
+	}
+	return kinds
+}
+
+func TestHealthPolicyEviction(t *testing.T) {
+	policy := HealthPolicy{Evict: true, Timeout: 3, Grace: 2, MinMembers: 4}
+	mgr, healths := newTestHealthMgr(t, 5, 1, policy)
+	down := healths[0]
+
+	for i := int32(0); i < policy.Timeout+policy.Grace; i++ {
+		tick(mgr, healths, down)
+	}
+	if mgr.CurrentSwitch() != nil {
+		t.Fatal("switch proposed within the grace period")
+	}
+	tick(mgr, healths, down)
+	var sv *SwitchValidator
+	select {
+	case sv = <-mgr.ChanTo():
+	case <-time.After(time.Second):
+		t.Fatal("no switch proposed after the grace period")
+	}
+	if sv.Remove != down || sv.Add != healths[5] {
+		t.Fatalf("wrong switch: %v", sv)
+	}
+	if err := mgr.VerifySwitch(sv); err != nil {
+		t.Fatalf("own switch not verified: %v", err)
+	}
+
+	// the member shows up again, the switch is restored
+	mgr.Update(down.ID)
+	tick(mgr, healths, nil)
+	select {
+	case restore := <-mgr.ChanTo():
+		if restore.From != 1 || restore.ID != sv.ID {
+			t.Fatalf("wrong restore: %v", restore)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("switch not restored")
+	}
+
+	events := mgr.History(0)
+	want := []string{HealthEventTimeout, HealthEventPropose, HealthEventRecover, HealthEventRestore}
+	if have := historyKinds(events); fmt.Sprint(have) != fmt.Sprint(want) {
+		t.Fatalf("history mismatch: have %v, want %v", have, want)
+	}
+	if events[1].ID != down.ID || events[1].Add != healths[5].ID || events[1].Switch != sv.ID || events[1].Reason == "" {
+		t.Fatalf("proposal not recorded: %v", events[1])
+	}
+	if last := mgr.History(1); len(last) != 1 || last[0] != events[3] {
+		t.Fatalf("limited history mismatch: %v", last)
+	}
+}
+
+func TestHealthPolicyMinMembers(t *testing.T) {
+	// with no member to spare the mgr never proposes
+	policy := HealthPolicy{Evict: true, Timeout: 1, Grace: 0, MinMembers: 5}
+	mgr, healths := newTestHealthMgr(t, 5, 1, policy)
+	for i := 0; i < 5; i++ {
+		tick(mgr, healths, healths[0])
+	}
+	if sv := mgr.CurrentSwitch(); sv != nil {
+		t.Fatalf("switch proposed below the minimum members: %v", sv)
+	}
+	if err := mgr.SetPolicy(HealthPolicy{Timeout: 1, MinMembers: 1}); err == nil {
+		t.Fatal("policy below the committee minimum accepted")
+	}
+}
+
+func TestHealthRejectRecorded(t *testing.T) {
+	policy := HealthPolicy{Evict: true, Timeout: 10, Grace: 0, MinMembers: 4}
+	mgr, healths := newTestHealthMgr(t, 5, 1, policy)
+	sv := mgr.makeSwitchValidators(healths[1], healths[5], "test", 0)
+	if err := mgr.VerifySwitch(sv); err == nil {
+		t.Fatal("switch removing a healthy member verified")
+	}
+	events := mgr.History(0)
+	if len(events) != 1 || events[0].Kind != HealthEventReject || events[0].ID != healths[1].ID {
+		t.Fatalf("rejection not recorded: %v", events)
+	}
+}
+
+func TestHealthHistoryJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tbft-health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "health.jsonl")
+
+	hh, err := OpenHealthHistory(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 6; i++ {
+		hh.Add(&HealthEvent{Committee: uint64(i % 2), Kind: HealthEventTimeout, Tick: int32(i)})
+	}
+	hh.Close()
+
+	// reopening keeps the last events up to the limit
+	hh, err = OpenHealthHistory(path, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hh.Close()
+	events := hh.Events(1, 0)
+	if len(events) != 2 || events[0].Tick != 3 || events[1].Tick != 5 {
+		t.Fatalf("committee 1 history mismatch: %v", events)
+	}
+	if events := hh.Events(0, 0); len(events) != 2 || events[0].Tick != 2 {
+		t.Fatalf("committee 0 history mismatch: %v", events)
+	}
+}
+
+func TestHealthHistoryCompact(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tbft-health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "health.jsonl")
+
+	hh, err := OpenHealthHistory(path, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hh.Close()
+	for i := 0; i < 50; i++ {
+		hh.Add(&HealthEvent{Committee: 1, Kind: HealthEventTimeout, Tick: int32(i)})
+		blob, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lines := strings.Count(string(blob), "\n"); lines >= 8 {
+			t.Fatalf("journal holds %d events after %d adds", lines, i+1)
+		}
+	}
+	// events after the compaction still go to the journal
+	reopened, err := OpenHealthHistory(path, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	events := reopened.Events(1, 0)
+	if len(events) != 4 || events[0].Tick != 46 || events[3].Tick != 49 {
+		t.Fatalf("history mismatch after compaction: %v", events)
+	}
+}
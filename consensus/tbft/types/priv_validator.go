@@ -25,8 +25,15 @@ const (
 	stepPrecommit uint8 = 3
 	//BlockPartSizeBytes is part's size
 	BlockPartSizeBytes uint = 65536 // 64kB,
+
+	// maxValidatorHistory is how many validator sets of the committee are kept
+	// to verify the blocks they committed
+	maxValidatorHistory = 16
 )
 
+// ErrHeightOutOfCommittee is returned for heights the committee doesn't commit.
+var ErrHeightOutOfCommittee = errors.New("height out of committee range")
+
 func voteToStep(vote *Vote) uint8 {
 	switch vote.Type {
 	case VoteTypePrevote:
@@ -51,6 +58,7 @@ type PrivValidator interface {
 
 type privValidator struct {
 	PrivKey       tcrypto.PrivKey
+	Address       help.Address  `json:"address,omitempty"` // the address of PrivKey if empty
 	LastHeight    uint64        `json:"last_height"`
 	LastRound     uint          `json:"last_round"`
 	LastStep      uint8         `json:"last_step"`
@@ -75,6 +83,17 @@ func NewPrivValidator(priv ecdsa.PrivateKey) PrivValidator {
 	}
 }
 
+//NewBLSPrivValidator return new private Validator signing with a BLS key, its
+//precommits can be aggregated into an AggregateCommit. address is the one of
+//the committee key the BLS key is registered with, see NewBLSValidator
+func NewBLSPrivValidator(address help.Address, priv tcrypto.PrivKeyBLS) PrivValidator {
+	return &privValidator{
+		PrivKey:  priv,
+		Address:  address,
+		LastStep: stepNone,
+	}
+}
+
 func (Validator *privValidator) Reset() {
 	var sig []byte
 	Validator.LastHeight = 0
@@ -96,6 +115,9 @@ func (Validator *privValidator) saveSigned(height uint64, round int, step uint8,
 }
 
 func (Validator *privValidator) GetAddress() help.Address {
+	if len(Validator.Address) > 0 {
+		return Validator.Address
+	}
 	return Validator.PrivKey.PubKey().Address()
 }
 func (Validator *privValidator) GetPubKey() tcrypto.PubKey {
@@ -118,7 +140,7 @@ func (Validator *privValidator) SignVote(chainID string, vote *Vote) error {
 // a previously signed vote (ie. we crashed after signing but before the vote hit the WAL).
 func (Validator *privValidator) signVote(chainID string, vote *Vote) error {
 	height, round, step := vote.Height, vote.Round, voteToStep(vote)
-	signBytes := vote.SignBytes(chainID)
+	signBytes := vote.signBytesFor(chainID, Validator.PrivKey.PubKey())
 
 	sameHRS, err := Validator.checkHRS(height, int(round), step)
 	if err != nil {
@@ -307,6 +329,7 @@ func (pvs PrivValidatorsByAddress) Swap(i, j int) {
 // StateAgent implements PrivValidator
 type StateAgent interface {
 	GetValidator() *ValidatorSet
+	GetValidatorAt(height uint64) (*ValidatorSet, error)
 	UpdateValidator(vset *ValidatorSet, makeids bool) error
 	GetLastValidator() *ValidatorSet
 	GetLastValidatorAddress() common.Address
@@ -329,16 +352,24 @@ type StateAgent interface {
 
 //StateAgentImpl agent state struct
 type StateAgentImpl struct {
-	Priv        *privValidator
+	Priv        PrivValidator
 	Agent       ctypes.PbftAgentProxy
 	Validators  *ValidatorSet
 	ids         map[string]interface{}
+	observers   map[string]interface{} // non-validator peers allowed to follow the committee
 	lock        *sync.Mutex
 	ChainID     string
 	LastHeight  uint64
 	BeginHeight uint64
 	EndHeight   uint64
 	CID         uint64
+	history     []validatorsFrom // validator sets in the order they took over
+}
+
+// validatorsFrom is a validator set and the first height it commits.
+type validatorsFrom struct {
+	height uint64
+	vals   *ValidatorSet
 }
 
 //NewStateAgent return new agent state
@@ -354,6 +385,7 @@ func NewStateAgent(agent ctypes.PbftAgentProxy, chainID string,
 		EndHeight:   0, // defualt 0,mean not work
 		LastHeight:  lh.Uint64(),
 		CID:         cid,
+		history:     []validatorsFrom{{height, vals}},
 	}
 	state.ids = vals.MakeIDs()
 	log.Debug("SetBeginEnd in Committee", "cid", state.CID, "begin", height, "end", state.EndHeight, "current", lh)
@@ -368,7 +400,7 @@ func MakePartSet(partSize uint, block *ctypes.Block) (*PartSet, error) {
 	if err != nil {
 		panic(err)
 	}
-	bz, err := cdc.MarshalBinary(bzs)
+	bz, err := cdc.MarshalBinaryLengthPrefixed(bzs)
 	if err != nil {
 		return nil, err
 	}
@@ -380,7 +412,7 @@ func MakeBlockFromPartSet(reader *PartSet) (*ctypes.Block, error) {
 	if reader.IsComplete() {
 		maxsize := int64(MaxBlockBytes)
 		b := make([]byte, maxsize, maxsize)
-		_, err := cdc.UnmarshalBinaryReader(reader.GetReader(), &b, maxsize)
+		_, err := cdc.UnmarshalBinaryLengthPrefixedReader(reader.GetReader(), &b, maxsize)
 		if err != nil {
 			return nil, err
 		}
@@ -395,7 +427,10 @@ func MakeBlockFromPartSet(reader *PartSet) (*ctypes.Block, error) {
 
 //PrivReset reset PrivValidator
 func (state *StateAgentImpl) PrivReset() {
-	state.Priv.Reset()
+	// a remote signer keeps its double sign state on its own
+	if pv, ok := state.Priv.(*privValidator); ok {
+		pv.Reset()
+	}
 }
 
 // HasPeerID judge the peerid whether in validators
@@ -408,9 +443,25 @@ func (state *StateAgentImpl) HasPeerID(id string) error {
 	if _, ok := state.ids[id]; ok {
 		return nil
 	}
+	if _, ok := state.observers[id]; ok {
+		return nil
+	}
 	return fmt.Errorf("the peerid is not in validators,peerid=%s", id)
 }
 
+// AddObservers lets the given peers connect although they are no validators.
+// Observers follow the consensus but hold no key, their votes are never counted.
+func (state *StateAgentImpl) AddObservers(ids ...string) {
+	state.lock.Lock()
+	defer state.lock.Unlock()
+	if state.observers == nil {
+		state.observers = make(map[string]interface{})
+	}
+	for _, id := range ids {
+		state.observers[id] = nil
+	}
+}
+
 //SetEndHeight set now committee fast block height for end. (begin,end]
 func (state *StateAgentImpl) SetEndHeight(h uint64) {
 	state.EndHeight = h
@@ -434,18 +485,27 @@ func (state *StateAgentImpl) GetChainID() string {
 
 //SetPrivValidator set state a new PrivValidator
 func (state *StateAgentImpl) SetPrivValidator(priv PrivValidator) {
-	pp := (priv).(*privValidator)
-	state.Priv = pp
+	state.Priv = priv
 }
 
 //UpdateValidator set new Validators when committee member was changed
 func (state *StateAgentImpl) UpdateValidator(vset *ValidatorSet, makeids bool) error {
 	state.Validators = vset
+	// the new members sign the blocks after the current one
+	from := state.GetLastBlockHeight() + 1
+	state.lock.Lock()
+	defer state.lock.Unlock()
+	if vset != nil {
+		for n := len(state.history); n > 0 && state.history[n-1].height >= from; n-- {
+			state.history = state.history[:n-1]
+		}
+		state.history = append(state.history, validatorsFrom{from, vset})
+		if len(state.history) > maxValidatorHistory {
+			state.history = state.history[len(state.history)-maxValidatorHistory:]
+		}
+	}
 	if makeids {
-		ids := state.Validators.MakeIDs()
-		state.lock.Lock()
-		defer state.lock.Unlock()
-		state.ids = ids
+		state.ids = state.Validators.MakeIDs()
 	}
 	return nil
 }
@@ -513,10 +573,10 @@ func (state *StateAgentImpl) ValidateBlock(block *ctypes.Block, result bool) (*K
 	tStart := time.Now()
 	sign, err := state.Agent.VerifyFastBlock(block, result)
 	metrics.MTime(metrics.VerifyFastBlockTime, time.Now().Sub(tStart))
-	log.Debug("VerifyFastBlockResult", "height", sign.FastHeight, "result", sign.Result, "err", err)
 	watch.EndWatch()
 	watch.Finish(block.NumberU64())
 	if sign != nil {
+		log.Debug("VerifyFastBlockResult", "height", sign.FastHeight, "result", sign.Result, "err", err)
 		return &KeepBlockSign{
 			Result: uint(sign.Result),
 			Sign:   sign.Sign,
@@ -531,6 +591,21 @@ func (state *StateAgentImpl) GetValidator() *ValidatorSet {
 	return state.Validators
 }
 
+// GetValidatorAt returns the validators which committed the block at height.
+func (state *StateAgentImpl) GetValidatorAt(height uint64) (*ValidatorSet, error) {
+	if height < state.BeginHeight || (state.EndHeight > 0 && height > state.EndHeight) {
+		return nil, ErrHeightOutOfCommittee
+	}
+	state.lock.Lock()
+	defer state.lock.Unlock()
+	for i := len(state.history) - 1; i >= 0; i-- {
+		if state.history[i].height <= height {
+			return state.history[i].vals, nil
+		}
+	}
+	return nil, fmt.Errorf("no validators kept for height %d", height)
+}
+
 //GetLastValidator is get state's Validators
 func (state *StateAgentImpl) GetLastValidator() *ValidatorSet {
 	return state.Validators
@@ -549,11 +624,17 @@ func (state *StateAgentImpl) GetLastBlockHeight() uint64 {
 
 //GetAddress get priv_validator's address
 func (state *StateAgentImpl) GetAddress() help.Address {
+	if state.Priv == nil {
+		return nil
+	}
 	return state.Priv.GetAddress()
 }
 
 //GetPubKey get priv_validator's public key
 func (state *StateAgentImpl) GetPubKey() tcrypto.PubKey {
+	if state.Priv == nil {
+		return nil
+	}
 	return state.Priv.GetPubKey()
 }
 
@@ -564,7 +645,7 @@ func (state *StateAgentImpl) SignVote(chainID string, vote *Vote) error {
 
 //SignProposal sign of proposal msg
 func (state *StateAgentImpl) SignProposal(chainID string, proposal *Proposal) error {
-	return state.Priv.signProposal(chainID, proposal)
+	return state.Priv.SignProposal(chainID, proposal)
 }
 
 //Broadcast is agent Broadcast block
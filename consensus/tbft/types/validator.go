@@ -28,6 +28,17 @@ func NewValidator(pubKey crypto.PubKey, votingPower int64) *Validator {
 	}
 }
 
+//NewBLSValidator is return a new Validator voting with a BLS key, it keeps the
+//address of its committee key so the signs of its headers stay attributable
+func NewBLSValidator(address help.Address, pubKey crypto.PubKeyBLS, votingPower int64) *Validator {
+	return &Validator{
+		Address:     address,
+		PubKey:      pubKey,
+		VotingPower: votingPower,
+		Accum:       0,
+	}
+}
+
 // Copy Creates a new copy of the validator so we can mutate accum.
 // Panics if the validator is nil.
 func (v *Validator) Copy() *Validator {
@@ -102,6 +102,29 @@ func (valSet *ValidatorSet) Copy() *ValidatorSet {
 	}
 }
 
+// CarryAccum takes over the accums of the validators also present in prev, so
+// a committee switch changing members or voting powers does not restart the
+// proposer rotation. The accums are then centered around zero, which keeps
+// validators joining with a zero accum from jumping ahead of the others.
+func (valSet *ValidatorSet) CarryAccum(prev *ValidatorSet) {
+	if prev == nil || len(valSet.Validators) == 0 {
+		return
+	}
+	var sum int64
+	for _, val := range valSet.Validators {
+		val.Accum = 0
+		if _, old := prev.GetByAddress(val.Address); old != nil {
+			val.Accum = old.Accum
+		}
+		sum = safeAddClip(sum, val.Accum)
+	}
+	avg := sum / int64(len(valSet.Validators))
+	for _, val := range valSet.Validators {
+		val.Accum = safeSubClip(val.Accum, avg)
+	}
+	valSet.Proposer = valSet.findProposer()
+}
+
 // HasAddress returns true if address given is in the validator set, false -
 // otherwise.
 func (valSet *ValidatorSet) HasAddress(address []byte) bool {
@@ -294,6 +317,7 @@ func (valSet *ValidatorSet) VerifyCommit(chainID string, blockID BlockID, height
 
 	talliedVotingPower := int64(0)
 	round := commit.Round()
+	aggregate := valSet.IsBLS()
 
 	for idx, precommit := range commit.Precommits {
 		// may be nil if validator skipped.
@@ -309,9 +333,12 @@ func (valSet *ValidatorSet) VerifyCommit(chainID string, blockID BlockID, height
 		if precommit.Type != VoteTypePrecommit {
 			return fmt.Errorf("invalid commit -- not precommit @ index %v", idx)
 		}
+		if aggregate {
+			continue // the signatures are checked at once below
+		}
 		_, val := valSet.GetByIndex(uint(idx))
 		// Validate signature
-		precommitSignBytes := precommit.SignBytes(chainID)
+		precommitSignBytes := precommit.signBytesFor(chainID, val.PubKey)
 		if !val.PubKey.VerifyBytes(precommitSignBytes, precommit.Signature) {
 			return fmt.Errorf("invalid commit -- invalid signature: %v", precommit)
 		}
@@ -323,6 +350,13 @@ func (valSet *ValidatorSet) VerifyCommit(chainID string, blockID BlockID, height
 			talliedVotingPower += val.VotingPower
 		}
 	}
+	if aggregate {
+		agg, err := commit.Aggregate()
+		if err != nil {
+			return fmt.Errorf("invalid commit -- %v", err)
+		}
+		return valSet.VerifyAggregateCommit(chainID, blockID, height, agg)
+	}
 
 	if talliedVotingPower > valSet.TotalVotingPower()*2/3 {
 		return nil
@@ -331,6 +365,64 @@ func (valSet *ValidatorSet) VerifyCommit(chainID string, blockID BlockID, height
 		talliedVotingPower, valSet.TotalVotingPower()*2/3+1)
 }
 
+//IsBLS returns true if every validator of the set votes with a BLS key, so the
+//commits of the set can be aggregated
+func (valSet *ValidatorSet) IsBLS() bool {
+	for _, val := range valSet.Validators {
+		if _, ok := val.PubKey.(crypto.PubKeyBLS); !ok {
+			return false
+		}
+	}
+	return len(valSet.Validators) > 0
+}
+
+//VerifyAggregateCommit Verify that +2/3 of the set had signed the aggregate commit,
+//every signer must be a BLS validator
+func (valSet *ValidatorSet) VerifyAggregateCommit(chainID string, blockID BlockID, height uint64, commit *AggregateCommit) error {
+	if commit.Signers == nil || valSet.Size() != commit.Signers.Size() {
+		return fmt.Errorf("invalid commit -- wrong set size: %v vs %v", valSet.Size(), commit.Signers.Size())
+	}
+	if height != commit.Height {
+		return fmt.Errorf("invalid commit -- wrong height: %v vs %v", height, commit.Height)
+	}
+	if !blockID.Equals(commit.BlockID) {
+		return fmt.Errorf("invalid commit -- wrong block id: %v vs %v", blockID, commit.BlockID)
+	}
+	var (
+		pubs               []crypto.PubKeyBLS
+		talliedVotingPower int64
+	)
+	for idx, val := range valSet.Validators {
+		if !commit.Signers.GetIndex(uint(idx)) {
+			continue
+		}
+		pub, ok := val.PubKey.(crypto.PubKeyBLS)
+		if !ok {
+			return fmt.Errorf("invalid commit -- validator %v has no bls key", val.Address)
+		}
+		pubs = append(pubs, pub)
+		talliedVotingPower += val.VotingPower
+	}
+	if !crypto.VerifyAggregateBLS(pubs, commit.SignBytes(chainID), commit.Signature) {
+		return fmt.Errorf("invalid commit -- invalid aggregate signature")
+	}
+	if talliedVotingPower > valSet.TotalVotingPower()*2/3 {
+		return nil
+	}
+	return fmt.Errorf("invalid commit -- insufficient voting power: got %v, needed %v",
+		talliedVotingPower, valSet.TotalVotingPower()*2/3+1)
+}
+
+//VerifyHeader Verify that +2/3 of the set had signed the given header, the signs
+//are the ones attached to the header when the block was committed
+func (valSet *ValidatorSet) VerifyHeader(header *ctypes.Header) error {
+	powers := make(map[common.Address]int64, len(valSet.Validators))
+	for _, val := range valSet.Validators {
+		powers[common.BytesToAddress(val.Address)] = val.VotingPower
+	}
+	return ctypes.VerifySigns(header, powers, valSet.TotalVotingPower())
+}
+
 // VerifyCommitAny will check to see if the set would
 // be valid with a different validator set.
 //
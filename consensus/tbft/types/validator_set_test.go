@@ -0,0 +1,91 @@
+package types
+
+import (
+	"testing"
+
+	tcrypto "ethereum/rpc-network/consensus/tbft/crypto"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func newTestValidators(t *testing.T, powers ...int64) []*Validator {
+	vals := make([]*Validator, len(powers))
+	for i, power := range powers {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		vals[i] = NewValidator(tcrypto.PubKeyTrue(key.PublicKey), power)
+	}
+	return vals
+}
+
+// proposerCounts advances the set by rounds and counts the proposals of every validator.
+func proposerCounts(valSet *ValidatorSet, rounds int) map[string]int64 {
+	counts := make(map[string]int64)
+	for i := 0; i < rounds; i++ {
+		counts[string(valSet.GetProposer().Address)]++
+		valSet.IncrementAccum(1)
+	}
+	return counts
+}
+
+// checkProposerCounts checks that every validator proposes in proportion to its
+// power, off by at most slack proposals.
+func checkProposerCounts(t *testing.T, valSet *ValidatorSet, cycles, slack int64) {
+	counts := proposerCounts(valSet, int(cycles*valSet.TotalVotingPower()))
+	for _, val := range valSet.Validators {
+		if have, want := counts[string(val.Address)], cycles*val.VotingPower; have < want-slack || have > want+slack {
+			t.Errorf("validator %x with power %d: proposed %d times, want %d", val.Address, val.VotingPower, have, want)
+		}
+	}
+}
+
+func TestProposerFrequency(t *testing.T) {
+	tests := [][]int64{
+		{1, 1, 1, 1},
+		{1, 2, 3, 4},
+		{1, 1, 1, 10},
+		{7, 3},
+		{100, 1, 1},
+	}
+	for _, powers := range tests {
+		checkProposerCounts(t, NewValidatorSet(newTestValidators(t, powers...)), 10, 0)
+	}
+}
+
+func TestProposerFrequencyAfterSwitch(t *testing.T) {
+	vals := newTestValidators(t, 1, 2, 3, 4)
+	valSet := NewValidatorSet(vals)
+	proposerCounts(valSet, 7)
+
+	// the heaviest validator is replaced and another one changes its power
+	next := []*Validator{vals[0].Copy(), vals[1].Copy(), vals[2].Copy(), newTestValidators(t, 5)[0]}
+	next[1].VotingPower = 6
+	nextSet := NewValidatorSet(next)
+	nextSet.CarryAccum(valSet)
+
+	// every validator is shifted by the same amount, the ones joining from zero
+	var sum int64
+	shift := nextSet.Validators[0].Accum - accumOf(valSet, nextSet.Validators[0])
+	for _, val := range nextSet.Validators {
+		if have := val.Accum - accumOf(valSet, val); have != shift {
+			t.Fatalf("validator %x accum shifted by %d, want %d", val.Address, have, shift)
+		}
+		sum += val.Accum
+	}
+	if sum <= -int64(nextSet.Size()) || sum >= int64(nextSet.Size()) {
+		t.Fatalf("accums not centered: sum %d", sum)
+	}
+	if nextSet.GetProposer() == nil {
+		t.Fatal("no proposer after switch")
+	}
+	checkProposerCounts(t, nextSet, 10, 1)
+}
+
+// accumOf returns the accum val had in valSet, zero if it was not a member.
+func accumOf(valSet *ValidatorSet, val *Validator) int64 {
+	if _, old := valSet.GetByAddress(val.Address); old != nil {
+		return old.Accum
+	}
+	return 0
+}
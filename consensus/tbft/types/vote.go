@@ -71,6 +71,28 @@ func (vote *Vote) SignBytes(chainID string) []byte {
 	return signBytes[:]
 }
 
+//AggregateSignBytes is the message signed by BLS validators. It leaves out the
+//timestamp and covers the result, so all agreeing precommits of a commit sign
+//the same message and their signatures can be aggregated
+func (vote *Vote) AggregateSignBytes(chainID string) []byte {
+	canonical := CanonicalVote(chainID, vote)
+	canonical.Timestamp = ""
+	bz, err := cdc.MarshalJSON(canonical)
+	if err != nil {
+		panic(err)
+	}
+	signBytes := help.RlpHash([]interface{}{bz, vote.Result})
+	return signBytes[:]
+}
+
+//signBytesFor return the message the given key signs for the vote
+func (vote *Vote) signBytesFor(chainID string, pubKey crypto.PubKey) []byte {
+	if _, ok := pubKey.(crypto.PubKeyBLS); ok {
+		return vote.AggregateSignBytes(chainID)
+	}
+	return vote.SignBytes(chainID)
+}
+
 // Copy return a vote Copy
 func (vote *Vote) Copy() *Vote {
 	voteCopy := *vote
@@ -108,11 +130,13 @@ func (vote *Vote) String() string {
 
 //Verify is Verify Signature and ValidatorAddress
 func (vote *Vote) Verify(chainID string, pubKey crypto.PubKey) error {
-	if !bytes.Equal(pubKey.Address(), vote.ValidatorAddress) {
+	// BLS validators keep the address of their committee key, the vote set
+	// checks it against the validator
+	if _, bls := pubKey.(crypto.PubKeyBLS); !bls && !bytes.Equal(pubKey.Address(), vote.ValidatorAddress) {
 		return ErrVoteInvalidValidatorAddress
 	}
 
-	if !pubKey.VerifyBytes(vote.SignBytes(chainID), vote.Signature) {
+	if !pubKey.VerifyBytes(vote.signBytesFor(chainID, pubKey), vote.Signature) {
 		return ErrVoteInvalidSignature
 	}
 	return nil
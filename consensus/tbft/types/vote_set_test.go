@@ -0,0 +1,179 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	tcrypto "ethereum/rpc-network/consensus/tbft/crypto"
+	"ethereum/rpc-network/consensus/tbft/help"
+	ctypes "ethereum/rpc-network/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const testChainID = "tbft-test"
+
+// newBLSValidators returns a set of validators voting with BLS keys and their
+// signers, in the order of the set.
+func newBLSValidators(t *testing.T, n int) (*ValidatorSet, []PrivValidator) {
+	vals := make([]*Validator, n)
+	privs := make(map[string]PrivValidator, n)
+	for i := range vals {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr := tcrypto.PubKeyTrue(key.PublicKey).Address()
+		bls := tcrypto.GenPrivKeyBLS()
+		vals[i] = NewBLSValidator(addr, bls.PubKey().(tcrypto.PubKeyBLS), 1)
+		privs[string(addr)] = NewBLSPrivValidator(addr, bls)
+	}
+	valSet := NewValidatorSet(vals)
+	signers := make([]PrivValidator, n)
+	for i, val := range valSet.Validators {
+		signers[i] = privs[string(val.Address)]
+	}
+	return valSet, signers
+}
+
+func testBlockID() BlockID {
+	return BlockID{
+		Hash:        help.HexBytes(crypto.Keccak256([]byte("block"))),
+		PartsHeader: PartSetHeader{Total: 1, Hash: help.HexBytes(crypto.Keccak256([]byte("parts")))},
+	}
+}
+
+func signTestVote(t *testing.T, signer PrivValidator, index int, height uint64, blockID BlockID) *Vote {
+	vote := &Vote{
+		ValidatorAddress: signer.GetAddress(),
+		ValidatorIndex:   uint(index),
+		Height:           height,
+		Round:            0,
+		Result:           ctypes.VoteAgree,
+		Timestamp:        time.Now().Add(time.Duration(index) * time.Millisecond),
+		Type:             VoteTypePrecommit,
+		BlockID:          blockID,
+	}
+	if err := signer.SignVote(testChainID, vote); err != nil {
+		t.Fatal(err)
+	}
+	return vote
+}
+
+// makeBLSCommit collects the precommits of the first n signers into a commit.
+func makeBLSCommit(t *testing.T, valSet *ValidatorSet, signers []PrivValidator, n int, blockID BlockID) *Commit {
+	voteSet := NewVoteSet(testChainID, 1, 0, VoteTypePrecommit, valSet)
+	for i := 0; i < n; i++ {
+		if _, err := voteSet.AddVote(signTestVote(t, signers[i], i, 1, blockID)); err != nil {
+			t.Fatalf("vote %d rejected: %v", i, err)
+		}
+	}
+	if !voteSet.HasTwoThirdsMajority() {
+		t.Fatal("no +2/3 majority")
+	}
+	return voteSet.MakeCommit()
+}
+
+func TestVoteSetBLS(t *testing.T) {
+	valSet, signers := newBLSValidators(t, 4)
+	blockID := testBlockID()
+	voteSet := NewVoteSet(testChainID, 1, 0, VoteTypePrecommit, valSet)
+
+	// votes carry the committee address of their validator
+	vote := signTestVote(t, signers[0], 0, 1, blockID)
+	if _, err := voteSet.AddVote(vote); err != nil {
+		t.Fatalf("valid vote rejected: %v", err)
+	}
+	// a vote signed by the key of another validator is rejected
+	forged := signTestVote(t, signers[2], 1, 1, blockID)
+	forged.ValidatorAddress = signers[1].GetAddress()
+	if _, err := voteSet.AddVote(forged); err == nil {
+		t.Fatal("vote with foreign signature added")
+	}
+	// and so is a vote claiming the index of another validator
+	misplaced := signTestVote(t, signers[3], 1, 1, blockID)
+	if _, err := voteSet.AddVote(misplaced); err == nil {
+		t.Fatal("vote with wrong index added")
+	}
+	for i := 1; i < 3; i++ {
+		if _, err := voteSet.AddVote(signTestVote(t, signers[i], i, 1, blockID)); err != nil {
+			t.Fatalf("vote %d rejected: %v", i, err)
+		}
+	}
+	if maj, ok := voteSet.TwoThirdsMajority(); !ok || !maj.Equals(blockID) {
+		t.Fatal("no +2/3 majority for the block")
+	}
+}
+
+func TestCommitAggregate(t *testing.T) {
+	valSet, signers := newBLSValidators(t, 4)
+	blockID := testBlockID()
+	commit := makeBLSCommit(t, valSet, signers, 3, blockID)
+
+	agg, err := commit.Aggregate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if agg.Height != 1 || agg.Round != 0 || !agg.BlockID.Equals(blockID) {
+		t.Fatalf("wrong aggregate commit: %+v", agg)
+	}
+	for i := 0; i < 4; i++ {
+		if have, want := agg.Signers.GetIndex(uint(i)), i < 3; have != want {
+			t.Errorf("signer %d: have %v, want %v", i, have, want)
+		}
+	}
+	if len(agg.Signature) != tcrypto.SignatureBLSSize {
+		t.Fatalf("aggregate signature size %d", len(agg.Signature))
+	}
+	if _, err := (&Commit{BlockID: blockID, Precommits: make([]*Vote, 4)}).Aggregate(); err == nil {
+		t.Fatal("commit without precommits aggregated")
+	}
+}
+
+func TestVerifyAggregateCommit(t *testing.T) {
+	valSet, signers := newBLSValidators(t, 4)
+	blockID := testBlockID()
+	commit := makeBLSCommit(t, valSet, signers, 3, blockID)
+	agg, err := commit.Aggregate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := valSet.VerifyAggregateCommit(testChainID, blockID, 1, agg); err != nil {
+		t.Fatalf("valid aggregate rejected: %v", err)
+	}
+	// the commit of the set is checked through the aggregate
+	if !valSet.IsBLS() {
+		t.Fatal("set not recognized as BLS")
+	}
+	if err := valSet.VerifyCommit(testChainID, blockID, 1, commit); err != nil {
+		t.Fatalf("valid commit rejected: %v", err)
+	}
+
+	if err := valSet.VerifyAggregateCommit(testChainID, blockID, 2, agg); err == nil {
+		t.Error("aggregate verified at another height")
+	}
+	other := testBlockID()
+	other.Hash = help.HexBytes(crypto.Keccak256([]byte("other")))
+	if err := valSet.VerifyAggregateCommit(testChainID, other, 1, agg); err == nil {
+		t.Error("aggregate verified for another block")
+	}
+	// claiming a signer that didn't sign breaks the signature
+	claimed := *agg
+	claimed.Signers = agg.Signers.Copy()
+	claimed.Signers.SetIndex(3, true)
+	if err := valSet.VerifyAggregateCommit(testChainID, blockID, 1, &claimed); err == nil {
+		t.Error("aggregate verified with a signer added")
+	}
+	// dropping one leaves it short of the signature and of +2/3
+	dropped := *agg
+	dropped.Signers = agg.Signers.Copy()
+	dropped.Signers.SetIndex(0, false)
+	if err := valSet.VerifyAggregateCommit(testChainID, blockID, 1, &dropped); err == nil {
+		t.Error("aggregate verified with a signer removed")
+	}
+	// an aggregate of only two signers doesn't reach +2/3
+	short := makeBLSCommit(t, valSet, signers, 3, blockID)
+	short.Precommits[2] = nil
+	if err := valSet.VerifyCommit(testChainID, blockID, 1, short); err == nil {
+		t.Error("commit without +2/3 verified")
+	}
+}
@@ -9,6 +9,7 @@ var cdc = amino.NewCodec()
 
 func init() {
 	RegisterConsensusMessages(cdc)
+	RegisterBlockSyncMessages(cdc)
 	// RegisterWALMessages(cdc)
 	types.RegisterBlockAmino(cdc)
 }
@@ -85,6 +85,10 @@ type Header struct {
 	Extra       []byte         `json:"extraData"        gencodec:"required"`
 	MixDigest   common.Hash    `json:"mixHash"`
 	Nonce       BlockNonce     `json:"nonce"`
+
+	// Signs are the committee signatures finalizing the block. They are appended
+	// to the legacy encoding and left out of the hash the committee signs.
+	Signs []*PbftSign `json:"signs,omitempty" rlp:"tail"`
 }
 
 // field type overrides for gencodec
@@ -99,8 +103,13 @@ type headerMarshaling struct {
 }
 
 // Hash returns the block hash of the header, which is simply the keccak256 hash of its
-// RLP encoding.
+// RLP encoding without the committee signs.
 func (h *Header) Hash() common.Hash {
+	if len(h.Signs) > 0 {
+		cpy := *h
+		cpy.Signs = nil
+		return rlpHash(&cpy)
+	}
 	return rlpHash(h)
 }
 
@@ -171,6 +180,9 @@ type Block struct {
 	uncles       []*Header
 	transactions Transactions
 
+	// infos are the committee member changes the block proposes to tbft.
+	infos []*CommitteeMember
+
 	// caches
 	hash atomic.Value
 	size atomic.Value
@@ -203,6 +215,7 @@ type extblock struct {
 	Header *Header
 	Txs    []*Transaction
 	Uncles []*Header
+	Infos  []*CommitteeMember `rlp:"tail"`
 }
 
 // [deprecated by eth/63]
@@ -274,6 +287,10 @@ func CopyHeader(h *Header) *Header {
 		cpy.Extra = make([]byte, len(h.Extra))
 		copy(cpy.Extra, h.Extra)
 	}
+	if len(h.Signs) > 0 {
+		cpy.Signs = make([]*PbftSign, len(h.Signs))
+		copy(cpy.Signs, h.Signs)
+	}
 	return &cpy
 }
 
@@ -284,7 +301,7 @@ func (b *Block) DecodeRLP(s *rlp.Stream) error {
 	if err := s.Decode(&eb); err != nil {
 		return err
 	}
-	b.header, b.uncles, b.transactions = eb.Header, eb.Uncles, eb.Txs
+	b.header, b.uncles, b.transactions, b.infos = eb.Header, eb.Uncles, eb.Txs, eb.Infos
 	b.size.Store(common.StorageSize(rlp.ListSize(size)))
 	return nil
 }
@@ -295,6 +312,7 @@ func (b *Block) EncodeRLP(w io.Writer) error {
 		Header: b.header,
 		Txs:    b.transactions,
 		Uncles: b.uncles,
+		Infos:  b.infos,
 	})
 }
 
@@ -313,6 +331,13 @@ func (b *StorageBlock) DecodeRLP(s *rlp.Stream) error {
 func (b *Block) Uncles() []*Header          { return b.uncles }
 func (b *Block) Transactions() Transactions { return b.transactions }
 
+// SwitchInfos returns the committee member changes proposed by the block.
+func (b *Block) SwitchInfos() []*CommitteeMember { return b.infos }
+
+// IsProposal reports whether the block proposes a committee change, in which
+// case tbft shouldn't hold it back waiting for transactions.
+func (b *Block) IsProposal() bool { return len(b.infos) > 0 }
+
 func (b *Block) Transaction(hash common.Hash) *Transaction {
 	for _, transaction := range b.transactions {
 		if transaction.Hash() == hash {
@@ -342,6 +367,23 @@ func (b *Block) Extra() []byte            { return common.CopyBytes(b.header.Ext
 
 func (b *Block) Header() *Header { return CopyHeader(b.header) }
 
+// Signs returns the committee signatures finalizing the block.
+func (b *Block) Signs() []*PbftSign { return b.header.Signs }
+
+// SetSign attaches the committee signatures finalizing the block. The block hash
+// is not affected.
+func (b *Block) SetSign(signs []*PbftSign) {
+	header := CopyHeader(b.header)
+	header.Signs = signs
+	b.header = header
+
+	if size := b.size.Load(); size != nil {
+		c := writeCounter(0)
+		rlp.Encode(&c, b)
+		b.size.Store(common.StorageSize(c))
+	}
+}
+
 // Body returns the non-header content of the block.
 func (b *Block) Body() *Body { return &Body{b.transactions, b.uncles} }
 
@@ -386,6 +428,7 @@ func (b *Block) WithSeal(header *Header) *Block {
 		header:       &cpy,
 		transactions: b.transactions,
 		uncles:       b.uncles,
+		infos:        b.infos,
 	}
 }
 
@@ -395,6 +438,7 @@ func (b *Block) WithBody(transactions []*Transaction, uncles []*Header) *Block {
 		header:       CopyHeader(b.header),
 		transactions: make([]*Transaction, len(transactions)),
 		uncles:       make([]*Header, len(uncles)),
+		infos:        b.infos,
 	}
 	copy(block.transactions, transactions)
 	for i := range uncles {
@@ -403,6 +447,19 @@ func (b *Block) WithBody(transactions []*Transaction, uncles []*Header) *Block {
 	return block
 }
 
+// WithSwitchInfos returns a new block with the data from b and the given
+// committee member changes.
+func (b *Block) WithSwitchInfos(infos []*CommitteeMember) *Block {
+	block := &Block{
+		header:       b.header,
+		transactions: b.transactions,
+		uncles:       b.uncles,
+		infos:        make([]*CommitteeMember, len(infos)),
+	}
+	copy(block.infos, infos)
+	return block
+}
+
 // Hash returns the keccak256 hash of b's header.
 // The hash is computed on the first call and cached thereafter.
 func (b *Block) Hash() common.Hash {
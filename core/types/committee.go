@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/ecdsa"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/big"
@@ -63,6 +64,11 @@ const (
 	SDownloaderPartCall
 )
 
+var (
+	// ErrHeightNotYet is returned when a proposed block is ahead of the local chain.
+	ErrHeightNotYet = errors.New("wait for last block arrived")
+)
+
 //CommitteeMembers committee members
 type CommitteeMembers []*CommitteeMember
 
@@ -72,8 +78,15 @@ type CommitteeMember struct {
 	Publickey     []byte
 	Flag          uint32
 	MType         uint32
+	VotingPower   uint64 // stake weight in the tbft committee, 0 counts as 1
+	BLSPublickey  []byte // tbft vote key, the member votes with Publickey if empty
+	BLSPossession []byte // proof of possession of BLSPublickey
 }
 
+// MaxMemberPower caps the voting power of a single member, so the total power
+// of a committee and the proposer accums built from it can't overflow an int64.
+const MaxMemberPower = 1 << 50
+
 // ElectionCommittee defines election members result
 type ElectionCommittee struct {
 	Members []*CommitteeMember
@@ -90,16 +103,29 @@ func NewCommitteeMember(coinBase common.Address, publicKey []byte, flag, mType u
 	}
 }
 
+// Power returns the voting power of the member in the tbft committee. Members
+// carrying no stake count as one, the power is capped at MaxMemberPower.
+func (c *CommitteeMember) Power() int64 {
+	switch {
+	case c.VotingPower == 0:
+		return 1
+	case c.VotingPower > MaxMemberPower:
+		return MaxMemberPower
+	}
+	return int64(c.VotingPower)
+}
+
 func (c *CommitteeMember) Compared(d *CommitteeMember) bool {
-	if c.MType == d.MType && c.Coinbase == d.Coinbase && c.CommitteeBase == d.CommitteeBase && bytes.Equal(c.Publickey, d.Publickey) {
+	if c.MType == d.MType && c.Coinbase == d.Coinbase && c.CommitteeBase == d.CommitteeBase && bytes.Equal(c.Publickey, d.Publickey) &&
+		bytes.Equal(c.BLSPublickey, d.BLSPublickey) {
 		return true
 	}
 	return false
 }
 
 func (c *CommitteeMember) String() string {
-	return fmt.Sprintf("F:%d,T:%d,C:%s,P:%s,A:%s", c.Flag, c.MType, hexutil.Encode(c.Coinbase[:]),
-		hexutil.Encode(c.Publickey), hexutil.Encode(c.CommitteeBase[:]))
+	return fmt.Sprintf("F:%d,T:%d,C:%s,P:%s,A:%s,V:%d", c.Flag, c.MType, hexutil.Encode(c.Coinbase[:]),
+		hexutil.Encode(c.Publickey), hexutil.Encode(c.CommitteeBase[:]), c.Power())
 }
 
 func (c *CommitteeMember) UnmarshalJSON(input []byte) error {
@@ -108,6 +134,9 @@ func (c *CommitteeMember) UnmarshalJSON(input []byte) error {
 		PubKey  *hexutil.Bytes `json:"publickey,omitempty"`
 		Flag    uint32         `json:"flag,omitempty"`
 		MType   uint32         `json:"mType,omitempty"`
+		Power   uint64         `json:"power,omitempty"`
+		BLSKey  *hexutil.Bytes `json:"blsPublickey,omitempty"`
+		BLSPoP  *hexutil.Bytes `json:"blsPossession,omitempty"`
 	}
 	var dec committee
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -117,9 +146,16 @@ func (c *CommitteeMember) UnmarshalJSON(input []byte) error {
 	c.Coinbase = dec.Address
 	c.Flag = dec.Flag
 	c.MType = dec.MType
+	c.VotingPower = dec.Power
 	if dec.PubKey != nil {
 		c.Publickey = *dec.PubKey
 	}
+	if dec.BLSKey != nil {
+		c.BLSPublickey = *dec.BLSKey
+	}
+	if dec.BLSPoP != nil {
+		c.BLSPossession = *dec.BLSPoP
+	}
 	/*var err error
 	if dec.PubKey != nil {
 		_, err = crypto.UnmarshalPubkey(*dec.PubKey)
@@ -173,10 +209,10 @@ type PbftSigns []*PbftSign
 
 //go:generate gencodec -type PbftSign -field-override pbftSignMarshaling -out gen_pbftSign_json.go
 type PbftSign struct {
-	FastHeight *big.Int
-	FastHash   common.Hash // fastblock hash
-	Result     uint32      // 0--against,1--agree
-	Sign       []byte      // sign for fastblock height + hash + result
+	FastHeight *big.Int    `json:"fastHeight"`
+	FastHash   common.Hash `json:"fastHash"` // fastblock hash
+	Result     uint32      `json:"result"`   // 0--against,1--agree
+	Sign       []byte      `json:"sign"`     // sign for fastblock height + hash + result
 
 	// caches
 	size atomic.Value
@@ -252,6 +288,48 @@ func (h *PbftSign) HashWithNoSign() common.Hash {
 	})
 }
 
+// Signer recovers the address of the committee member that produced the sign.
+func (h *PbftSign) Signer() (common.Address, error) {
+	pub, err := crypto.SigToPub(h.HashWithNoSign().Bytes(), h.Sign)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// VerifySigns checks that the header carries agree signs from members holding
+// more than two thirds of the total power. Signs of unknown members are rejected,
+// a member signing twice is only counted once.
+func VerifySigns(header *Header, powers map[common.Address]int64, total int64) error {
+	var (
+		hash   = header.Hash()
+		signed = make(map[common.Address]bool)
+		tally  int64
+	)
+	for i, sign := range header.Signs {
+		if sign == nil || sign.FastHash != hash || sign.FastHeight == nil || sign.FastHeight.Cmp(header.Number) != 0 {
+			return fmt.Errorf("invalid signs -- sign %d is not for block %d (%x)", i, header.Number, hash)
+		}
+		signer, err := sign.Signer()
+		if err != nil {
+			return fmt.Errorf("invalid signs -- sign %d: %v", i, err)
+		}
+		power, ok := powers[signer]
+		if !ok {
+			return fmt.Errorf("invalid signs -- unknown signer %x", signer)
+		}
+		if sign.Result != VoteAgree || signed[signer] {
+			continue
+		}
+		signed[signer] = true
+		tally += power
+	}
+	if tally > total*2/3 {
+		return nil
+	}
+	return fmt.Errorf("invalid signs -- insufficient voting power: got %v, needed %v", tally, total*2/3+1)
+}
+
 type CommitteeInfo struct {
 	Id          *big.Int
 	StartHeight *big.Int
@@ -272,6 +350,42 @@ func (c *CommitteeInfo) GetAllMembers() []*CommitteeMember {
 	return members
 }
 
+// VotingMembers returns the working members of the committee, the ones voting in
+// tbft. Members registering a BLS key vote with it, they are left out if verifyBLS
+// rejects its proof of possession.
+func (c *CommitteeInfo) VotingMembers(verifyBLS func(pub, proof []byte) error) []*CommitteeMember {
+	var members []*CommitteeMember
+	for _, m := range c.GetAllMembers() {
+		if m.Flag != StateUsedFlag {
+			continue
+		}
+		if len(m.BLSPublickey) > 0 && verifyBLS(m.BLSPublickey, m.BLSPossession) != nil {
+			continue
+		}
+		members = append(members, m)
+	}
+	return members
+}
+
+// VerifyHeader checks that the header was finalized by the voting members of the
+// committee, so a client knowing the committee can follow the chain without
+// executing the blocks. verifyBLS checks the BLS keys like for VotingMembers.
+func (c *CommitteeInfo) VerifyHeader(header *Header, verifyBLS func(pub, proof []byte) error) error {
+	var (
+		powers = make(map[common.Address]int64)
+		total  int64
+	)
+	for _, m := range c.VotingMembers(verifyBLS) {
+		pub, err := crypto.UnmarshalPubkey(m.Publickey)
+		if err != nil {
+			return err
+		}
+		powers[crypto.PubkeyToAddress(*pub)] = m.Power()
+		total += m.Power()
+	}
+	return VerifySigns(header, powers, total)
+}
+
 func (c *CommitteeInfo) String() string {
 	if c.Members != nil {
 		memStrings := make([]string, len(c.Members))
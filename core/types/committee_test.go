@@ -0,0 +1,196 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func newTestCommittee(t *testing.T, n int) (*CommitteeInfo, []*ecdsa.PrivateKey) {
+	committee := &CommitteeInfo{Id: common.Big1, StartHeight: common.Big0}
+	keys := make([]*ecdsa.PrivateKey, n)
+	for i := 0; i < n; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = key
+		committee.Members = append(committee.Members,
+			NewCommitteeMember(common.Address{}, crypto.FromECDSAPub(&key.PublicKey), StateUsedFlag, TypeWorked))
+	}
+	return committee, keys
+}
+
+func signHeader(t *testing.T, header *Header, result uint32, keys ...*ecdsa.PrivateKey) {
+	for _, key := range keys {
+		sign := &PbftSign{FastHeight: header.Number, FastHash: header.Hash(), Result: result}
+		var err error
+		if sign.Sign, err = crypto.Sign(sign.HashWithNoSign().Bytes(), key); err != nil {
+			t.Fatal(err)
+		}
+		header.Signs = append(header.Signs, sign)
+	}
+}
+
+func TestHeaderSignsEncoding(t *testing.T) {
+	_, keys := newTestCommittee(t, 2)
+	header := &Header{Number: big.NewInt(7), Difficulty: common.Big1, Extra: []byte("tbft")}
+
+	legacy, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := header.Hash()
+	signHeader(t, header, VoteAgree, keys...)
+
+	if header.Hash() != hash {
+		t.Fatalf("signs changed the header hash: have %x, want %x", header.Hash(), hash)
+	}
+	enc, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dec Header
+	if err := rlp.DecodeBytes(enc, &dec); err != nil {
+		t.Fatal("decode error: ", err)
+	}
+	if dec.Hash() != hash || len(dec.Signs) != len(keys) || !bytes.Equal(dec.Signs[1].Sign, header.Signs[1].Sign) {
+		t.Fatalf("signed header mismatch after RLP round trip")
+	}
+	// Headers without signs must decode from and into the legacy encoding
+	var old Header
+	if err := rlp.DecodeBytes(legacy, &old); err != nil {
+		t.Fatal("legacy decode error: ", err)
+	}
+	if old.Hash() != hash || len(old.Signs) != 0 {
+		t.Fatalf("legacy header mismatch")
+	}
+	blob, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var jsonDec Header
+	if err := json.Unmarshal(blob, &jsonDec); err != nil {
+		t.Fatal(err)
+	}
+	if jsonDec.Hash() != hash || len(jsonDec.Signs) != len(keys) || jsonDec.Signs[0].FastHash != hash {
+		t.Fatalf("signed header mismatch after JSON round trip")
+	}
+}
+
+func TestCommitteeVerifyHeader(t *testing.T) {
+	committee, keys := newTestCommittee(t, 4)
+	outsider, _ := crypto.GenerateKey()
+
+	tests := []struct {
+		sign func(header *Header)
+		ok   bool
+	}{
+		// Three of four members agreed
+		{func(h *Header) { signHeader(t, h, VoteAgree, keys[:3]...) }, true},
+		// Only two of four, not more than two thirds
+		{func(h *Header) { signHeader(t, h, VoteAgree, keys[:2]...) }, false},
+		// A member signing twice is counted once
+		{func(h *Header) { signHeader(t, h, VoteAgree, keys[0], keys[0], keys[1]) }, false},
+		// Against votes don't count
+		{func(h *Header) {
+			signHeader(t, h, VoteAgree, keys[:2]...)
+			signHeader(t, h, VoteAgreeAgainst, keys[2])
+		}, false},
+		// Signs from outside the committee are rejected
+		{func(h *Header) { signHeader(t, h, VoteAgree, keys[0], keys[1], keys[2], outsider) }, false},
+		// Signs for another block are rejected
+		{func(h *Header) {
+			signHeader(t, h, VoteAgree, keys[:3]...)
+			h.Signs[0].FastHash = common.Hash{0x01}
+		}, false},
+	}
+	for i, tt := range tests {
+		header := &Header{Number: big.NewInt(int64(i + 1)), Difficulty: common.Big1}
+		tt.sign(header)
+		if err := committee.VerifyHeader(header, nil); (err == nil) != tt.ok {
+			t.Errorf("test %d: verification mismatch: err %v, want ok %v", i, err, tt.ok)
+		}
+	}
+}
+
+func TestCommitteeVerifyHeaderPower(t *testing.T) {
+	committee, keys := newTestCommittee(t, 4)
+	committee.Members[0].VotingPower = 7 // 7 of a total 10
+
+	tests := []struct {
+		signers []*ecdsa.PrivateKey
+		ok      bool
+	}{
+		// The heavy member and one more hold 8 of 10
+		{[]*ecdsa.PrivateKey{keys[0], keys[1]}, true},
+		// The heavy member alone holds 7 of 10, more than two thirds
+		{[]*ecdsa.PrivateKey{keys[0]}, true},
+		// Three of four members only hold 3 of 10
+		{keys[1:], false},
+	}
+	for i, tt := range tests {
+		header := &Header{Number: big.NewInt(int64(i + 1)), Difficulty: common.Big1}
+		signHeader(t, header, VoteAgree, tt.signers...)
+		if err := committee.VerifyHeader(header, nil); (err == nil) != tt.ok {
+			t.Errorf("test %d: verification mismatch: err %v, want ok %v", i, err, tt.ok)
+		}
+	}
+}
+
+func TestCommitteeVerifyHeaderBLS(t *testing.T) {
+	committee, keys := newTestCommittee(t, 4)
+	committee.Members[2].BLSPublickey, committee.Members[2].BLSPossession = []byte{0x01}, []byte("valid")
+	committee.Members[3].BLSPublickey, committee.Members[3].BLSPossession = []byte{0x02}, []byte("forged")
+	verifyBLS := func(pub, proof []byte) error {
+		if string(proof) != "valid" {
+			return errors.New("invalid proof of possession")
+		}
+		return nil
+	}
+	if members := committee.VotingMembers(verifyBLS); len(members) != 3 {
+		t.Fatalf("wrong number of voting members: have %d, want 3", len(members))
+	}
+
+	tests := []struct {
+		signers []*ecdsa.PrivateKey
+		ok      bool
+	}{
+		// The three voting members are a quorum on their own
+		{keys[:3], true},
+		// Two of the three voting members aren't
+		{keys[1:3], false},
+		// The member with the rejected key doesn't vote
+		{keys[1:], false},
+	}
+	for i, tt := range tests {
+		header := &Header{Number: big.NewInt(int64(i + 1)), Difficulty: common.Big1}
+		signHeader(t, header, VoteAgree, tt.signers...)
+		if err := committee.VerifyHeader(header, verifyBLS); (err == nil) != tt.ok {
+			t.Errorf("test %d: verification mismatch: err %v, want ok %v", i, err, tt.ok)
+		}
+	}
+}
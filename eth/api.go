@@ -277,13 +277,13 @@ func NewPublicDebugAPI(eth *Ethereum) *PublicDebugAPI {
 }
 
 // DumpBlock retrieves the entire state of the database at a given block.
-func (api *PublicDebugAPI) DumpBlock(blockNr rpc.BlockNumber) (state.Dump, error) {
+func (api *PublicDebugAPI) DumpBlock(blockNr rpc.BlockNumber) (rpc.Stream, error) {
 	if blockNr == rpc.PendingBlockNumber {
 		// If we're dumping the pending state, we need to request
 		// both the pending block as well as the pending state from
 		// the miner and operate on those
 		_, stateDb := api.eth.miner.Pending()
-		return stateDb.RawDump(false, false, true), nil
+		return streamDump(stateDb), nil
 	}
 	var block *types.Block
 	if blockNr == rpc.LatestBlockNumber {
@@ -292,13 +292,58 @@ func (api *PublicDebugAPI) DumpBlock(blockNr rpc.BlockNumber) (state.Dump, error
 		block = api.eth.blockchain.GetBlockByNumber(uint64(blockNr))
 	}
 	if block == nil {
-		return state.Dump{}, fmt.Errorf("block #%d not found", blockNr)
+		return nil, fmt.Errorf("block #%d not found", blockNr)
 	}
 	stateDb, err := api.eth.BlockChain().StateAt(block.Root())
 	if err != nil {
-		return state.Dump{}, err
+		return nil, err
+	}
+	return streamDump(stateDb), nil
+}
+
+// dumpPageSize is the number of accounts streamed between checks whether the
+// response has failed, which stops iterating the state trie.
+const dumpPageSize = 256
+
+// streamDump returns the dump of the entire state as a stream, in the format of
+// state.Dump. Accounts are written while the state trie is iterated.
+func streamDump(stateDb *state.StateDB) rpc.Stream {
+	return func(ctx context.Context, w *rpc.StreamWriter) error {
+		dumper := &streamDumper{w: w}
+		for start := []byte{}; start != nil; {
+			start = stateDb.DumpToCollector(dumper, false, false, true, start, dumpPageSize)
+			if err := w.Err(); err != nil {
+				return err
+			}
+		}
+		w.EndObject()
+		return w.EndObject()
 	}
-	return stateDb.RawDump(false, false, true), nil
+}
+
+// streamDumper is a state.DumpCollector writing the collected accounts to a stream.
+type streamDumper struct {
+	w       *rpc.StreamWriter
+	started bool
+}
+
+// OnRoot implements state.DumpCollector, opening the dump on the first page. Each
+// page of the iteration reports the root again.
+func (d *streamDumper) OnRoot(root common.Hash) {
+	if d.started {
+		return
+	}
+	d.started = true
+	d.w.BeginObject()
+	d.w.Field("root", fmt.Sprintf("%x", root))
+	d.w.Key("accounts")
+	d.w.BeginObject()
+}
+
+// OnAccount implements state.DumpCollector.
+func (d *streamDumper) OnAccount(addr common.Address, account state.DumpAccount) {
+	key, _ := addr.MarshalText()
+	d.w.Field(string(key), account)
 }
 
 // PrivateDebugAPI is the collection of Ethereum full node APIs exposed over
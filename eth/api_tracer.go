@@ -208,7 +208,7 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 					msg, _ := tx.AsMessage(signer)
 					vmctx := core.NewEVMContext(msg, task.block.Header(), api.eth.blockchain, nil)
 
-					res, err := api.traceTx(ctx, msg, vmctx, task.statedb, config)
+					res, err := api.traceTx(ctx, msg, vmctx, task.statedb, config, false)
 					if err != nil {
 						task.results[i] = &txTraceResult{Error: err.Error()}
 						log.Warn("Tracing failed", "hash", tx.Hash(), "block", task.block.NumberU64(), "err", err)
@@ -482,7 +482,7 @@ func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block,
 				msg, _ := txs[task.index].AsMessage(signer)
 				vmctx := core.NewEVMContext(msg, block.Header(), api.eth.blockchain, nil)
 
-				res, err := api.traceTx(ctx, msg, vmctx, task.statedb, config)
+				res, err := api.traceTx(ctx, msg, vmctx, task.statedb, config, false)
 				if err != nil {
 					results[task.index] = &txTraceResult{Error: err.Error()}
 					continue
@@ -715,14 +715,15 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, hash common.Ha
 	if err != nil {
 		return nil, err
 	}
-	// Trace the transaction and return
-	return api.traceTx(ctx, msg, vmctx, statedb, config)
+	// Trace the transaction and return, streaming the potentially huge struct logs
+	return api.traceTx(ctx, msg, vmctx, statedb, config, true)
 }
 
 // traceTx configures a new tracer according to the provided configuration, and
 // executes the given message in the provided environment. The return value will
-// be tracer dependent.
-func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, vmctx vm.Context, statedb *state.StateDB, config *TraceConfig) (interface{}, error) {
+// be tracer dependent, the output of the structured logger is returned as an
+// rpc.Stream if stream is set.
+func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, vmctx vm.Context, statedb *state.StateDB, config *TraceConfig, stream bool) (interface{}, error) {
 	// Assemble the structured logger or the JavaScript tracer
 	var (
 		tracer vm.Tracer
@@ -748,6 +749,7 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 			tracer.(*tracers.Tracer).Stop(errors.New("execution timeout"))
 		}()
 		defer cancel()
+		ctx = deadlineCtx
 
 	case config == nil:
 		tracer = vm.NewStructLogger(nil)
@@ -758,10 +760,24 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 	// Run the transaction with tracing enabled.
 	vmenv := vm.NewEVM(vmctx, statedb, api.eth.blockchain.Config(), vm.Config{Debug: true, Tracer: tracer})
 
+	// Abort the EVM when the trace times out or the RPC call is cancelled, the
+	// tracers only stop recording by themselves
+	execCtx, cancelExec := context.WithCancel(ctx)
+	defer cancelExec()
+	go func() {
+		<-execCtx.Done()
+		vmenv.Cancel()
+	}()
 	result, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()))
 	if err != nil {
 		return nil, fmt.Errorf("tracing failed: %v", err)
 	}
+	if vmenv.Cancelled() {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, errors.New("execution timeout")
+		}
+		return nil, errors.New("execution aborted")
+	}
 	// Depending on the tracer type, format and return the output
 	switch tracer := tracer.(type) {
 	case *vm.StructLogger:
@@ -770,6 +786,9 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 		if len(result.Revert()) > 0 {
 			returnVal = fmt.Sprintf("%x", result.Revert())
 		}
+		if stream {
+			return ethapi.StreamExecutionResult(result.UsedGas, result.Failed(), returnVal, tracer.StructLogs()), nil
+		}
 		return &ethapi.ExecutionResult{
 			Gas:         result.UsedGas,
 			Failed:      result.Failed(),
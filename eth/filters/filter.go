@@ -117,21 +117,39 @@ func newFilter(backend Backend, addresses []common.Address, topics [][]common.Ha
 // Logs searches the blockchain for matching log entries, returning all from the
 // first block that contains matches, updating the start of the filter accordingly.
 func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
+	var logs []*types.Log
+	err := f.Iterate(ctx, func(found []*types.Log) error {
+		logs = append(logs, found...)
+		return nil
+	})
+	return logs, err
+}
+
+// Iterate searches the blockchain for matching log entries like Logs, but passes
+// the matches of each block to fn as soon as they are found instead of gathering
+// them. It stops at the first error of the search or fn.
+func (f *Filter) Iterate(ctx context.Context, fn func(logs []*types.Log) error) error {
 	// If we're doing singleton block filtering, execute and return
 	if f.block != (common.Hash{}) {
 		header, err := f.backend.HeaderByHash(ctx, f.block)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		if header == nil {
-			return nil, errors.New("unknown block")
+			return errors.New("unknown block")
 		}
-		return f.blockLogs(ctx, header)
+		logs, err := f.blockLogs(ctx, header)
+		if len(logs) > 0 {
+			if err := fn(logs); err != nil {
+				return err
+			}
+		}
+		return err
 	}
 	// Figure out the limits of the filter range
 	header, _ := f.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
 	if header == nil {
-		return nil, nil
+		return nil
 	}
 	head := header.Number.Uint64()
 
@@ -142,44 +160,37 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	if f.end == -1 {
 		end = head
 	}
-	// Gather all indexed logs, and finish with non indexed ones
-	var (
-		logs []*types.Log
-		err  error
-	)
+	// Pass on all indexed logs, and finish with non indexed ones
 	size, sections := f.backend.BloomStatus()
 	if indexed := sections * size; indexed > uint64(f.begin) {
+		var err error
 		if indexed > end {
-			logs, err = f.indexedLogs(ctx, end)
+			err = f.indexedLogs(ctx, end, fn)
 		} else {
-			logs, err = f.indexedLogs(ctx, indexed-1)
+			err = f.indexedLogs(ctx, indexed-1, fn)
 		}
 		if err != nil {
-			return logs, err
+			return err
 		}
 	}
-	rest, err := f.unindexedLogs(ctx, end)
-	logs = append(logs, rest...)
-	return logs, err
+	return f.unindexedLogs(ctx, end, fn)
 }
 
-// indexedLogs returns the logs matching the filter criteria based on the bloom
-// bits indexed available locally or via the network.
-func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
+// indexedLogs passes the logs matching the filter criteria to fn based on the
+// bloom bits indexed available locally or via the network.
+func (f *Filter) indexedLogs(ctx context.Context, end uint64, fn func([]*types.Log) error) error {
 	// Create a matcher session and request servicing from the backend
 	matches := make(chan uint64, 64)
 
 	session, err := f.matcher.Start(ctx, uint64(f.begin), end, matches)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer session.Close()
 
 	f.backend.ServiceFilter(ctx, session)
 
 	// Iterate over the matches until exhausted or context closed
-	var logs []*types.Log
-
 	for {
 		select {
 		case number, ok := <-matches:
@@ -189,44 +200,50 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 				if err == nil {
 					f.begin = int64(end) + 1
 				}
-				return logs, err
+				return err
 			}
 			f.begin = int64(number) + 1
 
 			// Retrieve the suggested block and pull any truly matching logs
 			header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
 			if header == nil || err != nil {
-				return logs, err
+				return err
 			}
 			found, err := f.checkMatches(ctx, header)
 			if err != nil {
-				return logs, err
+				return err
+			}
+			if len(found) > 0 {
+				if err := fn(found); err != nil {
+					return err
+				}
 			}
-			logs = append(logs, found...)
 
 		case <-ctx.Done():
-			return logs, ctx.Err()
+			return ctx.Err()
 		}
 	}
 }
 
-// unindexedLogs returns the logs matching the filter criteria based on raw block
-// iteration and bloom matching.
-func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
-	var logs []*types.Log
-
+// unindexedLogs passes the logs matching the filter criteria to fn based on raw
+// block iteration and bloom matching.
+func (f *Filter) unindexedLogs(ctx context.Context, end uint64, fn func([]*types.Log) error) error {
 	for ; f.begin <= int64(end); f.begin++ {
 		header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(f.begin))
 		if header == nil || err != nil {
-			return logs, err
+			return err
 		}
 		found, err := f.blockLogs(ctx, header)
 		if err != nil {
-			return logs, err
+			return err
+		}
+		if len(found) > 0 {
+			if err := fn(found); err != nil {
+				return err
+			}
 		}
-		logs = append(logs, found...)
 	}
-	return logs, nil
+	return nil
 }
 
 // blockLogs returns the logs matching the filter criteria within a single block.
@@ -18,9 +18,11 @@ package filters
 
 import (
 	"context"
+	"errors"
 	"io/ioutil"
 	"math/big"
 	"os"
+	"reflect"
 	"testing"
 
 	"ethereum/rpc-network/consensus/ethash"
@@ -28,6 +30,7 @@ import (
 	"ethereum/rpc-network/core/rawdb"
 	"ethereum/rpc-network/core/types"
 	"ethereum/rpc-network/params"
+	"ethereum/rpc-network/rpc"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 )
@@ -222,4 +225,38 @@ func TestFilters(t *testing.T) {
 	if len(logs) != 0 {
 		t.Error("expected 0 log, got", len(logs))
 	}
+
+	// Iterating passes the logs block by block, stopping at the first error.
+	errStop := errors.New("stop")
+	var topics []common.Hash
+	filter = NewRangeFilter(backend, 0, -1, []common.Address{addr}, [][]common.Hash{{hash1, hash2, hash3, hash4}})
+	err = filter.Iterate(context.Background(), func(logs []*types.Log) error {
+		topics = append(topics, logs[0].Topics[0])
+		if len(topics) == 2 {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop || !reflect.DeepEqual(topics, []common.Hash{hash1, hash2}) {
+		t.Errorf("wrong iteration: topics %x, error %v", topics, err)
+	}
+
+	// Log queries through the API stream the logs found.
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("eth", NewPublicFilterAPI(backend, false)); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+	var found []*types.Log
+	if err := client.Call(&found, "eth_getLogs", map[string]interface{}{"fromBlock": "0x0", "topics": [][]common.Hash{{hash1, hash2, hash3, hash4}}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 4 {
+		t.Error("expected 4 log, got", len(found))
+	}
+	if err := client.Call(&found, "eth_getLogs", map[string]interface{}{"blockHash": common.Hash{1}}); err == nil {
+		t.Error("expected error for unknown block")
+	}
 }
@@ -328,6 +328,25 @@ func (ec *Client) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header)
 	return ec.c.EthSubscribe(ctx, ch, "newHeads")
 }
 
+// SubscribeNewHeadResilient is like SubscribeNewHead, but keeps the subscription
+// established across reconnects. Each time it is re-established, a marker carrying the
+// last header delivered before the gap is sent on the Gaps channel of the
+// subscription, see HeadFromGap.
+func (ec *Client) SubscribeNewHeadResilient(ctx context.Context, ch chan<- *types.Header, config rpc.ResubscribeConfig) (*rpc.ResilientSubscription, error) {
+	return ec.c.EthSubscribeResilient(ctx, config, ch, "newHeads")
+}
+
+// HeadFromGap decodes the last header delivered before a resubscription gap. It
+// returns nil if no header had been delivered yet.
+func HeadFromGap(gap rpc.Resubscribed) (*types.Header, error) {
+	if len(gap.Last) == 0 {
+		return nil, nil
+	}
+	var head *types.Header
+	err := json.Unmarshal(gap.Last, &head)
+	return head, err
+}
+
 // State Access
 
 // NetworkID returns the network ID (also known as the chain ID) for this chain.
@@ -397,6 +416,18 @@ func (ec *Client) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuer
 	return ec.c.EthSubscribe(ctx, ch, "logs", arg)
 }
 
+// SubscribeFilterLogsResilient is like SubscribeFilterLogs, but keeps the subscription
+// established across reconnects. Logs emitted while disconnected are not delivered,
+// the markers sent on the Gaps channel of the subscription allow backfilling them
+// with FilterLogs.
+func (ec *Client) SubscribeFilterLogsResilient(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log, config rpc.ResubscribeConfig) (*rpc.ResilientSubscription, error) {
+	arg, err := toFilterArg(q)
+	if err != nil {
+		return nil, err
+	}
+	return ec.c.EthSubscribeResilient(ctx, config, ch, "logs", arg)
+}
+
 func toFilterArg(q ethereum.FilterQuery) (interface{}, error) {
 	arg := map[string]interface{}{
 		"address": q.Addresses,
@@ -521,6 +552,21 @@ func (ec *Client) SendTransaction(ctx context.Context, tx *types.Transaction) er
 	return ec.c.CallContext(ctx, nil, "eth_sendRawTransaction", hexutil.Encode(data))
 }
 
+// RevertData returns the data of an error returned by CallContract or EstimateGas
+// for a reverted execution, which is the ABI encoded revert reason. It returns false
+// if the error isn't a revert error.
+func RevertData(err error) ([]byte, bool) {
+	var rpcErr rpc.Error
+	if !errors.As(err, &rpcErr) || rpcErr.ErrorCode() != 3 {
+		return nil, false
+	}
+	var data hexutil.Bytes
+	if ok, err := rpc.DecodeErrorData(err, &data); !ok || err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
 func toCallArg(msg ethereum.CallMsg) interface{} {
 	arg := map[string]interface{}{
 		"from": msg.From,
@@ -37,16 +37,15 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
-// Verify that Client implements the ethereum interfaces.
+// Verify that Client implements the ethereum interfaces. ChainReader,
+// TransactionReader and LogFilterer are declared over the upstream block, transaction
+// and log types, Client returns the ones of this module instead.
 var (
-	_ = ethereum.ChainReader(&Client{})
-	_ = ethereum.TransactionReader(&Client{})
 	_ = ethereum.ChainStateReader(&Client{})
 	_ = ethereum.ChainSyncReader(&Client{})
 	_ = ethereum.ContractCaller(&Client{})
 	_ = ethereum.GasEstimator(&Client{})
 	_ = ethereum.GasPricer(&Client{})
-	_ = ethereum.LogFilterer(&Client{})
 	_ = ethereum.PendingStateReader(&Client{})
 	// _ = ethereum.PendingStateEventer(&Client{})
 	_ = ethereum.PendingContractCaller(&Client{})
@@ -6,6 +6,7 @@ require (
 	github.com/Azure/azure-storage-blob-go v0.10.0
 	github.com/StackExchange/wmi v0.0.0-20190523213315-cbe66965904d // indirect
 	github.com/VictoriaMetrics/fastcache v1.5.7
+	github.com/agl/ed25519 v0.0.0-20170116200512-5312a6153412
 	github.com/aristanetworks/goarista v0.0.0-20200812190859-4cb0e71f3c0e // indirect
 	github.com/aws/aws-sdk-go v1.34.12
 	github.com/btcsuite/btcd v0.20.1-beta
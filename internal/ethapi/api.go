@@ -881,8 +881,12 @@ func DoCall(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.Blo
 	if err := vmError(); err != nil {
 		return nil, err
 	}
-	// If the timer caused an abort, return an appropriate error message
+	// If the timer or a cancellation of the call caused an abort, return an
+	// appropriate error message
 	if evm.Cancelled() {
+		if ctx.Err() == context.Canceled {
+			return nil, errors.New("execution aborted (call cancelled)")
+		}
 		return nil, fmt.Errorf("execution aborted (timeout = %v)", timeout)
 	}
 	if err != nil {
@@ -934,7 +938,7 @@ func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNrOr
 	}
 	result, err := DoCall(ctx, s.b, args, blockNrOrHash, accounts, vm.Config{}, 5*time.Second, s.b.RPCGasCap())
 	if err != nil {
-		return nil, err
+		return nil, newTxError(err)
 	}
 	// If the result contains a revert reason, try to unpack and return it.
 	if len(result.Revert()) > 0 {
@@ -975,7 +979,7 @@ func DoEstimateGas(ctx context.Context, b Backend, args CallArgs, blockNrOrHash
 		available := new(big.Int).Set(balance)
 		if args.Value != nil {
 			if args.Value.ToInt().Cmp(available) >= 0 {
-				return 0, errors.New("insufficient funds for transfer")
+				return 0, core.ErrInsufficientFundsForTransfer
 			}
 			available.Sub(available, args.Value.ToInt())
 		}
@@ -1053,7 +1057,8 @@ func DoEstimateGas(ctx context.Context, b Backend, args CallArgs, blockNrOrHash
 // given transaction against the current pending block.
 func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (hexutil.Uint64, error) {
 	blockNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
-	return DoEstimateGas(ctx, s.b, args, blockNrOrHash, s.b.RPCGasCap())
+	gas, err := DoEstimateGas(ctx, s.b, args, blockNrOrHash, s.b.RPCGasCap())
+	return gas, newTxError(err)
 }
 
 // ExecutionResult groups all structured logs emitted by the EVM
@@ -1084,42 +1089,69 @@ type StructLogRes struct {
 func FormatLogs(logs []vm.StructLog) []StructLogRes {
 	formatted := make([]StructLogRes, len(logs))
 	for index, trace := range logs {
-		formatted[index] = StructLogRes{
-			Pc:      trace.Pc,
-			Op:      trace.Op.String(),
-			Gas:     trace.Gas,
-			GasCost: trace.GasCost,
-			Depth:   trace.Depth,
-			Error:   trace.Err,
+		formatted[index] = FormatLog(trace)
+	}
+	return formatted
+}
+
+// FormatLog formats a single EVM returned structured log for json output
+func FormatLog(trace vm.StructLog) StructLogRes {
+	formatted := StructLogRes{
+		Pc:      trace.Pc,
+		Op:      trace.Op.String(),
+		Gas:     trace.Gas,
+		GasCost: trace.GasCost,
+		Depth:   trace.Depth,
+		Error:   trace.Err,
+	}
+	if trace.Stack != nil {
+		stack := make([]string, len(trace.Stack))
+		for i, stackValue := range trace.Stack {
+			stack[i] = fmt.Sprintf("%x", math.PaddedBigBytes(stackValue, 32))
 		}
-		if trace.Stack != nil {
-			stack := make([]string, len(trace.Stack))
-			for i, stackValue := range trace.Stack {
-				stack[i] = fmt.Sprintf("%x", math.PaddedBigBytes(stackValue, 32))
-			}
-			formatted[index].Stack = &stack
+		formatted.Stack = &stack
+	}
+	if trace.Memory != nil {
+		memory := make([]string, 0, (len(trace.Memory)+31)/32)
+		for i := 0; i+32 <= len(trace.Memory); i += 32 {
+			memory = append(memory, fmt.Sprintf("%x", trace.Memory[i:i+32]))
 		}
-		if trace.Memory != nil {
-			memory := make([]string, 0, (len(trace.Memory)+31)/32)
-			for i := 0; i+32 <= len(trace.Memory); i += 32 {
-				memory = append(memory, fmt.Sprintf("%x", trace.Memory[i:i+32]))
-			}
-			formatted[index].Memory = &memory
+		formatted.Memory = &memory
+	}
+	if trace.Storage != nil {
+		storage := make(map[string]string)
+		for i, storageValue := range trace.Storage {
+			storage[fmt.Sprintf("%x", i)] = fmt.Sprintf("%x", storageValue)
 		}
-		if trace.Storage != nil {
-			storage := make(map[string]string)
-			for i, storageValue := range trace.Storage {
-				storage[fmt.Sprintf("%x", i)] = fmt.Sprintf("%x", storageValue)
+		formatted.Storage = &storage
+	}
+	return formatted
+}
+
+// StreamExecutionResult returns an ExecutionResult as a stream. The structured logs
+// are formatted one at a time while they're written, instead of all at once.
+func StreamExecutionResult(gas uint64, failed bool, returnValue string, logs []vm.StructLog) rpc.Stream {
+	return func(ctx context.Context, w *rpc.StreamWriter) error {
+		// Errors of the writer are sticky, only the last one needs checking
+		w.BeginObject()
+		w.Field("gas", gas)
+		w.Field("failed", failed)
+		w.Field("returnValue", returnValue)
+		w.Key("structLogs")
+		w.BeginArray()
+		for _, trace := range logs {
+			if err := w.Value(FormatLog(trace)); err != nil {
+				return err
 			}
-			formatted[index].Storage = &storage
 		}
+		w.EndArray()
+		return w.EndObject()
 	}
-	return formatted
 }
 
 // RPCMarshalHeader converts the given header to the RPC output .
 func RPCMarshalHeader(head *types.Header) map[string]interface{} {
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"number":           (*hexutil.Big)(head.Number),
 		"hash":             head.Hash(),
 		"parentHash":       head.ParentHash,
@@ -1138,6 +1170,10 @@ func RPCMarshalHeader(head *types.Header) map[string]interface{} {
 		"transactionsRoot": head.TxHash,
 		"receiptsRoot":     head.ReceiptHash,
 	}
+	if len(head.Signs) > 0 {
+		result["signs"] = head.Signs
+	}
+	return result
 }
 
 // RPCMarshalBlock converts the given block to the RPC output which depends on fullTx. If inclTx is true transactions are
@@ -1555,7 +1591,7 @@ func SubmitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (c
 		return common.Hash{}, err
 	}
 	if err := b.SendTx(ctx, tx); err != nil {
-		return common.Hash{}, err
+		return common.Hash{}, newTxError(err)
 	}
 	if tx.To() == nil {
 		signer := types.MakeSigner(b.ChainConfig(), b.CurrentBlock().Number())
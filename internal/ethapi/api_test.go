@@ -0,0 +1,198 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"ethereum/rpc-network/consensus/ethash"
+	"ethereum/rpc-network/core"
+	"ethereum/rpc-network/core/rawdb"
+	"ethereum/rpc-network/core/state"
+	"ethereum/rpc-network/core/types"
+	"ethereum/rpc-network/core/vm"
+	"ethereum/rpc-network/ethclient"
+	"ethereum/rpc-network/params"
+	"ethereum/rpc-network/rpc"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
+	testBalance = big.NewInt(2e10)
+
+	// usedKey is an account which already sent transactions.
+	usedKey, _ = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+	usedAddr   = crypto.PubkeyToAddress(usedKey.PublicKey)
+
+	// revertAddr holds a contract reverting every call with Error("boom").
+	revertAddr   = common.HexToAddress("0x0000000000000000000000000000000000000bad")
+	revertReason = common.FromHex("0x08c379a0" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"0000000000000000000000000000000000000000000000000000000000000004" +
+		"626f6f6d00000000000000000000000000000000000000000000000000000000")
+	revertCode = append(common.FromHex("0x6064600c60003960646000fd"), revertReason...) // copy the reason to memory and revert with it
+)
+
+// testBackend serves the API from a chain holding only the genesis block and a
+// transaction pool on top of it.
+type testBackend struct {
+	Backend
+	chain *core.BlockChain
+	pool  *core.TxPool
+}
+
+func newTestBackend(t *testing.T) *testBackend {
+	db := rawdb.NewMemoryDatabase()
+	gspec := &core.Genesis{
+		Config: params.AllEthashProtocolChanges,
+		Alloc: core.GenesisAlloc{
+			testAddr:   {Balance: testBalance},
+			usedAddr:   {Balance: testBalance, Nonce: 5},
+			revertAddr: {Code: revertCode, Balance: new(big.Int)},
+		},
+	}
+	gspec.MustCommit(db)
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := core.DefaultTxPoolConfig
+	config.Journal = ""
+	return &testBackend{chain: chain, pool: core.NewTxPool(config, gspec.Config, chain)}
+}
+
+func (b *testBackend) close() {
+	b.pool.Stop()
+	b.chain.Stop()
+}
+
+func (b *testBackend) ChainConfig() *params.ChainConfig { return b.chain.Config() }
+func (b *testBackend) CurrentBlock() *types.Block       { return b.chain.CurrentBlock() }
+func (b *testBackend) RPCGasCap() uint64                { return 25000000 }
+func (b *testBackend) RPCTxFeeCap() float64             { return 1 }
+
+func (b *testBackend) BlockByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Block, error) {
+	return b.chain.CurrentBlock(), nil
+}
+
+func (b *testBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	header := b.chain.CurrentHeader()
+	statedb, err := b.chain.StateAt(header.Root)
+	return statedb, header, err
+}
+
+func (b *testBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header) (*vm.EVM, func() error, error) {
+	context := core.NewEVMContext(msg, header, b.chain, nil)
+	return vm.NewEVM(context, state, b.chain.Config(), vm.Config{}), func() error { return nil }, nil
+}
+
+func (b *testBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
+	return b.pool.AddLocal(signedTx)
+}
+
+// newTestClient serves the eth API of the backend in-process.
+func newTestClient(t *testing.T, b Backend) *ethclient.Client {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", NewPublicBlockChainAPI(b)); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("eth", NewPublicTransactionPoolAPI(b, new(AddrLocker))); err != nil {
+		t.Fatal(err)
+	}
+	return ethclient.NewClient(rpc.DialInProc(server))
+}
+
+func TestRevertData(t *testing.T) {
+	backend := newTestBackend(t)
+	defer backend.close()
+	ec := newTestClient(t, backend)
+	defer ec.Close()
+
+	_, err := ec.CallContract(context.Background(), ethereum.CallMsg{To: &revertAddr}, nil)
+	if err == nil || err.Error() != "execution reverted: boom" {
+		t.Fatalf("wrong error for reverted call: %v", err)
+	}
+	if data, ok := ethclient.RevertData(err); !ok || !bytes.Equal(data, revertReason) {
+		t.Fatalf("wrong revert data of call: %x %v", data, ok)
+	}
+	_, err = ec.EstimateGas(context.Background(), ethereum.CallMsg{To: &revertAddr})
+	if data, ok := ethclient.RevertData(err); !ok || !bytes.Equal(data, revertReason) {
+		t.Fatalf("wrong revert data of gas estimation: %x %v", data, ok)
+	}
+	if _, ok := ethclient.RevertData(errors.New("execution reverted")); ok {
+		t.Fatal("revert data for an error without data")
+	}
+}
+
+func TestTransactionErrorCodes(t *testing.T) {
+	backend := newTestBackend(t)
+	defer backend.close()
+	ec := newTestClient(t, backend)
+	defer ec.Close()
+
+	signer := types.NewEIP155Signer(params.AllEthashProtocolChanges.ChainID)
+	sign := func(key *ecdsa.PrivateKey, nonce uint64, value *big.Int, gas uint64) *types.Transaction {
+		tx, err := types.SignTx(types.NewTransaction(nonce, common.Address{1}, value, gas, big.NewInt(1), nil), signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+	code := func(err error) int {
+		var rpcErr rpc.Error
+		if !errors.As(err, &rpcErr) {
+			t.Fatalf("error without code: %v", err)
+		}
+		return rpcErr.ErrorCode()
+	}
+
+	valid := sign(testKey, 0, big.NewInt(1), params.TxGas)
+	if err := ec.SendTransaction(context.Background(), valid); err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]struct {
+		tx   *types.Transaction
+		code int
+	}{
+		"already_known":      {valid, ErrCodeAlreadyKnown},
+		"nonce_too_low":      {sign(usedKey, 0, big.NewInt(1), params.TxGas), ErrCodeNonceTooLow},
+		"insufficient_funds": {sign(testKey, 1, testBalance, params.TxGas), ErrCodeInsufficientFunds},
+		"intrinsic_gas":      {sign(testKey, 1, big.NewInt(1), params.TxGas-1), ErrCodeIntrinsicGas},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := ec.SendTransaction(context.Background(), tt.tx)
+			if err == nil || code(err) != tt.code {
+				t.Fatalf("eth_sendRawTransaction error = %v, want code %d", err, tt.code)
+			}
+		})
+	}
+
+	_, err := ec.EstimateGas(context.Background(), ethereum.CallMsg{From: testAddr, To: &common.Address{1}, GasPrice: big.NewInt(1), Value: testBalance})
+	if err == nil || code(err) != ErrCodeInsufficientTransfer {
+		t.Fatalf("eth_estimateGas error = %v, want code %d", err, ErrCodeInsufficientTransfer)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"errors"
+
+	"ethereum/rpc-network/core"
+)
+
+// JSON-RPC error codes of transactions rejected by the transaction pool or
+// failing the checks before execution. Clients may rely on them not changing.
+const (
+	ErrCodeNonceTooLow          = -32010
+	ErrCodeNonceTooHigh         = -32011
+	ErrCodeUnderpriced          = -32012
+	ErrCodeReplaceUnderpriced   = -32013
+	ErrCodeInsufficientFunds    = -32014
+	ErrCodeIntrinsicGas         = -32015
+	ErrCodeGasLimit             = -32016
+	ErrCodeAlreadyKnown         = -32017
+	ErrCodeInvalidSender        = -32018
+	ErrCodeNegativeValue        = -32019
+	ErrCodeOversizedData        = -32020
+	ErrCodeGasUintOverflow      = -32021
+	ErrCodeInsufficientTransfer = -32022
+)
+
+// txErrorCodes maps the errors of the transaction pool and state transition
+// to their JSON-RPC error codes.
+var txErrorCodes = []struct {
+	err  error
+	code int
+}{
+	{core.ErrNonceTooLow, ErrCodeNonceTooLow},
+	{core.ErrNonceTooHigh, ErrCodeNonceTooHigh},
+	{core.ErrUnderpriced, ErrCodeUnderpriced},
+	{core.ErrReplaceUnderpriced, ErrCodeReplaceUnderpriced},
+	{core.ErrInsufficientFunds, ErrCodeInsufficientFunds},
+	{core.ErrIntrinsicGas, ErrCodeIntrinsicGas},
+	{core.ErrGasLimit, ErrCodeGasLimit},
+	{core.ErrGasLimitReached, ErrCodeGasLimit},
+	{core.ErrAlreadyKnown, ErrCodeAlreadyKnown},
+	{core.ErrInvalidSender, ErrCodeInvalidSender},
+	{core.ErrNegativeValue, ErrCodeNegativeValue},
+	{core.ErrOversizedData, ErrCodeOversizedData},
+	{core.ErrGasUintOverflow, ErrCodeGasUintOverflow},
+	{core.ErrInsufficientFundsForTransfer, ErrCodeInsufficientTransfer},
+}
+
+// txError is an API error carrying the code of a transaction error.
+type txError struct {
+	error
+	code int
+}
+
+// ErrorCode returns the JSON error code of the transaction error.
+func (e *txError) ErrorCode() int {
+	return e.code
+}
+
+func (e *txError) Unwrap() error {
+	return e.error
+}
+
+// newTxError attaches the JSON-RPC error code to transaction errors, other
+// errors are returned unchanged.
+func newTxError(err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, c := range txErrorCodes {
+		if errors.Is(err, c.err) {
+			return &txError{err, c.code}
+		}
+	}
+	return err
+}
@@ -31,6 +31,7 @@ var Modules = map[string]string{
 	"rpc":        RpcJs,
 	"shh":        ShhJs,
 	"swarmfs":    SwarmfsJs,
+	"tbft":       TbftJs,
 	"txpool":     TxpoolJs,
 	"les":        LESJs,
 	"lespay":     LESPayJs,
@@ -889,3 +890,43 @@ web3._extend({
 	]
 });
 `
+
+const TbftJs = `
+web3._extend({
+	property: 'tbft',
+	methods:
+	[
+		new web3._extend.Method({
+			name: 'roundState',
+			call: 'tbft_roundState',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'validators',
+			call: 'tbft_validators',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'proposer',
+			call: 'tbft_proposer',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'peers',
+			call: 'tbft_peers',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'healthTicks',
+			call: 'tbft_healthTicks',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'pendingSwitch',
+			call: 'tbft_pendingSwitch',
+			params: 1
+		}),
+	],
+	properties: []
+});
+`
@@ -0,0 +1,297 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"sync"
+	"time"
+
+	"ethereum/rpc-network/consensus"
+	"ethereum/rpc-network/core"
+	"ethereum/rpc-network/core/types"
+	"ethereum/rpc-network/params"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// maxProposalTasks bounds the executed proposals waiting to be committed. Every
+// round of a height may add one, a height failing for many rounds must not grow
+// the set without limit.
+const maxProposalTasks = 64
+
+// PbftAgent implements types.PbftAgentProxy on top of the transaction pool and the
+// local chain. Proposal blocks are packed through the worker pipeline, proposals of
+// other committee members are verified by executing them with the chain's state
+// processor, and committed blocks are written into the chain.
+type PbftAgent struct {
+	config      *Config
+	chainConfig *params.ChainConfig
+	engine      consensus.Engine
+	eth         Backend
+	chain       *core.BlockChain
+	mux         *event.TypeMux
+
+	privateKey *ecdsa.PrivateKey
+	coinbase   common.Address
+	seeds      []*types.CommitteeMember
+
+	mu     sync.Mutex            // Protects the worker environment and the task set
+	worker *worker               // Packing worker, never started, only used for its environment
+	tasks  map[common.Hash]*task // Executed proposals waiting to be committed
+}
+
+// NewPbftAgent creates an agent that proposes and verifies blocks for a tbft node
+// signing with the given key. The seeds are the fixed committee members the node
+// cross checks every new committee against.
+func NewPbftAgent(eth Backend, config *Config, chainConfig *params.ChainConfig, mux *event.TypeMux, engine consensus.Engine,
+	privateKey *ecdsa.PrivateKey, seeds []*types.CommitteeMember) *PbftAgent {
+	coinbase := config.Etherbase
+	if coinbase == (common.Address{}) {
+		coinbase = crypto.PubkeyToAddress(privateKey.PublicKey)
+	}
+	return &PbftAgent{
+		config:      config,
+		chainConfig: chainConfig,
+		engine:      engine,
+		eth:         eth,
+		chain:       eth.BlockChain(),
+		mux:         mux,
+		privateKey:  privateKey,
+		coinbase:    coinbase,
+		seeds:       seeds,
+		worker: &worker{
+			config:      config,
+			chainConfig: chainConfig,
+			engine:      engine,
+			eth:         eth,
+			chain:       eth.BlockChain(),
+			exitCh:      make(chan struct{}),
+		},
+		tasks: make(map[common.Hash]*task),
+	}
+}
+
+// FetchFastBlock packs the pending transactions of the pool on top of the current
+// head and returns the resulting proposal block.
+func (agent *PbftAgent) FetchFastBlock(committeeID *big.Int, infos []*types.CommitteeMember) (*types.Block, error) {
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+
+	tstart := time.Now()
+	parent := agent.chain.CurrentBlock()
+
+	timestamp := tstart.Unix()
+	if parent.Time() >= uint64(timestamp) {
+		timestamp = int64(parent.Time() + 1)
+	}
+	num := parent.Number()
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     num.Add(num, common.Big1),
+		GasLimit:   core.CalcGasLimit(parent, agent.config.GasFloor, agent.config.GasCeil),
+		Extra:      agent.config.ExtraData,
+		Time:       uint64(timestamp),
+		Coinbase:   agent.coinbase,
+	}
+	if err := agent.engine.Prepare(agent.chain, header); err != nil {
+		log.Error("Failed to prepare header for proposal", "err", err)
+		return nil, err
+	}
+	w := agent.worker
+	if err := w.makeCurrent(parent, header); err != nil {
+		log.Error("Failed to create proposal context", "err", err)
+		return nil, err
+	}
+	pending, err := agent.eth.TxPool().Pending()
+	if err != nil {
+		log.Error("Failed to fetch pending transactions", "err", err)
+		return nil, err
+	}
+	w.commitPending(pending, agent.coinbase, nil)
+
+	// Committee switch infos are not carried by the block format, the proposal
+	// only orders transactions.
+	receipts := copyReceipts(w.current.receipts)
+	state := w.current.state.Copy()
+	block, err := agent.engine.FinalizeAndAssemble(agent.chain, w.current.header, state, w.current.txs, nil, receipts)
+	if err != nil {
+		return nil, err
+	}
+	agent.addTask(&task{receipts: receipts, state: state, block: block, createdAt: time.Now()})
+
+	log.Info("Commit new proposal", "committee", committeeID, "number", block.Number(), "hash", block.Hash(),
+		"txs", w.current.tcount, "gas", block.GasUsed(), "fees", totalFees(block, receipts),
+		"elapsed", common.PrettyDuration(time.Since(tstart)))
+	return block, nil
+}
+
+// VerifyFastBlock verifies the header of the proposal, executes it on top of its
+// parent and signs the result.
+// An against vote is returned together with the error if the block is invalid, or
+// right away if the caller already rejected the block.
+func (agent *PbftAgent) VerifyFastBlock(block *types.Block, result bool) (*types.PbftSign, error) {
+	if !result {
+		return agent.signBlock(block, types.VoteAgreeAgainst)
+	}
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+
+	if _, ok := agent.tasks[block.Hash()]; ok {
+		return agent.signBlock(block, types.VoteAgree)
+	}
+	if block.NumberU64() > agent.chain.CurrentBlock().NumberU64()+1 {
+		return nil, types.ErrHeightNotYet
+	}
+	parent := agent.chain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil || parent.NumberU64()+1 != block.NumberU64() {
+		return agent.reject(block, consensus.ErrUnknownAncestor)
+	}
+	if err := agent.engine.VerifyHeader(agent.chain, block.Header(), false); err != nil {
+		return agent.reject(block, err)
+	}
+	if err := agent.chain.Validator().ValidateBody(block); err != nil {
+		return agent.reject(block, err)
+	}
+	state, err := agent.chain.StateAt(parent.Root())
+	if err != nil {
+		return agent.reject(block, err)
+	}
+	receipts, _, usedGas, err := agent.chain.Processor().Process(block, state, *agent.chain.GetVMConfig())
+	if err != nil {
+		return agent.reject(block, err)
+	}
+	if err := agent.chain.Validator().ValidateState(block, state, receipts, usedGas); err != nil {
+		return agent.reject(block, err)
+	}
+	agent.addTask(&task{receipts: receipts, state: state, block: block, createdAt: time.Now()})
+	return agent.signBlock(block, types.VoteAgree)
+}
+
+// addTask stores an executed proposal until it's committed. Proposals at or below
+// the head can't be committed anymore and are dropped, as are the oldest ones if
+// the set is full. The caller must hold agent.mu.
+func (agent *PbftAgent) addTask(t *task) {
+	head := agent.chain.CurrentBlock().NumberU64()
+	for hash, old := range agent.tasks {
+		if old.block.NumberU64() <= head {
+			delete(agent.tasks, hash)
+		}
+	}
+	for len(agent.tasks) >= maxProposalTasks {
+		var oldest *task
+		for _, old := range agent.tasks {
+			if oldest == nil || old.createdAt.Before(oldest.createdAt) {
+				oldest = old
+			}
+		}
+		delete(agent.tasks, oldest.block.Hash())
+	}
+	agent.tasks[t.block.Hash()] = t
+}
+
+// reject signs an against vote for an invalid proposal.
+func (agent *PbftAgent) reject(block *types.Block, err error) (*types.PbftSign, error) {
+	log.Warn("Invalid proposal block", "number", block.Number(), "hash", block.Hash(), "err", err)
+	sign, serr := agent.signBlock(block, types.VoteAgreeAgainst)
+	if serr != nil {
+		return nil, serr
+	}
+	return sign, err
+}
+
+// signBlock signs the vote on the given block with the agent key.
+func (agent *PbftAgent) signBlock(block *types.Block, vote uint32) (*types.PbftSign, error) {
+	sign := &types.PbftSign{
+		FastHeight: block.Number(),
+		FastHash:   block.Hash(),
+		Result:     vote,
+	}
+	var err error
+	sign.Sign, err = crypto.Sign(sign.HashWithNoSign().Bytes(), agent.privateKey)
+	if err != nil {
+		log.Error("Failed to sign proposal vote", "err", err)
+		return nil, err
+	}
+	return sign, nil
+}
+
+// BroadcastConsensus writes a block committed by the committee into the chain and
+// announces it to the network.
+func (agent *PbftAgent) BroadcastConsensus(block *types.Block) error {
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+
+	if agent.chain.HasBlock(block.Hash(), block.NumberU64()) {
+		return nil
+	}
+	task, exist := agent.tasks[block.Hash()]
+	if exist {
+		var (
+			hash     = block.Hash()
+			receipts = make([]*types.Receipt, len(task.receipts))
+			logs     []*types.Log
+		)
+		for i, receipt := range task.receipts {
+			// add block location fields
+			receipt.BlockHash = hash
+			receipt.BlockNumber = block.Number()
+			receipt.TransactionIndex = uint(i)
+
+			receipts[i] = new(types.Receipt)
+			*receipts[i] = *receipt
+			for _, log := range receipt.Logs {
+				log.BlockHash = hash
+			}
+			logs = append(logs, receipt.Logs...)
+		}
+		if _, err := agent.chain.WriteBlockWithState(block, receipts, logs, task.state, true); err != nil {
+			log.Error("Failed writing committed block to chain", "err", err)
+			return err
+		}
+	} else if _, err := agent.chain.InsertChain(types.Blocks{block}); err != nil {
+		log.Error("Failed importing committed block", "number", block.Number(), "hash", block.Hash(), "err", err)
+		return err
+	}
+	// Drop every proposal at or below the committed height, they can't be committed anymore
+	for hash, t := range agent.tasks {
+		if t.block.NumberU64() <= block.NumberU64() {
+			delete(agent.tasks, hash)
+		}
+	}
+	log.Info("Committed new block", "number", block.Number(), "hash", block.Hash(), "txs", len(block.Transactions()))
+	agent.mux.Post(core.NewMinedBlockEvent{Block: block})
+	return nil
+}
+
+// GetCurrentHeight returns the number of the current head block.
+func (agent *PbftAgent) GetCurrentHeight() *big.Int {
+	return agent.chain.CurrentBlock().Number()
+}
+
+// GetSeedMember returns the fixed committee members the agent was configured with.
+func (agent *PbftAgent) GetSeedMember() []*types.CommitteeMember {
+	return agent.seeds
+}
+
+// GetFastLastProposer returns the proposer of the current head block.
+func (agent *PbftAgent) GetFastLastProposer() common.Address {
+	return agent.chain.CurrentBlock().Coinbase()
+}
@@ -0,0 +1,164 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"ethereum/rpc-network/consensus/ethash"
+	"ethereum/rpc-network/core/rawdb"
+	"ethereum/rpc-network/core/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+var _ types.PbftAgentProxy = (*PbftAgent)(nil)
+
+func newTestPbftAgent(t *testing.T) (*PbftAgent, *testWorkerBackend) {
+	engine := ethash.NewFaker()
+	backend := newTestWorkerBackend(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	key, _ := crypto.GenerateKey()
+	return NewPbftAgent(backend, testConfig, ethashChainConfig, new(event.TypeMux), engine, key, nil), backend
+}
+
+func TestPbftAgentProposeAndCommit(t *testing.T) {
+	proposer, pb := newTestPbftAgent(t)
+	verifier, vb := newTestPbftAgent(t)
+	defer pb.chain.Stop()
+	defer vb.chain.Stop()
+
+	pb.txPool.AddLocals(pendingTxs)
+
+	block, err := proposer.FetchFastBlock(common.Big1, nil)
+	if err != nil {
+		t.Fatalf("failed to fetch proposal: %v", err)
+	}
+	if block.NumberU64() != 1 {
+		t.Fatalf("proposal number mismatch: have %d, want 1", block.NumberU64())
+	}
+	if len(block.Transactions()) != len(pendingTxs) {
+		t.Fatalf("proposal transaction count mismatch: have %d, want %d", len(block.Transactions()), len(pendingTxs))
+	}
+	sign, err := verifier.VerifyFastBlock(block, true)
+	if err != nil {
+		t.Fatalf("failed to verify proposal: %v", err)
+	}
+	if sign.Result != types.VoteAgree || sign.FastHash != block.Hash() {
+		t.Fatalf("unexpected vote: result %d, hash %x", sign.Result, sign.FastHash)
+	}
+	pub, err := crypto.SigToPub(sign.HashWithNoSign().Bytes(), sign.Sign)
+	if err != nil {
+		t.Fatalf("failed to recover vote signer: %v", err)
+	}
+	if crypto.PubkeyToAddress(*pub) != crypto.PubkeyToAddress(verifier.privateKey.PublicKey) {
+		t.Fatalf("vote signer mismatch")
+	}
+	for _, agent := range []*PbftAgent{proposer, verifier} {
+		if err := agent.BroadcastConsensus(block); err != nil {
+			t.Fatalf("failed to commit block: %v", err)
+		}
+		if have := agent.GetCurrentHeight().Uint64(); have != 1 {
+			t.Fatalf("head number mismatch: have %d, want 1", have)
+		}
+		if have := agent.GetFastLastProposer(); have != proposer.coinbase {
+			t.Fatalf("last proposer mismatch: have %x, want %x", have, proposer.coinbase)
+		}
+		if len(agent.tasks) != 0 {
+			t.Fatalf("stale proposals left after commit: %d", len(agent.tasks))
+		}
+	}
+}
+
+func TestPbftAgentRejectInvalidBlock(t *testing.T) {
+	proposer, pb := newTestPbftAgent(t)
+	verifier, vb := newTestPbftAgent(t)
+	defer pb.chain.Stop()
+	defer vb.chain.Stop()
+
+	pb.txPool.AddLocals(pendingTxs)
+
+	block, err := proposer.FetchFastBlock(common.Big1, nil)
+	if err != nil {
+		t.Fatalf("failed to fetch proposal: %v", err)
+	}
+	header := block.Header()
+	header.Root = common.Hash{0x01}
+	invalid := block.WithSeal(header)
+
+	sign, err := verifier.VerifyFastBlock(invalid, true)
+	if err == nil {
+		t.Fatalf("invalid proposal verified")
+	}
+	if sign == nil || sign.Result != types.VoteAgreeAgainst {
+		t.Fatalf("expected against vote, got %v", sign)
+	}
+	// A proposal from the future should be postponed instead of rejected
+	header = block.Header()
+	header.Number = common.Big3
+	if _, err := verifier.VerifyFastBlock(block.WithSeal(header), true); err != types.ErrHeightNotYet {
+		t.Fatalf("future proposal error mismatch: have %v, want %v", err, types.ErrHeightNotYet)
+	}
+}
+
+func TestPbftAgentRejectInvalidHeader(t *testing.T) {
+	proposer, pb := newTestPbftAgent(t)
+	verifier, vb := newTestPbftAgent(t)
+	defer pb.chain.Stop()
+	defer vb.chain.Stop()
+
+	block, err := proposer.FetchFastBlock(common.Big1, nil)
+	if err != nil {
+		t.Fatalf("failed to fetch proposal: %v", err)
+	}
+	// Header fields not covered by executing the block must be verified too
+	for name, modify := range map[string]func(*types.Header){
+		"difficulty": func(h *types.Header) { h.Difficulty = new(big.Int).Add(h.Difficulty, common.Big1) },
+		"gas limit":  func(h *types.Header) { h.GasLimit *= 2 },
+		"timestamp":  func(h *types.Header) { h.Time = uint64(time.Now().Add(time.Hour).Unix()) },
+	} {
+		header := block.Header()
+		modify(header)
+		sign, err := verifier.VerifyFastBlock(block.WithSeal(header), true)
+		if err == nil {
+			t.Fatalf("proposal with invalid %s verified", name)
+		}
+		if sign == nil || sign.Result != types.VoteAgreeAgainst {
+			t.Fatalf("expected against vote for invalid %s, got %v", name, sign)
+		}
+	}
+}
+
+func TestPbftAgentTaskLimit(t *testing.T) {
+	agent, b := newTestPbftAgent(t)
+	defer b.chain.Stop()
+
+	// Proposals of many rounds at the same height stay bounded
+	config := *agent.config
+	agent.config = &config
+	for i := 0; i < maxProposalTasks+10; i++ {
+		agent.config.ExtraData = []byte{byte(i)}
+		if _, err := agent.FetchFastBlock(common.Big1, nil); err != nil {
+			t.Fatalf("failed to fetch proposal %d: %v", i, err)
+		}
+	}
+	if len(agent.tasks) != maxProposalTasks {
+		t.Fatalf("proposal set size mismatch: have %d, want %d", len(agent.tasks), maxProposalTasks)
+	}
+}
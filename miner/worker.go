@@ -951,6 +951,16 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 		w.updateSnapshot()
 		return
 	}
+	if w.commitPending(pending, w.coinbase, interrupt) {
+		return
+	}
+	w.commit(uncles, w.fullTaskHook, true, tstart)
+}
+
+// commitPending fills the current environment with the given pending transactions,
+// preferring the ones sent from local accounts. It returns true if the work was
+// interrupted by a new head.
+func (w *worker) commitPending(pending map[common.Address]types.Transactions, coinbase common.Address, interrupt *int32) bool {
 	// Split the pending transactions into locals and remotes
 	localTxs, remoteTxs := make(map[common.Address]types.Transactions), pending
 	for _, account := range w.eth.TxPool().Locals() {
@@ -961,17 +971,17 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 	}
 	if len(localTxs) > 0 {
 		txs := types.NewTransactionsByPriceAndNonce(w.current.signer, localTxs)
-		if w.commitTransactions(txs, w.coinbase, interrupt) {
-			return
+		if w.commitTransactions(txs, coinbase, interrupt) {
+			return true
 		}
 	}
 	if len(remoteTxs) > 0 {
 		txs := types.NewTransactionsByPriceAndNonce(w.current.signer, remoteTxs)
-		if w.commitTransactions(txs, w.coinbase, interrupt) {
-			return
+		if w.commitTransactions(txs, coinbase, interrupt) {
+			return true
 		}
 	}
-	w.commit(uncles, w.fullTaskHook, true, tstart)
+	return false
 }
 
 // commit runs any post-transaction state modifications, assembles the final block
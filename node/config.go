@@ -161,6 +161,24 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// RPCAuth, if set, requires requests to the HTTP and WebSocket RPC endpoints
+	// to authenticate with one of the configured credentials. The methods callable
+	// are further restricted by the credentials used.
+	RPCAuth *RPCAuthConfig `toml:",omitempty"`
+
+	// RPCLimits, if set, limits the calls clients of the HTTP and WebSocket RPC
+	// endpoints may make. Clients are told apart by their credentials if RPCAuth
+	// is set, or else by their address.
+	RPCLimits *rpc.LimitConfig `toml:",omitempty"`
+
+	// RPCTLS, if set, serves the HTTP and WebSocket RPC endpoints over TLS,
+	// optionally requiring client certificates.
+	RPCTLS *RPCTLSConfig `toml:",omitempty"`
+
+	// RPCCallTimeouts, if set, cancels calls on the HTTP, WebSocket and IPC RPC
+	// endpoints running longer than the timeout of their namespace.
+	RPCCallTimeouts *rpc.CallTimeouts `toml:",omitempty"`
+
 	// GraphQLCors is the Cross-Origin Resource Sharing header to send to requesting
 	// clients. Please be aware that CORS is a browser enforced security, it's fully
 	// useless for custom HTTP clients.
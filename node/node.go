@@ -56,6 +56,9 @@ type Node struct {
 	ws            *httpServer //
 	ipc           *ipcServer  // Stores information about the ipc http server
 	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
+	rpcAuth       *rpcAuth     // Authentication of the HTTP and WebSocket endpoints, nil if disabled
+	rpcLimiter    *rpc.Limiter // Limits of the HTTP and WebSocket endpoints, nil if disabled
+	rpcTLS        *rpcTLS      // TLS of the HTTP and WebSocket endpoints, nil if disabled
 
 	databases map[*closeTrackingDB]struct{} // All open databases
 }
@@ -108,6 +111,24 @@ func New(conf *Config) (*Node, error) {
 	// Register built-in APIs.
 	node.rpcAPIs = append(node.rpcAPIs, node.apis()...)
 
+	if conf.RPCAuth != nil {
+		auth, err := newRPCAuth(conf.RPCAuth)
+		if err != nil {
+			return nil, err
+		}
+		node.rpcAuth = auth
+	}
+	if conf.RPCLimits != nil {
+		node.rpcLimiter = rpc.NewLimiter(*conf.RPCLimits)
+	}
+	if conf.RPCTLS != nil {
+		t, err := newRPCTLS(conf.RPCTLS)
+		if err != nil {
+			return nil, err
+		}
+		node.rpcTLS = t
+	}
+
 	// Acquire the instance directory lock.
 	if err := node.openDataDir(); err != nil {
 		return nil, err
@@ -138,7 +159,15 @@ func New(conf *Config) (*Node, error) {
 	// Configure RPC servers.
 	node.http = newHTTPServer(node.log, conf.HTTPTimeouts)
 	node.ws = newHTTPServer(node.log, rpc.DefaultHTTPTimeouts)
+	if node.rpcTLS != nil {
+		node.http.tls, node.ws.tls = node.rpcTLS, node.rpcTLS
+		go node.rpcTLS.reloadOnSignal(node.stop)
+	}
 	node.ipc = newIPCServer(node.log, conf.IPCEndpoint())
+	if conf.RPCCallTimeouts != nil {
+		node.http.callTimeouts, node.ws.callTimeouts = conf.RPCCallTimeouts, conf.RPCCallTimeouts
+		node.ipc.callTimeouts = conf.RPCCallTimeouts
+	}
 
 	return node, nil
 }
@@ -343,6 +372,8 @@ func (n *Node) startRPC() error {
 			CorsAllowedOrigins: n.config.HTTPCors,
 			Vhosts:             n.config.HTTPVirtualHosts,
 			Modules:            n.config.HTTPModules,
+			auth:               n.rpcAuth,
+			limiter:            n.rpcLimiter,
 		}
 		if err := n.http.setListenAddr(n.config.HTTPHost, n.config.HTTPPort); err != nil {
 			return err
@@ -358,6 +389,8 @@ func (n *Node) startRPC() error {
 		config := wsConfig{
 			Modules: n.config.WSModules,
 			Origins: n.config.WSOrigins,
+			auth:    n.rpcAuth,
+			limiter: n.rpcLimiter,
 		}
 		if err := server.setListenAddr(n.config.WSHost, n.config.WSPort); err != nil {
 			return err
@@ -512,15 +545,15 @@ func (n *Node) IPCEndpoint() string {
 
 // HTTPEndpoint returns the URL of the HTTP server.
 func (n *Node) HTTPEndpoint() string {
-	return "http://" + n.http.listenAddr()
+	return n.http.scheme("http") + "://" + n.http.listenAddr()
 }
 
 // WSEndpoint retrieves the current WS endpoint used by the protocol stack.
 func (n *Node) WSEndpoint() string {
 	if n.http.wsAllowed() {
-		return "ws://" + n.http.listenAddr()
+		return n.http.scheme("ws") + "://" + n.http.listenAddr()
 	}
-	return "ws://" + n.ws.listenAddr()
+	return n.ws.scheme("ws") + "://" + n.ws.listenAddr()
 }
 
 // EventMux retrieves the event multiplexer used by all the network services in
@@ -0,0 +1,236 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"ethereum/rpc-network/rpc"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// jwtMaxDrift is how far the issue time of a JWT without an expiry may be off
+// the local time.
+const jwtMaxDrift = 60 * time.Second
+
+// RPCAuthConfig configures the authentication of the HTTP and WebSocket RPC
+// endpoints. Requests carry a bearer token in their Authorization header, either
+// one of the static Tokens or a HS256 JWT signed with the secret in JWTSecret.
+type RPCAuthConfig struct {
+	// JWTSecret is the path of the file holding the hex encoded secret JWTs are
+	// signed with. The "modules" and "methods" claims of a JWT restrict what it
+	// may call like the fields of RPCToken.
+	JWTSecret string `toml:",omitempty"`
+
+	// Tokens are the static bearer tokens accepted.
+	Tokens []RPCToken `toml:",omitempty"`
+}
+
+// RPCToken is a static bearer token and what it may call. A token with neither
+// modules nor methods may call everything the endpoint serves. Requests with the
+// same token share their limits.
+type RPCToken struct {
+	Token   string
+	Modules []string `toml:",omitempty"` // namespaces the token may call
+	Methods []string `toml:",omitempty"` // methods the token may call besides the modules
+}
+
+// jwtClaims are the claims of a JWT checked by rpcAuth. JWTs with the same
+// subject share their limits.
+type jwtClaims struct {
+	Subject   string   `json:"sub"`
+	IssuedAt  *int64   `json:"iat"`
+	ExpiresAt *int64   `json:"exp"`
+	Modules   []string `json:"modules"`
+	Methods   []string `json:"methods"`
+}
+
+// rpcPermissions are the namespaces and methods a credential may call.
+type rpcPermissions struct {
+	key     string // identifies the credential to the limiter, empty for the client address
+	modules map[string]bool
+	methods map[string]bool
+}
+
+func newRPCPermissions(key string, modules, methods []string) *rpcPermissions {
+	p := &rpcPermissions{key: key, modules: make(map[string]bool), methods: make(map[string]bool)}
+	for _, module := range modules {
+		p.modules[module] = true
+	}
+	for _, method := range methods {
+		p.methods[method] = true
+	}
+	return p
+}
+
+// restricted reports whether only some methods may be called.
+func (p *rpcPermissions) restricted() bool {
+	return len(p.modules) > 0 || len(p.methods) > 0
+}
+
+// allowed reports whether method may be called.
+func (p *rpcPermissions) allowed(method string) bool {
+	if !p.restricted() {
+		return true
+	}
+	if p.methods[method] {
+		return true
+	}
+	return p.modules[strings.SplitN(method, "_", 2)[0]]
+}
+
+type rpcToken struct {
+	token []byte
+	perms *rpcPermissions
+}
+
+// rpcAuth authenticates requests according to a RPCAuthConfig.
+type rpcAuth struct {
+	secret []byte
+	tokens []rpcToken
+}
+
+func newRPCAuth(config *RPCAuthConfig) (*rpcAuth, error) {
+	auth := new(rpcAuth)
+	if config.JWTSecret != "" {
+		blob, err := ioutil.ReadFile(config.JWTSecret)
+		if err != nil {
+			return nil, fmt.Errorf("can't read JWT secret: %v", err)
+		}
+		secret, err := hexutil.Decode("0x" + strings.TrimPrefix(strings.TrimSpace(string(blob)), "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT secret: %v", err)
+		}
+		if len(secret) < 32 {
+			return nil, errors.New("JWT secret must be at least 32 bytes")
+		}
+		auth.secret = secret
+	}
+	for _, token := range config.Tokens {
+		if token.Token == "" {
+			return nil, errors.New("empty RPC token")
+		}
+		hash := sha256.Sum256([]byte(token.Token))
+		key := fmt.Sprintf("token:%x", hash[:8])
+		auth.tokens = append(auth.tokens, rpcToken{[]byte(token.Token), newRPCPermissions(key, token.Modules, token.Methods)})
+	}
+	if auth.secret == nil && len(auth.tokens) == 0 {
+		return nil, errors.New("RPC authentication needs a JWT secret or tokens")
+	}
+	return auth, nil
+}
+
+// authenticate checks the bearer token of r, returning what it may call.
+func (a *rpcAuth) authenticate(r *http.Request) (*rpcPermissions, error) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return nil, errors.New("missing bearer token")
+	}
+	token := strings.TrimPrefix(header, "Bearer ")
+	for _, t := range a.tokens {
+		if subtle.ConstantTimeCompare(t.token, []byte(token)) == 1 {
+			return t.perms, nil
+		}
+	}
+	if a.secret == nil {
+		return nil, errors.New("invalid token")
+	}
+	var claims jwtClaims
+	if err := rpc.VerifyJWT(a.secret, token, &claims); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	switch {
+	case claims.ExpiresAt != nil:
+		if now.After(time.Unix(*claims.ExpiresAt, 0)) {
+			return nil, errors.New("token expired")
+		}
+	case claims.IssuedAt != nil:
+		if drift := now.Sub(time.Unix(*claims.IssuedAt, 0)); drift > jwtMaxDrift || drift < -jwtMaxDrift {
+			return nil, errors.New("stale token")
+		}
+	default:
+		return nil, errors.New("token without issue time")
+	}
+	var key string
+	if claims.Subject != "" {
+		key = "jwt:" + claims.Subject
+	}
+	return newRPCPermissions(key, claims.Modules, claims.Methods), nil
+}
+
+// newAuthHandler returns a handler authenticating requests before passing them
+// on to next, which only serves the methods the credentials allow. It applies
+// to WebSocket upgrades as well, restricting the calls on the connection.
+func newAuthHandler(auth *rpcAuth, next http.Handler) http.Handler {
+	if auth == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		perms, ok := auth.check(w, r)
+		if !ok {
+			return
+		}
+		ctx := rpc.WithMethodFilter(r.Context(), perms.allowed)
+		if perms.key != "" {
+			ctx = rpc.WithClientKey(ctx, perms.key)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// newHandlerAuthHandler returns a handler authenticating requests to the handlers
+// registered with Node.RegisterHandler before passing them on to next. Those don't
+// serve methods the credentials could name, so restricted credentials are refused.
+func newHandlerAuthHandler(auth *rpcAuth, next http.Handler) http.Handler {
+	if auth == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		perms, ok := auth.check(w, r)
+		if !ok {
+			return
+		}
+		if perms.restricted() {
+			http.Error(w, "token is restricted to RPC methods", http.StatusForbidden)
+			return
+		}
+		ctx := r.Context()
+		if perms.key != "" {
+			ctx = rpc.WithClientKey(ctx, perms.key)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// check authenticates r, replying with status 401 if it fails.
+func (a *rpcAuth) check(w http.ResponseWriter, r *http.Request) (*rpcPermissions, bool) {
+	perms, err := a.authenticate(r)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return nil, false
+	}
+	return perms, true
+}
@@ -19,6 +19,7 @@ package node
 import (
 	"compress/gzip"
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -39,17 +40,22 @@ type httpConfig struct {
 	Modules            []string
 	CorsAllowedOrigins []string
 	Vhosts             []string
+	auth               *rpcAuth     // nil if requests aren't authenticated
+	limiter            *rpc.Limiter // nil if requests aren't limited
 }
 
 // wsConfig is the JSON-RPC/Websocket configuration
 type wsConfig struct {
 	Origins []string
 	Modules []string
+	auth    *rpcAuth     // nil if connections aren't authenticated
+	limiter *rpc.Limiter // nil if calls aren't limited
 }
 
 type rpcHandler struct {
 	http.Handler
 	server *rpc.Server
+	mux    http.Handler // serves the handlers registered via Node.RegisterHandler
 }
 
 type httpServer struct {
@@ -60,6 +66,9 @@ type httpServer struct {
 	mu       sync.Mutex
 	server   *http.Server
 	listener net.Listener // non-nil when server is running
+	tls      *rpcTLS      // nil if the server doesn't use TLS
+
+	callTimeouts *rpc.CallTimeouts // nil if calls don't time out
 
 	// HTTP RPC handler things.
 	httpConfig  httpConfig
@@ -137,17 +146,21 @@ func (h *httpServer) start() error {
 		h.disableWS()
 		return err
 	}
+	if h.tls != nil {
+		listener = tls.NewListener(listener, h.tls.serverConfig())
+	}
 	h.listener = listener
 	go h.server.Serve(listener)
 
 	// if server is websocket only, return after logging
 	if h.wsAllowed() && !h.rpcAllowed() {
-		h.log.Info("WebSocket enabled", "url", fmt.Sprintf("ws://%v", listener.Addr()))
+		h.log.Info("WebSocket enabled", "url", fmt.Sprintf("%s://%v", h.scheme("ws"), listener.Addr()))
 		return nil
 	}
 	// Log http endpoint.
 	h.log.Info("HTTP server started",
 		"endpoint", listener.Addr(),
+		"tls", h.tls != nil,
 		"cors", strings.Join(h.httpConfig.CorsAllowedOrigins, ","),
 		"vhosts", strings.Join(h.httpConfig.Vhosts, ","),
 	)
@@ -162,13 +175,21 @@ func (h *httpServer) start() error {
 	for _, path := range paths {
 		name := h.handlerNames[path]
 		if !logged[name] {
-			log.Info(name+" enabled", "url", "http://"+listener.Addr().String()+path)
+			log.Info(name+" enabled", "url", h.scheme("http")+"://"+listener.Addr().String()+path)
 			logged[name] = true
 		}
 	}
 	return nil
 }
 
+// scheme returns the URL scheme of the server, given the scheme without TLS.
+func (h *httpServer) scheme(plain string) string {
+	if h.tls != nil {
+		return plain + "s"
+	}
+	return plain
+}
+
 func (h *httpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	rpc := h.httpHandler.Load().(*rpcHandler)
 	if r.RequestURI == "/" {
@@ -185,8 +206,9 @@ func (h *httpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	} else if rpc != nil {
 		// Requests to a path below root are handled by the mux,
 		// which has all the handlers registered via Node.RegisterHandler.
-		// These are made available when RPC is enabled.
-		h.mux.ServeHTTP(w, r)
+		// These are made available when RPC is enabled, behind its
+		// authentication and limits.
+		rpc.mux.ServeHTTP(w, r)
 		return
 	}
 	w.WriteHeader(404)
@@ -235,13 +257,18 @@ func (h *httpServer) enableRPC(apis []rpc.API, config httpConfig) error {
 
 	// Create RPC server and handler.
 	srv := rpc.NewServer()
+	srv.SetLimiter(config.limiter)
+	if h.callTimeouts != nil {
+		srv.SetCallTimeouts(*h.callTimeouts)
+	}
 	if err := RegisterApisFromWhitelist(apis, config.Modules, srv, false); err != nil {
 		return err
 	}
 	h.httpConfig = config
 	h.httpHandler.Store(&rpcHandler{
-		Handler: NewHTTPHandlerStack(srv, config.CorsAllowedOrigins, config.Vhosts),
+		Handler: NewHTTPHandlerStack(newCertHandler(h.tls, newAuthHandler(config.auth, srv)), config.CorsAllowedOrigins, config.Vhosts),
 		server:  srv,
+		mux:     newHandlerCertHandler(h.tls, newHandlerAuthHandler(config.auth, config.limiter.HTTPHandler(&h.mux))),
 	})
 	return nil
 }
@@ -267,12 +294,16 @@ func (h *httpServer) enableWS(apis []rpc.API, config wsConfig) error {
 
 	// Create RPC server and handler.
 	srv := rpc.NewServer()
+	srv.SetLimiter(config.limiter)
+	if h.callTimeouts != nil {
+		srv.SetCallTimeouts(*h.callTimeouts)
+	}
 	if err := RegisterApisFromWhitelist(apis, config.Modules, srv, false); err != nil {
 		return err
 	}
 	h.wsConfig = config
 	h.wsHandler.Store(&rpcHandler{
-		Handler: srv.WebsocketHandler(config.Origins),
+		Handler: newCertHandler(h.tls, newAuthHandler(config.auth, srv.WebsocketHandler(config.Origins))),
 		server:  srv,
 	})
 	return nil
@@ -429,6 +460,8 @@ type ipcServer struct {
 	log      log.Logger
 	endpoint string
 
+	callTimeouts *rpc.CallTimeouts // nil if calls don't time out
+
 	mu       sync.Mutex
 	listener net.Listener
 	srv      *rpc.Server
@@ -450,6 +483,9 @@ func (is *ipcServer) start(apis []rpc.API) error {
 	if err != nil {
 		return err
 	}
+	if is.callTimeouts != nil {
+		srv.SetCallTimeouts(*is.callTimeouts)
+	}
 	is.log.Info("IPC endpoint opened", "url", is.endpoint)
 	is.listener, is.srv = listener, srv
 	return nil
@@ -18,11 +18,17 @@ package node
 
 import (
 	"bytes"
+	"context"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"ethereum/rpc-network/internal/testlog"
 	"ethereum/rpc-network/rpc"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
@@ -108,3 +114,116 @@ func testRequest(t *testing.T, key, value, host string, srv *httpServer) *http.R
 	}
 	return resp
 }
+
+// TestAuthHandler makes sure requests and websocket upgrades are authenticated
+// and restricted to what the credentials allow.
+func TestAuthHandler(t *testing.T) {
+	secret := bytes.Repeat([]byte{0x42}, 32)
+	dir, err := ioutil.TempDir("", "rpcauth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	secretFile := filepath.Join(dir, "jwtsecret")
+	if err := ioutil.WriteFile(secretFile, []byte(hexutil.Encode(secret)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	auth, err := newRPCAuth(&RPCAuthConfig{
+		JWTSecret: secretFile,
+		Tokens: []RPCToken{
+			{Token: "all"},
+			{Token: "eth", Modules: []string{"eth"}},
+		},
+	})
+	assert.NoError(t, err)
+	srv := createAndStartServer(t, httpConfig{auth: auth}, true, wsConfig{auth: auth})
+	defer srv.stop()
+	url := "http://" + srv.listenAddr()
+
+	resp := testRequest(t, "", "", "", srv)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp = testRequest(t, "Authorization", "Bearer nope", "", srv)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	call := func(creds rpc.Credentials) error {
+		client, err := rpc.DialWithCredentials(context.Background(), url, creds)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		var modules map[string]string
+		return client.Call(&modules, "rpc_modules")
+	}
+	assert.NoError(t, call(rpc.BearerToken("all")))
+	assert.Error(t, call(rpc.BearerToken("eth")), "call outside the token's modules")
+	assert.NoError(t, call(rpc.JWTCredentials(secret, map[string]interface{}{"modules": []string{"rpc"}})))
+	assert.Error(t, call(rpc.JWTCredentials(secret, map[string]interface{}{"methods": []string{"rpc_other"}})))
+	stale, _ := rpc.SignJWT(secret, map[string]interface{}{"iat": time.Now().Add(-time.Hour).Unix()})
+	assert.Error(t, call(rpc.BearerToken(stale)), "call with a stale token")
+
+	// the same holds for websocket connections
+	wsurl := "ws://" + srv.listenAddr()
+	_, err = rpc.DialWebsocket(context.Background(), wsurl, "")
+	assert.Error(t, err, "websocket upgrade without credentials")
+	client, err := rpc.DialWithCredentials(context.Background(), wsurl, rpc.BearerToken("eth"))
+	assert.NoError(t, err)
+	defer client.Close()
+	assert.Error(t, client.Call(nil, "rpc_modules"), "websocket call outside the token's modules")
+}
+
+// TestLimitsByToken makes sure authenticated clients are limited by their token.
+func TestLimitsByToken(t *testing.T) {
+	auth, err := newRPCAuth(&RPCAuthConfig{Tokens: []RPCToken{{Token: "a"}, {Token: "b"}}})
+	assert.NoError(t, err)
+	limiter := rpc.NewLimiter(rpc.LimitConfig{Rate: 0.001, Burst: 1})
+	srv := createAndStartServer(t, httpConfig{auth: auth, limiter: limiter}, false, wsConfig{})
+	defer srv.stop()
+
+	call := func(token string) error {
+		client, err := rpc.DialWithCredentials(context.Background(), "http://"+srv.listenAddr(), rpc.BearerToken(token))
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		var modules map[string]string
+		return client.Call(&modules, "rpc_modules")
+	}
+	assert.NoError(t, call("a"))
+	assert.Error(t, call("a"), "call over the limit of the token")
+	assert.NoError(t, call("b"))
+}
+
+// TestRegisteredHandlerAuth makes sure the handlers registered next to the RPC
+// endpoint, like /graphql, are authenticated and limited as well.
+func TestRegisteredHandlerAuth(t *testing.T) {
+	auth, err := newRPCAuth(&RPCAuthConfig{Tokens: []RPCToken{{Token: "all"}, {Token: "eth", Modules: []string{"eth"}}}})
+	assert.NoError(t, err)
+	limiter := rpc.NewLimiter(rpc.LimitConfig{Rate: 0.001, Burst: 2})
+	srv := newHTTPServer(testlog.Logger(t, log.LvlDebug), rpc.DefaultHTTPTimeouts)
+	srv.mux.Handle("/graphql", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	assert.NoError(t, srv.enableRPC(nil, httpConfig{auth: auth, limiter: limiter}))
+	assert.NoError(t, srv.setListenAddr("localhost", 0))
+	assert.NoError(t, srv.start())
+	defer srv.stop()
+
+	get := func(token string) int {
+		req, _ := http.NewRequest("POST", "http://"+srv.listenAddr()+"/graphql", bytes.NewReader([]byte(`{"query":"{syncing{currentBlock}}"}`)))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Equal(t, http.StatusUnauthorized, get(""))
+	assert.Equal(t, http.StatusUnauthorized, get("nope"))
+	assert.Equal(t, http.StatusForbidden, get("eth"), "token restricted to modules")
+	assert.Equal(t, http.StatusOK, get("all"))
+	assert.Equal(t, http.StatusOK, get("all"))
+	assert.Equal(t, http.StatusTooManyRequests, get("all"), "request over the limit of the token")
+}
@@ -0,0 +1,193 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+
+	"ethereum/rpc-network/rpc"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// RPCTLSConfig configures TLS on the HTTP and WebSocket RPC endpoints. The
+// certificate files are reloaded when the process receives SIGHUP.
+type RPCTLSConfig struct {
+	CertFile string // PEM encoded certificate chain of the server
+	KeyFile  string // PEM encoded private key of the server
+
+	// ClientCAFile is the path of PEM encoded CA certificates. If set, clients
+	// must present a certificate signed by one of them.
+	ClientCAFile string `toml:",omitempty"`
+
+	// ClientCerts restricts what clients may call by the subject of their
+	// certificate. If set, clients whose certificate isn't listed are refused.
+	ClientCerts []RPCClientCert `toml:",omitempty"`
+}
+
+// RPCClientCert restricts the calls of clients presenting a certificate with the
+// given subject common name. A certificate with neither modules nor methods may
+// call everything the endpoint serves. Clients with the same certificate subject
+// share their limits.
+type RPCClientCert struct {
+	CommonName string
+	Modules    []string `toml:",omitempty"` // namespaces the client may call
+	Methods    []string `toml:",omitempty"` // methods the client may call besides the modules
+}
+
+// rpcTLS holds the TLS configuration of the RPC endpoints.
+type rpcTLS struct {
+	config *RPCTLSConfig
+	certs  map[string]*rpcPermissions
+	loaded atomic.Value // *tls.Config
+}
+
+func newRPCTLS(config *RPCTLSConfig) (*rpcTLS, error) {
+	if config.CertFile == "" || config.KeyFile == "" {
+		return nil, errors.New("RPC TLS needs a certificate and key file")
+	}
+	if len(config.ClientCerts) > 0 && config.ClientCAFile == "" {
+		return nil, errors.New("RPC client certificates need a client CA file")
+	}
+	t := &rpcTLS{config: config, certs: make(map[string]*rpcPermissions)}
+	for _, cert := range config.ClientCerts {
+		if cert.CommonName == "" {
+			return nil, errors.New("RPC client certificate without common name")
+		}
+		t.certs[cert.CommonName] = newRPCPermissions("cert:"+cert.CommonName, cert.Modules, cert.Methods)
+	}
+	if err := t.reload(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// reload reads the certificate files. The previous configuration is kept in use
+// if they are invalid.
+func (t *rpcTLS) reload() error {
+	cert, err := tls.LoadX509KeyPair(t.config.CertFile, t.config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("can't load RPC TLS certificate: %v", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"http/1.1"},
+	}
+	if t.config.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(t.config.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("can't read RPC client CA file: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.New("no certificates in RPC client CA file")
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	t.loaded.Store(config)
+	return nil
+}
+
+// reloadOnSignal reloads the certificate files whenever SIGHUP is received,
+// until stop is closed.
+func (t *rpcTLS) reloadOnSignal(stop <-chan struct{}) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+
+	for {
+		select {
+		case <-sighup:
+			if err := t.reload(); err != nil {
+				log.Error("Failed to reload RPC TLS certificates", "err", err)
+			} else {
+				log.Info("Reloaded RPC TLS certificates", "cert", t.config.CertFile)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// serverConfig returns the TLS configuration of listeners, which picks up the
+// certificates last loaded on each handshake.
+func (t *rpcTLS) serverConfig() *tls.Config {
+	return &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return t.loaded.Load().(*tls.Config), nil
+		},
+	}
+}
+
+// newCertHandler returns a handler restricting the methods callable by clients
+// according to their certificate before passing requests on to next. Clients
+// whose certificate isn't listed are refused.
+func newCertHandler(t *rpcTLS, next http.Handler) http.Handler {
+	if t == nil || len(t.certs) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		perms, ok := t.check(w, r)
+		if !ok {
+			return
+		}
+		ctx := rpc.WithMethodFilter(r.Context(), perms.allowed)
+		ctx = rpc.WithClientKey(ctx, perms.key)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// newHandlerCertHandler is newCertHandler for the handlers registered with
+// Node.RegisterHandler, which restricted certificates may not use.
+func newHandlerCertHandler(t *rpcTLS, next http.Handler) http.Handler {
+	if t == nil || len(t.certs) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		perms, ok := t.check(w, r)
+		if !ok {
+			return
+		}
+		if perms.restricted() {
+			http.Error(w, "certificate is restricted to RPC methods", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(rpc.WithClientKey(r.Context(), perms.key)))
+	})
+}
+
+// check looks up the listed certificate of the client of r, replying with status
+// 403 if there is none.
+func (t *rpcTLS) check(w http.ResponseWriter, r *http.Request) (*rpcPermissions, bool) {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		if perms := t.certs[r.TLS.VerifiedChains[0][0].Subject.CommonName]; perms != nil {
+			return perms, true
+		}
+	}
+	http.Error(w, "client certificate not allowed", http.StatusForbidden)
+	return nil, false
+}
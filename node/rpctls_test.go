@@ -0,0 +1,190 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ethereum/rpc-network/internal/testlog"
+	"ethereum/rpc-network/rpc"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// testCA issues certificates for tests, writing them to dir.
+type testCA struct {
+	t    *testing.T
+	dir  string
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, dir string) *testCA {
+	ca := &testCA{t: t, dir: dir}
+	ca.cert, ca.key = ca.issue("ca", "test CA", true)
+	return ca
+}
+
+// issue creates a certificate with the given common name, signed by the CA, and
+// writes it to name.crt and name.key.
+func (ca *testCA) issue(name, cn string, isCA bool) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		ca.t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		DNSNames:              []string{"localhost"},
+	}
+	parent, signer := template, key
+	if !isCA {
+		parent, signer = ca.cert, ca.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	if err != nil {
+		ca.t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		ca.t.Fatal(err)
+	}
+	ca.write(name+".crt", "CERTIFICATE", der)
+	ca.write(name+".key", "EC PRIVATE KEY", keyDer)
+	cert, _ := x509.ParseCertificate(der)
+	return cert, key
+}
+
+func (ca *testCA) write(name, typ string, der []byte) {
+	blob := pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+	if err := ioutil.WriteFile(ca.path(name), blob, 0600); err != nil {
+		ca.t.Fatal(err)
+	}
+}
+
+func (ca *testCA) path(name string) string {
+	return filepath.Join(ca.dir, name)
+}
+
+func TestRPCTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rpctls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ca := newTestCA(t, dir)
+	ca.issue("server", "server", false)
+	ca.issue("restricted", "restricted", false)
+	ca.issue("other", "other", false)
+	ca.issue("stranger", "stranger", false)
+
+	rpcTLS, err := newRPCTLS(&RPCTLSConfig{
+		CertFile:     ca.path("server.crt"),
+		KeyFile:      ca.path("server.key"),
+		ClientCAFile: ca.path("ca.crt"),
+		ClientCerts:  []RPCClientCert{{CommonName: "restricted", Modules: []string{"web3"}}, {CommonName: "other"}},
+	})
+	assert.NoError(t, err)
+	srv := newHTTPServer(testlog.Logger(t, log.LvlDebug), rpc.DefaultHTTPTimeouts)
+	srv.tls = rpcTLS
+	srv.mux.Handle("/graphql", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	assert.NoError(t, srv.enableRPC(nil, httpConfig{}))
+	assert.NoError(t, srv.enableWS(nil, wsConfig{}))
+	assert.NoError(t, srv.setListenAddr("127.0.0.1", 0))
+	assert.NoError(t, srv.start())
+	defer srv.stop()
+
+	call := func(url, client string) error {
+		var certFile, keyFile string
+		if client != "" {
+			certFile, keyFile = ca.path(client+".crt"), ca.path(client+".key")
+		}
+		config, err := rpc.ClientTLSConfig(ca.path("ca.crt"), certFile, keyFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := rpc.DialTLS(context.Background(), url, config, nil)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		var modules map[string]string
+		return c.Call(&modules, "rpc_modules")
+	}
+	for _, url := range []string{"https://" + srv.listenAddr(), "wss://" + srv.listenAddr()} {
+		assert.Error(t, call(url, ""), "call without client certificate to %s", url)
+		assert.NoError(t, call(url, "other"), "call with unrestricted certificate to %s", url)
+		assert.Error(t, call(url, "restricted"), "call outside the certificate's modules to %s", url)
+		assert.Error(t, call(url, "stranger"), "call with unlisted certificate to %s", url)
+	}
+
+	// Unlisted and restricted certificates are refused by the registered handlers.
+	get := func(client string) int {
+		config, err := rpc.ClientTLSConfig(ca.path("ca.crt"), ca.path(client+".crt"), ca.path(client+".key"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		hc := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+		resp, err := hc.Get("https://" + srv.listenAddr() + "/graphql")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Equal(t, http.StatusForbidden, get("stranger"))
+	assert.Equal(t, http.StatusForbidden, get("restricted"))
+	assert.Equal(t, http.StatusOK, get("other"))
+
+	// Reloading picks up a new server certificate for new connections.
+	ca.issue("server", "reloaded", false)
+	assert.NoError(t, rpcTLS.reload())
+	clientConfig, _ := rpc.ClientTLSConfig(ca.path("ca.crt"), ca.path("other.crt"), ca.path("other.key"))
+	conn, err := tls.Dial("tcp", srv.listenAddr(), clientConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if cn := conn.ConnectionState().PeerCertificates[0].Subject.CommonName; cn != "reloaded" {
+		t.Errorf("wrong server certificate after reload: %q", cn)
+	}
+}
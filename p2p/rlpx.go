@@ -729,3 +729,52 @@ func putInt24(v uint32, b []byte) {
 	b[1] = byte(v >> 8)
 	b[2] = byte(v)
 }
+
+// RLPxConn is an RLPx connection carrying a protocol other than devp2p. It
+// authenticates with the secp256k1 node key, so a protocol running on it
+// shares its identity with the enode of the node. Unlike the devp2p
+// transport it doesn't set any deadlines, these are left to the caller.
+type RLPxConn struct {
+	fd net.Conn
+
+	rmu, wmu sync.Mutex
+	rw       *rlpxFrameRW
+}
+
+// NewRLPxConn wraps fd. Handshake must be called before any message is sent.
+func NewRLPxConn(fd net.Conn) *RLPxConn {
+	return &RLPxConn{fd: fd}
+}
+
+// Handshake runs the encryption handshake and returns the key of the remote
+// node. The dialer passes the key it expects, the listener passes nil.
+func (c *RLPxConn) Handshake(prv *ecdsa.PrivateKey, dial *ecdsa.PublicKey) (*ecdsa.PublicKey, error) {
+	t := &rlpx{fd: c.fd}
+	remote, err := t.doEncHandshake(prv, dial)
+	if err != nil {
+		return nil, err
+	}
+	c.wmu.Lock()
+	c.rw = t.rw
+	c.wmu.Unlock()
+	return remote, nil
+}
+
+// ReadMsg reads the next message.
+func (c *RLPxConn) ReadMsg() (Msg, error) {
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+	return c.rw.ReadMsg()
+}
+
+// WriteMsg writes msg.
+func (c *RLPxConn) WriteMsg(msg Msg) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	return c.rw.WriteMsg(msg)
+}
+
+// Close closes the underlying connection.
+func (c *RLPxConn) Close() error {
+	return c.fd.Close()
+}
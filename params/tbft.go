@@ -0,0 +1,283 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"path/filepath"
+	"time"
+)
+
+// MinimumCommitteeNumber is the smallest committee tbft can finalize with, the
+// committee tolerates one faulty member out of four.
+const MinimumCommitteeNumber = 4
+
+// emptyBlocksWaitStep is how long an empty proposal is held back at a time while
+// waiting for transactions, see ConsensusConfig.WaitForEmptyBlocks.
+const emptyBlocksWaitStep = time.Second
+
+// TbftConfig is the configuration of a tbft node.
+type TbftConfig struct {
+	RootDir   string // Directory the relative paths are resolved against
+	Moniker   string // Node name announced to the peers
+	P2P       *P2PConfig
+	Consensus *ConsensusConfig
+}
+
+// DefaultConfig returns the default tbft node configuration.
+func DefaultConfig() *TbftConfig {
+	return &TbftConfig{
+		Moniker:   "tbft",
+		P2P:       DefaultP2PConfig(),
+		Consensus: DefaultConsensusConfig(),
+	}
+}
+
+// TestConfig returns a tbft node configuration with short timeouts for testing.
+func TestConfig() *TbftConfig {
+	return &TbftConfig{
+		Moniker:   "tbft",
+		P2P:       TestP2PConfig(),
+		Consensus: TestConsensusConfig(),
+	}
+}
+
+// P2PConfig is the configuration of the tbft peer to peer network.
+type P2PConfig struct {
+	RootDir string
+
+	ListenAddress   string // Address to listen for incoming connections
+	ListenAddress1  string // Listen address for the committees with an even ID
+	ListenAddress2  string // Listen address for the committees with an odd ID
+	ExternalAddress string // Address advertised to the peers, ListenAddress if empty
+
+	Seeds           string // Comma separated list of seed nodes
+	PersistentPeers string // Comma separated list of nodes to keep connected to
+	PrivatePeerIDs  string // Comma separated list of peer IDs kept out of the address book
+	UPNP            bool   // Set up the port forwarding through UPNP
+
+	AddrBook       string // Path to the address book, relative to RootDir
+	AddrBookStrict bool   // Only accept routable addresses into the address book
+
+	MaxNumPeers         int
+	MaxNumInboundPeers  int
+	MaxNumOutboundPeers int
+
+	FlushThrottleTimeout    time.Duration // Time to wait before flushing the connection buffers
+	MaxPacketMsgPayloadSize int           // Maximum payload of a single packet in bytes
+	SendRate                int64         // Outgoing rate limit in bytes per second
+	RecvRate                int64         // Incoming rate limit in bytes per second
+
+	PexReactor       bool // Exchange peer addresses with the other nodes
+	SeedMode         bool // Crawl the network and disconnect, for seed nodes
+	AllowDuplicateIP bool // Accept several peers from the same IP
+
+	HandshakeTimeout time.Duration
+	DialTimeout      time.Duration
+
+	// Testing parameters
+	TestDialFail   bool // Fail every dial
+	TestFuzz       bool // Wrap the connections in a fuzzer
+	TestFuzzConfig *FuzzConnConfig
+}
+
+// DefaultP2PConfig returns the default p2p configuration of a tbft node.
+func DefaultP2PConfig() *P2PConfig {
+	return &P2PConfig{
+		ListenAddress:           "tcp://0.0.0.0:30310",
+		ListenAddress1:          "tcp://0.0.0.0:30310",
+		ListenAddress2:          "tcp://0.0.0.0:30311",
+		AddrBook:                "addrbook.json",
+		AddrBookStrict:          true,
+		MaxNumPeers:             50,
+		MaxNumInboundPeers:      40,
+		MaxNumOutboundPeers:     10,
+		FlushThrottleTimeout:    100 * time.Millisecond,
+		MaxPacketMsgPayloadSize: 1024,
+		SendRate:                5120000,
+		RecvRate:                5120000,
+		PexReactor:              true,
+		AllowDuplicateIP:        true,
+		HandshakeTimeout:        20 * time.Second,
+		DialTimeout:             3 * time.Second,
+		TestFuzzConfig:          DefaultFuzzConnConfig(),
+	}
+}
+
+// TestP2PConfig returns a p2p configuration for testing, listening on a random
+// local port and accepting local addresses.
+func TestP2PConfig() *P2PConfig {
+	cfg := DefaultP2PConfig()
+	cfg.ListenAddress = "tcp://127.0.0.1:0"
+	cfg.ListenAddress1 = "tcp://127.0.0.1:0"
+	cfg.ListenAddress2 = "tcp://127.0.0.1:0"
+	cfg.AddrBookStrict = false
+	cfg.FlushThrottleTimeout = 10 * time.Millisecond
+	return cfg
+}
+
+// AddrBookFile returns the full path to the address book.
+func (cfg *P2PConfig) AddrBookFile() string {
+	return rootify(cfg.AddrBook, cfg.RootDir)
+}
+
+// FuzzConnConfig is the configuration of the connection fuzzer used in testing.
+type FuzzConnConfig struct {
+	Mode         int           // Drop the traffic, or delay it
+	MaxDelay     time.Duration // Maximum delay of a read or write in delay mode
+	ProbDropRW   float64       // Probability of dropping a read or write
+	ProbDropConn float64       // Probability of dropping the connection
+	ProbSleep    float64       // Probability of delaying a read or write
+}
+
+// DefaultFuzzConnConfig returns the default connection fuzzer configuration.
+func DefaultFuzzConnConfig() *FuzzConnConfig {
+	return &FuzzConnConfig{
+		Mode:         0,
+		MaxDelay:     3 * time.Second,
+		ProbDropRW:   0.2,
+		ProbDropConn: 0.00,
+		ProbSleep:    0.00,
+	}
+}
+
+// ConsensusConfig is the configuration of the tbft consensus state machine. A
+// round waits TimeoutX for step X, and every further round waits TimeoutXDelta
+// longer.
+type ConsensusConfig struct {
+	RootDir string
+	WalPath string // Path to the write ahead log, relative to RootDir
+
+	TimeoutPropose        time.Duration
+	TimeoutProposeDelta   time.Duration
+	TimeoutPrevote        time.Duration
+	TimeoutPrevoteDelta   time.Duration
+	TimeoutPrecommit      time.Duration
+	TimeoutPrecommitDelta time.Duration
+	TimeoutCommit         time.Duration
+
+	// Move to the next height as soon as all the precommits are in
+	SkipTimeoutCommit bool
+
+	// Propose empty blocks, after holding them back up to CreateEmptyBlocksInterval
+	CreateEmptyBlocks         bool
+	CreateEmptyBlocksInterval time.Duration
+
+	// Reactor sleep durations
+	PeerGossipSleepDuration     time.Duration
+	PeerQueryMaj23SleepDuration time.Duration
+}
+
+// DefaultConsensusConfig returns the default consensus configuration.
+func DefaultConsensusConfig() *ConsensusConfig {
+	return &ConsensusConfig{
+		WalPath:                     filepath.Join("data", "cs.wal", "wal"),
+		TimeoutPropose:              3000 * time.Millisecond,
+		TimeoutProposeDelta:         500 * time.Millisecond,
+		TimeoutPrevote:              1000 * time.Millisecond,
+		TimeoutPrevoteDelta:         500 * time.Millisecond,
+		TimeoutPrecommit:            1000 * time.Millisecond,
+		TimeoutPrecommitDelta:       500 * time.Millisecond,
+		TimeoutCommit:               1000 * time.Millisecond,
+		SkipTimeoutCommit:           false,
+		CreateEmptyBlocks:           true,
+		CreateEmptyBlocksInterval:   0,
+		PeerGossipSleepDuration:     100 * time.Millisecond,
+		PeerQueryMaj23SleepDuration: 2000 * time.Millisecond,
+	}
+}
+
+// TestConsensusConfig returns a consensus configuration with short timeouts for
+// testing.
+func TestConsensusConfig() *ConsensusConfig {
+	cfg := DefaultConsensusConfig()
+	cfg.TimeoutPropose = 40 * time.Millisecond
+	cfg.TimeoutProposeDelta = 1 * time.Millisecond
+	cfg.TimeoutPrevote = 10 * time.Millisecond
+	cfg.TimeoutPrevoteDelta = 1 * time.Millisecond
+	cfg.TimeoutPrecommit = 10 * time.Millisecond
+	cfg.TimeoutPrecommitDelta = 1 * time.Millisecond
+	cfg.TimeoutCommit = 10 * time.Millisecond
+	cfg.SkipTimeoutCommit = true
+	cfg.PeerGossipSleepDuration = 5 * time.Millisecond
+	cfg.PeerQueryMaj23SleepDuration = 250 * time.Millisecond
+	return cfg
+}
+
+// Propose returns the amount of time to wait for a proposal in the given round.
+func (cfg *ConsensusConfig) Propose(round int) time.Duration {
+	return cfg.TimeoutPropose + cfg.TimeoutProposeDelta*time.Duration(round)
+}
+
+// Prevote returns the amount of time to wait for straggler prevotes in the
+// given round.
+func (cfg *ConsensusConfig) Prevote(round int) time.Duration {
+	return cfg.TimeoutPrevote + cfg.TimeoutPrevoteDelta*time.Duration(round)
+}
+
+// Precommit returns the amount of time to wait for straggler precommits in the
+// given round.
+func (cfg *ConsensusConfig) Precommit(round int) time.Duration {
+	return cfg.TimeoutPrecommit + cfg.TimeoutPrecommitDelta*time.Duration(round)
+}
+
+// Commit returns the time the next height starts, for a block committed at t.
+func (cfg *ConsensusConfig) Commit(t time.Time) time.Time {
+	return t.Add(cfg.TimeoutCommit)
+}
+
+// CatchupTime returns the latest time the next height starts at in step with a
+// proposal made at t. A node starting later is catching up and starts at once.
+func (cfg *ConsensusConfig) CatchupTime(t time.Time) time.Time {
+	return t.Add(cfg.Propose(0) + cfg.Prevote(0) + cfg.Precommit(0) + cfg.TimeoutCommit)
+}
+
+// WaitForEmptyBlocks reports whether an empty proposal should be held back for
+// the wait-th time. Empty proposals are held back in steps of a second, until
+// CreateEmptyBlocksInterval has passed.
+func (cfg *ConsensusConfig) WaitForEmptyBlocks(wait int) bool {
+	return cfg.CreateEmptyBlocksInterval > 0 &&
+		time.Duration(wait-1)*emptyBlocksWaitStep < cfg.CreateEmptyBlocksInterval
+}
+
+// EmptyBlocksIntervalForPer returns how long an empty proposal is held back for
+// the wait-th time.
+func (cfg *ConsensusConfig) EmptyBlocksIntervalForPer(wait int) time.Duration {
+	if left := cfg.CreateEmptyBlocksInterval - time.Duration(wait-1)*emptyBlocksWaitStep; left < emptyBlocksWaitStep {
+		return left
+	}
+	return emptyBlocksWaitStep
+}
+
+// PeerGossipSleep returns the amount of time to sleep if there is nothing to
+// send from the consensus reactor.
+func (cfg *ConsensusConfig) PeerGossipSleep() time.Duration {
+	return cfg.PeerGossipSleepDuration
+}
+
+// PeerQueryMaj23Sleep returns the amount of time to sleep after each
+// VoteSetMaj23Message is sent in the consensus reactor.
+func (cfg *ConsensusConfig) PeerQueryMaj23Sleep() time.Duration {
+	return cfg.PeerQueryMaj23SleepDuration
+}
+
+// rootify returns path if it's absolute, and path joined to root otherwise.
+func rootify(path, root string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(root, path)
+}
@@ -0,0 +1,164 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	errJWTFormat    = errors.New("malformed JWT")
+	errJWTAlgorithm = errors.New("unsupported JWT algorithm, only HS256 is accepted")
+	errJWTSignature = errors.New("invalid JWT signature")
+)
+
+type methodFilterKey struct{}
+
+// WithMethodFilter returns a copy of ctx restricting the methods served on it to
+// those allowed returns true for. The HTTP and WebSocket handlers of Server apply
+// the filter found in the context of the request, all other methods are answered
+// as if they were not available. A filter already present in ctx still applies.
+func WithMethodFilter(ctx context.Context, allowed func(method string) bool) context.Context {
+	if outer, ok := ctx.Value(methodFilterKey{}).(func(string) bool); ok {
+		inner := allowed
+		allowed = func(method string) bool { return outer(method) && inner(method) }
+	}
+	return context.WithValue(ctx, methodFilterKey{}, allowed)
+}
+
+// methodAllowed reports whether the filter of ctx, if any, allows method.
+func methodAllowed(ctx context.Context, method string) bool {
+	allowed, ok := ctx.Value(methodFilterKey{}).(func(string) bool)
+	return !ok || allowed(method)
+}
+
+// connContext returns the context of a connection upgraded from a request with
+// context ctx, keeping the method filter and client key of the request.
+func connContext(ctx context.Context) context.Context {
+	connCtx := context.Background()
+	if allowed, ok := ctx.Value(methodFilterKey{}).(func(string) bool); ok {
+		connCtx = WithMethodFilter(connCtx, allowed)
+	}
+	if key, ok := ctx.Value(clientKeyKey{}).(string); ok {
+		connCtx = WithClientKey(connCtx, key)
+	}
+	return connCtx
+}
+
+// Credentials authenticate the requests of a client.
+type Credentials interface {
+	// Authorization returns the value of the Authorization header.
+	Authorization() (string, error)
+}
+
+type bearerToken string
+
+// BearerToken returns credentials sending the static token as a bearer token.
+func BearerToken(token string) Credentials {
+	return bearerToken(token)
+}
+
+func (t bearerToken) Authorization() (string, error) {
+	return "Bearer " + string(t), nil
+}
+
+type jwtCredentials struct {
+	secret []byte
+	claims map[string]interface{}
+}
+
+// JWTCredentials returns credentials sending a HS256 JWT signed with secret as
+// bearer token. The token carries claims and is signed anew for every use, with
+// the current time as the "iat" claim.
+func JWTCredentials(secret []byte, claims map[string]interface{}) Credentials {
+	return &jwtCredentials{secret: secret, claims: claims}
+}
+
+func (c *jwtCredentials) Authorization() (string, error) {
+	claims := map[string]interface{}{"iat": time.Now().Unix()}
+	for k, v := range c.claims {
+		claims[k] = v
+	}
+	token, err := SignJWT(c.secret, claims)
+	if err != nil {
+		return "", err
+	}
+	return "Bearer " + token, nil
+}
+
+// jwtHeader is the header of every token issued by SignJWT.
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// SignJWT returns a HS256 JWT carrying claims, signed with secret.
+func SignJWT(secret []byte, claims interface{}) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(jwtSign(secret, signed)), nil
+}
+
+// VerifyJWT checks token is a HS256 JWT signed with secret and decodes its
+// claims into claims. Checking the claims is left to the caller.
+func VerifyJWT(secret []byte, token string, claims interface{}) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return errJWTFormat
+	}
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return errJWTFormat
+	}
+	var h struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(header, &h); err != nil {
+		return errJWTFormat
+	}
+	if h.Alg != "HS256" {
+		return errJWTAlgorithm
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return errJWTFormat
+	}
+	if !hmac.Equal(sig, jwtSign(secret, parts[0]+"."+parts[1])) {
+		return errJWTSignature
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return errJWTFormat
+	}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return errJWTFormat
+	}
+	return nil
+}
+
+func jwtSign(secret []byte, signed string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return mac.Sum(nil)
+}
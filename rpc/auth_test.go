@@ -0,0 +1,108 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJWT(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	token, err := SignJWT(secret, map[string]interface{}{"iat": 1, "modules": []string{"test"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claims struct {
+		IssuedAt int64    `json:"iat"`
+		Modules  []string `json:"modules"`
+	}
+	if err := VerifyJWT(secret, token, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.IssuedAt != 1 || len(claims.Modules) != 1 || claims.Modules[0] != "test" {
+		t.Fatalf("wrong claims %+v", claims)
+	}
+	if err := VerifyJWT([]byte("another secret"), token, &claims); err != errJWTSignature {
+		t.Fatalf("token verified with the wrong secret: %v", err)
+	}
+	if err := VerifyJWT(secret, token[:len(token)-2], &claims); err == nil {
+		t.Fatal("truncated token verified")
+	}
+}
+
+// authTestHandler only lets through requests authorized with token, and only
+// for methods of the test namespace.
+func authTestHandler(srv *Server, token string, ws bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		r = r.WithContext(WithMethodFilter(r.Context(), func(method string) bool {
+			return strings.HasPrefix(method, "test_")
+		}))
+		if ws {
+			srv.WebsocketHandler([]string{"*"}).ServeHTTP(w, r)
+		} else {
+			srv.ServeHTTP(w, r)
+		}
+	})
+}
+
+func TestClientCredentials(t *testing.T) {
+	for _, ws := range []bool{false, true} {
+		srv := newTestServer()
+		httpsrv := httptest.NewServer(authTestHandler(srv, "secret", ws))
+		url := httpsrv.URL
+		if ws {
+			url = "ws:" + strings.TrimPrefix(url, "http:")
+		}
+
+		if _, err := DialWithCredentials(context.Background(), url, BearerToken("wrong")); err == nil && ws {
+			t.Fatal("websocket dialed with the wrong token")
+		}
+		client, err := DialWithCredentials(context.Background(), url, BearerToken("secret"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result echoResult
+		if err := client.Call(&result, "test_echo", "hello", 10, &echoArgs{"world"}); err != nil {
+			t.Fatalf("ws=%v: allowed call failed: %v", ws, err)
+		}
+		err = client.Call(nil, "nftest_echo", 1)
+		if rerr, ok := err.(Error); !ok || rerr.ErrorCode() != -32601 {
+			t.Fatalf("ws=%v: filtered call not refused: %v", ws, err)
+		}
+		client.Close()
+		httpsrv.Close()
+		srv.Stop()
+	}
+}
+
+func TestMethodFilterNesting(t *testing.T) {
+	ctx := WithMethodFilter(context.Background(), func(method string) bool { return strings.HasPrefix(method, "eth_") })
+	ctx = WithMethodFilter(ctx, func(method string) bool { return method != "eth_sign" })
+	for method, want := range map[string]bool{"eth_call": true, "eth_sign": false, "admin_peers": false} {
+		if have := methodAllowed(ctx, method); have != want {
+			t.Errorf("methodAllowed(%q) = %v, want %v", method, have, want)
+		}
+	}
+}
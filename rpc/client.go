@@ -25,6 +25,7 @@ import (
 	"net/url"
 	"reflect"
 	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -77,6 +78,11 @@ type Client struct {
 	idgen    func() ID // for subscriptions
 	isHTTP   bool
 	services *serviceRegistry
+	connCtx  context.Context // parent of the contexts calls from the server see
+
+	interceptMu  sync.Mutex    // serializes Use
+	interceptors []Interceptor // protected by interceptMu
+	invoker      atomic.Value  // Invoker running the interceptors, unset if there are none
 
 	idCounter uint32
 
@@ -110,7 +116,7 @@ type clientConn struct {
 }
 
 func (c *Client) newClientConn(conn ServerCodec) *clientConn {
-	ctx := context.WithValue(context.Background(), clientContextKey{}, c)
+	ctx := context.WithValue(c.connCtx, clientContextKey{}, c)
 	handler := newHandler(ctx, conn, c.idgen, c.services)
 	return &clientConn{conn, handler}
 }
@@ -139,6 +145,9 @@ func (op *requestOp) wait(ctx context.Context, c *Client) (*jsonrpcMessage, erro
 		if !c.isHTTP {
 			select {
 			case c.reqTimeout <- op:
+				if op.sub == nil {
+					go c.sendCancel(op)
+				}
 			case <-c.closing:
 			}
 		}
@@ -185,6 +194,29 @@ func DialContext(ctx context.Context, rawurl string) (*Client, error) {
 	}
 }
 
+// DialWithCredentials creates a new RPC client authenticating with creds. HTTP
+// clients authenticate every request, WebSocket clients the connection upgrade,
+// also when reconnecting. Other transports don't support credentials.
+func DialWithCredentials(ctx context.Context, rawurl string, creds Credentials) (*Client, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https":
+		c, err := DialHTTP(rawurl)
+		if err != nil {
+			return nil, err
+		}
+		c.SetCredentials(creds)
+		return c, nil
+	case "ws", "wss":
+		return dialWebsocket(ctx, rawurl, "", newWebsocketDialer(), creds)
+	default:
+		return nil, fmt.Errorf("no credentials support for URL scheme %q", u.Scheme)
+	}
+}
+
 // Client retrieves the client from the context, if any. This can be used to perform
 // 'reverse calls' in a handler method.
 func ClientFromContext(ctx context.Context) (*Client, bool) {
@@ -197,14 +229,15 @@ func newClient(initctx context.Context, connect reconnectFunc) (*Client, error)
 	if err != nil {
 		return nil, err
 	}
-	c := initClient(conn, randomIDGenerator(), new(serviceRegistry))
+	c := initClient(context.Background(), conn, randomIDGenerator(), new(serviceRegistry))
 	c.reconnectFunc = connect
 	return c, nil
 }
 
-func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry) *Client {
+func initClient(connCtx context.Context, conn ServerCodec, idgen func() ID, services *serviceRegistry) *Client {
 	_, isHTTP := conn.(*httpConn)
 	c := &Client{
+		connCtx:     connCtx,
 		idgen:       idgen,
 		isHTTP:      isHTTP,
 		services:    services,
@@ -273,6 +306,20 @@ func (c *Client) SetHeader(key, value string) {
 	conn.mu.Unlock()
 }
 
+// SetCredentials authenticates the client's requests with creds, setting the
+// Authorization header of every request. Like SetHeader, this method only works
+// for clients using HTTP. Use DialWithCredentials to authenticate WebSocket
+// connections.
+func (c *Client) SetCredentials(creds Credentials) {
+	if !c.isHTTP {
+		return
+	}
+	conn := c.writeConn.(*httpConn)
+	conn.mu.Lock()
+	conn.creds = creds
+	conn.mu.Unlock()
+}
+
 // Call performs a JSON-RPC call with the given arguments and unmarshals into
 // result if no error occurred.
 //
@@ -285,6 +332,7 @@ func (c *Client) Call(result interface{}, method string, args ...interface{}) er
 
 // CallContext performs a JSON-RPC call with the given arguments. If the context is
 // canceled before the call has successfully returned, CallContext returns immediately.
+// On WebSocket and IPC connections the server is then told to cancel the call.
 //
 // The result must be a pointer so that package json can unmarshal into it. You
 // can also pass nil, in which case the result is ignored.
@@ -292,6 +340,10 @@ func (c *Client) CallContext(ctx context.Context, result interface{}, method str
 	if result != nil && reflect.TypeOf(result).Kind() != reflect.Ptr {
 		return fmt.Errorf("call result parameter must be pointer or nil interface: %v", result)
 	}
+	return c.invoke(ctx, &Request{Kind: CallRequest, Method: method, Args: args, Result: result})
+}
+
+func (c *Client) callContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
 	msg, err := c.newMessage(method, args...)
 	if err != nil {
 		return err
@@ -342,6 +394,10 @@ func (c *Client) BatchCall(b []BatchElem) error {
 //
 // Note that batch calls may not be executed atomically on the server side.
 func (c *Client) BatchCallContext(ctx context.Context, b []BatchElem) error {
+	return c.invoke(ctx, &Request{Kind: BatchRequest, Batch: b})
+}
+
+func (c *Client) batchCallContext(ctx context.Context, b []BatchElem) error {
 	msgs := make([]*jsonrpcMessage, len(b))
 	op := &requestOp{
 		ids:  make([]json.RawMessage, len(b)),
@@ -409,6 +465,21 @@ func (c *Client) Notify(ctx context.Context, method string, args ...interface{})
 	}
 }
 
+// sendCancel tells the server that the calls of op are no longer waited for, so
+// that it can stop executing them.
+func (c *Client) sendCancel(op *requestOp) {
+	params, err := json.Marshal(op.ids)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cancelNotifyTimeout)
+	defer cancel()
+	msg := &jsonrpcMessage{Version: vsn, Method: cancelMethod, Params: params}
+	if err := c.send(ctx, new(requestOp), msg); err != nil {
+		log.Debug("Failed to cancel RPC call", "err", err)
+	}
+}
+
 // EthSubscribe registers a subscripion under the "eth" namespace.
 func (c *Client) EthSubscribe(ctx context.Context, channel interface{}, args ...interface{}) (*ClientSubscription, error) {
 	return c.Subscribe(ctx, "eth", channel, args...)
@@ -443,7 +514,14 @@ func (c *Client) Subscribe(ctx context.Context, namespace string, channel interf
 	if c.isHTTP {
 		return nil, ErrNotificationsUnsupported
 	}
+	req := &Request{Kind: SubscribeRequest, Method: namespace + subscribeMethodSuffix, Args: args, Namespace: namespace, Channel: channel}
+	if err := c.invoke(ctx, req); err != nil {
+		return nil, err
+	}
+	return req.Subscription, nil
+}
 
+func (c *Client) subscribe(ctx context.Context, namespace string, chanVal reflect.Value, args ...interface{}) (*ClientSubscription, error) {
 	msg, err := c.newMessage(namespace+subscribeMethodSuffix, args...)
 	if err != nil {
 		return nil, err
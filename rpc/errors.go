@@ -25,6 +25,9 @@ var (
 	_ Error = new(invalidRequestError)
 	_ Error = new(invalidMessageError)
 	_ Error = new(invalidParamsError)
+	_ Error = new(limitExceededError)
+	_ Error = new(responseTooLargeError)
+	_ Error = new(replayMissError)
 )
 
 const defaultErrorCode = -32000
@@ -72,3 +75,46 @@ type invalidParamsError struct{ message string }
 func (e *invalidParamsError) ErrorCode() int { return -32602 }
 
 func (e *invalidParamsError) Error() string { return e.message }
+
+// a limit of the server was exceeded
+type limitExceededError struct{ message string }
+
+func (e *limitExceededError) ErrorCode() int { return -32005 }
+
+func (e *limitExceededError) Error() string { return e.message }
+
+// the result of a call exceeded the size limit of the server
+type responseTooLargeError struct{ limit int }
+
+func (e *responseTooLargeError) ErrorCode() int { return -32006 }
+
+func (e *responseTooLargeError) Error() string {
+	return fmt.Sprintf("response exceeds the limit of %d bytes", e.limit)
+}
+
+// no exchange was recorded for a replayed request
+type replayMissError struct{ method string }
+
+func (e *replayMissError) ErrorCode() int { return -32004 }
+
+func (e *replayMissError) Error() string {
+	return fmt.Sprintf("no recorded response for %s", e.method)
+}
+
+// a call exceeded the timeout of the server
+type timeoutError struct{ method string }
+
+func (e *timeoutError) ErrorCode() int { return -32002 }
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("call to %s timed out", e.method)
+}
+
+// a call was canceled by the client
+type canceledError struct{ method string }
+
+func (e *canceledError) ErrorCode() int { return -32800 }
+
+func (e *canceledError) Error() string {
+	return fmt.Sprintf("call to %s canceled", e.method)
+}
@@ -0,0 +1,72 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+type bigDataError struct{}
+
+func (bigDataError) Error() string          { return "big data" }
+func (bigDataError) ErrorCode() int         { return 445 }
+func (bigDataError) ErrorData() interface{} { return new(big.Int).Lsh(big.NewInt(1), 80) }
+
+type errorDataService struct{}
+
+func (errorDataService) Wrapped() error {
+	return fmt.Errorf("call failed: %w", bigDataError{})
+}
+
+func (errorDataService) Plain() error {
+	return errors.New("plain")
+}
+
+func TestErrorData(t *testing.T) {
+	server := NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("errtest", errorDataService{}); err != nil {
+		t.Fatal(err)
+	}
+	client := DialInProc(server)
+	defer client.Close()
+
+	// Codes and data of wrapped errors are sent, data is decoded as sent.
+	err := client.Call(nil, "errtest_wrapped")
+	var rpcErr Error
+	if !errors.As(err, &rpcErr) || rpcErr.ErrorCode() != 445 || err.Error() != "call failed: big data" {
+		t.Fatalf("wrong error: %v", err)
+	}
+	var data *big.Int
+	if ok, err := DecodeErrorData(err, &data); !ok || err != nil {
+		t.Fatalf("can't decode error data: %v, %v", ok, err)
+	}
+	if want := new(big.Int).Lsh(big.NewInt(1), 80); data.Cmp(want) != 0 {
+		t.Errorf("wrong error data: got %v, want %v", data, want)
+	}
+
+	err = client.Call(nil, "errtest_plain")
+	if !errors.As(err, &rpcErr) || rpcErr.ErrorCode() != defaultErrorCode {
+		t.Fatalf("wrong error: %v", err)
+	}
+	if ok, _ := DecodeErrorData(err, &data); ok {
+		t.Error("error without data decoded")
+	}
+}
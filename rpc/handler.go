@@ -61,6 +61,11 @@ type handler struct {
 	conn           jsonWriter                     // where responses will be sent
 	log            log.Logger
 	allowSubscribe bool
+	limiter        *Limiter      // nil if calls aren't limited
+	timeouts       *CallTimeouts // nil if calls don't time out
+
+	inflightMu sync.Mutex
+	inflight   map[string]context.CancelFunc // cancel functions of pending calls by ID
 
 	subLock    sync.Mutex
 	serverSubs map[ID]*Subscription
@@ -83,11 +88,14 @@ func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *
 		cancelRoot:     cancelRoot,
 		allowSubscribe: true,
 		serverSubs:     make(map[ID]*Subscription),
+		inflight:       make(map[string]context.CancelFunc),
 		log:            log.Root(),
 	}
 	if conn.remoteAddr() != "" {
 		h.log = h.log.New("conn", conn.remoteAddr())
 	}
+	h.limiter, _ = connCtx.Value(limiterKey{}).(*Limiter)
+	h.timeouts, _ = connCtx.Value(callTimeoutsKey{}).(*CallTimeouts)
 	h.unsubscribeCb = newCallback(reflect.Value{}, reflect.ValueOf(h.unsubscribe))
 	return h
 }
@@ -101,6 +109,23 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		})
 		return
 	}
+	if h.limiter != nil {
+		if err := h.limiter.checkBatch(len(msgs)); err != nil {
+			// Refuse every call of the batch.
+			h.startCallProc(func(cp *callProc) {
+				answers := make([]*jsonrpcMessage, 0, len(msgs))
+				for _, msg := range msgs {
+					if msg.isCall() {
+						answers = append(answers, msg.errorResponse(err))
+					}
+				}
+				if len(answers) > 0 {
+					h.conn.writeJSON(cp.ctx, answers)
+				}
+			})
+			return
+		}
+	}
 
 	// Handle non-call messages first:
 	calls := make([]*jsonrpcMessage, 0, len(msgs))
@@ -113,10 +138,12 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		return
 	}
 	// Process calls on a goroutine because they may block indefinitely:
+	ctxs, release := h.trackCalls(calls)
 	h.startCallProc(func(cp *callProc) {
+		defer release()
 		answers := make([]*jsonrpcMessage, 0, len(msgs))
-		for _, msg := range calls {
-			if answer := h.handleCallMsg(cp, msg); answer != nil {
+		for i, msg := range calls {
+			if answer := h.handleCallMsg(ctxs[i], cp, msg); answer != nil {
 				answers = append(answers, answer)
 			}
 		}
@@ -135,8 +162,10 @@ func (h *handler) handleMsg(msg *jsonrpcMessage) {
 	if ok := h.handleImmediate(msg); ok {
 		return
 	}
+	ctxs, release := h.trackCalls([]*jsonrpcMessage{msg})
 	h.startCallProc(func(cp *callProc) {
-		answer := h.handleCallMsg(cp, msg)
+		defer release()
+		answer := h.handleCallMsg(ctxs[0], cp, msg)
 		h.addSubscriptions(cp.notifiers)
 		if answer != nil {
 			h.conn.writeJSON(cp.ctx, answer)
@@ -237,6 +266,10 @@ func (h *handler) handleImmediate(msg *jsonrpcMessage) bool {
 			h.handleSubscriptionResult(msg)
 			return true
 		}
+		if msg.Method == cancelMethod {
+			h.handleCancel(msg)
+			return true
+		}
 		return false
 	case msg.isResponse():
 		h.handleResponse(msg)
@@ -286,16 +319,16 @@ func (h *handler) handleResponse(msg *jsonrpcMessage) {
 	}
 }
 
-// handleCallMsg executes a call message and returns the answer.
-func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
+// handleCallMsg executes a call message with context callCtx and returns the answer.
+func (h *handler) handleCallMsg(callCtx context.Context, cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
 	start := time.Now()
 	switch {
 	case msg.isNotification():
-		h.handleCall(ctx, msg)
+		h.handleCall(callCtx, cp, msg)
 		h.log.Debug("Served "+msg.Method, "t", time.Since(start))
 		return nil
 	case msg.isCall():
-		resp := h.handleCall(ctx, msg)
+		resp := h.handleCall(callCtx, cp, msg)
 		var ctx []interface{}
 		ctx = append(ctx, "reqid", idForLog{msg.ID}, "t", time.Since(start))
 		if resp.Error != nil {
@@ -316,9 +349,22 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 }
 
 // handleCall processes method calls.
-func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
+func (h *handler) handleCall(ctx context.Context, cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
+	if !methodAllowed(ctx, msg.Method) {
+		return msg.errorResponse(&methodNotFoundError{method: msg.Method})
+	}
+	if h.limiter != nil && !msg.isUnsubscribe() {
+		key := clientKey(ctx, h.conn.remoteAddr())
+		if err := h.limiter.allow(key, msg.Method); err != nil {
+			return msg.errorResponse(err)
+		}
+		if err := h.limiter.start(key); err != nil {
+			return msg.errorResponse(err)
+		}
+		defer h.limiter.done(key)
+	}
 	if msg.isSubscribe() {
-		return h.handleSubscribe(cp, msg)
+		return h.handleSubscribe(ctx, cp, msg)
 	}
 	var callb *callback
 	if msg.isUnsubscribe() {
@@ -333,8 +379,18 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage
 	if err != nil {
 		return msg.errorResponse(&invalidParamsError{err.Error()})
 	}
+	var cancel context.CancelFunc
+	if timeout := h.timeouts.timeout(msg.Method); timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
 	start := time.Now()
-	answer := h.runMethod(cp.ctx, msg, callb, args)
+	answer := h.runMethod(ctx, msg, callb, args)
+	if answer.stream != nil {
+		// The result is produced while it's written, the timeout lasts until then.
+		answer.stream.cancel = cancel
+	} else if cancel != nil {
+		cancel()
+	}
 
 	// Collect the statistics for RPC calls if metrics is enabled.
 	// We only care about pure rpc call. Filter out subscription.
@@ -352,7 +408,7 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage
 }
 
 // handleSubscribe processes *_subscribe method calls.
-func (h *handler) handleSubscribe(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
+func (h *handler) handleSubscribe(ctx context.Context, cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
 	if !h.allowSubscribe {
 		return msg.errorResponse(ErrNotificationsUnsupported)
 	}
@@ -379,7 +435,7 @@ func (h *handler) handleSubscribe(cp *callProc, msg *jsonrpcMessage) *jsonrpcMes
 	// Install notifier in context so the subscription handler can find it.
 	n := &Notifier{h: h, namespace: namespace}
 	cp.notifiers = append(cp.notifiers, n)
-	ctx := context.WithValue(cp.ctx, notifierKey{}, n)
+	ctx = context.WithValue(ctx, notifierKey{}, n)
 
 	return h.runMethod(ctx, msg, callb, args)
 }
@@ -388,9 +444,21 @@ func (h *handler) handleSubscribe(cp *callProc, msg *jsonrpcMessage) *jsonrpcMes
 func (h *handler) runMethod(ctx context.Context, msg *jsonrpcMessage, callb *callback, args []reflect.Value) *jsonrpcMessage {
 	result, err := callb.call(ctx, msg.Method, args)
 	if err != nil {
-		return msg.errorResponse(err)
+		return msg.errorResponse(callError(ctx, msg.Method, err))
+	}
+	var limit int
+	if h.limiter != nil {
+		limit = h.limiter.config.MaxResponseSize
+	}
+	if stream, ok := result.(Stream); ok && stream != nil {
+		return &jsonrpcMessage{Version: vsn, ID: msg.ID, stream: &streamResult{ctx: ctx, method: msg.Method, fn: stream, limit: limit}}
+	}
+	resp := msg.response(result)
+	if limit > 0 && len(resp.Result) > limit {
+		rpcLimitedResponseMeter.Mark(1)
+		return msg.errorResponse(&responseTooLargeError{limit})
 	}
-	return msg.response(result)
+	return resp
 }
 
 // unsubscribe is the callback function for all *_unsubscribe calls.
@@ -44,8 +44,9 @@ type httpConn struct {
 	url       string
 	closeOnce sync.Once
 	closeCh   chan interface{}
-	mu        sync.Mutex // protects headers
+	mu        sync.Mutex // protects headers and creds
 	headers   http.Header
+	creds     Credentials
 }
 
 // httpConn is treated specially by Client.
@@ -186,7 +187,15 @@ func (hc *httpConn) doRequest(ctx context.Context, msg interface{}) (io.ReadClos
 	// set headers
 	hc.mu.Lock()
 	req.Header = hc.headers.Clone()
+	creds := hc.creds
 	hc.mu.Unlock()
+	if creds != nil {
+		auth, err := creds.Authorization()
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("authorization", auth)
+	}
 
 	// do request
 	resp, err := hc.client.Do(req)
@@ -0,0 +1,244 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// RequestKind tells the kinds of requests passing through interceptors apart.
+type RequestKind int
+
+const (
+	CallRequest      RequestKind = iota // CallContext, fields Method, Args and Result
+	BatchRequest                        // BatchCallContext, field Batch
+	SubscribeRequest                    // Subscribe, fields Method, Args, Namespace, Channel and Subscription
+)
+
+func (k RequestKind) String() string {
+	switch k {
+	case CallRequest:
+		return "call"
+	case BatchRequest:
+		return "batch"
+	case SubscribeRequest:
+		return "subscribe"
+	default:
+		return fmt.Sprintf("RequestKind(%d)", int(k))
+	}
+}
+
+// Request is a request of a client passing through its interceptors.
+type Request struct {
+	Kind   RequestKind
+	Method string        // method called, namespace_subscribe for subscriptions
+	Args   []interface{} // arguments of the method
+	Result interface{}   // where the result of a call is decoded to, may be nil
+	Batch  []BatchElem   // elements of a batch, results and errors are set on them
+
+	Namespace    string              // namespace of a subscription
+	Channel      interface{}         // channel notifications of a subscription are sent to
+	Subscription *ClientSubscription // set once a subscription is established
+}
+
+// Invoker sends req, waiting for its response.
+type Invoker func(ctx context.Context, req *Request) error
+
+// Interceptor wraps the requests of a client, across all transports. It sends
+// req by calling next, and may inspect or change the request before and the
+// result after. Calling next several times retries the request, not calling it
+// fails the request with the returned error.
+type Interceptor func(ctx context.Context, req *Request, next Invoker) error
+
+// Use adds interceptors to the client. The first interceptor added is the
+// outermost, it sees requests first and results last.
+func (c *Client) Use(interceptors ...Interceptor) {
+	c.interceptMu.Lock()
+	defer c.interceptMu.Unlock()
+
+	c.interceptors = append(c.interceptors, interceptors...)
+	invoker := Invoker(c.send0)
+	for i := len(c.interceptors) - 1; i >= 0; i-- {
+		invoker = chainInterceptor(c.interceptors[i], invoker)
+	}
+	c.invoker.Store(invoker)
+}
+
+func chainInterceptor(interceptor Interceptor, next Invoker) Invoker {
+	return func(ctx context.Context, req *Request) error {
+		return interceptor(ctx, req, next)
+	}
+}
+
+// invoke sends req through the interceptors.
+func (c *Client) invoke(ctx context.Context, req *Request) error {
+	if invoker, ok := c.invoker.Load().(Invoker); ok {
+		return invoker(ctx, req)
+	}
+	return c.send0(ctx, req)
+}
+
+// send0 is the innermost invoker, sending req on the connection.
+func (c *Client) send0(ctx context.Context, req *Request) error {
+	switch req.Kind {
+	case CallRequest:
+		return c.callContext(ctx, req.Result, req.Method, req.Args...)
+	case BatchRequest:
+		return c.batchCallContext(ctx, req.Batch)
+	case SubscribeRequest:
+		sub, err := c.subscribe(ctx, req.Namespace, reflect.ValueOf(req.Channel), req.Args...)
+		req.Subscription = sub
+		return err
+	default:
+		return fmt.Errorf("unknown request kind %v", req.Kind)
+	}
+}
+
+// RetryConfig configures RetryInterceptor.
+type RetryConfig struct {
+	Attempts  int                  // attempts made at most, including the first one
+	Backoff   time.Duration        // delay before the first retry, doubled for every further one
+	MaxDelay  time.Duration        // upper bound of the delay, zero for none
+	Retryable func(err error) bool // reports whether a failure is retried, defaults to IsTransientError
+	Methods   []string             // methods retried besides the idempotent ones, namespace_subscribe for subscriptions
+}
+
+// RetryInterceptor returns an interceptor retrying failed requests with
+// exponential backoff and jitter. Only requests failing as a whole are retried,
+// errors of single batch elements are left to the caller.
+//
+// A failed request may have been run by the server anyway, so requests calling
+// methods that aren't idempotent are only retried if the server rejected them
+// over its limits, unless the methods are listed in config. Batches are retried
+// if all of their calls are.
+func RetryInterceptor(config RetryConfig) Interceptor {
+	if config.Retryable == nil {
+		config.Retryable = IsTransientError
+	}
+	allowed := make(map[string]bool, len(config.Methods))
+	for _, method := range config.Methods {
+		allowed[method] = true
+	}
+	repeatable := func(method string) bool {
+		return allowed[method] || IsIdempotentMethod(method)
+	}
+	return func(ctx context.Context, req *Request, next Invoker) error {
+		safe := repeatable(req.Method)
+		switch req.Kind {
+		case BatchRequest:
+			safe = true
+			for _, elem := range req.Batch {
+				safe = safe && repeatable(elem.Method)
+			}
+		case SubscribeRequest:
+			safe = allowed[req.Method]
+		}
+		delay := config.Backoff
+		for attempt := 1; ; attempt++ {
+			err := next(ctx, req)
+			if err == nil || attempt >= config.Attempts || !config.Retryable(err) {
+				return err
+			}
+			if !safe && !isLimitError(err) {
+				return err
+			}
+			wait := delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+			if delay *= 2; config.MaxDelay > 0 && delay > config.MaxDelay {
+				delay = config.MaxDelay
+			}
+		}
+	}
+}
+
+// idempotentPrefixes are the prefixes of the methods which don't change what the
+// server returns later on when called.
+var idempotentPrefixes = []string{
+	"eth_get", "eth_call", "eth_estimateGas", "eth_blockNumber", "eth_chainId",
+	"eth_gasPrice", "eth_syncing", "eth_protocolVersion", "eth_accounts",
+	"net_", "web3_", "rpc_", "txpool_", "debug_trace",
+}
+
+// IsIdempotentMethod reports whether calling method again after a failure has no
+// other effect than calling it once. It knows the read-only methods of the
+// standard namespaces.
+func IsIdempotentMethod(method string) bool {
+	if method == "eth_getFilterChanges" || method == "eth_getWork" {
+		return false // these hand out something new on every call
+	}
+	for _, prefix := range idempotentPrefixes {
+		if strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLimitError reports whether err is the server refusing a request over its
+// limits. Such requests weren't run.
+func isLimitError(err error) bool {
+	rerr, ok := err.(Error)
+	return ok && rerr.ErrorCode() == (&limitExceededError{}).ErrorCode()
+}
+
+// IsTransientError reports whether err may go away when retrying the request:
+// failures to reach the server and requests over the limits of the server.
+// Other errors returned by the server, results over its size limit among them, and
+// closed clients are permanent.
+func IsTransientError(err error) bool {
+	switch err {
+	case nil, ErrClientQuit, ErrNoResult, ErrNotificationsUnsupported, context.Canceled, context.DeadlineExceeded:
+		return false
+	}
+	if _, ok := err.(Error); ok {
+		return isLimitError(err)
+	}
+	return true
+}
+
+// MetricsInterceptor returns an interceptor recording the latency of requests
+// by method and outcome, as rpc/client/duration/<method>/<success|failure>.
+// Batches are recorded as the method "batch".
+func MetricsInterceptor() Interceptor {
+	return func(ctx context.Context, req *Request, next Invoker) error {
+		start := time.Now()
+		err := next(ctx, req)
+		method := req.Method
+		if req.Kind == BatchRequest {
+			method = "batch"
+		}
+		flag := "success"
+		if err != nil {
+			flag = "failure"
+		}
+		metrics.GetOrRegisterTimer(fmt.Sprintf("rpc/client/duration/%s/%s", method, flag), nil).UpdateSince(start)
+		return err
+	}
+}
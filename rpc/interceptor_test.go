@@ -0,0 +1,187 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+func TestClientInterceptors(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	var trace []string
+	tracer := func(name string) Interceptor {
+		return func(ctx context.Context, req *Request, next Invoker) error {
+			trace = append(trace, fmt.Sprintf("%s>%s:%s", name, req.Kind, req.Method))
+			err := next(ctx, req)
+			trace = append(trace, name+"<")
+			return err
+		}
+	}
+	client.Use(tracer("a"), tracer("b"))
+
+	var result echoResult
+	if err := client.Call(&result, "test_echo", "x", 1, &echoArgs{"y"}); err != nil {
+		t.Fatal(err)
+	}
+	if result.String != "x" {
+		t.Fatalf("wrong result %v", result)
+	}
+	if err := client.BatchCall([]BatchElem{{Method: "test_noArgsRets", Result: new(interface{})}}); err != nil {
+		t.Fatal(err)
+	}
+	nc := make(chan int)
+	sub, err := client.Subscribe(context.Background(), "nftest", nc, "someSubscription", 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub == nil || <-nc != 0 {
+		t.Fatal("subscription not established through the interceptors")
+	}
+	sub.Unsubscribe()
+
+	want := []string{
+		"a>call:test_echo", "b>call:test_echo", "b<", "a<",
+		"a>batch:", "b>batch:", "b<", "a<",
+		"a>subscribe:nftest_subscribe", "b>subscribe:nftest_subscribe", "b<", "a<",
+		"a>call:nftest_unsubscribe", "b>call:nftest_unsubscribe", "b<", "a<",
+	}
+	if !reflect.DeepEqual(trace, want) {
+		t.Fatalf("interceptors not run in order:\nhave %v\nwant %v", trace, want)
+	}
+}
+
+func TestRetryInterceptor(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	var (
+		attempts, failures int
+		failure            error = errors.New("connection reset")
+	)
+	client.Use(RetryInterceptor(RetryConfig{Attempts: 3, Backoff: time.Millisecond, Methods: []string{"test_noArgsRets"}}), func(ctx context.Context, req *Request, next Invoker) error {
+		attempts++
+		if attempts <= failures {
+			return failure
+		}
+		return next(ctx, req)
+	})
+
+	failures = 2
+	if err := client.Call(nil, "test_noArgsRets"); err != nil {
+		t.Fatalf("call not retried: %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("wrong number of attempts %d", attempts)
+	}
+
+	attempts, failures = 0, 5
+	if err := client.Call(nil, "test_noArgsRets"); err == nil || attempts != 3 {
+		t.Fatalf("retried beyond the attempts: %d %v", attempts, err)
+	}
+
+	// errors returned by the server are final
+	attempts, failures = 0, 0
+	if err := client.Call(nil, "test_returnError"); err == nil || attempts != 1 {
+		t.Fatalf("server error retried: %d %v", attempts, err)
+	}
+
+	// methods that aren't idempotent are only retried when the server refused them
+	attempts, failures = 0, 1
+	if err := client.Call(nil, "test_echo", "x", 1); err == nil || attempts != 1 {
+		t.Fatalf("call of a method that isn't idempotent retried: %d %v", attempts, err)
+	}
+	attempts = 0
+	if err := client.BatchCall([]BatchElem{{Method: "test_noArgsRets"}, {Method: "test_echo", Args: []interface{}{"x", 1}}}); err == nil || attempts != 1 {
+		t.Fatalf("batch with a method that isn't idempotent retried: %d %v", attempts, err)
+	}
+	attempts, failure = 0, &limitExceededError{"request rate limit exceeded"}
+	if err := client.Call(nil, "test_echo", "x", 1); err != nil || attempts != 2 {
+		t.Fatalf("call refused over the limits not retried: %d %v", attempts, err)
+	}
+
+	// results over the size limit would fail again
+	attempts, failure = 0, &responseTooLargeError{10}
+	if err := client.Call(nil, "test_echo", "x", 1); err == nil || attempts != 1 {
+		t.Fatalf("call with a result over the limit retried: %d %v", attempts, err)
+	}
+	attempts = 0
+	if err := client.Call(nil, "test_noArgsRets"); err == nil || attempts != 1 {
+		t.Fatalf("idempotent call with a result over the limit retried: %d %v", attempts, err)
+	}
+}
+
+func TestIsIdempotentMethod(t *testing.T) {
+	for method, want := range map[string]bool{
+		"eth_getBalance":         true,
+		"eth_call":               true,
+		"eth_estimateGas":        true,
+		"net_version":            true,
+		"eth_sendRawTransaction": false,
+		"eth_getFilterChanges":   false,
+		"eth_newFilter":          false,
+		"personal_unlockAccount": false,
+	} {
+		if have := IsIdempotentMethod(method); have != want {
+			t.Errorf("IsIdempotentMethod(%q) = %v, want %v", method, have, want)
+		}
+	}
+}
+
+func TestMetricsInterceptor(t *testing.T) {
+	enabled := metrics.Enabled
+	metrics.Enabled = true
+	defer func() { metrics.Enabled = enabled }()
+
+	server := newTestServer()
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+	client.Use(MetricsInterceptor())
+
+	// the registry is shared, other tests may have recorded calls already
+	names := []string{"rpc/client/duration/test_noArgsRets/success", "rpc/client/duration/test_returnError/failure"}
+	count := func(name string) int64 {
+		if timer, ok := metrics.DefaultRegistry.Get(name).(metrics.Timer); ok {
+			return timer.Count()
+		}
+		return 0
+	}
+	before := make(map[string]int64)
+	for _, name := range names {
+		before[name] = count(name)
+	}
+	client.Call(nil, "test_noArgsRets")
+	client.Call(nil, "test_returnError")
+	for _, name := range names {
+		if delta := count(name) - before[name]; delta != 1 {
+			t.Fatalf("%s recorded %d times, want 1", name, delta)
+		}
+	}
+}
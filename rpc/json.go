@@ -55,6 +55,8 @@ type jsonrpcMessage struct {
 	Params  json.RawMessage `json:"params,omitempty"`
 	Error   *jsonError      `json:"error,omitempty"`
 	Result  json.RawMessage `json:"result,omitempty"`
+
+	stream *streamResult // result written while sending, instead of Result
 }
 
 func (msg *jsonrpcMessage) isNotification() bool {
@@ -111,12 +113,13 @@ func errorMessage(err error) *jsonrpcMessage {
 		Code:    defaultErrorCode,
 		Message: err.Error(),
 	}}
-	ec, ok := err.(Error)
-	if ok {
+	// Codes and data of wrapped errors are sent as well.
+	var ec Error
+	if errors.As(err, &ec) {
 		msg.Error.Code = ec.ErrorCode()
 	}
-	de, ok := err.(DataError)
-	if ok {
+	var de DataError
+	if errors.As(err, &de) {
 		msg.Error.Data = de.ErrorData()
 	}
 	return msg
@@ -126,6 +129,25 @@ type jsonError struct {
 	Code    int         `json:"code"`
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
+
+	rawData json.RawMessage // data as received, set on errors decoded from JSON
+}
+
+func (err *jsonError) UnmarshalJSON(input []byte) error {
+	type jsonErrorFields jsonError
+	var dec struct {
+		jsonErrorFields
+		Data json.RawMessage `json:"data,omitempty"`
+	}
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	*err = jsonError(dec.jsonErrorFields)
+	if len(dec.Data) > 0 && !bytes.Equal(dec.Data, null) {
+		err.rawData = dec.Data
+		return json.Unmarshal(dec.Data, &err.Data)
+	}
+	return nil
 }
 
 func (err *jsonError) Error() string {
@@ -171,6 +193,10 @@ type jsonCodec struct {
 	encMu   sync.Mutex                // guards the encoder
 	encode  func(v interface{}) error // encoder to allow multiple transports
 	conn    deadlineCloser
+
+	// nextWriter returns the writer of the next message, nil if the transport
+	// can't write messages incrementally.
+	nextWriter func() (io.WriteCloser, error)
 }
 
 // NewFuncCodec creates a codec which uses the given functions to read and write. If conn
@@ -195,9 +221,15 @@ func NewCodec(conn Conn) ServerCodec {
 	enc := json.NewEncoder(conn)
 	dec := json.NewDecoder(conn)
 	dec.UseNumber()
-	return NewFuncCodec(conn, enc.Encode, dec.Decode)
+	codec := NewFuncCodec(conn, enc.Encode, dec.Decode).(*jsonCodec)
+	codec.nextWriter = func() (io.WriteCloser, error) { return nopWriteCloser{conn}, nil }
+	return codec
 }
 
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
 func (c *jsonCodec) remoteAddr() string {
 	return c.remote
 }
@@ -214,15 +246,73 @@ func (c *jsonCodec) readBatch() (msg []*jsonrpcMessage, batch bool, err error) {
 }
 
 func (c *jsonCodec) writeJSON(ctx context.Context, v interface{}) error {
+	if hasStream(v) {
+		return c.writeStream(ctx, v)
+	}
 	c.encMu.Lock()
 	defer c.encMu.Unlock()
 
+	c.setWriteDeadline(ctx)
+	return c.encode(v)
+}
+
+// writeStream writes messages containing streamed results. The results are produced
+// before taking the encoder lock, so that slow streams don't hold up the other
+// responses of the connection. Only the rest of results larger than streamHoldSize
+// is produced while writing to the connection.
+func (c *jsonCodec) writeStream(ctx context.Context, v interface{}) error {
+	if c.nextWriter == nil {
+		enc, err := materialize(v)
+		if err != nil {
+			return err
+		}
+		c.encMu.Lock()
+		defer c.encMu.Unlock()
+		c.setWriteDeadline(ctx)
+		return c.encode(enc)
+	}
+	var (
+		w      io.WriteCloser
+		locked bool
+	)
+	spool := &spoolWriter{limit: streamHoldSize, open: func() (io.Writer, error) {
+		c.encMu.Lock()
+		locked = true
+		c.setWriteDeadline(ctx)
+		var err error
+		if w, err = c.nextWriter(); err != nil {
+			return nil, err
+		}
+		return &deadlineWriter{Writer: w, ctx: ctx, conn: c.conn}, nil
+	}}
+	err := writeMessages(spool, v, streamHoldSize)
+	if err == nil {
+		err = spool.flush()
+	}
+	if w != nil {
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if locked {
+		c.encMu.Unlock()
+	}
+	if err != nil {
+		// A response may have been cut off or lost, don't leave the client
+		// waiting for the rest of it.
+		c.close()
+	}
+	return err
+}
+
+// setWriteDeadline sets the write deadline of the connection to the deadline of
+// ctx, or the default write timeout.
+func (c *jsonCodec) setWriteDeadline(ctx context.Context) {
 	deadline, ok := ctx.Deadline()
 	if !ok {
 		deadline = time.Now().Add(defaultWriteTimeout)
 	}
 	c.conn.SetWriteDeadline(deadline)
-	return c.encode(v)
 }
 
 func (c *jsonCodec) close() {
@@ -0,0 +1,225 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// limiterPruneInterval is how often idle clients are dropped by a Limiter.
+const limiterPruneInterval = time.Minute
+
+// LimitConfig configures the limits enforced by a Limiter. Zero values disable the
+// respective limit.
+type LimitConfig struct {
+	Rate            float64        // cost a client may spend per second
+	Burst           int            // cost a client may spend at once, at least the rate
+	MaxBatch        int            // requests in a batch
+	MaxConcurrent   int            // calls a client runs at once, across all its connections
+	MaxResponseSize int            // bytes in the result of a call, streamed results are aborted
+	Costs           map[string]int // cost of the calls of a method or HTTP path, other calls cost one
+}
+
+// Limiter enforces a LimitConfig on the calls served by the servers it is set on.
+// Clients are told apart by the key set with WithClientKey, or else by the host of
+// their remote address.
+type Limiter struct {
+	config LimitConfig
+
+	mu        sync.Mutex
+	clients   map[string]*clientLimit
+	lastPrune time.Time
+}
+
+type clientLimit struct {
+	bucket  *rate.Limiter // nil if the rate isn't limited
+	running int           // calls running at once
+	used    time.Time
+}
+
+// NewLimiter creates a limiter enforcing config.
+func NewLimiter(config LimitConfig) *Limiter {
+	burst := int(math.Ceil(config.Rate))
+	if config.Burst > burst {
+		burst = config.Burst
+	}
+	for _, cost := range config.Costs {
+		if cost > burst {
+			burst = cost // or the method could never be called
+		}
+	}
+	config.Burst = burst
+	return &Limiter{config: config, clients: make(map[string]*clientLimit), lastPrune: time.Now()}
+}
+
+// Config returns the limits enforced, with the burst adjusted to the costs.
+func (l *Limiter) Config() LimitConfig {
+	return l.config
+}
+
+func (l *Limiter) cost(method string) int {
+	if cost, ok := l.config.Costs[method]; ok {
+		return cost
+	}
+	return 1
+}
+
+// allow charges the client key for a call of method, failing if the client is
+// over its rate.
+func (l *Limiter) allow(key, method string) error {
+	if l.config.Rate <= 0 {
+		return nil
+	}
+	cost := l.cost(method)
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.client(key, now).bucket.AllowN(now, cost) {
+		rpcLimitedRateMeter.Mark(1)
+		return &limitExceededError{"request rate limit exceeded"}
+	}
+	rpcCostMeter.Mark(int64(cost))
+	return nil
+}
+
+// start counts a call of the client key as running, failing if the client already
+// runs as many calls as allowed. A call started must be ended with done.
+func (l *Limiter) start(key string) error {
+	if l.config.MaxConcurrent <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	client := l.client(key, time.Now())
+	if client.running >= l.config.MaxConcurrent {
+		rpcLimitedConcurrentMeter.Mark(1)
+		return &limitExceededError{"too many concurrent calls"}
+	}
+	client.running++
+	return nil
+}
+
+// done ends a call of the client key counted by start.
+func (l *Limiter) done(key string) {
+	if l.config.MaxConcurrent <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	client := l.clients[key]
+	client.running--
+	if client.running == 0 && client.bucket == nil {
+		delete(l.clients, key)
+		rpcLimiterClientsGauge.Update(int64(len(l.clients)))
+	}
+}
+
+// client returns the limits of the client key, adding them if the client is new.
+// The caller must hold l.mu.
+func (l *Limiter) client(key string, now time.Time) *clientLimit {
+	if l.config.Rate > 0 && now.Sub(l.lastPrune) > limiterPruneInterval {
+		l.prune(now)
+	}
+	client := l.clients[key]
+	if client == nil {
+		client = new(clientLimit)
+		if l.config.Rate > 0 {
+			client.bucket = rate.NewLimiter(rate.Limit(l.config.Rate), l.config.Burst)
+		}
+		l.clients[key] = client
+		rpcLimiterClientsGauge.Update(int64(len(l.clients)))
+	}
+	client.used = now
+	return client
+}
+
+// prune drops the clients whose buckets are full again and which run no calls,
+// they are no different from new ones. The caller must hold l.mu.
+func (l *Limiter) prune(now time.Time) {
+	idle := time.Duration(float64(l.config.Burst) / l.config.Rate * float64(time.Second))
+	for key, client := range l.clients {
+		if client.running == 0 && now.Sub(client.used) > idle {
+			delete(l.clients, key)
+		}
+	}
+	l.lastPrune = now
+	rpcLimiterClientsGauge.Update(int64(len(l.clients)))
+}
+
+// checkBatch fails if a batch of n requests is too large.
+func (l *Limiter) checkBatch(n int) error {
+	if l.config.MaxBatch > 0 && n > l.config.MaxBatch {
+		rpcLimitedBatchMeter.Mark(1)
+		return &limitExceededError{"batch too large"}
+	}
+	return nil
+}
+
+type clientKeyKey struct{}
+
+// WithClientKey returns a copy of ctx identifying the client of the requests
+// served on it by key, which a Limiter uses instead of the remote address. This
+// lets clients authenticated by a token share their limits across addresses.
+func WithClientKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, clientKeyKey{}, key)
+}
+
+// clientKey returns the key a Limiter tells the client with context ctx and
+// remote address remote apart by.
+func clientKey(ctx context.Context, remote string) string {
+	if key, ok := ctx.Value(clientKeyKey{}).(string); ok {
+		return key
+	}
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		return host
+	}
+	return remote
+}
+
+type limiterKey struct{}
+
+// withLimiter returns a copy of ctx carrying l to the handlers of a server.
+func withLimiter(ctx context.Context, l *Limiter) context.Context {
+	if l == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, limiterKey{}, l)
+}
+
+// HTTPHandler returns a handler charging the clients of next for a call named
+// by the path of each request, replying with status 429 once they are over their
+// rate. This limits the plain HTTP handlers served next to the RPC endpoint.
+func (l *Limiter) HTTPHandler(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := l.allow(clientKey(r.Context(), r.RemoteAddr), r.URL.Path); err != nil {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
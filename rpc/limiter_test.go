@@ -0,0 +1,150 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func checkLimited(t *testing.T, err error) {
+	t.Helper()
+	if rerr, ok := err.(Error); !ok || rerr.ErrorCode() != -32005 {
+		t.Fatalf("call not limited: %v", err)
+	}
+}
+
+func TestLimiterRate(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	server.SetLimiter(NewLimiter(LimitConfig{Rate: 0.001, Burst: 3, Costs: map[string]int{"test_sleep": 3}}))
+	client := DialInProc(server)
+	defer client.Close()
+
+	for i := 0; i < 3; i++ {
+		if err := client.Call(nil, "test_noArgsRets"); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	checkLimited(t, client.Call(nil, "test_noArgsRets"))
+
+	// clients with another key have their own limits
+	l := NewLimiter(LimitConfig{Rate: 0.001, Costs: map[string]int{"test_sleep": 3}})
+	if l.Config().Burst != 3 {
+		t.Fatalf("burst not raised to the highest cost: %d", l.Config().Burst)
+	}
+	if err := l.allow("a", "test_sleep"); err != nil {
+		t.Fatal(err)
+	}
+	checkLimited(t, l.allow("a", "test_noArgsRets"))
+	if err := l.allow("b", "test_noArgsRets"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLimiterBatch(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	server.SetLimiter(NewLimiter(LimitConfig{MaxBatch: 2}))
+	client := DialInProc(server)
+	defer client.Close()
+
+	batch := []BatchElem{
+		{Method: "test_noArgsRets", Result: new(interface{})},
+		{Method: "test_noArgsRets", Result: new(interface{})},
+	}
+	if err := client.BatchCall(batch); err != nil || batch[0].Error != nil {
+		t.Fatalf("batch within the limit failed: %v %v", err, batch[0].Error)
+	}
+	batch = append(batch, BatchElem{Method: "test_noArgsRets", Result: new(interface{})})
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	for _, elem := range batch {
+		checkLimited(t, elem.Error)
+	}
+}
+
+func TestLimiterConcurrent(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	server.SetLimiter(NewLimiter(LimitConfig{MaxConcurrent: 1}))
+	client := DialInProc(server)
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- client.CallContext(ctx, nil, "test_sleep", 500*time.Millisecond) }()
+	time.Sleep(100 * time.Millisecond)
+	checkLimited(t, client.Call(nil, "test_noArgsRets"))
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if err := client.Call(nil, "test_noArgsRets"); err != nil {
+		t.Fatalf("call after the running one finished: %v", err)
+	}
+}
+
+// Every HTTP request is served on a connection of its own, the calls of a client
+// are counted across them.
+func TestLimiterConcurrentHTTP(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	server.SetLimiter(NewLimiter(LimitConfig{MaxConcurrent: 1}))
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	dial := func() *Client {
+		client, err := DialHTTP(httpsrv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client
+	}
+	client, other := dial(), dial()
+	defer client.Close()
+	defer other.Close()
+
+	errc := make(chan error, 1)
+	go func() { errc <- client.Call(nil, "test_sleep", 500*time.Millisecond) }()
+	time.Sleep(100 * time.Millisecond)
+	checkLimited(t, other.Call(nil, "test_noArgsRets"))
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Call(nil, "test_noArgsRets"); err != nil {
+		t.Fatalf("call after the running one finished: %v", err)
+	}
+
+	// clients with another key run their own calls
+	l := NewLimiter(LimitConfig{MaxConcurrent: 1})
+	if err := l.start("a"); err != nil {
+		t.Fatal(err)
+	}
+	checkLimited(t, l.start("a"))
+	if err := l.start("b"); err != nil {
+		t.Fatal(err)
+	}
+	l.done("a")
+	l.done("b")
+	if len(l.clients) != 0 {
+		t.Fatalf("clients kept after their calls ended: %d", len(l.clients))
+	}
+}
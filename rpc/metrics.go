@@ -27,6 +27,18 @@ var (
 	successfulRequestGauge = metrics.NewRegisteredGauge("rpc/success", nil)
 	failedReqeustGauge     = metrics.NewRegisteredGauge("rpc/failure", nil)
 	rpcServingTimer        = metrics.NewRegisteredTimer("rpc/duration/all", nil)
+
+	rpcCostMeter              = metrics.NewRegisteredMeter("rpc/limiter/cost", nil)
+	rpcLimiterClientsGauge    = metrics.NewRegisteredGauge("rpc/limiter/clients", nil)
+	rpcLimitedRateMeter       = metrics.NewRegisteredMeter("rpc/limited/rate", nil)
+	rpcLimitedBatchMeter      = metrics.NewRegisteredMeter("rpc/limited/batch", nil)
+	rpcLimitedConcurrentMeter = metrics.NewRegisteredMeter("rpc/limited/concurrent", nil)
+	rpcLimitedResponseMeter   = metrics.NewRegisteredMeter("rpc/limited/response", nil)
+
+	rpcTimedOutMeter = metrics.NewRegisteredMeter("rpc/calls/timeout", nil)
+	rpcCanceledMeter = metrics.NewRegisteredMeter("rpc/calls/canceled", nil)
+
+	resubscribeMeter = metrics.NewRegisteredMeter("rpc/client/resubscribe", nil)
 )
 
 func newRPCServingTimer(method string, valid bool) metrics.Timer {
@@ -0,0 +1,316 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// OpenRPCVersion is the version of the OpenRPC specification rpc.discover
+// documents follow.
+const OpenRPCVersion = "1.2.6"
+
+// openRPCDiscover is the method name the OpenRPC specification reserves for
+// service discovery, served as rpc_discover.
+const openRPCDiscover = "rpc.discover"
+
+// OpenRPCDocument describes the methods of a server, see https://spec.open-rpc.org.
+type OpenRPCDocument struct {
+	OpenRPC    string            `json:"openrpc"`
+	Info       OpenRPCInfo       `json:"info"`
+	Methods    []*OpenRPCMethod  `json:"methods"`
+	Components OpenRPCComponents `json:"components"`
+}
+
+// OpenRPCInfo is the metadata of an OpenRPC document.
+type OpenRPCInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// OpenRPCComponents holds the schemas of the named types referenced by the
+// methods of a document.
+type OpenRPCComponents struct {
+	Schemas map[string]Schema `json:"schemas"`
+}
+
+// OpenRPCMethod describes a method. Subscriptions of a namespace are described
+// by its subscribe method, listing them in the "x-subscriptions" extension.
+type OpenRPCMethod struct {
+	Name           string                 `json:"name"`
+	Params         []*ContentDescriptor   `json:"params"`
+	Result         *ContentDescriptor     `json:"result"`
+	ParamStructure string                 `json:"paramStructure"`
+	Subscriptions  []*OpenRPCSubscription `json:"x-subscriptions,omitempty"`
+}
+
+// OpenRPCSubscription describes a subscription, created by calling the
+// subscribe method of its namespace with its name and params.
+type OpenRPCSubscription struct {
+	Name   string               `json:"name"`
+	Params []*ContentDescriptor `json:"params"`
+}
+
+// ContentDescriptor describes a parameter or result of a method.
+type ContentDescriptor struct {
+	Name     string `json:"name"`
+	Required bool   `json:"required,omitempty"`
+	Schema   Schema `json:"schema"`
+}
+
+// Schema is a JSON schema.
+type Schema map[string]interface{}
+
+var (
+	hexPattern      = "^0x[0-9a-fA-F]*$"
+	quantityPattern = "^0x(0|[1-9a-fA-F][0-9a-fA-F]*)$"
+	quantitySchema  = Schema{"type": "string", "pattern": quantityPattern}
+	hashSchema      = Schema{"type": "string", "pattern": "^0x[0-9a-fA-F]{64}$"}
+	blockTagSchema  = Schema{"type": "string", "enum": []string{"earliest", "latest", "pending"}}
+	blockNumSchema  = Schema{"oneOf": []Schema{blockTagSchema, quantitySchema}}
+
+	// knownSchemas are the schemas of the types whose JSON encoding isn't
+	// derived from their Go type.
+	knownSchemas = map[reflect.Type]Schema{
+		reflect.TypeOf(common.Address{}):  {"type": "string", "pattern": "^0x[0-9a-fA-F]{40}$"},
+		reflect.TypeOf(common.Hash{}):     hashSchema,
+		reflect.TypeOf(hexutil.Big{}):     quantitySchema,
+		reflect.TypeOf(hexutil.Uint64(0)): quantitySchema,
+		reflect.TypeOf(hexutil.Uint(0)):   quantitySchema,
+		reflect.TypeOf(hexutil.Bytes{}):   {"type": "string", "pattern": hexPattern},
+		reflect.TypeOf(big.Int{}):         {"type": "integer"},
+		reflect.TypeOf(time.Time{}):       {"type": "string", "format": "date-time"},
+		reflect.TypeOf(json.RawMessage{}): {},
+		reflect.TypeOf(ID("")):            {"type": "string"},
+		reflect.TypeOf(BlockNumber(0)):    blockNumSchema,
+		reflect.TypeOf(BlockNumberOrHash{}): {"oneOf": []Schema{
+			blockNumSchema,
+			hashSchema,
+			{"type": "object", "properties": Schema{
+				"blockNumber":      blockNumSchema,
+				"blockHash":        hashSchema,
+				"requireCanonical": Schema{"type": "boolean"},
+			}},
+		}},
+	}
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Discover returns the OpenRPC document describing the methods of the server.
+// It is also served as rpc.discover, the name the OpenRPC specification gives it.
+func (s *RPCService) Discover() *OpenRPCDocument {
+	s.server.services.mu.Lock()
+	defer s.server.services.mu.Unlock()
+
+	g := newSchemaGen()
+	doc := &OpenRPCDocument{
+		OpenRPC: OpenRPCVersion,
+		Info:    OpenRPCInfo{Title: "JSON-RPC API", Version: "1.0"},
+	}
+	for name, svc := range s.server.services.services {
+		for method, cb := range svc.callbacks {
+			doc.Methods = append(doc.Methods, &OpenRPCMethod{
+				Name:           name + serviceMethodSeparator + method,
+				Params:         g.params(cb.argTypes),
+				Result:         g.result(cb),
+				ParamStructure: "by-position",
+			})
+		}
+		if len(svc.subscriptions) == 0 {
+			continue
+		}
+		subscribe := &OpenRPCMethod{
+			Name:           name + subscribeMethodSuffix,
+			Result:         &ContentDescriptor{Name: "subscription", Schema: Schema{"type": "string"}},
+			ParamStructure: "by-position",
+		}
+		var names []string
+		for sub, cb := range svc.subscriptions {
+			names = append(names, sub)
+			subscribe.Subscriptions = append(subscribe.Subscriptions, &OpenRPCSubscription{Name: sub, Params: g.params(cb.argTypes)})
+		}
+		sort.Strings(names)
+		sort.Slice(subscribe.Subscriptions, func(i, j int) bool {
+			return subscribe.Subscriptions[i].Name < subscribe.Subscriptions[j].Name
+		})
+		subscribe.Params = []*ContentDescriptor{{Name: "name", Required: true, Schema: Schema{"type": "string", "enum": names}}}
+		doc.Methods = append(doc.Methods, subscribe, &OpenRPCMethod{
+			Name:           name + unsubscribeMethodSuffix,
+			Params:         []*ContentDescriptor{{Name: "subscription", Required: true, Schema: Schema{"type": "string"}}},
+			Result:         &ContentDescriptor{Name: "result", Schema: Schema{"type": "boolean"}},
+			ParamStructure: "by-position",
+		})
+	}
+	sort.Slice(doc.Methods, func(i, j int) bool { return doc.Methods[i].Name < doc.Methods[j].Name })
+	doc.Components.Schemas = g.defs
+	return doc
+}
+
+// schemaGen derives JSON schemas from Go types, collecting the schemas of named
+// struct types in defs.
+type schemaGen struct {
+	defs  map[string]Schema
+	names map[reflect.Type]string
+}
+
+func newSchemaGen() *schemaGen {
+	return &schemaGen{defs: make(map[string]Schema), names: make(map[reflect.Type]string)}
+}
+
+// params describes the arguments of a method. Go doesn't keep the names of
+// arguments, they are named by position. Pointer arguments are optional.
+func (g *schemaGen) params(types []reflect.Type) []*ContentDescriptor {
+	params := make([]*ContentDescriptor, len(types))
+	for i, t := range types {
+		params[i] = &ContentDescriptor{
+			Name:     fmt.Sprintf("arg%d", i),
+			Required: t.Kind() != reflect.Ptr,
+			Schema:   g.schema(t),
+		}
+	}
+	return params
+}
+
+// result describes the result of a method, null if it has none.
+func (g *schemaGen) result(cb *callback) *ContentDescriptor {
+	fntype := cb.fn.Type()
+	if fntype.NumOut() == 0 || cb.errPos == 0 {
+		return &ContentDescriptor{Name: "result", Schema: Schema{"type": "null"}}
+	}
+	return &ContentDescriptor{Name: "result", Schema: g.schema(fntype.Out(0))}
+}
+
+// schema returns the schema of the JSON encoding of t.
+func (g *schemaGen) schema(t reflect.Type) Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if s, ok := knownSchemas[t]; ok {
+		return s
+	}
+	switch {
+	case t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType):
+		return Schema{} // the encoding is up to the type
+	case t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType):
+		return Schema{"type": "string"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return Schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			return Schema{"type": "string", "contentEncoding": "base64"}
+		}
+		return Schema{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return Schema{"$ref": "#/components/schemas/" + g.define(t)}
+	default:
+		return Schema{}
+	}
+}
+
+// define adds the schema of the named struct type t to the definitions,
+// returning its name.
+func (g *schemaGen) define(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := path.Base(t.PkgPath()) + "." + t.Name()
+	for i := 2; g.defs[name] != nil; i++ {
+		name = fmt.Sprintf("%s.%s%d", path.Base(t.PkgPath()), t.Name(), i)
+	}
+	g.names[t] = name
+	g.defs[name] = Schema{} // reserved for recursive types
+	g.defs[name] = g.structSchema(t)
+	return name
+}
+
+func (g *schemaGen) structSchema(t reflect.Type) Schema {
+	props, required := make(Schema), []string{}
+	g.structProperties(t, props, &required)
+	s := Schema{"type": "object", "properties": props}
+	if len(required) > 0 {
+		sort.Strings(required)
+		s["required"] = required
+	}
+	return s
+}
+
+// structProperties adds the properties of the JSON encoding of the fields of t
+// to props, following the rules of encoding/json.
+func (g *schemaGen) structProperties(t reflect.Type, props Schema, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		opts := strings.Split(tag, ",")
+		name := opts[0]
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if field.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			g.structProperties(ft, props, required)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue // not exported
+		}
+		if name == "" {
+			name = field.Name
+		}
+		var omitempty, asString bool
+		for _, opt := range opts[1:] {
+			omitempty = omitempty || opt == "omitempty"
+			asString = asString || opt == "string"
+		}
+		if asString {
+			props[name] = Schema{"type": "string"}
+		} else {
+			props[name] = g.schema(field.Type)
+		}
+		if !omitempty && field.Type.Kind() != reflect.Ptr {
+			*required = append(*required, name)
+		}
+	}
+}
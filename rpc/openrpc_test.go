@@ -0,0 +1,107 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+func findMethod(doc *OpenRPCDocument, name string) *OpenRPCMethod {
+	for _, m := range doc.Methods {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+func TestDiscover(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	var doc OpenRPCDocument
+	if err := client.Call(&doc, "rpc.discover"); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenRPC != OpenRPCVersion {
+		t.Fatalf("wrong version %q", doc.OpenRPC)
+	}
+	echo := findMethod(&doc, "test_echo")
+	if echo == nil || len(echo.Params) != 3 {
+		t.Fatalf("test_echo not described: %+v", echo)
+	}
+	if echo.Params[0].Schema["type"] != "string" || !echo.Params[0].Required || echo.Params[2].Required {
+		t.Fatalf("wrong params of test_echo: %+v %+v %+v", echo.Params[0], echo.Params[1], echo.Params[2])
+	}
+	if ref := echo.Params[2].Schema["$ref"]; ref != "#/components/schemas/rpc.echoArgs" {
+		t.Fatalf("wrong reference %v", ref)
+	}
+	if _, ok := doc.Components.Schemas["rpc.echoResult"]; !ok {
+		t.Fatal("result type not defined")
+	}
+	if m := findMethod(&doc, "test_noArgsRets"); m == nil || m.Result.Schema["type"] != "null" {
+		t.Fatalf("method without result not described: %+v", m)
+	}
+	sub := findMethod(&doc, "nftest_subscribe")
+	if sub == nil || len(sub.Subscriptions) != 2 || sub.Subscriptions[1].Name != "someSubscription" || len(sub.Subscriptions[1].Params) != 2 {
+		t.Fatalf("subscriptions not described: %+v", sub)
+	}
+	if findMethod(&doc, "rpc_discover") == nil || findMethod(&doc, "nftest_unsubscribe") == nil {
+		t.Fatal("methods missing")
+	}
+}
+
+func TestSchema(t *testing.T) {
+	type embedded struct {
+		Nonce hexutil.Uint64 `json:"nonce"`
+	}
+	type tx struct {
+		embedded
+		From   common.Address  `json:"from"`
+		Value  *hexutil.Big    `json:"value"`
+		Data   hexutil.Bytes   `json:"data,omitempty"`
+		Number BlockNumber     `json:"number"`
+		Gas    uint64          `json:"gas,string"`
+		Logs   []*tx           `json:"logs"`
+		Extra  map[string]bool `json:"extra"`
+		Hidden int             `json:"-"`
+	}
+	g := newSchemaGen()
+	if ref := g.schema(reflect.TypeOf(&tx{}))["$ref"]; ref != "#/components/schemas/rpc.tx" {
+		t.Fatalf("wrong reference %v", ref)
+	}
+	have, _ := json.Marshal(g.defs["rpc.tx"])
+	want := `{"properties":{"data":{"pattern":"^0x[0-9a-fA-F]*$","type":"string"},` +
+		`"extra":{"additionalProperties":{"type":"boolean"},"type":"object"},` +
+		`"from":{"pattern":"^0x[0-9a-fA-F]{40}$","type":"string"},` +
+		`"gas":{"type":"string"},` +
+		`"logs":{"items":{"$ref":"#/components/schemas/rpc.tx"},"type":"array"},` +
+		`"nonce":{"pattern":"^0x(0|[1-9a-fA-F][0-9a-fA-F]*)$","type":"string"},` +
+		`"number":{"oneOf":[{"enum":["earliest","latest","pending"],"type":"string"},{"pattern":"^0x(0|[1-9a-fA-F][0-9a-fA-F]*)$","type":"string"}]},` +
+		`"value":{"pattern":"^0x(0|[1-9a-fA-F][0-9a-fA-F]*)$","type":"string"}},` +
+		`"required":["extra","from","gas","logs","nonce","number"],"type":"object"}`
+	if string(have) != want {
+		t.Fatalf("schema mismatch:\nhave %s\nwant %s", have, want)
+	}
+}
@@ -0,0 +1,325 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// Exchange is a recorded JSON-RPC request and its response. Recordings are stored as
+// one exchange per line.
+type Exchange struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *jsonError      `json:"error,omitempty"`
+}
+
+// Recorder captures JSON-RPC exchanges, either on the client side through its
+// Interceptor or on the server side through its Handler.
+type Recorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewRecorder creates a recorder writing exchanges to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w)}
+}
+
+// Err returns the first error that occurred writing the recording.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *Recorder) record(ex *Exchange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		r.err = r.enc.Encode(ex)
+	}
+}
+
+// Interceptor returns an interceptor recording the calls and batches of a client.
+// Responses are recorded as received from the server. Requests failing without a
+// response, e.g. due to connection errors, and subscriptions are not recorded.
+// Request IDs are not known to interceptors, replay recordings made this way without
+// matching IDs.
+func (r *Recorder) Interceptor() Interceptor {
+	return func(ctx context.Context, req *Request, next Invoker) error {
+		switch req.Kind {
+		case CallRequest:
+			params, err := json.Marshal(req.Args)
+			if err != nil {
+				return next(ctx, req)
+			}
+			var (
+				result = req.Result
+				raw    json.RawMessage
+			)
+			req.Result = &raw
+			err = next(ctx, req)
+			req.Result = result
+			if ex := newExchange(req.Method, params, raw, err); ex != nil {
+				r.record(ex)
+			}
+			if err != nil {
+				return err
+			}
+			return json.Unmarshal(raw, &result)
+
+		case BatchRequest:
+			var (
+				params  = make([]json.RawMessage, len(req.Batch))
+				results = make([]interface{}, len(req.Batch))
+				raws    = make([]json.RawMessage, len(req.Batch))
+			)
+			for i := range req.Batch {
+				var err error
+				if params[i], err = json.Marshal(req.Batch[i].Args); err != nil {
+					return next(ctx, req)
+				}
+				results[i] = req.Batch[i].Result
+				req.Batch[i].Result = &raws[i]
+			}
+			err := next(ctx, req)
+			for i := range req.Batch {
+				elem := &req.Batch[i]
+				elem.Result = results[i]
+				if err != nil {
+					continue
+				}
+				if ex := newExchange(elem.Method, params[i], raws[i], elem.Error); ex != nil {
+					r.record(ex)
+				}
+				if elem.Error == nil {
+					elem.Error = json.Unmarshal(raws[i], elem.Result)
+				}
+			}
+			return err
+
+		default:
+			return next(ctx, req)
+		}
+	}
+}
+
+// newExchange creates the exchange of a call, or nil if it failed without response.
+func newExchange(method string, params, result json.RawMessage, err error) *Exchange {
+	ex := &Exchange{Method: method, Params: params}
+	switch err := err.(type) {
+	case nil:
+		ex.Result = result
+	case *jsonError:
+		ex.Error = err
+	default:
+		return nil
+	}
+	return ex
+}
+
+// Handler wraps an HTTP handler serving JSON-RPC, recording the exchanges it serves
+// including their IDs. Combined with a reverse proxy, it records the traffic of any
+// client against a remote endpoint.
+func (r *Recorder) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(io.LimitReader(req.Body, maxRequestContentLength))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		rw := &recordingWriter{ResponseWriter: w}
+		next.ServeHTTP(rw, req)
+
+		reqs, _ := parseMessage(body)
+		resps, _ := parseMessage(rw.body.Bytes())
+		byID := make(map[string]*jsonrpcMessage, len(resps))
+		for _, resp := range resps {
+			if resp != nil && resp.isResponse() {
+				byID[string(resp.ID)] = resp
+			}
+		}
+		for _, msg := range reqs {
+			if msg == nil || !msg.isCall() {
+				continue
+			}
+			if resp := byID[string(msg.ID)]; resp != nil {
+				r.record(&Exchange{ID: msg.ID, Method: msg.Method, Params: msg.Params, Result: resp.Result, Error: resp.Error})
+			}
+		}
+	})
+}
+
+// recordingWriter is a response writer keeping a copy of the body.
+type recordingWriter struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// ReadRecording reads the exchanges of a recording. Exchanges aren't limited in
+// size, recorded results may be larger than any request.
+func ReadRecording(r io.Reader) ([]Exchange, error) {
+	var (
+		exchanges []Exchange
+		dec       = json.NewDecoder(r)
+	)
+	for {
+		var ex Exchange
+		if err := dec.Decode(&ex); err == io.EOF {
+			return exchanges, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("recording exchange %d: %v", len(exchanges)+1, err)
+		}
+		exchanges = append(exchanges, ex)
+	}
+}
+
+// ReplayServer serves recorded exchanges over HTTP. Requests are matched on method
+// and params, and optionally on ID. Exchanges recorded for the same request are
+// served in order, the last one is repeated once all have been served. Requests
+// that weren't recorded fail with error code -32004.
+type ReplayServer struct {
+	matchID bool
+
+	mu        sync.Mutex
+	exchanges map[string][]*Exchange
+	served    map[string]int
+	missed    []string
+}
+
+// NewReplayServer creates a server replaying the given exchanges.
+func NewReplayServer(exchanges []Exchange, matchID bool) *ReplayServer {
+	s := &ReplayServer{
+		matchID:   matchID,
+		exchanges: make(map[string][]*Exchange),
+		served:    make(map[string]int),
+	}
+	for i := range exchanges {
+		ex := &exchanges[i]
+		key := s.key(ex.ID, ex.Method, ex.Params)
+		s.exchanges[key] = append(s.exchanges[key], ex)
+	}
+	return s
+}
+
+// Missed returns the requests for which no exchange was recorded, formatted as
+// method followed by params.
+func (s *ReplayServer) Missed() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.missed...)
+}
+
+// ServeHTTP answers JSON-RPC requests and batches from the recording.
+func (s *ReplayServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if code, err := validateRequest(r); err != nil {
+		http.Error(w, err.Error(), code)
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxRequestContentLength))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("content-type", contentType)
+
+	msgs, batch := parseMessage(body)
+	resps := make([]*jsonrpcMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		switch {
+		case msg == nil:
+			resps = append(resps, errorMessage(&parseError{"invalid request"}))
+		case msg.isCall():
+			resps = append(resps, s.reply(msg))
+		}
+	}
+	switch {
+	case batch:
+		json.NewEncoder(w).Encode(resps)
+	case len(resps) == 1:
+		json.NewEncoder(w).Encode(resps[0])
+	}
+}
+
+func (s *ReplayServer) reply(msg *jsonrpcMessage) *jsonrpcMessage {
+	key := s.key(msg.ID, msg.Method, msg.Params)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recorded := s.exchanges[key]
+	if len(recorded) == 0 {
+		s.missed = append(s.missed, msg.Method+" "+string(canonicalParams(msg.Params)))
+		return msg.errorResponse(&replayMissError{msg.Method})
+	}
+	n := s.served[key]
+	if n < len(recorded)-1 {
+		s.served[key] = n + 1
+	}
+	ex := recorded[n]
+	if ex.Error != nil {
+		return &jsonrpcMessage{Version: vsn, ID: msg.ID, Error: ex.Error}
+	}
+	result := ex.Result
+	if result == nil {
+		result = null
+	}
+	return &jsonrpcMessage{Version: vsn, ID: msg.ID, Result: result}
+}
+
+func (s *ReplayServer) key(id json.RawMessage, method string, params json.RawMessage) string {
+	key := method + " " + string(canonicalParams(params))
+	if s.matchID {
+		key = string(id) + " " + key
+	}
+	return key
+}
+
+// canonicalParams re-encodes params in a canonical form, so that requests match
+// regardless of whitespace and object key order. Absent params equal an empty list.
+func canonicalParams(params json.RawMessage) []byte {
+	if len(bytes.TrimSpace(params)) == 0 || bytes.Equal(bytes.TrimSpace(params), null) {
+		return []byte("[]")
+	}
+	dec := json.NewDecoder(bytes.NewReader(params))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return params
+	}
+	enc, err := json.Marshal(v)
+	if err != nil {
+		return params
+	}
+	return enc
+}
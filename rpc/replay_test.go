@@ -0,0 +1,146 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// replayCalls performs the calls recorded and replayed by the tests below.
+func replayCalls(t *testing.T, client *Client) []interface{} {
+	var (
+		echo  echoResult
+		batch = []BatchElem{
+			{Method: "test_echo", Args: []interface{}{"x", 2, &echoArgs{"y"}}, Result: new(echoResult)},
+			{Method: "test_returnError", Result: new(interface{})},
+		}
+	)
+	if err := client.Call(&echo, "test_echo", "hello", 1, nil); err != nil {
+		t.Fatal(err)
+	}
+	err := client.Call(nil, "test_returnError")
+	if rpcErr, ok := err.(Error); !ok || rpcErr.ErrorCode() != 444 {
+		t.Fatalf("wrong error: %v", err)
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	return []interface{}{echo, err.Error(), batch[0].Result, batch[1].Error.Error()}
+}
+
+func TestRecordReplay(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	var recording bytes.Buffer
+	recorder := NewRecorder(&recording)
+	client.Use(recorder.Interceptor())
+	want := replayCalls(t, client)
+	if err := recorder.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(recording.String(), "\n"); n != 4 {
+		t.Fatalf("wrong number of recorded exchanges: got %d, want 4\n%s", n, recording.String())
+	}
+
+	exchanges, err := ReadRecording(&recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay := NewReplayServer(exchanges, false)
+	hs := httptest.NewServer(replay)
+	defer hs.Close()
+	replayClient, err := DialHTTP(hs.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replayClient.Close()
+
+	if have := replayCalls(t, replayClient); !reflect.DeepEqual(have, want) {
+		t.Errorf("wrong replayed results:\nhave %v\nwant %v", have, want)
+	}
+	err = replayClient.Call(nil, "test_echo", "other", 1, nil)
+	if rpcErr, ok := err.(Error); !ok || rpcErr.ErrorCode() != -32004 {
+		t.Errorf("wrong error for request not recorded: %v", err)
+	}
+	if missed := replay.Missed(); len(missed) != 1 || missed[0] != `test_echo ["other",1,null]` {
+		t.Errorf("wrong missed requests: %q", missed)
+	}
+}
+
+func TestRecordHandlerReplayIDs(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+
+	var recording bytes.Buffer
+	recorder := NewRecorder(&recording)
+	hs := httptest.NewServer(recorder.Handler(server))
+	defer hs.Close()
+	client, err := DialHTTP(hs.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := replayCalls(t, client)
+	client.Close()
+
+	exchanges, err := ReadRecording(&recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exchanges) != 4 || len(exchanges[0].ID) == 0 {
+		t.Fatalf("wrong recorded exchanges: %+v", exchanges)
+	}
+
+	// A new client sends the same IDs, so requests are matched including them.
+	replay := httptest.NewServer(NewReplayServer(exchanges, true))
+	defer replay.Close()
+	replayClient, err := DialHTTP(replay.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replayClient.Close()
+	if have := replayCalls(t, replayClient); !reflect.DeepEqual(have, want) {
+		t.Errorf("wrong replayed results:\nhave %v\nwant %v", have, want)
+	}
+	// Repeating the calls uses different IDs, which were not recorded.
+	var echo echoResult
+	if err := replayClient.Call(&echo, "test_echo", "hello", 1, nil); err == nil {
+		t.Error("replayed a request with an ID not recorded")
+	}
+}
+
+func TestReadRecordingLargeExchange(t *testing.T) {
+	result := `"` + strings.Repeat("a", 2*maxRequestContentLength) + `"`
+	recording := `{"method":"test_small","result":1}` + "\n\n" +
+		`{"method":"test_large","result":` + result + "}\n"
+	exchanges, err := ReadRecording(strings.NewReader(recording))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exchanges) != 2 || string(exchanges[1].Result) != result {
+		t.Fatalf("wrong exchanges read: %d", len(exchanges))
+	}
+	if _, err := ReadRecording(strings.NewReader(`{"method":"test_small"}` + "\n{")); err == nil {
+		t.Fatal("no error for a truncated recording")
+	}
+}
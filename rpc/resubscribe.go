@@ -0,0 +1,265 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	defaultResubscribeBackoff  = 100 * time.Millisecond
+	defaultResubscribeMaxDelay = 30 * time.Second
+)
+
+// ResubscribeConfig configures a resilient subscription.
+type ResubscribeConfig struct {
+	Backoff  time.Duration // delay after the first failed attempt, doubled on each failure
+	MaxDelay time.Duration // upper bound of the delay between attempts
+}
+
+// Resubscribed is the marker delivered when a resilient subscription has been
+// re-established after the previous one ended.
+type Resubscribed struct {
+	Err      error           // error that ended the previous subscription
+	Attempts int             // number of subscribe calls needed to re-establish it
+	Last     json.RawMessage // last notification delivered before the gap, nil if none
+}
+
+// ResilientSubscription is a subscription that survives connection loss. It is created
+// through the Client's ResilientSubscribe method.
+type ResilientSubscription struct {
+	client    *Client
+	config    ResubscribeConfig
+	namespace string
+	args      []interface{}
+	channel   reflect.Value
+	etype     reflect.Type
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	done      chan struct{}
+	gaps      chan Resubscribed
+	errOnce   sync.Once
+	err       chan error
+	unsubOnce sync.Once
+}
+
+// ResilientSubscribe works like Subscribe, but keeps the subscription established when
+// the connection to the server is lost. The client reconnects, re-issues the subscribe
+// call with the original arguments and signals the gap on the Gaps channel.
+//
+// The initial subscription must succeed, its error is returned otherwise. Resilient
+// subscriptions are not supported on HTTP connections.
+func (c *Client) ResilientSubscribe(ctx context.Context, config ResubscribeConfig, namespace string, channel interface{}, args ...interface{}) (*ResilientSubscription, error) {
+	chanVal := reflect.ValueOf(channel)
+	if chanVal.Kind() != reflect.Chan || chanVal.Type().ChanDir()&reflect.SendDir == 0 {
+		panic("channel given to ResilientSubscribe must be a writable channel")
+	}
+	if chanVal.IsNil() {
+		panic("channel given to ResilientSubscribe must not be nil")
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = defaultResubscribeBackoff
+	}
+	if config.MaxDelay < config.Backoff {
+		config.MaxDelay = defaultResubscribeMaxDelay
+		if config.MaxDelay < config.Backoff {
+			config.MaxDelay = config.Backoff
+		}
+	}
+	raw := make(chan json.RawMessage)
+	sub, err := c.Subscribe(ctx, namespace, raw, args...)
+	if err != nil {
+		return nil, err
+	}
+	s := &ResilientSubscription{
+		client:    c,
+		config:    config,
+		namespace: namespace,
+		args:      args,
+		channel:   chanVal,
+		etype:     chanVal.Type().Elem(),
+		done:      make(chan struct{}),
+		gaps:      make(chan Resubscribed, 1),
+		err:       make(chan error, 1),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	go s.loop(sub, raw)
+	return s, nil
+}
+
+// EthSubscribeResilient registers a resilient subscription under the "eth" namespace.
+func (c *Client) EthSubscribeResilient(ctx context.Context, config ResubscribeConfig, channel interface{}, args ...interface{}) (*ResilientSubscription, error) {
+	return c.ResilientSubscribe(ctx, config, "eth", channel, args...)
+}
+
+// Err returns the subscription error channel. It receives a value when the subscription
+// has ended for a reason other than Unsubscribe: nil if the client was closed, or the
+// error that made the notifications undecodable.
+//
+// The error channel is closed when Unsubscribe is called on the subscription.
+func (s *ResilientSubscription) Err() <-chan error {
+	return s.err
+}
+
+// Gaps returns the channel receiving a marker each time the subscription has been
+// re-established. Notifications sent by the server while no subscription was active
+// are lost, the marker allows the consumer to backfill them.
+//
+// Markers never hold up the notifications. The channel buffers one marker, and a
+// marker still unreceived when the subscription is re-established again is merged
+// with the new one: the merged marker carries the last notification before the
+// earlier gap, the latest error and the attempts of both. Consumers not interested
+// in gaps don't need to receive from the channel.
+func (s *ResilientSubscription) Gaps() <-chan Resubscribed {
+	return s.gaps
+}
+
+// Unsubscribe ends the subscription and closes the error channel.
+// It can safely be called more than once.
+func (s *ResilientSubscription) Unsubscribe() {
+	s.unsubOnce.Do(s.cancel)
+	<-s.done
+	s.errOnce.Do(func() { close(s.err) })
+}
+
+func (s *ResilientSubscription) loop(sub *ClientSubscription, raw chan json.RawMessage) {
+	defer close(s.done)
+
+	var last json.RawMessage
+	for {
+		cause, err := s.forward(sub, raw, &last)
+		sub.Unsubscribe()
+		if cause == nil {
+			// Unsubscribed or client closed.
+			s.finish(err)
+			return
+		}
+		log.Debug("RPC subscription lost, resubscribing", "namespace", s.namespace, "err", cause)
+		resubscribeMeter.Mark(1)
+
+		var attempts int
+		if sub, raw, attempts, err = s.resubscribe(); err != nil {
+			s.finish(nil)
+			return
+		}
+		s.signalGap(Resubscribed{Err: cause, Attempts: attempts, Last: last})
+	}
+}
+
+// signalGap queues gap on the gaps channel without blocking, merging it with the
+// marker still queued, if any. Only the loop sends on the channel, so there is room
+// once the queued marker is taken out.
+func (s *ResilientSubscription) signalGap(gap Resubscribed) {
+	select {
+	case queued := <-s.gaps:
+		gap.Attempts += queued.Attempts
+		gap.Last = queued.Last
+	default:
+	}
+	s.gaps <- gap
+}
+
+// finish reports err on the error channel unless the subscription was unsubscribed.
+func (s *ResilientSubscription) finish(err error) {
+	if s.ctx.Err() == nil {
+		s.err <- err
+	}
+}
+
+// forward delivers notifications of sub to the consumer channel until sub ends. It
+// returns the cause if the subscription should be re-established, or the error to
+// report if it should not.
+func (s *ResilientSubscription) forward(sub *ClientSubscription, raw chan json.RawMessage, last *json.RawMessage) (cause, err error) {
+	rawCase := reflect.ValueOf(raw)
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(s.ctx.Done())},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(sub.Err())},
+		{Dir: reflect.SelectRecv, Chan: rawCase},
+		{Dir: reflect.SelectSend},
+	}
+	var pending json.RawMessage
+	for {
+		// Only one notification is held at a time. While it is pending, further
+		// notifications are buffered by the underlying subscription.
+		chosen, recv, _ := reflect.Select(cases)
+
+		switch chosen {
+		case 0: // <-s.ctx.Done()
+			return nil, nil
+		case 1: // <-sub.Err()
+			if recv.IsNil() {
+				// The client was closed.
+				return nil, nil
+			}
+			return recv.Interface().(error), nil
+		case 2: // <-raw
+			pending = recv.Interface().(json.RawMessage)
+			val := reflect.New(s.etype)
+			if err := json.Unmarshal(pending, val.Interface()); err != nil {
+				return nil, err
+			}
+			cases[2].Chan = reflect.Value{}
+			cases[3].Chan, cases[3].Send = s.channel, val.Elem()
+		case 3: // s.channel<-
+			*last = pending
+			pending = nil
+			cases[2].Chan = rawCase
+			cases[3].Chan, cases[3].Send = reflect.Value{}, reflect.Value{}
+		}
+	}
+}
+
+// resubscribe re-issues the subscribe call until it succeeds, backing off between
+// attempts. It fails only if the subscription was unsubscribed or the client closed.
+func (s *ResilientSubscription) resubscribe() (*ClientSubscription, chan json.RawMessage, int, error) {
+	delay := s.config.Backoff
+	for attempts := 1; ; attempts++ {
+		ctx, cancel := context.WithTimeout(s.ctx, defaultDialTimeout)
+		raw := make(chan json.RawMessage)
+		sub, err := s.client.Subscribe(ctx, s.namespace, raw, s.args...)
+		cancel()
+		if err == nil {
+			log.Debug("RPC subscription re-established", "namespace", s.namespace, "attempts", attempts)
+			return sub, raw, attempts, nil
+		}
+		if err == ErrClientQuit || s.ctx.Err() != nil {
+			return nil, nil, attempts, err
+		}
+		log.Trace("RPC resubscribe failed", "namespace", s.namespace, "attempts", attempts, "err", err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-s.ctx.Done():
+			timer.Stop()
+			return nil, nil, attempts, s.ctx.Err()
+		case <-s.client.closing:
+			timer.Stop()
+			return nil, nil, attempts, ErrClientQuit
+		}
+		if delay *= 2; delay > s.config.MaxDelay {
+			delay = s.config.MaxDelay
+		}
+	}
+}
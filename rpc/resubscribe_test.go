@@ -0,0 +1,191 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pipeDialer connects clients to an in-process server and allows dropping the
+// current connection.
+type pipeDialer struct {
+	server *Server
+	mu     sync.Mutex
+	conn   net.Conn
+	dials  int
+}
+
+func (d *pipeDialer) dial(context.Context) (ServerCodec, error) {
+	p1, p2 := net.Pipe()
+	go d.server.ServeCodec(NewCodec(p1), 0)
+	d.mu.Lock()
+	d.conn = p2
+	d.dials++
+	d.mu.Unlock()
+	return NewCodec(p2), nil
+}
+
+func (d *pipeDialer) drop() {
+	d.mu.Lock()
+	d.conn.Close()
+	d.mu.Unlock()
+}
+
+func TestResilientSubscribe(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	dialer := &pipeDialer{server: server}
+	client, err := newClient(context.Background(), dialer.dial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	nc := make(chan int)
+	cfg := ResubscribeConfig{Backoff: 10 * time.Millisecond}
+	sub, err := client.ResilientSubscribe(context.Background(), cfg, "nftest", nc, "someSubscription", 3, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	expect := func(want int) {
+		t.Helper()
+		select {
+		case v := <-nc:
+			if v != want {
+				t.Fatalf("wrong notification: got %d, want %d", v, want)
+			}
+		case err := <-sub.Err():
+			t.Fatal("subscription ended:", err)
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for notification", want)
+		}
+	}
+	for i := 10; i < 13; i++ {
+		expect(i)
+	}
+
+	dialer.drop()
+	select {
+	case gap := <-sub.Gaps():
+		if gap.Err == nil || gap.Attempts < 1 {
+			t.Errorf("bad gap marker: %+v", gap)
+		}
+		if string(gap.Last) != "12" {
+			t.Errorf("wrong last notification: got %s, want 12", gap.Last)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("subscription not re-established")
+	}
+	// The subscription is re-issued with the original arguments.
+	for i := 10; i < 13; i++ {
+		expect(i)
+	}
+	dialer.mu.Lock()
+	dials := dialer.dials
+	dialer.mu.Unlock()
+	if dials != 2 {
+		t.Errorf("wrong number of dials: got %d, want 2", dials)
+	}
+
+	sub.Unsubscribe()
+	if _, ok := <-sub.Err(); ok {
+		t.Error("error channel not closed after unsubscribe")
+	}
+}
+
+func TestResilientSubscribeClientClose(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	client := DialInProc(server)
+
+	nc := make(chan int)
+	sub, err := client.ResilientSubscribe(context.Background(), ResubscribeConfig{}, "nftest", nc, "someSubscription", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+	select {
+	case err := <-sub.Err():
+		if err != nil {
+			t.Fatal("wrong error after client close:", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("subscription did not end after client close")
+	}
+	sub.Unsubscribe()
+}
+
+// Markers left unreceived don't hold up the notifications, further gaps are merged
+// into them.
+func TestResilientSubscribeGapsMerged(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	dialer := &pipeDialer{server: server}
+	client, err := newClient(context.Background(), dialer.dial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	nc := make(chan int)
+	cfg := ResubscribeConfig{Backoff: 10 * time.Millisecond}
+	sub, err := client.ResilientSubscribe(context.Background(), cfg, "nftest", nc, "someSubscription", 3, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	for round := 0; round < 3; round++ {
+		if round > 0 {
+			dialer.drop()
+		}
+		for want := 10; want < 13; want++ {
+			select {
+			case v := <-nc:
+				if v != want {
+					t.Fatalf("round %d: wrong notification: got %d, want %d", round, v, want)
+				}
+			case err := <-sub.Err():
+				t.Fatalf("round %d: subscription ended: %v", round, err)
+			case <-time.After(2 * time.Second):
+				t.Fatalf("round %d: timed out waiting for notification %d", round, want)
+			}
+		}
+	}
+	select {
+	case gap := <-sub.Gaps():
+		if gap.Err == nil || gap.Attempts < 2 {
+			t.Errorf("gaps not merged: %+v", gap)
+		}
+		if string(gap.Last) != "12" {
+			t.Errorf("wrong last notification: got %s, want 12", gap.Last)
+		}
+	default:
+		t.Fatal("no gap marker queued")
+	}
+	select {
+	case gap := <-sub.Gaps():
+		t.Fatalf("second gap marker queued: %+v", gap)
+	default:
+	}
+}
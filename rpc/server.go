@@ -46,6 +46,8 @@ type Server struct {
 	idgen    func() ID
 	run      int32
 	codecs   mapset.Set
+	limiter  *Limiter
+	timeouts atomic.Value // *CallTimeouts
 }
 
 // NewServer creates a new server instance with no registered handlers.
@@ -66,12 +68,37 @@ func (s *Server) RegisterName(name string, receiver interface{}) error {
 	return s.services.registerName(name, receiver)
 }
 
+// SetLimiter makes the server enforce the limits of l on the calls it serves. It
+// must be called before serving any requests, l may be shared by several servers.
+func (s *Server) SetLimiter(l *Limiter) {
+	s.limiter = l
+}
+
+// SetCallTimeouts makes the server cancel method calls running longer than the
+// timeouts of t. It may be called while serving, connections opened before keep
+// the timeouts they started with.
+func (s *Server) SetCallTimeouts(t CallTimeouts) {
+	s.timeouts.Store(&t)
+}
+
+// handlerContext returns a copy of ctx carrying the settings of the server to the
+// handler of a connection.
+func (s *Server) handlerContext(ctx context.Context) context.Context {
+	timeouts, _ := s.timeouts.Load().(*CallTimeouts)
+	return withCallTimeouts(withLimiter(ctx, s.limiter), timeouts)
+}
+
 // ServeCodec reads incoming requests from codec, calls the appropriate callback and writes
 // the response back using the given codec. It will block until the codec is closed or the
 // server is stopped. In either case the codec is closed.
 //
 // Note that codec options are no longer supported.
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
+	s.serveCodec(context.Background(), codec)
+}
+
+// serveCodec serves codec, the calls made on it see the values of ctx.
+func (s *Server) serveCodec(ctx context.Context, codec ServerCodec) {
 	defer codec.close()
 
 	// Don't serve if server is stopped.
@@ -83,7 +110,7 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	s.codecs.Add(codec)
 	defer s.codecs.Remove(codec)
 
-	c := initClient(codec, s.idgen, &s.services)
+	c := initClient(s.handlerContext(ctx), codec, s.idgen, &s.services)
 	<-codec.closed()
 	c.Close()
 }
@@ -97,7 +124,7 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec) {
 		return
 	}
 
-	h := newHandler(ctx, codec, s.idgen, &s.services)
+	h := newHandler(s.handlerContext(ctx), codec, s.idgen, &s.services)
 	h.allowSubscribe = false
 	defer h.close(io.EOF, nil)
 
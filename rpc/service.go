@@ -94,6 +94,9 @@ func (r *serviceRegistry) registerName(name string, rcvr interface{}) error {
 
 // callback returns the callback corresponding to the given RPC method name.
 func (r *serviceRegistry) callback(method string) *callback {
+	if method == openRPCDiscover {
+		method = MetadataApi + serviceMethodSeparator + "discover"
+	}
 	elem := strings.SplitN(method, serviceMethodSeparator, 2)
 	if len(elem) != 2 {
 		return nil
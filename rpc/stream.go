@@ -0,0 +1,492 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+)
+
+const (
+	// streamBufferSize is the size of the buffer streamed responses are written through.
+	streamBufferSize = 32 * 1024
+
+	// streamHoldSize is how much of a streamed result is held back before sending it.
+	// A stream failing before is answered with just the error, a stream failing
+	// later closes the connection.
+	streamHoldSize = 1024 * 1024
+)
+
+var errStreamValue = errors.New("stream already has a value")
+
+// Stream is the result of a method encoded while it is written to the connection,
+// for results too large to be built in memory first. A method returns a Stream
+// instead of its result, the function writes the result to w when the response is
+// sent. Writing blocks while the client isn't reading. ctx is the context of the
+// call, its timeout and cancellation cover writing the result.
+//
+// The writes fail if the connection breaks, ctx is done or the response exceeds the
+// maximum size of the server. The function should then return the error, which is
+// sent to the client instead of the result. The first megabyte of a result is held
+// back for this, a stream failing after part of a larger result was sent closes the
+// connection instead.
+type Stream func(ctx context.Context, w *StreamWriter) error
+
+// StreamArray returns a Stream writing the n values returned by elem as an array.
+// Elements are produced while the array is written, so each may be built lazily.
+func StreamArray(n int, elem func(i int) interface{}) Stream {
+	return func(ctx context.Context, w *StreamWriter) error {
+		if err := w.BeginArray(); err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			if err := w.Value(elem(i)); err != nil {
+				return err
+			}
+		}
+		return w.EndArray()
+	}
+}
+
+// StreamWriter writes a single JSON value incrementally. Arrays and objects are
+// written between their Begin and End calls, the elements of arrays with Value,
+// the members of objects with Key followed by a value. The first error fails all
+// further writes, which return it again.
+type StreamWriter struct {
+	ctx   context.Context
+	w     io.Writer
+	limit int // maximum bytes written, zero if unlimited
+	n     int
+
+	stack    []streamLevel // open arrays and objects
+	wrote    bool          // top level value written
+	afterKey bool          // a key was written, its value is pending
+	err      error         // first error, fails all further writes
+	writeErr bool          // err occurred writing to w
+}
+
+type streamLevel struct {
+	object bool
+	empty  bool
+}
+
+// BeginArray opens an array.
+func (sw *StreamWriter) BeginArray() error {
+	return sw.open(false)
+}
+
+// EndArray closes the innermost array.
+func (sw *StreamWriter) EndArray() error {
+	return sw.close(false)
+}
+
+// BeginObject opens an object.
+func (sw *StreamWriter) BeginObject() error {
+	return sw.open(true)
+}
+
+// EndObject closes the innermost object.
+func (sw *StreamWriter) EndObject() error {
+	return sw.close(true)
+}
+
+// Key writes the key of the next member of the innermost object.
+func (sw *StreamWriter) Key(name string) error {
+	if sw.check() != nil {
+		return sw.err
+	}
+	if len(sw.stack) == 0 || !sw.stack[len(sw.stack)-1].object || sw.afterKey {
+		return sw.fail(errors.New("stream key outside of object"))
+	}
+	key, _ := json.Marshal(name)
+	if err := sw.reserve(sw.separatorLen() + len(key) + 1); err != nil {
+		return err
+	}
+	if err := sw.separate(); err != nil {
+		return err
+	}
+	if err := sw.write(append(key, ':')); err != nil {
+		return err
+	}
+	sw.afterKey = true
+	return nil
+}
+
+// Field writes a member of the innermost object.
+func (sw *StreamWriter) Field(name string, v interface{}) error {
+	if err := sw.Key(name); err != nil {
+		return err
+	}
+	return sw.Value(v)
+}
+
+// Value writes v, encoded like a method result. It is the whole value at the top
+// level, an element in arrays and the value of the last key in objects.
+func (sw *StreamWriter) Value(v interface{}) error {
+	if sw.check() != nil {
+		return sw.err
+	}
+	enc, err := json.Marshal(v)
+	if err != nil {
+		return sw.fail(err)
+	}
+	if err := sw.reserve(sw.separatorLen() + len(enc)); err != nil {
+		return err
+	}
+	if err := sw.beginValue(); err != nil {
+		return err
+	}
+	return sw.write(enc)
+}
+
+// Err returns the error that failed the writer, if any. It also reports whether the
+// context is done, for streams doing work between writes.
+func (sw *StreamWriter) Err() error {
+	return sw.check()
+}
+
+func (sw *StreamWriter) open(object bool) error {
+	if sw.check() != nil {
+		return sw.err
+	}
+	if err := sw.reserve(sw.separatorLen() + 1); err != nil {
+		return err
+	}
+	if err := sw.beginValue(); err != nil {
+		return err
+	}
+	delim := []byte{'['}
+	if object {
+		delim[0] = '{'
+	}
+	if err := sw.write(delim); err != nil {
+		return err
+	}
+	sw.stack = append(sw.stack, streamLevel{object: object, empty: true})
+	return nil
+}
+
+func (sw *StreamWriter) close(object bool) error {
+	if sw.check() != nil {
+		return sw.err
+	}
+	if len(sw.stack) == 0 || sw.stack[len(sw.stack)-1].object != object || sw.afterKey {
+		return sw.fail(errors.New("stream end without matching begin"))
+	}
+	sw.stack = sw.stack[:len(sw.stack)-1]
+	delim := []byte{']'}
+	if object {
+		delim[0] = '}'
+	}
+	return sw.write(delim)
+}
+
+// beginValue prepares writing a value in the current position.
+func (sw *StreamWriter) beginValue() error {
+	switch {
+	case len(sw.stack) == 0:
+		if sw.wrote {
+			return sw.fail(errStreamValue)
+		}
+		sw.wrote = true
+	case sw.stack[len(sw.stack)-1].object:
+		if !sw.afterKey {
+			return sw.fail(errors.New("stream value in object without key"))
+		}
+		sw.afterKey = false
+	default:
+		return sw.separate()
+	}
+	return nil
+}
+
+// separatorLen returns the length of the separator written before the next value
+// or key in the current position.
+func (sw *StreamWriter) separatorLen() int {
+	if len(sw.stack) == 0 || sw.afterKey || sw.stack[len(sw.stack)-1].empty {
+		return 0
+	}
+	return 1
+}
+
+// separate writes the comma before the next element of the innermost container.
+func (sw *StreamWriter) separate() error {
+	level := &sw.stack[len(sw.stack)-1]
+	if level.empty {
+		level.empty = false
+		return nil
+	}
+	return sw.write([]byte{','})
+}
+
+// check fails the writer if its context is done.
+func (sw *StreamWriter) check() error {
+	if sw.err == nil && sw.ctx.Err() != nil {
+		sw.fail(sw.ctx.Err())
+	}
+	return sw.err
+}
+
+func (sw *StreamWriter) fail(err error) error {
+	if sw.err == nil {
+		sw.err = err
+	}
+	return sw.err
+}
+
+// reserve fails if writing n more bytes exceeds the size limit. Nothing is written
+// then, so that the partial result stays well-formed.
+func (sw *StreamWriter) reserve(n int) error {
+	if sw.limit > 0 && sw.n+n > sw.limit {
+		rpcLimitedResponseMeter.Mark(1)
+		return sw.fail(&responseTooLargeError{sw.limit})
+	}
+	return nil
+}
+
+// write writes b. Callers reserve the size of values beforehand, closing open
+// arrays and objects isn't limited.
+func (sw *StreamWriter) write(b []byte) error {
+	sw.n += len(b)
+	if _, err := sw.w.Write(b); err != nil {
+		sw.writeErr = true
+		return sw.fail(err)
+	}
+	return nil
+}
+
+// terminate completes the value, closing all open arrays and objects. Values
+// missing are written as null.
+func (sw *StreamWriter) terminate() {
+	if !sw.wrote || sw.afterKey {
+		sw.w.Write(null)
+		sw.wrote, sw.afterKey = true, false
+	}
+	for i := len(sw.stack) - 1; i >= 0; i-- {
+		if sw.stack[i].object {
+			sw.w.Write([]byte{'}'})
+		} else {
+			sw.w.Write([]byte{']'})
+		}
+	}
+	sw.stack = nil
+}
+
+// holdWriter holds back the start of a message, its head and the first bytes of
+// the streamed result, until more than hold bytes of result were written. Until
+// then the message can be replaced.
+type holdWriter struct {
+	w    io.Writer
+	head []byte
+	buf  bytes.Buffer
+	hold int // bytes of result held back at most, negative to hold everything
+	sent bool
+}
+
+func (hw *holdWriter) Write(b []byte) (int, error) {
+	if !hw.sent {
+		if hw.hold < 0 || hw.buf.Len()+len(b) <= hw.hold {
+			return hw.buf.Write(b)
+		}
+		if err := hw.flush(); err != nil {
+			return 0, err
+		}
+	}
+	return hw.w.Write(b)
+}
+
+// flush sends what is held back, further writes pass through.
+func (hw *holdWriter) flush() error {
+	hw.sent = true
+	if _, err := hw.w.Write(hw.head); err != nil {
+		return err
+	}
+	_, err := hw.w.Write(hw.buf.Bytes())
+	hw.buf = bytes.Buffer{}
+	return err
+}
+
+// streamResult is the pending result of a call returning a Stream. The call lasts
+// until the result is written, the stream runs with the context of the call.
+type streamResult struct {
+	ctx    context.Context
+	cancel context.CancelFunc // ends the call timeout, nil if there's none
+	method string
+	fn     Stream
+	limit  int
+}
+
+// run writes the result to sw and ends the call. Streams failing because the call
+// timed out or was canceled return the same errors as other calls.
+func (sr *streamResult) run(sw *StreamWriter) error {
+	if sr.cancel != nil {
+		defer sr.cancel()
+	}
+	sw.ctx = sr.ctx
+	err := sr.fn(sr.ctx, sw)
+	if err == nil {
+		err = sw.err
+	}
+	if err != nil && !sw.writeErr {
+		err = callError(sr.ctx, sr.method, err)
+	}
+	return err
+}
+
+// hasStream reports whether v is a message or batch of messages containing a
+// streamed result.
+func hasStream(v interface{}) bool {
+	switch v := v.(type) {
+	case *jsonrpcMessage:
+		return v.stream != nil
+	case []*jsonrpcMessage:
+		for _, msg := range v {
+			if msg.stream != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeMessages writes the message or batch of messages v to w, streaming results.
+// Up to hold bytes of each result are held back, negative to hold whole results.
+func writeMessages(w io.Writer, v interface{}, hold int) error {
+	bw := bufio.NewWriterSize(w, streamBufferSize)
+	switch v := v.(type) {
+	case *jsonrpcMessage:
+		if err := writeMessage(bw, v, hold); err != nil {
+			return err
+		}
+	case []*jsonrpcMessage:
+		bw.WriteByte('[')
+		for i, msg := range v {
+			if i > 0 {
+				bw.WriteByte(',')
+			}
+			if err := writeMessage(bw, msg, hold); err != nil {
+				return err
+			}
+		}
+		bw.WriteByte(']')
+	}
+	bw.WriteByte('\n')
+	return bw.Flush()
+}
+
+// writeMessage writes msg to w. If the result stream fails while the result is held
+// back, a message with just the error is written instead. Otherwise the error is
+// returned, the message can't be completed.
+func writeMessage(w *bufio.Writer, msg *jsonrpcMessage, hold int) error {
+	if msg.stream == nil {
+		enc, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(enc)
+		return err
+	}
+	head := append([]byte(`{"jsonrpc":"2.0","id":`), msg.ID...)
+	hw := &holdWriter{w: w, head: append(head, `,"result":`...), hold: hold}
+	sw := &StreamWriter{w: hw, limit: msg.stream.limit}
+	err := msg.stream.run(sw)
+	switch {
+	case sw.writeErr:
+		return sw.err
+	case err != nil && hw.sent:
+		return err
+	case err != nil:
+		failed := errorMessage(err)
+		failed.ID = msg.ID
+		enc, _ := json.Marshal(failed)
+		_, err = w.Write(enc)
+		return err
+	}
+	sw.terminate()
+	hw.Write([]byte{'}'})
+	if !hw.sent {
+		return hw.flush()
+	}
+	return nil
+}
+
+// deadlineWriter extends the write deadline of a connection before each write,
+// so that streams may take longer than the write timeout as long as the client
+// keeps reading.
+type deadlineWriter struct {
+	io.Writer
+	ctx  context.Context
+	conn deadlineCloser
+}
+
+func (w *deadlineWriter) Write(b []byte) (int, error) {
+	deadline, ok := w.ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultWriteTimeout)
+	}
+	w.conn.SetWriteDeadline(deadline)
+	return w.Writer.Write(b)
+}
+
+// spoolWriter buffers a response up to limit bytes, so that it's produced without
+// holding the connection. Writing more, or flushing, opens the connection and writes
+// the buffered bytes to it, further writes pass through.
+type spoolWriter struct {
+	buf   bytes.Buffer
+	limit int
+	open  func() (io.Writer, error)
+	w     io.Writer // nil until opened
+	err   error     // error opening the connection
+}
+
+func (sw *spoolWriter) Write(b []byte) (int, error) {
+	if sw.w == nil && sw.err == nil && sw.buf.Len()+len(b) <= sw.limit {
+		return sw.buf.Write(b)
+	}
+	if err := sw.flush(); err != nil {
+		return 0, err
+	}
+	return sw.w.Write(b)
+}
+
+// flush opens the connection, if it isn't yet, and writes the buffered bytes.
+func (sw *spoolWriter) flush() error {
+	if sw.w != nil || sw.err != nil {
+		return sw.err
+	}
+	if sw.w, sw.err = sw.open(); sw.err != nil {
+		return sw.err
+	}
+	_, err := sw.w.Write(sw.buf.Bytes())
+	sw.buf = bytes.Buffer{}
+	return err
+}
+
+// materialize writes v to a buffer, for codecs that can't stream. Results are held
+// back whole, they end up in memory anyway.
+func materialize(v interface{}) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	if err := writeMessages(&buf, v, -1); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(buf.Bytes()), nil
+}
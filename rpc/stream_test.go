@@ -0,0 +1,330 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type streamService struct{}
+
+type streamItem struct {
+	N    int    `json:"n"`
+	Name string `json:"name"`
+}
+
+// Items streams n items.
+func (streamService) Items(n int) Stream {
+	return StreamArray(n, func(i int) interface{} {
+		return streamItem{i, strings.Repeat("x", 10)}
+	})
+}
+
+// Object streams an object with a nested array.
+func (streamService) Object() Stream {
+	return func(ctx context.Context, w *StreamWriter) error {
+		w.BeginObject()
+		w.Field("gas", 21000)
+		w.Key("logs")
+		w.BeginArray()
+		w.Value("a")
+		w.Value("b")
+		w.EndArray()
+		return w.EndObject()
+	}
+}
+
+// Failing fails after writing a partial result.
+func (streamService) Failing() Stream {
+	return func(ctx context.Context, w *StreamWriter) error {
+		w.BeginObject()
+		w.Key("logs")
+		w.BeginArray()
+		w.Value(1)
+		return errors.New("stream failed")
+	}
+}
+
+// Endless streams numbers until the writer fails.
+func (streamService) Endless() Stream {
+	return func(ctx context.Context, w *StreamWriter) error {
+		w.BeginArray()
+		for i := 0; ; i++ {
+			if err := w.Value(i); err != nil {
+				return err
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+// Large returns a result of n bytes without streaming.
+func (streamService) Large(n int) string {
+	return strings.Repeat("x", n)
+}
+
+func newStreamTestServer(limit int) *Server {
+	server := NewServer()
+	if err := server.RegisterName("stream", streamService{}); err != nil {
+		panic(err)
+	}
+	if limit > 0 {
+		server.SetLimiter(NewLimiter(LimitConfig{MaxResponseSize: limit}))
+	}
+	return server
+}
+
+func TestStreamTransports(t *testing.T) {
+	server := newStreamTestServer(0)
+	defer server.Stop()
+
+	wsClient, wsServer := httpTestClient(server, "ws", nil)
+	defer wsServer.Close()
+	httpClient, httpServer := httpTestClient(server, "http", nil)
+	defer httpServer.Close()
+	clients := map[string]*Client{"inproc": DialInProc(server), "ws": wsClient, "http": httpClient}
+
+	for name, client := range clients {
+		var items []streamItem
+		if err := client.Call(&items, "stream_items", 1000); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(items) != 1000 || items[999].N != 999 {
+			t.Fatalf("%s: wrong items: %d", name, len(items))
+		}
+		var obj map[string]interface{}
+		if err := client.Call(&obj, "stream_object"); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if want := map[string]interface{}{"gas": 21000.0, "logs": []interface{}{"a", "b"}}; !reflect.DeepEqual(obj, want) {
+			t.Errorf("%s: wrong object: %v", name, obj)
+		}
+		if err := client.Call(&obj, "stream_failing"); err == nil || err.Error() != "stream failed" {
+			t.Errorf("%s: wrong error of failing stream: %v", name, err)
+		}
+		batch := []BatchElem{
+			{Method: "stream_items", Args: []interface{}{3}, Result: new([]streamItem)},
+			{Method: "stream_large", Args: []interface{}{3}, Result: new(string)},
+			{Method: "stream_failing", Result: new(interface{})},
+		}
+		if err := client.BatchCall(batch); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if items := *batch[0].Result.(*[]streamItem); batch[0].Error != nil || len(items) != 3 {
+			t.Errorf("%s: wrong streamed batch result: %v %v", name, items, batch[0].Error)
+		}
+		if batch[1].Error != nil || *batch[1].Result.(*string) != "xxx" || batch[2].Error == nil {
+			t.Errorf("%s: wrong batch results: %v %v", name, batch[1].Error, batch[2].Error)
+		}
+		client.Close()
+	}
+}
+
+func TestStreamLimit(t *testing.T) {
+	server := newStreamTestServer(1000)
+	defer server.Stop()
+
+	wsClient, wsServer := httpTestClient(server, "ws", nil)
+	defer wsServer.Close()
+	defer wsClient.Close()
+	client := DialInProc(server)
+	defer client.Close()
+
+	for name, client := range map[string]*Client{"inproc": client, "ws": wsClient} {
+		var items []streamItem
+		err := client.Call(&items, "stream_items", 1000)
+		if rpcErr, ok := err.(Error); !ok || rpcErr.ErrorCode() != -32006 {
+			t.Fatalf("%s: wrong error for streamed response over the limit: %v", name, err)
+		}
+		var large string
+		err = client.Call(&large, "stream_large", 2000)
+		if rpcErr, ok := err.(Error); !ok || rpcErr.ErrorCode() != -32006 {
+			t.Fatalf("%s: wrong error for response over the limit: %v", name, err)
+		}
+		// The connection is still usable.
+		if err := client.Call(&items, "stream_items", 10); err != nil || len(items) != 10 {
+			t.Fatalf("%s: call after aborted stream failed: %v", name, err)
+		}
+	}
+}
+
+func TestStreamTimeout(t *testing.T) {
+	server := newStreamTestServer(0)
+	defer server.Stop()
+	server.SetCallTimeouts(CallTimeouts{Namespaces: map[string]time.Duration{"stream": 100 * time.Millisecond}})
+
+	wsClient, wsServer := httpTestClient(server, "ws", nil)
+	defer wsServer.Close()
+	defer wsClient.Close()
+	httpClient, httpServer := httpTestClient(server, "http", nil)
+	defer httpServer.Close()
+	defer httpClient.Close()
+
+	for name, client := range map[string]*Client{"ws": wsClient, "http": httpClient} {
+		// The method returns at once, the stream runs into the timeout of the call.
+		start := time.Now()
+		err := client.Call(nil, "stream_endless")
+		if rpcErr, ok := err.(Error); !ok || rpcErr.ErrorCode() != -32002 {
+			t.Fatalf("%s: stream not timed out: %v", name, err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Fatalf("%s: stream timed out after %v", name, elapsed)
+		}
+		var items []streamItem
+		if err := client.Call(&items, "stream_items", 10); err != nil || len(items) != 10 {
+			t.Fatalf("%s: call after timed out stream failed: %v", name, err)
+		}
+	}
+}
+
+func TestStreamWriterAbort(t *testing.T) {
+	msg := &jsonrpcMessage{ID: json.RawMessage("1"), stream: &streamResult{ctx: context.Background(), fn: func(ctx context.Context, w *StreamWriter) error {
+		w.BeginObject()
+		w.Field("a", []int{1})
+		w.Key("b")
+		if err := w.Value(func() {}); err == nil {
+			t.Error("no error for unencodable value")
+		}
+		if err := w.Value(1); err == nil {
+			t.Error("no error writing after failure")
+		}
+		return nil
+	}}}
+	var buf bytes.Buffer
+	bw := bufio.NewWriter(&buf)
+	if err := writeMessage(bw, msg, streamHoldSize); err != nil {
+		t.Fatal(err)
+	}
+	bw.Flush()
+
+	var resp map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response %s: %v", buf.Bytes(), err)
+	}
+	if _, ok := resp["result"]; ok || resp["error"] == nil || string(resp["id"]) != "1" {
+		t.Errorf("wrong aborted response: %s", buf.Bytes())
+	}
+
+	// Once part of the result was sent, the message can't be replaced.
+	buf.Reset()
+	bw.Reset(&buf)
+	if err := writeMessage(bw, msg, 4); err == nil {
+		t.Errorf("no error for stream failing after sending: %s", buf.Bytes())
+	}
+
+	// A larger size limit doesn't hold back more.
+	buf.Reset()
+	bw.Reset(&buf)
+	msg.stream = &streamResult{ctx: context.Background(), fn: StreamArray(100, func(i int) interface{} { return i }), limit: 100}
+	if err := writeMessage(bw, msg, 4); err == nil {
+		t.Errorf("no error for stream exceeding the limit after sending: %s", buf.Bytes())
+	}
+}
+
+func TestStreamFailureAfterSending(t *testing.T) {
+	server := NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("stream", streamFailingLarge{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, transport := range []string{"ws", "http"} {
+		client, hs := httpTestClient(server, transport, nil)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		var result interface{}
+		err := client.CallContext(ctx, &result, "stream_large")
+		if err == nil || err == context.DeadlineExceeded {
+			t.Errorf("%s: wrong error for stream failing after sending: %v", transport, err)
+		}
+		cancel()
+		client.Close()
+		hs.Close()
+	}
+}
+
+type streamFailingLarge struct{}
+
+// Large fails after writing more than is held back.
+func (streamFailingLarge) Large() Stream {
+	return func(ctx context.Context, w *StreamWriter) error {
+		w.BeginArray()
+		w.Value(strings.Repeat("x", streamHoldSize))
+		return errors.New("stream failed")
+	}
+}
+
+type streamBlocking struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+// Blocking writes a partial result and waits to be released.
+func (s *streamBlocking) Blocking() Stream {
+	return func(ctx context.Context, w *StreamWriter) error {
+		w.BeginArray()
+		w.Value(1)
+		s.started <- struct{}{}
+		<-s.release
+		return w.EndArray()
+	}
+}
+
+// Items streams n numbers.
+func (s *streamBlocking) Items(n int) Stream {
+	return StreamArray(n, func(i int) interface{} { return i })
+}
+
+func TestStreamConcurrentCalls(t *testing.T) {
+	service := &streamBlocking{started: make(chan struct{}, 1), release: make(chan struct{})}
+	server := NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("stream", service); err != nil {
+		t.Fatal(err)
+	}
+	wsClient, wsServer := httpTestClient(server, "ws", nil)
+	defer wsServer.Close()
+	clients := map[string]*Client{"inproc": DialInProc(server), "ws": wsClient}
+
+	for name, client := range clients {
+		blocked := make(chan error, 1)
+		go func() {
+			var result []int
+			blocked <- client.Call(&result, "stream_blocking")
+		}()
+		<-service.started
+
+		// The blocked stream doesn't hold up other calls on the connection.
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		var items []int
+		if err := client.CallContext(ctx, &items, "stream_items", 3); err != nil || len(items) != 3 {
+			t.Fatalf("%s: call blocked by stream: %v", name, err)
+		}
+		cancel()
+		service.release <- struct{}{}
+		if err := <-blocked; err != nil {
+			t.Fatalf("%s: blocked stream failed: %v", name, err)
+		}
+		client.Close()
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// cancelMethod is the notification a client sends to cancel calls it no longer
+// waits for. Its parameter is the array of the IDs of the calls.
+const cancelMethod = "rpc_cancel"
+
+// cancelNotifyTimeout bounds the time a client spends sending a cancellation.
+const cancelNotifyTimeout = 5 * time.Second
+
+// CallTimeouts configures the time a server lets method calls run. The context of a
+// call is canceled when its timeout elapses, and the call is answered with a timeout
+// error if it fails afterwards. Zero values disable the respective timeout.
+type CallTimeouts struct {
+	Default    time.Duration            // calls of namespaces not listed
+	Namespaces map[string]time.Duration // calls of a namespace, e.g. "debug"
+}
+
+// timeout returns the timeout of calls to method.
+func (t *CallTimeouts) timeout(method string) time.Duration {
+	if t == nil {
+		return 0
+	}
+	msg := jsonrpcMessage{Method: method}
+	if timeout, ok := t.Namespaces[msg.namespace()]; ok {
+		return timeout
+	}
+	return t.Default
+}
+
+type callTimeoutsKey struct{}
+
+// withCallTimeouts returns a copy of ctx carrying t to the handlers of a server.
+func withCallTimeouts(ctx context.Context, t *CallTimeouts) context.Context {
+	if t == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, callTimeoutsKey{}, t)
+}
+
+// trackCalls creates the contexts of the calls msgs. The contexts are registered
+// under the IDs of the calls until the returned function releases them, so that
+// clients can cancel the calls with a cancelMethod notification, also while they're
+// queued in a batch. Calls are tracked before their call proc starts, cancellations
+// sent right after them may not miss them.
+func (h *handler) trackCalls(msgs []*jsonrpcMessage) ([]context.Context, func()) {
+	var (
+		ctxs    = make([]context.Context, len(msgs))
+		cancels = make([]context.CancelFunc, len(msgs))
+		ids     []string
+	)
+	h.inflightMu.Lock()
+	for i, msg := range msgs {
+		ctxs[i], cancels[i] = context.WithCancel(h.rootCtx)
+		if !msg.isCall() {
+			continue
+		}
+		id := string(msg.ID)
+		if _, dup := h.inflight[id]; dup {
+			// Calls reusing the ID of a pending call can't be canceled.
+			continue
+		}
+		h.inflight[id] = cancels[i]
+		ids = append(ids, id)
+	}
+	h.inflightMu.Unlock()
+
+	return ctxs, func() {
+		h.inflightMu.Lock()
+		for _, id := range ids {
+			delete(h.inflight, id)
+		}
+		h.inflightMu.Unlock()
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}
+}
+
+// handleCancel cancels the calls listed by a cancelMethod notification. Unknown IDs
+// belong to calls that have already been answered, they're ignored.
+func (h *handler) handleCancel(msg *jsonrpcMessage) {
+	var ids []json.RawMessage
+	if err := json.Unmarshal(msg.Params, &ids); err != nil {
+		h.log.Debug("Dropping invalid cancellation", "err", err)
+		return
+	}
+	h.inflightMu.Lock()
+	defer h.inflightMu.Unlock()
+	for _, id := range ids {
+		if cancel := h.inflight[string(id)]; cancel != nil {
+			rpcCanceledMeter.Mark(1)
+			cancel()
+		}
+	}
+}
+
+// callError returns the error answering a call with context ctx that failed with
+// err. Calls failing after their context is done are answered with the reason.
+func callError(ctx context.Context, method string, err error) error {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		rpcTimedOutMeter.Mark(1)
+		return &timeoutError{method}
+	case context.Canceled:
+		return &canceledError{method}
+	}
+	return err
+}
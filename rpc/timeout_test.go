@@ -0,0 +1,106 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCallTimeouts(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	server.SetCallTimeouts(CallTimeouts{
+		Default:    time.Hour,
+		Namespaces: map[string]time.Duration{"test": 50 * time.Millisecond, "rpc": 0},
+	})
+	client := DialInProc(server)
+	defer client.Close()
+
+	err := client.Call(nil, "test_block")
+	if rerr, ok := err.(Error); !ok || rerr.ErrorCode() != -32002 {
+		t.Fatalf("call not timed out: %v", err)
+	}
+	if err := client.Call(nil, "test_noArgsRets"); err != nil {
+		t.Fatal(err)
+	}
+	if timeout := server.handlerContext(context.Background()).Value(callTimeoutsKey{}).(*CallTimeouts).timeout("rpc_modules"); timeout != 0 {
+		t.Fatalf("namespace without timeout has timeout %v", timeout)
+	}
+}
+
+type cancelService struct {
+	started chan struct{}
+	stopped chan error
+}
+
+func (s *cancelService) Wait(ctx context.Context) error {
+	s.started <- struct{}{}
+	<-ctx.Done()
+	s.stopped <- ctx.Err()
+	return ctx.Err()
+}
+
+func TestCallCancel(t *testing.T) {
+	service := &cancelService{started: make(chan struct{}, 2), stopped: make(chan error, 2)}
+	server := NewServer()
+	defer server.Stop()
+	server.RegisterName("cancel", service)
+	client := DialInProc(server)
+	defer client.Close()
+
+	checkStopped := func(n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			select {
+			case err := <-service.stopped:
+				if err != context.Canceled {
+					t.Fatalf("wrong context error in handler: %v", err)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("call not canceled on the server")
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- client.CallContext(ctx, nil, "cancel_wait") }()
+	<-service.started
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Fatalf("wrong error from canceled call: %v", err)
+	}
+	checkStopped(1)
+
+	// canceling a batch also cancels the calls queued behind the running one
+	ctx, cancel = context.WithCancel(context.Background())
+	batch := []BatchElem{{Method: "cancel_wait"}, {Method: "cancel_wait"}}
+	go func() { errc <- client.BatchCallContext(ctx, batch) }()
+	<-service.started
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Fatalf("wrong error from canceled batch: %v", err)
+	}
+	checkStopped(2)
+
+	// the connection serves further calls
+	if err := client.Call(nil, "rpc_modules"); err != nil {
+		t.Fatal(err)
+	}
+}
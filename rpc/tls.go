@@ -0,0 +1,83 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// ClientTLSConfig creates the TLS configuration of a client. The server certificate
+// is verified against the PEM encoded CA certificates in caFile, or the system roots
+// if caFile is empty. If certFile and keyFile are set, the client presents the
+// certificate they contain to servers requiring client certificates.
+func ClientTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates in CA file")
+		}
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// DialTLS creates a new RPC client connecting to an https or wss endpoint with the
+// given TLS configuration. If creds is non-nil, the client also authenticates with
+// them like clients created by DialWithCredentials.
+func DialTLS(ctx context.Context, rawurl string, config *tls.Config, creds Credentials) (*Client, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "https":
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = config
+		c, err := DialHTTPWithClient(rawurl, &http.Client{Transport: transport})
+		if err != nil {
+			return nil, err
+		}
+		if creds != nil {
+			c.SetCredentials(creds)
+		}
+		return c, nil
+	case "wss":
+		dialer := newWebsocketDialer()
+		dialer.TLSClientConfig = config
+		return dialWebsocket(ctx, rawurl, "", dialer, creds)
+	default:
+		return nil, fmt.Errorf("no TLS support for URL scheme %q", u.Scheme)
+	}
+}
@@ -19,6 +19,7 @@ package rpc
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"strings"
@@ -47,6 +48,25 @@ type DataError interface {
 	ErrorData() interface{} // returns the error data
 }
 
+// DecodeErrorData decodes the data of an error returned by a call into v, which
+// must be a pointer. The data of errors returned by Client calls is decoded as
+// sent by the server. It returns false if err carries no data.
+func DecodeErrorData(err error, v interface{}) (bool, error) {
+	var je *jsonError
+	if errors.As(err, &je) && je.rawData != nil {
+		return true, json.Unmarshal(je.rawData, v)
+	}
+	var de DataError
+	if !errors.As(err, &de) || de.ErrorData() == nil {
+		return false, nil
+	}
+	enc, encErr := json.Marshal(de.ErrorData())
+	if encErr != nil {
+		return true, encErr
+	}
+	return true, json.Unmarshal(enc, v)
+}
+
 // ServerCodec implements reading, parsing and writing RPC messages for the server side of
 // a RPC session. Implementations must be go-routine safe since the codec can be called in
 // multiple go-routines concurrently.
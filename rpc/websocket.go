@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
@@ -59,7 +60,7 @@ func (s *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 			return
 		}
 		codec := newWebsocketCodec(conn)
-		s.ServeCodec(codec, 0)
+		s.serveCodec(connContext(r.Context()), codec)
 	})
 }
 
@@ -123,11 +124,26 @@ func (e wsHandshakeError) Error() string {
 // DialWebsocketWithDialer creates a new RPC client that communicates with a JSON-RPC server
 // that is listening on the given endpoint using the provided dialer.
 func DialWebsocketWithDialer(ctx context.Context, endpoint, origin string, dialer websocket.Dialer) (*Client, error) {
+	return dialWebsocket(ctx, endpoint, origin, dialer, nil)
+}
+
+// dialWebsocket dials endpoint, authenticating the connection with creds if
+// they are non-nil.
+func dialWebsocket(ctx context.Context, endpoint, origin string, dialer websocket.Dialer, creds Credentials) (*Client, error) {
 	endpoint, header, err := wsClientHeaders(endpoint, origin)
 	if err != nil {
 		return nil, err
 	}
 	return newClient(ctx, func(ctx context.Context) (ServerCodec, error) {
+		header := header
+		if creds != nil {
+			auth, err := creds.Authorization()
+			if err != nil {
+				return nil, err
+			}
+			header = header.Clone()
+			header.Set("authorization", auth)
+		}
 		conn, resp, err := dialer.DialContext(ctx, endpoint, header)
 		if err != nil {
 			hErr := wsHandshakeError{err: err}
@@ -146,12 +162,15 @@ func DialWebsocketWithDialer(ctx context.Context, endpoint, origin string, diale
 // The context is used for the initial connection establishment. It does not
 // affect subsequent interactions with the client.
 func DialWebsocket(ctx context.Context, endpoint, origin string) (*Client, error) {
-	dialer := websocket.Dialer{
+	return DialWebsocketWithDialer(ctx, endpoint, origin, newWebsocketDialer())
+}
+
+func newWebsocketDialer() websocket.Dialer {
+	return websocket.Dialer{
 		ReadBufferSize:  wsReadBuffer,
 		WriteBufferSize: wsWriteBuffer,
 		WriteBufferPool: wsBufferPool,
 	}
-	return DialWebsocketWithDialer(ctx, endpoint, origin, dialer)
 }
 
 func wsClientHeaders(endpoint, origin string) (string, http.Header, error) {
@@ -186,6 +205,7 @@ func newWebsocketCodec(conn *websocket.Conn) ServerCodec {
 		conn:      conn,
 		pingReset: make(chan struct{}, 1),
 	}
+	wc.nextWriter = func() (io.WriteCloser, error) { return conn.NextWriter(websocket.TextMessage) }
 	wc.wg.Add(1)
 	go wc.pingLoop()
 	return wc